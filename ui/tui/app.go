@@ -17,20 +17,71 @@ import (
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "audit" {
+		os.Exit(runAuditCLI(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "events" {
+		os.Exit(runEventsCLI(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "serve" {
+		os.Exit(runServeCLI(os.Args[2:]))
+	}
+
 	var smoke bool
 	var serve bool
+	var jsonMode bool
+	var eventsSocketFlag string
 	var sessionOverride string
+	var tuiHeightFlag string
+	var listenFlag string
+	var recordFlag string
+	var replayFlag string
+	var speedFlag float64
+	var untilFlag string
+	var otlpEndpointFlag string
 	flag.BoolVar(&smoke, "smoke", false, "run deterministic non-interactive smoke simulation")
 	flag.BoolVar(&serve, "serve", false, "run headless command-bus driven session (for CLI/devops control)")
+	flag.BoolVar(&jsonMode, "json", false, "open the hooks event socket at the default path (stateDir/<sessionID>/hooks.sock) for line-delimited JSON events in and busCommand JSON in")
+	flag.StringVar(&eventsSocketFlag, "events-socket", "", "open the hooks event socket at this unix:/path instead of the default (implies --json)")
 	flag.StringVar(&sessionOverride, "session-id", "", "override session id (for dev sessions)")
+	flag.StringVar(&listenFlag, "listen", "", "bind a JSON-RPC 2.0 control API (busCommand verbs as request/response) to this comma-separated list of unix:/path and/or tcp:host:port addresses (default WORKBENCH_BUS_LISTEN)")
+	flag.StringVar(&tuiHeightFlag, "tui-height", "", "render into a bounded bottom region instead of full-screen, e.g. 30% or 20 (default: WORKBENCH_TUI_HEIGHT, or 60% under tmux)")
+	flag.StringVar(&recordFlag, "record", "", "append every accepted bus command to this file as a JSONL session tape (see --replay)")
+	flag.StringVar(&replayFlag, "replay", "", "feed a tape captured with --record back through the command bus")
+	flag.Float64Var(&speedFlag, "speed", 1, "replay cadence multiplier (higher is faster; <=0 replays as fast as possible, ignoring recorded timing)")
+	flag.StringVar(&untilFlag, "until", "", "stop replay just before the first command of this type (e.g. stop)")
+	flag.StringVar(&otlpEndpointFlag, "otlp-endpoint", "", "mirror session events to this OTLP/HTTP logs endpoint (default WORKBENCH_OTLP_ENDPOINT)")
 	flag.Parse()
 
+	otlpEndpoint := strings.TrimSpace(otlpEndpointFlag)
+	if otlpEndpoint == "" {
+		otlpEndpoint = strings.TrimSpace(os.Getenv("WORKBENCH_OTLP_ENDPOINT"))
+	}
+
+	var replayRecords []tapeRecord
+	if strings.TrimSpace(replayFlag) != "" {
+		recs, err := loadTape(replayFlag)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "replay:", err)
+			os.Exit(1)
+		}
+		replayRecords = recs
+	}
+
 	stateDir := os.Getenv("WORKBENCH_STATE_DIR")
 	if strings.TrimSpace(stateDir) == "" {
 		stateDir = ".workbench"
 	}
 
-	disableNetwork := envBool("WORKBENCH_TUI_DISABLE_NETWORK") || ((smoke || serve) && !envBool("WORKBENCH_TUI_ENABLE_NETWORK"))
+	eventsSocketPath := strings.TrimPrefix(strings.TrimSpace(eventsSocketFlag), "unix:")
+	jsonMode = jsonMode || eventsSocketPath != ""
+
+	controlListen := strings.TrimSpace(listenFlag)
+	if controlListen == "" {
+		controlListen = strings.TrimSpace(os.Getenv("WORKBENCH_BUS_LISTEN"))
+	}
+
+	disableNetwork := envBool("WORKBENCH_TUI_DISABLE_NETWORK") || ((smoke || serve) && !envBool("WORKBENCH_TUI_ENABLE_NETWORK") && !jsonMode)
 
 	sessionID := strings.TrimSpace(sessionOverride)
 	if sessionID == "" {
@@ -47,6 +98,16 @@ func main() {
 	}
 	mcpConnected := readMcpConnectedCount(stateDir)
 
+	tuiHeightRows := 0
+	if !smoke && !serve {
+		if spec := resolveTUIHeightSpec(tuiHeightFlag); spec != "" {
+			_, termHeight := terminalSize()
+			if rows, ok := parseTUIHeightRows(spec, termHeight); ok {
+				tuiHeightRows = rows
+			}
+		}
+	}
+
 	m := newAppModel(appConfig{
 		stateDir:      stateDir,
 		sessionID:     sessionID,
@@ -64,8 +125,22 @@ func main() {
 		opencodeRequestsPath:  filepath.Join(stateDir, sessionID, "opencode.requests.jsonl"),
 		opencodeResponsesPath: filepath.Join(stateDir, sessionID, "opencode.responses.jsonl"),
 		opencodeEventsPath:    filepath.Join(stateDir, sessionID, "opencode.events.jsonl"),
+		tuiHeightRows:         tuiHeightRows,
+		eventsSocketPath:      eventsSocketPath,
+		controlListen:         controlListen,
+		tapePath:              strings.TrimSpace(recordFlag),
+		replayRecords:         replayRecords,
+		replaySpeed:           speedFlag,
+		replayUntil:           untilFlag,
+		otlpEndpoint:          otlpEndpoint,
 	})
 
+	if jsonMode {
+		if path := m.hookBus.SocketPath(); path != "" {
+			fmt.Fprintf(os.Stderr, "events socket: unix:%s\n", path)
+		}
+	}
+
 	if smoke {
 		outDir := os.Getenv("WORKBENCH_TUI_SMOKE_OUT_DIR")
 		if strings.TrimSpace(outDir) == "" {
@@ -98,8 +173,15 @@ func main() {
 		return
 	}
 
-	p := tea.NewProgram(m, tea.WithAltScreen())
+	opts := []tea.ProgramOption{tea.WithMouseCellMotion()}
+	if tuiHeightRows <= 0 {
+		opts = append(opts, tea.WithAltScreen())
+	}
+	p := tea.NewProgram(m, opts...)
 	finalModel, err := p.Run()
+	if tuiHeightRows > 0 {
+		clearHeightModeRegion(tuiHeightRows)
+	}
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
@@ -160,7 +242,7 @@ func runSmoke(m appModel) smokeReport {
 	}
 	model, _ = model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("d")})
 	if am, ok := model.(appModel); ok {
-		items := filteredCommandPaletteItems(am.commandPaletteNamespace, am.commandPaletteQuery)
+		items := filteredCommandPaletteItems(am.cmdRegistry, am.commandPaletteNamespace, am.commandPaletteQuery, am.recentCommands)
 		systemPaletteHasDocker = len(items) > 0 && items[0].cmd == "docker"
 	}
 	model, _ = model.Update(tea.KeyMsg{Type: tea.KeyEscape})
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// auditRecord is one entry of stateDir/<sessionID>/audit.jsonl: every
+// busCommand applyBusCommand accepts, every synthetic key
+// applySyntheticKey resolves, every outbound Codex request/response
+// codexChatStream drives, and every systemAlert, in a hash chain where each
+// record's Hash covers its own fields (with Hash itself blanked) plus
+// PrevHash, so altering or dropping any prior line changes every hash after
+// it. The first record's PrevHash seeds from the session ID instead of a
+// zero value, so a chain can't be silently replaced by a fresh, shorter one
+// rooted at the same all-zero genesis.
+type auditRecord struct {
+	Seq           uint64 `json:"seq"`
+	Timestamp     string `json:"timestamp"`
+	Type          string `json:"type"`
+	Source        string `json:"source,omitempty"`
+	Payload       any    `json:"payload,omitempty"`
+	CorrelationID string `json:"correlation_id,omitempty"`
+	PrevHash      string `json:"prevHash"`
+	Hash          string `json:"hash"`
+}
+
+type auditLogger struct {
+	path string
+
+	mu       sync.Mutex
+	seq      uint64
+	lastHash string
+}
+
+// newAuditLogger opens stateDir/<sessionID>/audit.jsonl, resuming the hash
+// chain from its last record if it already has one (so a session that
+// reopens the same audit log doesn't fork the chain).
+func newAuditLogger(stateDir string, sessionID string) *auditLogger {
+	if strings.TrimSpace(sessionID) == "" {
+		sessionID = "sess_unknown"
+	}
+	dir := filepath.Join(stateDir, sessionID)
+	_ = os.MkdirAll(dir, 0o755)
+	path := filepath.Join(dir, "audit.jsonl")
+	seq, lastHash := auditChainTail(path, sessionID)
+	return &auditLogger{path: path, seq: seq, lastHash: lastHash}
+}
+
+// auditSeedHash is the PrevHash the first record of a session's chain seeds
+// from, derived from the session ID so two empty chains for different
+// sessions don't start identically.
+func auditSeedHash(sessionID string) string {
+	sum := sha256.Sum256([]byte("workbench.audit.v1:" + sessionID))
+	return hex.EncodeToString(sum[:])
+}
+
+func auditChainTail(path string, sessionID string) (uint64, string) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, auditSeedHash(sessionID)
+	}
+	defer f.Close()
+
+	seq := uint64(0)
+	hash := auditSeedHash(sessionID)
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var rec auditRecord
+		if json.Unmarshal([]byte(line), &rec) != nil {
+			continue
+		}
+		seq = rec.Seq
+		hash = rec.Hash
+	}
+	return seq, hash
+}
+
+// auditRecordHash hashes rec's canonical JSON with Hash itself blanked, so
+// the stored Hash is exactly "sha256(everything else, including PrevHash)".
+func auditRecordHash(rec auditRecord) string {
+	rec.Hash = ""
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// Append chains and persists one audit record. It is safe to call from the
+// async goroutines codexChatStream's caller runs on, the same way
+// appendCodexEvent already is.
+func (l *auditLogger) Append(eventType string, source string, payload any, correlationID string) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	rec := auditRecord{
+		Seq:           l.seq + 1,
+		Timestamp:     time.Now().UTC().Format(time.RFC3339Nano),
+		Type:          eventType,
+		Source:        source,
+		Payload:       payload,
+		CorrelationID: correlationID,
+		PrevHash:      l.lastHash,
+	}
+	rec.Hash = auditRecordHash(rec)
+
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return
+	}
+	f, err := os.OpenFile(l.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return
+	}
+	_, werr := f.Write(append(b, '\n'))
+	_ = f.Close()
+	if werr != nil {
+		return
+	}
+	l.seq = rec.Seq
+	l.lastHash = rec.Hash
+}
+
+// auditVerifyResult is what "//verify audit" reports.
+type auditVerifyResult struct {
+	OK       bool
+	Total    int
+	BrokenAt int // 1-based record number of the first break, 0 if OK
+	Reason   string
+}
+
+// verifyAuditChain walks stateDir/<sessionID>/audit.jsonl from the session
+// seed hash, recomputing each record's hash and checking it both matches
+// what's stored and chains from the previous record's hash. A missing file
+// is a trivially valid (empty) chain.
+func verifyAuditChain(stateDir string, sessionID string) auditVerifyResult {
+	path := filepath.Join(stateDir, sessionID, "audit.jsonl")
+	f, err := os.Open(path)
+	if err != nil {
+		return auditVerifyResult{OK: true}
+	}
+	defer f.Close()
+
+	prevHash := auditSeedHash(sessionID)
+	total := 0
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		total++
+		var rec auditRecord
+		if err := json.Unmarshal([]byte(line), &rec); err != nil {
+			return auditVerifyResult{OK: false, Total: total, BrokenAt: total, Reason: fmt.Sprintf("malformed record: %s", err)}
+		}
+		if rec.PrevHash != prevHash {
+			return auditVerifyResult{OK: false, Total: total, BrokenAt: total, Reason: "prevHash does not match the preceding record"}
+		}
+		if want := auditRecordHash(rec); want != rec.Hash {
+			return auditVerifyResult{OK: false, Total: total, BrokenAt: total, Reason: "hash does not match record contents"}
+		}
+		prevHash = rec.Hash
+	}
+	return auditVerifyResult{OK: true, Total: total}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// runAuditCLI handles "workbench audit export --session <id>", producing a
+// tarball of audit.jsonl alongside the other per-session JSONL buses and a
+// manifest.json summarizing the chain, analogous to how enhanced session
+// recording packages exec/open/tcp events alongside session metadata. It is
+// dispatched directly from main() before flag.Parse() touches the TUI's own
+// flags, since "audit" is a subcommand rather than one of them.
+func runAuditCLI(args []string) int {
+	if len(args) == 0 || args[0] != "export" {
+		fmt.Fprintln(os.Stderr, "usage: workbench audit export --session <id> [--out <path>]")
+		return 2
+	}
+	fs := flag.NewFlagSet("audit export", flag.ContinueOnError)
+	sessionID := fs.String("session", "", "session id to export")
+	out := fs.String("out", "", "output tarball path (default stateDir/<sessionID>/audit-export.tar.gz)")
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2
+	}
+	if strings.TrimSpace(*sessionID) == "" {
+		fmt.Fprintln(os.Stderr, "audit export: --session is required")
+		return 2
+	}
+	stateDir := os.Getenv("WORKBENCH_STATE_DIR")
+	if strings.TrimSpace(stateDir) == "" {
+		stateDir = ".workbench"
+	}
+	outPath := strings.TrimSpace(*out)
+	if outPath == "" {
+		outPath = filepath.Join(stateDir, *sessionID, "audit-export.tar.gz")
+	}
+	if err := exportAudit(stateDir, *sessionID, outPath); err != nil {
+		fmt.Fprintln(os.Stderr, "audit export:", err)
+		return 1
+	}
+	fmt.Println(outPath)
+	return 0
+}
+
+// exportAudit packages audit.jsonl, the Codex request/response/event buses,
+// and commands.jsonl from stateDir/<sessionID>/ into a gzipped tarball at
+// outPath, alongside a manifest.json recording each file's record count and
+// the audit chain's terminal hash (or its first break, so an export still
+// honestly reports a tampered chain instead of hiding it). Missing files
+// (e.g. a session that never used the Codex runtime) are skipped rather
+// than failing the export.
+func exportAudit(stateDir string, sessionID string, outPath string) error {
+	dir := filepath.Join(stateDir, sessionID)
+	files := []string{"audit.jsonl", "codex.requests.jsonl", "codex.responses.jsonl", "codex.events.jsonl", "commands.jsonl"}
+
+	if err := os.MkdirAll(filepath.Dir(outPath), 0o755); err != nil {
+		return err
+	}
+	f, err := os.Create(outPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	gz := gzip.NewWriter(f)
+	defer gz.Close()
+	tw := tar.NewWriter(gz)
+	defer tw.Close()
+
+	counts := map[string]int{}
+	for _, name := range files {
+		raw, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			continue
+		}
+		counts[name] = countJSONLRecords(raw)
+		if err := writeTarFile(tw, name, raw); err != nil {
+			return err
+		}
+	}
+
+	result := verifyAuditChain(stateDir, sessionID)
+	manifest := map[string]any{
+		"sessionId":  sessionID,
+		"exportedAt": time.Now().UTC().Format(time.RFC3339),
+		"files":      counts,
+		"auditOK":    result.OK,
+		"auditTotal": result.Total,
+	}
+	if !result.OK {
+		manifest["auditBrokenAt"] = result.BrokenAt
+		manifest["auditBrokenReason"] = result.Reason
+	} else if result.Total > 0 {
+		_, lastHash := auditChainTail(filepath.Join(dir, "audit.jsonl"), sessionID)
+		manifest["auditTerminalHash"] = lastHash
+	}
+	manifestBytes, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeTarFile(tw, "manifest.json", manifestBytes)
+}
+
+func writeTarFile(tw *tar.Writer, name string, content []byte) error {
+	hdr := &tar.Header{Name: name, Mode: 0o644, Size: int64(len(content))}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err := tw.Write(content)
+	return err
+}
+
+func countJSONLRecords(raw []byte) int {
+	n := 0
+	for _, line := range strings.Split(strings.TrimRight(string(raw), "\n"), "\n") {
+		if strings.TrimSpace(line) != "" {
+			n++
+		}
+	}
+	return n
+}
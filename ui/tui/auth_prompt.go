@@ -0,0 +1,213 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// authPromptStep is which screen of the "n" (new profile) flow from
+// viewAuthSelect is showing.
+type authPromptStep int
+
+const (
+	// authPromptChoice offers "Create new OAuth flow" vs. "Paste existing
+	// token/username"; the former isn't implemented here (it needs a real
+	// browser-based OAuth exchange) and just explains that with an inline
+	// error, same as an invalid paste would.
+	authPromptChoice authPromptStep = iota
+	authPromptPaste
+)
+
+// authPromptField is which field of the paste form Tab/input is editing.
+type authPromptField int
+
+const (
+	authPromptFieldUsername authPromptField = iota
+	authPromptFieldToken
+)
+
+// authPromptState drives viewAuthSelect's "n" flow: step picks between
+// starting a fresh OAuth flow and pasting an existing token, and for the
+// paste path holds the masked username/token fields plus any
+// validation/registration error to show inline (via m.th.Danger) without
+// dismissing the overlay, so the user can just retry.
+type authPromptState struct {
+	Active bool
+	step   authPromptStep
+	choice int
+
+	field    authPromptField
+	username textField
+	token    textField
+
+	err string
+}
+
+// openAuthPrompt enters the "n" flow from a fresh overlayAuthSelect.
+func (m appModel) openAuthPrompt() appModel {
+	m.authPrompt = authPromptState{Active: true, step: authPromptChoice}
+	return m
+}
+
+func (m appModel) closeAuthPrompt() appModel {
+	m.authPrompt = authPromptState{}
+	return m
+}
+
+// updateAuthPrompt handles key input while m.authPrompt.Active; called from
+// updateAuthSelect instead of its normal profile-list handling.
+func (m appModel) updateAuthPrompt(k tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if k.String() == "esc" {
+		return m.closeAuthPrompt(), nil
+	}
+
+	switch m.authPrompt.step {
+	case authPromptChoice:
+		return m.updateAuthPromptChoice(k)
+	case authPromptPaste:
+		return m.updateAuthPromptPaste(k)
+	}
+	return m, nil
+}
+
+func (m appModel) updateAuthPromptChoice(k tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch k.Type {
+	case tea.KeyUp:
+		if m.authPrompt.choice > 0 {
+			m.authPrompt.choice--
+		}
+	case tea.KeyDown:
+		if m.authPrompt.choice < 1 {
+			m.authPrompt.choice++
+		}
+	case tea.KeyEnter:
+		if m.authPrompt.choice == 0 {
+			m.authPrompt.err = "Starting a new browser-based OAuth flow isn't supported from the TUI yet; use the paste flow instead."
+			return m, nil
+		}
+		m.authPrompt.step = authPromptPaste
+		m.authPrompt.field = authPromptFieldUsername
+		m.authPrompt.err = ""
+	}
+	return m, nil
+}
+
+func (m appModel) updateAuthPromptPaste(k tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch k.Type {
+	case tea.KeyTab, tea.KeyDown:
+		m.authPrompt.field = authPromptFieldToken
+		return m, nil
+	case tea.KeyShiftTab, tea.KeyUp:
+		m.authPrompt.field = authPromptFieldUsername
+		return m, nil
+	case tea.KeyEnter:
+		return m.submitAuthPromptPaste()
+	}
+
+	var f *textField
+	if m.authPrompt.field == authPromptFieldUsername {
+		f = &m.authPrompt.username
+	} else {
+		f = &m.authPrompt.token
+	}
+	if next, ok := f.handleKey(k); ok {
+		*f = next
+		m.authPrompt.err = ""
+	}
+	return m, nil
+}
+
+// submitAuthPromptPaste validates the username/token fields, registers the
+// credential with the OAuth pool, and re-sorts the profile list for display
+// on success. On failure it leaves the overlay open with m.authPrompt.err
+// set rather than dismissing it, so the user can fix the field and retry.
+func (m appModel) submitAuthPromptPaste() (tea.Model, tea.Cmd) {
+	username := string(m.authPrompt.username)
+	token := string(m.authPrompt.token)
+	if err := validateOAuthPaste(username, token); err != nil {
+		m.authPrompt.err = err.Error()
+		return m, nil
+	}
+	profile, err := registerOAuthPastedCredential(m.cfg.stateDir, username, token)
+	if err != nil {
+		m.authPrompt.err = fmt.Sprintf("Failed to register credential: %s", err.Error())
+		return m, nil
+	}
+
+	m.oauthPool.Profiles = append(m.oauthPool.Profiles, oauthPoolProfile{
+		Profile: profile,
+		Email:   nonEmpty(strings.TrimSpace(username), profile),
+		Status:  "STANDBY",
+	})
+	ordered := orderOAuthProfilesForDisplay(m.oauthPool.Profiles)
+	for i, p := range ordered {
+		if p.Profile == profile {
+			m.authSelectIndex = i
+			break
+		}
+	}
+	m.systemAlert(alertInfo, "auth.paste.registered", "Registered OAuth credential", map[string]any{"profile": profile})
+	m = m.closeAuthPrompt()
+	return m, nil
+}
+
+// viewAuthPrompt renders the current step of the "n" flow in place of
+// viewAuthSelect's profile list.
+func (m appModel) viewAuthPrompt() string {
+	switch m.authPrompt.step {
+	case authPromptPaste:
+		return m.viewAuthPromptPaste()
+	default:
+		return m.viewAuthPromptChoice()
+	}
+}
+
+func (m appModel) viewAuthPromptChoice() string {
+	lines := []string{
+		m.th.Accent.Render("//auth  NEW OAUTH PROFILE"),
+		m.th.Muted.Render("Esc: back    Enter: select"),
+		"",
+	}
+	options := []string{"Create new OAuth flow", "Paste existing token/username"}
+	for i, opt := range options {
+		prefix := "  "
+		row := opt
+		if i == m.authPrompt.choice {
+			prefix = m.th.Accent.Render("> ")
+			row = m.th.Accent.Render(row)
+		}
+		lines = append(lines, prefix+row)
+	}
+	if m.authPrompt.err != "" {
+		lines = append(lines, "", m.th.Danger.Render(m.authPrompt.err))
+	}
+	return m.th.OverlayBox.Render(strings.Join(lines, "\n"))
+}
+
+func (m appModel) viewAuthPromptPaste() string {
+	lines := []string{
+		m.th.Accent.Render("//auth  PASTE TOKEN"),
+		m.th.Muted.Render("Esc: back    Tab: next field    Enter: submit"),
+		"",
+		m.viewAuthPromptField("Username", string(m.authPrompt.username), false, m.authPrompt.field == authPromptFieldUsername),
+		m.viewAuthPromptField("Token", string(m.authPrompt.token), true, m.authPrompt.field == authPromptFieldToken),
+	}
+	if m.authPrompt.err != "" {
+		lines = append(lines, "", m.th.Danger.Render(m.authPrompt.err))
+	}
+	return m.th.OverlayBox.Render(strings.Join(lines, "\n"))
+}
+
+func (m appModel) viewAuthPromptField(label string, value string, masked bool, focused bool) string {
+	display := value
+	if masked {
+		display = strings.Repeat("*", len([]rune(value)))
+	}
+	row := fmt.Sprintf("%s: %s", label, display)
+	if focused {
+		row = m.th.Accent.Render(row + "█")
+	}
+	return row
+}
@@ -0,0 +1,231 @@
+// Package bus owns the JSONL append-only streams the TUI, CLI, and hook
+// subprocesses exchange commands and results over, replacing the ad-hoc
+// in-memory byte offsets the individual *_bus.go files used to track
+// themselves. It borrows the etcd-style backend-index split: a stream is
+// just bytes on disk, and each reader is a named Consumer whose position is
+// checkpointed to its own file so it survives process restarts, session
+// rotation, and crash recovery without replaying from byte 0.
+//
+// MaybeCompact keeps a stream from growing without bound: once it exceeds
+// WORKBENCH_BUS_QUOTA_BYTES, it truncates everything before the slowest
+// registered consumer's checkpoint and shifts every consumer's checkpoint to
+// match, the same "advance past the snapshot index, then compact" shape
+// Raft logs use.
+package bus
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// defaultQuotaBytes is the stream size at which MaybeCompact starts
+// compacting when WORKBENCH_BUS_QUOTA_BYTES isn't set or isn't a valid
+// positive integer.
+const defaultQuotaBytes = 64 * 1024 * 1024
+
+// Consumer tracks one named reader's position in a single JSONL stream,
+// persisting it to a checkpoint file (<stream>.checkpoints/<name>.offset) so
+// the position survives process restarts.
+type Consumer struct {
+	streamPath     string
+	checkpointPath string
+	name           string
+	offset         int64
+}
+
+// OpenConsumer opens (creating if needed) the stream at path and the named
+// consumer's durable checkpoint, returning a Consumer positioned at whatever
+// offset was last persisted (0 for a brand new consumer or stream).
+func OpenConsumer(path string, name string) (*Consumer, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		name = "default"
+	}
+	if strings.TrimSpace(path) == "" {
+		return nil, fmt.Errorf("bus: empty stream path")
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(path); err != nil {
+		if err := os.WriteFile(path, []byte{}, 0o644); err != nil {
+			return nil, err
+		}
+	}
+	cpDir := checkpointDir(path)
+	if err := os.MkdirAll(cpDir, 0o755); err != nil {
+		return nil, err
+	}
+	c := &Consumer{
+		streamPath:     path,
+		checkpointPath: filepath.Join(cpDir, name+".offset"),
+		name:           name,
+	}
+	if off, ok := readOffsetFile(c.checkpointPath); ok {
+		c.offset = off
+	}
+	return c, nil
+}
+
+func checkpointDir(streamPath string) string {
+	return streamPath + ".checkpoints"
+}
+
+func readOffsetFile(path string) (int64, bool) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return 0, false
+	}
+	off, err := strconv.ParseInt(strings.TrimSpace(string(raw)), 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return off, true
+}
+
+// StreamPath returns the underlying JSONL file this consumer reads.
+func (c *Consumer) StreamPath() string {
+	return c.streamPath
+}
+
+// Offset returns the consumer's last-persisted read position, re-reading
+// the checkpoint file so a shift made by MaybeCompact is picked up.
+func (c *Consumer) Offset() int64 {
+	if off, ok := readOffsetFile(c.checkpointPath); ok {
+		c.offset = off
+	}
+	return c.offset
+}
+
+// Advance persists a new read position atomically (write-to-temp, then
+// rename) so a crash mid-write can't leave a corrupt checkpoint.
+func (c *Consumer) Advance(offset int64) error {
+	if offset == c.offset {
+		return nil
+	}
+	tmp := c.checkpointPath + ".tmp"
+	if err := os.WriteFile(tmp, []byte(strconv.FormatInt(offset, 10)), 0o644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, c.checkpointPath); err != nil {
+		return err
+	}
+	c.offset = offset
+	return nil
+}
+
+// Close is a no-op; checkpoints are durable on disk and need no in-memory
+// teardown. It exists so a Consumer can be closed and reopened across a
+// session rotation the same way hooks.Bus is (see appModel.startNewSession).
+func (c *Consumer) Close() error {
+	return nil
+}
+
+// quotaBytes reads WORKBENCH_BUS_QUOTA_BYTES, falling back to
+// defaultQuotaBytes when unset or not a valid positive integer.
+func quotaBytes() int64 {
+	raw := strings.TrimSpace(os.Getenv("WORKBENCH_BUS_QUOTA_BYTES"))
+	if raw == "" {
+		return defaultQuotaBytes
+	}
+	n, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil || n <= 0 {
+		return defaultQuotaBytes
+	}
+	return n
+}
+
+// consumerOffsets discovers every consumer ever opened against streamPath
+// from its checkpoint directory, returning their names and the minimum
+// (slowest) persisted offset. A stream with no checkpointed consumers yet
+// reports a minimum of 0, which MaybeCompact treats as "nothing is safe to
+// drop."
+func consumerOffsets(streamPath string) (names []string, min int64) {
+	entries, err := os.ReadDir(checkpointDir(streamPath))
+	if err != nil {
+		return nil, 0
+	}
+	min = -1
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".offset") {
+			continue
+		}
+		off, ok := readOffsetFile(filepath.Join(checkpointDir(streamPath), e.Name()))
+		if !ok {
+			continue
+		}
+		names = append(names, strings.TrimSuffix(e.Name(), ".offset"))
+		if min < 0 || off < min {
+			min = off
+		}
+	}
+	if min < 0 {
+		min = 0
+	}
+	return names, min
+}
+
+// MaybeCompact rewrites the stream at path to drop every byte before the
+// slowest registered consumer's checkpoint, then shifts each consumer's
+// checkpoint back by the same amount, so every consumer's next read picks
+// up exactly where it left off. It is a no-op unless the stream exceeds
+// WORKBENCH_BUS_QUOTA_BYTES and every registered consumer has advanced past
+// byte 0 (compacting past an unread consumer would lose it data).
+func MaybeCompact(path string) error {
+	st, err := os.Stat(path)
+	if err != nil {
+		return nil
+	}
+	if st.Size() < quotaBytes() {
+		return nil
+	}
+	names, min := consumerOffsets(path)
+	if min <= 0 {
+		return nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, err := f.Seek(min, 0); err != nil {
+		return err
+	}
+
+	tmp := path + ".compact.tmp"
+	out, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	_, copyErr := io.Copy(out, f)
+	closeErr := out.Close()
+	if copyErr != nil {
+		_ = os.Remove(tmp)
+		return copyErr
+	}
+	if closeErr != nil {
+		_ = os.Remove(tmp)
+		return closeErr
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		cpPath := filepath.Join(checkpointDir(path), name+".offset")
+		off, ok := readOffsetFile(cpPath)
+		if !ok {
+			continue
+		}
+		tmp := cpPath + ".tmp"
+		if os.WriteFile(tmp, []byte(strconv.FormatInt(off-min, 10)), 0o644) == nil {
+			_ = os.Rename(tmp, cpPath)
+		}
+	}
+	return nil
+}
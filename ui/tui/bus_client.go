@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"os"
+	"strings"
+)
+
+// busClient is the transport-agnostic interface both the JSONL file bus and
+// the gRPC bus (grpc_bus.go) implement for the opencode turn lifecycle. It
+// lets call sites like sendChat go through one seam regardless of which
+// transport WORKBENCH_BUS selected.
+type busClient interface {
+	SubmitTurn(ctx context.Context, req opencodeTurnRequest) error
+	Cancel(ctx context.Context, correlationID string) error
+	Close() error
+}
+
+// busTransport returns the transport selected via WORKBENCH_BUS. Defaults to
+// "file" (the existing JSONL append-only buses) so existing sessions and
+// executors keep working unchanged.
+func busTransport() string {
+	v := strings.ToLower(strings.TrimSpace(os.Getenv("WORKBENCH_BUS")))
+	if v != "grpc" && v != "file" {
+		v = "file"
+	}
+	return v
+}
+
+// fileBusClient adapts the existing opencode JSONL bus (appendOpencodeRequest)
+// to busClient so callers don't need to special-case the file transport.
+type fileBusClient struct {
+	requestsPath string
+}
+
+func newFileBusClient(requestsPath string) *fileBusClient {
+	return &fileBusClient{requestsPath: requestsPath}
+}
+
+func (c *fileBusClient) SubmitTurn(ctx context.Context, req opencodeTurnRequest) error {
+	return appendOpencodeRequest(c.requestsPath, req)
+}
+
+func (c *fileBusClient) Cancel(ctx context.Context, correlationID string) error {
+	return appendOpencodeRequest(c.requestsPath, opencodeTurnRequest{
+		Type:          "cancel",
+		CorrelationID: correlationID,
+	})
+}
+
+// Close is a no-op: the file bus has no connection to release.
+func (c *fileBusClient) Close() error {
+	return nil
+}
+
+// newOpencodeBusClient is the seam sendChat/cancelInFlightChat dial through:
+// it resolves busTransport() to a grpcBusClient dialed at the address the
+// executor published in its ready sentinel (opencodeExecutorBusAddr), or
+// falls back to the pre-existing fileBusClient — both when WORKBENCH_BUS is
+// unset/"file" and when it's "grpc" but the executor hasn't published an
+// address yet (e.g. still starting up), so a turn is never silently lost.
+func newOpencodeBusClient(stateDir string, sessionID string, requestsPath string) busClient {
+	if busTransport() == "grpc" {
+		if addr := opencodeExecutorBusAddr(stateDir, sessionID); addr != "" {
+			if c, err := dialGrpcBus(addr); err == nil {
+				return c
+			}
+		}
+	}
+	return newFileBusClient(requestsPath)
+}
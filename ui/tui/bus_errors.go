@@ -0,0 +1,164 @@
+package main
+
+import "fmt"
+
+// busErrorCode enumerates the causes bus/usage-fetch callers need to tell
+// apart programmatically (via errors.Is), modeled after the enum-plus-
+// message pattern libraries like go-augeas use for their error types.
+type busErrorCode int
+
+const (
+	ErrUnknown busErrorCode = iota
+	ErrRateLimited
+	ErrAuthExpired
+	ErrCorruptLine
+	ErrExecutorStale
+	ErrIO
+	ErrUnauthorized
+)
+
+func (c busErrorCode) String() string {
+	switch c {
+	case ErrRateLimited:
+		return "rate_limited"
+	case ErrAuthExpired:
+		return "auth_expired"
+	case ErrCorruptLine:
+		return "corrupt_line"
+	case ErrExecutorStale:
+		return "executor_stale"
+	case ErrIO:
+		return "io"
+	case ErrUnauthorized:
+		return "unauthorized"
+	default:
+		return "unknown"
+	}
+}
+
+// busError is the structured error type returned by the file buses and
+// fetchUsage in place of bare errors.New strings, so callers can tell
+// "profile file missing" (ErrIO) apart from "429 from upstream"
+// (ErrRateLimited) apart from "malformed JSONL line" (ErrCorruptLine) via
+// errors.Is/As instead of substring-matching Error().
+type busError struct {
+	Code      busErrorCode
+	Message   string
+	ResetAtMs int64 // set for ErrRateLimited when the upstream gave a reset time
+	Err       error // wrapped cause, if any
+}
+
+func newBusError(code busErrorCode, message string) *busError {
+	return &busError{Code: code, Message: message}
+}
+
+func wrapBusError(code busErrorCode, message string, cause error) *busError {
+	return &busError{Code: code, Message: message, Err: cause}
+}
+
+func (e *busError) Error() string {
+	if e == nil {
+		return ""
+	}
+	if e.Message == "" {
+		return fmt.Sprintf("%s", e.Code)
+	}
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+func (e *busError) Unwrap() error {
+	if e == nil {
+		return nil
+	}
+	return e.Err
+}
+
+// Is lets errors.Is(err, errRateLimitedSentinel) work by comparing codes;
+// see errRateLimitedSentinel and friends below.
+func (e *busError) Is(target error) bool {
+	t, ok := target.(*busError)
+	if !ok || e == nil || t == nil {
+		return false
+	}
+	return e.Code == t.Code
+}
+
+// Sentinel busErrors for use with errors.Is(err, errXxx); only Code is
+// compared, so these never need their Message/Err fields populated.
+var (
+	errRateLimitedSentinel   = &busError{Code: ErrRateLimited}
+	errAuthExpiredSentinel   = &busError{Code: ErrAuthExpired}
+	errCorruptLineSentinel   = &busError{Code: ErrCorruptLine}
+	errExecutorStaleSentinel = &busError{Code: ErrExecutorStale}
+	errIOSentinel            = &busError{Code: ErrIO}
+	errUnauthorizedSentinel  = &busError{Code: ErrUnauthorized}
+)
+
+// multiError accumulates non-fatal errors (e.g. one ErrCorruptLine per bad
+// JSONL line) alongside a successful partial result, so callers can surface
+// "N lines failed to parse" without losing the lines that did parse.
+type multiError struct {
+	Errs []error
+}
+
+func (m *multiError) Error() string {
+	if m == nil || len(m.Errs) == 0 {
+		return ""
+	}
+	if len(m.Errs) == 1 {
+		return m.Errs[0].Error()
+	}
+	return fmt.Sprintf("%d errors (first: %s)", len(m.Errs), m.Errs[0].Error())
+}
+
+// Unwrap lets errors.Is/As traverse every accumulated error (Go 1.20+
+// multi-error unwrap support).
+func (m *multiError) Unwrap() []error {
+	if m == nil {
+		return nil
+	}
+	return m.Errs
+}
+
+func (m *multiError) add(err error) *multiError {
+	if err == nil {
+		return m
+	}
+	if m == nil {
+		m = &multiError{}
+	}
+	m.Errs = append(m.Errs, err)
+	return m
+}
+
+// asMultiErrorOrNil returns m as an error, or nil if it accumulated nothing
+// — so callers can write `return items, offset, asMultiErrorOrNil(merr)`
+// without an explicit len check at every call site.
+func asMultiErrorOrNil(m *multiError) error {
+	if m == nil || len(m.Errs) == 0 {
+		return nil
+	}
+	return m
+}
+
+// mergeBusErrors flattens a and b (either of which may be nil, a *multiError,
+// or a plain error) into a single error, so callers that accumulate errors
+// from two independent passes over the same lines (e.g. corrupt-JSON from
+// readJSONLFrom plus ACL rejections from a decode callback) can report both
+// without nesting multiErrors inside multiErrors.
+func mergeBusErrors(a, b error) error {
+	var m *multiError
+	for _, err := range []error{a, b} {
+		if err == nil {
+			continue
+		}
+		if me, ok := err.(*multiError); ok {
+			for _, e := range me.Errs {
+				m = m.add(e)
+			}
+			continue
+		}
+		m = m.add(err)
+	}
+	return asMultiErrorOrNil(m)
+}
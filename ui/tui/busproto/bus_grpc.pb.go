@@ -0,0 +1,163 @@
+package busproto
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+)
+
+// workbenchBusServiceName must match bus.proto's "package busproto; service
+// WorkbenchBus" so method full-names line up with what protoc-gen-go-grpc
+// would have emitted (busproto.WorkbenchBus/<Method>).
+const workbenchBusServiceName = "busproto.WorkbenchBus"
+
+// WorkbenchBusServer is the service surface implemented by the in-process
+// gRPC bus server in grpc_bus.go. Regenerate alongside bus.pb.go.
+type WorkbenchBusServer interface {
+	SubmitTurn(req *OpencodeTurnRequest, stream WorkbenchBus_SubmitTurnServer) error
+	SubmitSystem(ctx context.Context, req *SystemRequest) (*SystemResult, error)
+	Cancel(ctx context.Context, req *CancelRequest) (*CancelResult, error)
+}
+
+// WorkbenchBus_SubmitTurnServer is the server-streaming handle SubmitTurn
+// uses to push turn events as they happen, mirroring grpc.ServerStream.
+type WorkbenchBus_SubmitTurnServer interface {
+	Send(*OpencodeTurnEvent) error
+	Context() context.Context
+}
+
+type workbenchBusSubmitTurnServer struct {
+	grpc.ServerStream
+}
+
+func (s *workbenchBusSubmitTurnServer) Send(ev *OpencodeTurnEvent) error {
+	return s.ServerStream.SendMsg(ev)
+}
+
+func workbenchBusSubmitTurnHandler(srv any, stream grpc.ServerStream) error {
+	req := new(OpencodeTurnRequest)
+	if err := stream.RecvMsg(req); err != nil {
+		return err
+	}
+	return srv.(WorkbenchBusServer).SubmitTurn(req, &workbenchBusSubmitTurnServer{stream})
+}
+
+func workbenchBusSubmitSystemHandler(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+	in := new(SystemRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WorkbenchBusServer).SubmitSystem(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/" + workbenchBusServiceName + "/SubmitSystem"}
+	handler := func(ctx context.Context, req any) (any, error) {
+		return srv.(WorkbenchBusServer).SubmitSystem(ctx, req.(*SystemRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func workbenchBusCancelHandler(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+	in := new(CancelRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WorkbenchBusServer).Cancel(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/" + workbenchBusServiceName + "/Cancel"}
+	handler := func(ctx context.Context, req any) (any, error) {
+		return srv.(WorkbenchBusServer).Cancel(ctx, req.(*CancelRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// WorkbenchBus_ServiceDesc is what a real protoc-gen-go-grpc run would name
+// _WorkbenchBus_serviceDesc; exported here since this file is hand-written
+// rather than generated.
+var WorkbenchBus_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: workbenchBusServiceName,
+	HandlerType: (*WorkbenchBusServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{MethodName: "SubmitSystem", Handler: workbenchBusSubmitSystemHandler},
+		{MethodName: "Cancel", Handler: workbenchBusCancelHandler},
+	},
+	Streams: []grpc.StreamDesc{
+		{StreamName: "SubmitTurn", Handler: workbenchBusSubmitTurnHandler, ServerStreams: true},
+	},
+	Metadata: "bus.proto",
+}
+
+// RegisterWorkbenchBusServer registers srv's RPC handlers on s. Callers
+// must do this before s.Serve, same as generated code requires.
+func RegisterWorkbenchBusServer(s *grpc.Server, srv WorkbenchBusServer) {
+	s.RegisterService(&WorkbenchBus_ServiceDesc, srv)
+}
+
+// WorkbenchBusClient is the client surface used by grpcBusClient.
+type WorkbenchBusClient interface {
+	SubmitTurn(ctx context.Context, req *OpencodeTurnRequest) (WorkbenchBus_SubmitTurnClient, error)
+	SubmitSystem(ctx context.Context, req *SystemRequest) (*SystemResult, error)
+	Cancel(ctx context.Context, req *CancelRequest) (*CancelResult, error)
+}
+
+// WorkbenchBus_SubmitTurnClient is the client-side receive half of the
+// SubmitTurn stream.
+type WorkbenchBus_SubmitTurnClient interface {
+	Recv() (*OpencodeTurnEvent, error)
+}
+
+type workbenchBusClient struct {
+	cc *grpc.ClientConn
+}
+
+// NewWorkbenchBusClient builds a WorkbenchBusClient over cc. Every call is
+// forced onto jsonCodec via grpc.CallContentSubtype so it round-trips
+// regardless of whether the real protobuf codec is registered in this
+// binary (see codec.go).
+func NewWorkbenchBusClient(cc *grpc.ClientConn) WorkbenchBusClient {
+	return &workbenchBusClient{cc: cc}
+}
+
+type workbenchBusSubmitTurnClient struct {
+	grpc.ClientStream
+}
+
+func (x *workbenchBusSubmitTurnClient) Recv() (*OpencodeTurnEvent, error) {
+	ev := new(OpencodeTurnEvent)
+	if err := x.ClientStream.RecvMsg(ev); err != nil {
+		return nil, err
+	}
+	return ev, nil
+}
+
+func (c *workbenchBusClient) SubmitTurn(ctx context.Context, req *OpencodeTurnRequest) (WorkbenchBus_SubmitTurnClient, error) {
+	stream, err := c.cc.NewStream(ctx, &WorkbenchBus_ServiceDesc.Streams[0], "/"+workbenchBusServiceName+"/SubmitTurn", grpc.CallContentSubtype(jsonCodecName))
+	if err != nil {
+		return nil, err
+	}
+	x := &workbenchBusSubmitTurnClient{stream}
+	if err := x.ClientStream.SendMsg(req); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+func (c *workbenchBusClient) SubmitSystem(ctx context.Context, req *SystemRequest) (*SystemResult, error) {
+	out := new(SystemResult)
+	if err := c.cc.Invoke(ctx, "/"+workbenchBusServiceName+"/SubmitSystem", req, out, grpc.CallContentSubtype(jsonCodecName)); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *workbenchBusClient) Cancel(ctx context.Context, req *CancelRequest) (*CancelResult, error) {
+	out := new(CancelResult)
+	if err := c.cc.Invoke(ctx, "/"+workbenchBusServiceName+"/Cancel", req, out, grpc.CallContentSubtype(jsonCodecName)); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
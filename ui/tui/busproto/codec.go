@@ -0,0 +1,28 @@
+package busproto
+
+import (
+	"encoding/json"
+
+	"google.golang.org/grpc/encoding"
+)
+
+// jsonCodecName is the grpc content-subtype the WorkbenchBus client/server
+// force on every call via grpc.CallContentSubtype so both sides agree on
+// jsonCodec regardless of whether the real "proto" codec happens to be
+// registered in the binary.
+const jsonCodecName = "busjson"
+
+// jsonCodec stands in for the protobuf wire codec protoc-gen-go-grpc would
+// normally pair with generated code: this tree has no protoc toolchain
+// wired up (see bus.pb.go), so messages round-trip as JSON instead of
+// protobuf wire bytes. Field names/shapes still mirror bus.proto, so
+// swapping in the real generated codec later only touches this file.
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v any) ([]byte, error) { return json.Marshal(v) }
+func (jsonCodec) Unmarshal(data []byte, v any) error { return json.Unmarshal(data, v) }
+func (jsonCodec) Name() string { return jsonCodecName }
+
+func init() {
+	encoding.RegisterCodec(jsonCodec{})
+}
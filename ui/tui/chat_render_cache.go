@@ -0,0 +1,91 @@
+package main
+
+import (
+	"hash/fnv"
+	"sort"
+)
+
+// chatRenderCacheMinEntries is the floor for chatRenderCacheBound, so a
+// short transcript doesn't thrash the cache on every scroll key before it
+// has 4x-visible-history worth of entries to justify the bound.
+const chatRenderCacheMinEntries = 256
+
+type chatRenderCacheEntry struct {
+	width int
+	lines []string
+	used  int
+}
+
+// chatRenderCache memoizes wrapChatBlock's wrapped output per
+// (role, raw text, innerWidth) so that re-rendering the same scrollback -
+// once per delta while a reply streams in, once per PgUp/PgDn keystroke -
+// doesn't re-wrap the whole transcript. Entries are keyed by a hash of the
+// role+text rather than a chatRoleLines index, since trimChatRoleLines
+// drops entries from the front and shifts every later index.
+type chatRenderCache struct {
+	entries map[uint64]*chatRenderCacheEntry
+	clock   int
+}
+
+func newChatRenderCache() *chatRenderCache {
+	return &chatRenderCache{entries: map[uint64]*chatRenderCacheEntry{}}
+}
+
+func chatRenderCacheKey(role, text string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(role))
+	_, _ = h.Write([]byte{0})
+	_, _ = h.Write([]byte(text))
+	return h.Sum64()
+}
+
+// wrap returns the wrapped lines for (role, text) at innerWidth, calling
+// render to compute (and cache) them on a miss or a width change. A cached
+// entry at a stale width is simply overwritten, since each key only ever
+// needs to serve the current render width.
+func (c *chatRenderCache) wrap(role, text string, innerWidth int, render func() []string) []string {
+	c.clock++
+	key := chatRenderCacheKey(role, text)
+	if e, ok := c.entries[key]; ok && e.width == innerWidth {
+		e.used = c.clock
+		return e.lines
+	}
+	lines := render()
+	c.entries[key] = &chatRenderCacheEntry{width: innerWidth, lines: lines, used: c.clock}
+	return lines
+}
+
+// reset drops every cached entry; called on window resize since nearly
+// every entry needs rewrapping at the new width anyway.
+func (c *chatRenderCache) reset() {
+	c.entries = map[uint64]*chatRenderCacheEntry{}
+}
+
+// evictLRU drops the least-recently-used entries once the cache holds more
+// than maxEntries, so memoized scrollback from messages long since
+// scrolled out of view doesn't grow the cache unbounded over a long
+// session.
+func (c *chatRenderCache) evictLRU(maxEntries int) {
+	if len(c.entries) <= maxEntries {
+		return
+	}
+	keys := make([]uint64, 0, len(c.entries))
+	for k := range c.entries {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return c.entries[keys[i]].used < c.entries[keys[j]].used })
+	for _, k := range keys[:len(keys)-maxEntries] {
+		delete(c.entries, k)
+	}
+}
+
+// chatRenderCacheBound is the eviction bound for a pane currently showing
+// visibleRoleLines entries: 4x that, with a floor so a short transcript
+// doesn't thrash the cache before it's worth bounding.
+func chatRenderCacheBound(visibleRoleLines int) int {
+	bound := visibleRoleLines * 4
+	if bound < chatRenderCacheMinEntries {
+		bound = chatRenderCacheMinEntries
+	}
+	return bound
+}
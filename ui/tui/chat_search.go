@@ -0,0 +1,195 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// openChatSearch enters scrollback search mode (Ctrl-R): it swaps the
+// currently rendered, in-memory-trimmed m.chatRoleLines for the full
+// persisted scrollback (up to scrollbackMaxEntries), so a query can match
+// turns trimChatRoleLines already dropped, and remembers what to restore
+// on close.
+func (m appModel) openChatSearch() appModel {
+	if m.chatSearchActive {
+		return m
+	}
+	entries, err := m.chatScrollback.Load()
+	if err != nil {
+		m.systemAlert(alertWarn, "chat.search.load_failed", "Failed to load scrollback", map[string]any{"error": err.Error()})
+		return m
+	}
+	m.chatSearchSavedRoleLines = m.chatRoleLines
+	m.chatSearchSavedOffset = m.chatScrollOffset
+	m.chatRoleLines = scrollbackRoleLines(entries)
+	m.chatSearchActive = true
+	m.chatSearchCommitted = false
+	m.chatSearchQuery = ""
+	m.chatSearchMatches = nil
+	m.chatSearchMatchIndex = 0
+	return m
+}
+
+// closeChatSearch leaves search mode, restoring the transcript and scroll
+// offset it had before openChatSearch swapped them out.
+func (m appModel) closeChatSearch() appModel {
+	if !m.chatSearchActive {
+		return m
+	}
+	m.chatRoleLines = m.chatSearchSavedRoleLines
+	m.chatScrollOffset = m.chatSearchSavedOffset
+	m.chatSearchSavedRoleLines = nil
+	m.chatSearchActive = false
+	m.chatSearchCommitted = false
+	m.chatSearchQuery = ""
+	m.chatSearchMatches = nil
+	m.chatSearchMatchIndex = 0
+	return m
+}
+
+func scrollbackRoleLines(entries []scrollbackEntry) []chatRoleLine {
+	out := make([]chatRoleLine, 0, len(entries))
+	for _, e := range entries {
+		out = append(out, chatRoleLine{Role: e.Role, Text: e.Text})
+	}
+	return out
+}
+
+// updateChatSearch handles key input while chatSearchActive: typing edits
+// the query and re-ranks matches (fuzzyMatch, the same scorer the command
+// palette uses) until Enter commits, after which n/N step between the
+// already-ranked hits instead of editing further.
+func (m appModel) updateChatSearch(k tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if k.String() == "esc" {
+		return m.closeChatSearch(), nil
+	}
+	if k.Type == tea.KeyCtrlC {
+		return m.closeChatSearch(), tea.Quit
+	}
+
+	if !m.chatSearchCommitted {
+		switch k.Type {
+		case tea.KeyEnter:
+			m.chatSearchCommitted = true
+			return m, nil
+		case tea.KeyCtrlR:
+			m = m.jumpToSearchMatch(m.chatSearchMatchIndex - 1)
+			return m, nil
+		}
+		if f, ok := textField(m.chatSearchQuery).handleKey(k); ok {
+			m.chatSearchQuery = string(f)
+			m = m.recomputeChatSearchMatches()
+		}
+		return m, nil
+	}
+
+	switch {
+	case k.String() == "n":
+		m = m.jumpToSearchMatch(m.chatSearchMatchIndex + 1)
+	case k.String() == "N":
+		m = m.jumpToSearchMatch(m.chatSearchMatchIndex - 1)
+	case k.Type == tea.KeyCtrlR:
+		m.chatSearchCommitted = false
+	case k.Type == tea.KeyEnter:
+		return m.closeChatSearch(), nil
+	}
+	return m, nil
+}
+
+// recomputeChatSearchMatches re-ranks every line in the (now
+// scrollback-backed) m.chatRoleLines against m.chatSearchQuery, highest
+// score first and, among equal scores, most recent hit first, and scrolls
+// to the top hit.
+func (m appModel) recomputeChatSearchMatches() appModel {
+	m.chatSearchMatches = nil
+	if strings.TrimSpace(m.chatSearchQuery) == "" {
+		m.chatScrollOffset = 0
+		return m
+	}
+	type scored struct {
+		idx   int
+		score int
+	}
+	var hits []scored
+	for i, e := range m.chatRoleLines {
+		score, _, ok := fuzzyMatch(m.chatSearchQuery, e.Text)
+		if !ok {
+			continue
+		}
+		hits = append(hits, scored{idx: i, score: score})
+	}
+	sort.SliceStable(hits, func(a, b int) bool {
+		if hits[a].score != hits[b].score {
+			return hits[a].score > hits[b].score
+		}
+		return hits[a].idx > hits[b].idx
+	})
+	for _, h := range hits {
+		m.chatSearchMatches = append(m.chatSearchMatches, h.idx)
+	}
+	if len(m.chatSearchMatches) == 0 {
+		m.chatScrollOffset = 0
+		return m
+	}
+	return m.jumpToSearchMatch(0)
+}
+
+// jumpToSearchMatch selects hit idx (wrapping around) and scrolls
+// chatScrollOffset so the matched line is the last line shown at the bottom
+// of the visible chat window.
+func (m appModel) jumpToSearchMatch(idx int) appModel {
+	if len(m.chatSearchMatches) == 0 {
+		return m
+	}
+	n := len(m.chatSearchMatches)
+	idx = ((idx % n) + n) % n
+	m.chatSearchMatchIndex = idx
+
+	lineIdx := m.chatSearchMatches[idx]
+	w, _ := m.effectiveSize()
+	innerW := chatInnerWidth(w)
+	below := 0
+	for _, e := range m.chatRoleLines[lineIdx+1:] {
+		below += m.chatWrappedLineCountWidth(e.Role, e.Text, innerW)
+	}
+	m.chatScrollOffset = below
+	return m
+}
+
+// chatSearchViewLabel renders the "View:" status replacing Follow/Scrollback
+// while search is active, e.g. `Search "foo" 3/12`.
+func (m appModel) chatSearchViewLabel() string {
+	if strings.TrimSpace(m.chatSearchQuery) == "" {
+		return "Search (type to filter)"
+	}
+	if len(m.chatSearchMatches) == 0 {
+		return fmt.Sprintf("Search %q (no hits)", m.chatSearchQuery)
+	}
+	return fmt.Sprintf("Search %q %d/%d", m.chatSearchQuery, m.chatSearchMatchIndex+1, len(m.chatSearchMatches))
+}
+
+// renderSearchHighlightedLine renders the current search hit with its
+// matched runes bolded in the accent color, the same treatment
+// highlightPaletteMatches gives a palette row. It bypasses chatRenderCache
+// since the highlight depends on the live query rather than just
+// (role, text).
+func (m appModel) renderSearchHighlightedLine(e chatRoleLine, innerWidth int) []string {
+	_, positions, _ := fuzzyMatch(m.chatSearchQuery, e.Text)
+	set := make(map[int]bool, len(positions))
+	for _, p := range positions {
+		set[p] = true
+	}
+	runes := []rune(e.Text)
+	var b strings.Builder
+	for i, r := range runes {
+		if set[i] {
+			b.WriteString(m.th.Accent.Bold(true).Render(string(r)))
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	return wrapChatBlockForRole(m.th, e.Role, b.String(), innerWidth)
+}
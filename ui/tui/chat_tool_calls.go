@@ -0,0 +1,211 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// chatToolCall is one tool invocation surfaced during an in-flight turn,
+// collapsed into a single block in chat scrollback instead of flooding it
+// with one "Codex/<tool>: ..." line per event. A tool_use event opens the
+// call (appModel.beginChatToolCall); the matching step_finish closes it
+// (appModel.finishChatToolCall). FileChanges is attached separately from
+// the turn result, since the wire protocol reports file changes per-turn
+// rather than per-tool-call.
+type chatToolCall struct {
+	ID            string
+	CorrelationID string
+	Tool          string
+	Args          string
+	Result        string
+	FileChanges   []string
+	StartedAt     time.Time
+	EndedAt       time.Time
+	Done          bool
+}
+
+// duration reports how long the call has run: elapsed-so-far while it's
+// still open, or its total time once Done.
+func (tc *chatToolCall) duration(now time.Time) time.Duration {
+	if tc.StartedAt.IsZero() {
+		return 0
+	}
+	end := tc.EndedAt
+	if !tc.Done {
+		end = now
+	}
+	if end.Before(tc.StartedAt) {
+		return 0
+	}
+	return end.Sub(tc.StartedAt)
+}
+
+func parseEventTime(raw string, fallback time.Time) time.Time {
+	if t, err := time.Parse(time.RFC3339, strings.TrimSpace(raw)); err == nil {
+		return t
+	}
+	return fallback
+}
+
+// beginChatToolCall opens a new chatToolCall for a tool_use event, appends
+// its collapsed placeholder line to chatRoleLines, and marks it the turn's
+// active call so the matching step_finish event can find it - the wire
+// protocol has no per-call ID, so this assumes a turn runs its tool calls
+// one at a time, which matches how the single chatCorrelationID in-flight
+// field already assumes one turn at a time.
+func (m appModel) beginChatToolCall(correlationID, tool, args, atStr string) appModel {
+	if m.chatToolCalls == nil {
+		m.chatToolCalls = map[string]*chatToolCall{}
+	}
+	id := fmt.Sprintf("%s#%d", correlationID, m.chatToolCallSeq)
+	m.chatToolCallSeq++
+	m.chatToolCalls[id] = &chatToolCall{
+		ID:            id,
+		CorrelationID: correlationID,
+		Tool:          tool,
+		Args:          args,
+		StartedAt:     parseEventTime(atStr, m.now),
+	}
+	m.chatActiveToolCallID = id
+	if m.chatScrollOffset > 0 {
+		m.chatScrollOffset++
+	}
+	m.chatRoleLines = append(m.chatRoleLines, chatRoleLine{Role: "tool", ToolCallID: id})
+	return m.trimChatRoleLines()
+}
+
+// finishChatToolCall closes whichever call beginChatToolCall left active,
+// recording its result and end time. A stray step_finish with no active
+// call (e.g. right after a history replay) is ignored.
+func (m appModel) finishChatToolCall(result, atStr string) appModel {
+	tc, ok := m.chatToolCalls[m.chatActiveToolCallID]
+	m.chatActiveToolCallID = ""
+	if !ok {
+		return m
+	}
+	tc.Result = result
+	tc.EndedAt = parseEventTime(atStr, m.now)
+	tc.Done = true
+	return m
+}
+
+// attachFileChangesToLastToolCall finds the most recently appended tool
+// call belonging to correlationID and records files against it, so the
+// turn's file changes render inside that block instead of a separate
+// systemAlert. Reports whether a call was found to attach to, so the
+// caller can fall back to the alert when a turn had no tool calls.
+func (m appModel) attachFileChangesToLastToolCall(correlationID string, files []string) bool {
+	for i := len(m.chatRoleLines) - 1; i >= 0; i-- {
+		line := m.chatRoleLines[i]
+		if line.Role != "tool" {
+			continue
+		}
+		tc, ok := m.chatToolCalls[line.ToolCallID]
+		if !ok || tc.CorrelationID != correlationID {
+			continue
+		}
+		tc.FileChanges = files
+		return true
+	}
+	return false
+}
+
+// toggleChatToolCallFocus expands/collapses the focused tool call block; a
+// no-op if nothing is focused.
+func (m appModel) toggleChatToolCallFocus() appModel {
+	if m.chatToolCallFocus == "" {
+		return m
+	}
+	if m.chatToolCallExpanded == nil {
+		m.chatToolCallExpanded = map[string]bool{}
+	}
+	m.chatToolCallExpanded[m.chatToolCallFocus] = !m.chatToolCallExpanded[m.chatToolCallFocus]
+	return m
+}
+
+// cycleChatToolCallFocus moves chatToolCallFocus to the next tool call
+// appearing in lines (transcript order), wrapping back to unfocused once
+// past the last one.
+func (m appModel) cycleChatToolCallFocus(lines []chatRoleLine) appModel {
+	ids := make([]string, 0, 4)
+	for _, l := range lines {
+		if l.Role == "tool" {
+			ids = append(ids, l.ToolCallID)
+		}
+	}
+	if len(ids) == 0 {
+		m.chatToolCallFocus = ""
+		return m
+	}
+	if m.chatToolCallFocus == "" {
+		m.chatToolCallFocus = ids[0]
+		return m
+	}
+	for i, id := range ids {
+		if id == m.chatToolCallFocus {
+			if i+1 < len(ids) {
+				m.chatToolCallFocus = ids[i+1]
+			} else {
+				m.chatToolCallFocus = ""
+			}
+			return m
+		}
+	}
+	m.chatToolCallFocus = ids[0]
+	return m
+}
+
+// renderChatToolCallBlock renders one tool call as a single collapsed
+// summary line ("▸ ran `tool` (240ms)") or, once expanded, a block with
+// its args, result, and any attached file changes. Unlike
+// cachedWrappedChatBlock this is never routed through chatRenderCache: a
+// call's Done/Result/FileChanges mutate in place while its turn streams
+// in, and its collapsed/expanded state can flip on every keystroke.
+func (m appModel) renderChatToolCallBlock(id string, innerWidth int) []string {
+	tc, ok := m.chatToolCalls[id]
+	if !ok {
+		return nil
+	}
+	focused := m.chatToolCallFocus == id
+	expanded := m.chatToolCallExpanded[id]
+
+	marker := "▸ "
+	if expanded {
+		marker = "▾ "
+	}
+	markerStyle := m.th.Muted
+	if focused {
+		markerStyle = m.th.Accent.Bold(true)
+	}
+	status := fmt.Sprintf("(%s)", formatToolCallDuration(tc.duration(m.now)))
+	if !tc.Done {
+		status = "(running…)"
+	}
+	summary := markerStyle.Render(marker) + m.th.Muted.Render(fmt.Sprintf("ran `%s` %s", tc.Tool, status))
+	lines := wrapChatBlock(summary, "  ", "", innerWidth)
+	if !expanded {
+		return lines
+	}
+
+	if strings.TrimSpace(tc.Args) != "" {
+		lines = append(lines, wrapChatBlock(m.th.Muted.Render("    args: "), "          ", tc.Args, innerWidth)...)
+	}
+	if strings.TrimSpace(tc.Result) != "" {
+		lines = append(lines, wrapChatBlock(m.th.Muted.Render("    → "), "      ", tc.Result, innerWidth)...)
+	}
+	if len(tc.FileChanges) > 0 {
+		lines = append(lines, wrapChatBlock(m.th.Muted.Render("    files: "), "           ", strings.Join(tc.FileChanges, "\n"), innerWidth)...)
+	}
+	return lines
+}
+
+func formatToolCallDuration(d time.Duration) string {
+	if d <= 0 {
+		return "0ms"
+	}
+	if d < time.Second {
+		return fmt.Sprintf("%dms", d.Milliseconds())
+	}
+	return d.Round(100 * time.Millisecond).String()
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+)
+
+// codeLangAlias normalizes a fence's language tag to the key codeKeywords
+// and lineCommentPrefix use, so ```js and ```javascript share one entry.
+var codeLangAlias = map[string]string{
+	"js":    "javascript",
+	"ts":    "typescript",
+	"py":    "python",
+	"shell": "bash",
+}
+
+// codeKeywords maps a normalized language tag to its highlighted keyword
+// set. A tag absent from this map falls back to th.Muted whole-line in
+// highlightCodeLine, per the "unknown languages" fallback.
+var codeKeywords = map[string]map[string]bool{
+	"go":         setOf("func", "package", "import", "var", "const", "type", "struct", "interface", "return", "if", "else", "for", "range", "switch", "case", "default", "break", "continue", "go", "defer", "chan", "select", "map", "nil", "true", "false", "err"),
+	"javascript": setOf("function", "const", "let", "var", "return", "if", "else", "for", "while", "switch", "case", "default", "break", "continue", "class", "extends", "new", "import", "export", "from", "async", "await", "true", "false", "null", "undefined"),
+	"typescript": setOf("function", "const", "let", "var", "return", "if", "else", "for", "while", "switch", "case", "default", "break", "continue", "class", "extends", "interface", "type", "new", "import", "export", "from", "async", "await", "true", "false", "null", "undefined"),
+	"python":     setOf("def", "class", "return", "if", "elif", "else", "for", "while", "break", "continue", "import", "from", "as", "with", "try", "except", "finally", "raise", "lambda", "yield", "async", "await", "True", "False", "None", "self"),
+	"bash":       setOf("if", "then", "else", "elif", "fi", "for", "while", "do", "done", "case", "esac", "function", "return", "local", "export", "echo"),
+	"json":       setOf("true", "false", "null"),
+	"yaml":       setOf("true", "false", "null"),
+}
+
+// lineCommentPrefix maps a normalized language tag to its line-comment
+// marker, used by highlightCodeLine to mute everything from the marker on.
+var lineCommentPrefix = map[string]string{
+	"go": "//", "javascript": "//", "typescript": "//", "rust": "//", "java": "//", "c": "//", "cpp": "//",
+	"python": "#", "bash": "#", "yaml": "#", "toml": "#",
+}
+
+func setOf(words ...string) map[string]bool {
+	s := make(map[string]bool, len(words))
+	for _, w := range words {
+		s[w] = true
+	}
+	return s
+}
+
+// highlightCodeLine tokenizes one line of a fenced code block for lang:
+// the line comment (if lang has one) renders muted from its marker on,
+// quoted strings and numbers render accent, and words in codeKeywords
+// render bold accent. A lang with no codeKeywords entry - including an
+// empty fence tag - renders the whole line th.Muted, the same "don't
+// guess" fallback styleCodeSpan uses for an unrecognized inline span.
+func highlightCodeLine(th theme, lang string, line string) string {
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	if alias, ok := codeLangAlias[lang]; ok {
+		lang = alias
+	}
+	keywords, known := codeKeywords[lang]
+	if !known {
+		return th.Muted.Render(line)
+	}
+
+	if marker, ok := lineCommentPrefix[lang]; ok {
+		if idx := strings.Index(line, marker); idx >= 0 {
+			return highlightCodeTokens(th, keywords, line[:idx]) + th.Muted.Render(line[idx:])
+		}
+	}
+	return highlightCodeTokens(th, keywords, line)
+}
+
+// highlightCodeTokens walks line byte-by-byte, styling quoted strings,
+// numbers, and keywords drawn from keywords; everything else (operators,
+// punctuation, non-keyword identifiers) passes through unstyled.
+func highlightCodeTokens(th theme, keywords map[string]bool, line string) string {
+	var b strings.Builder
+	for i := 0; i < len(line); {
+		c := line[i]
+		switch {
+		case c == '"' || c == '\'' || c == '`':
+			j := i + 1
+			for j < len(line) && line[j] != c {
+				j++
+			}
+			if j < len(line) {
+				j++
+			}
+			b.WriteString(th.Accent.Render(line[i:j]))
+			i = j
+		case unicode.IsDigit(rune(c)):
+			j := i
+			for j < len(line) && (unicode.IsDigit(rune(line[j])) || line[j] == '.') {
+				j++
+			}
+			b.WriteString(th.Accent.Render(line[i:j]))
+			i = j
+		case isIdentStart(rune(c)):
+			j := i
+			for j < len(line) && isIdentPart(rune(line[j])) {
+				j++
+			}
+			word := line[i:j]
+			if keywords[word] {
+				b.WriteString(th.Accent.Bold(true).Render(word))
+			} else {
+				b.WriteString(word)
+			}
+			i = j
+		default:
+			b.WriteByte(c)
+			i++
+		}
+	}
+	return b.String()
+}
+
+func isIdentStart(r rune) bool {
+	return unicode.IsLetter(r) || r == '_'
+}
+
+func isIdentPart(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+}
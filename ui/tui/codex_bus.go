@@ -1,8 +1,8 @@
 package main
 
 import (
-	"bufio"
 	"encoding/json"
+	"errors"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -20,6 +20,10 @@ type codexTurnRequest struct {
 	NoShell       bool   `json:"noShell,omitempty"`
 	Think         bool   `json:"think,omitempty"` // request narrated reasoning/plan stream
 	PermissionMode string `json:"permissionMode,omitempty"` // plan|bypass (executor-defined)
+	DeadlineMs    int64  `json:"deadlineMs,omitempty"`    // abort if no turn.result by this many ms after submission
+	IdleTimeoutMs int64  `json:"idleTimeoutMs,omitempty"` // abort if no turn.event arrives for this many ms (see codexTurnTracker)
+	Sig           string `json:"sig,omitempty"`           // Ed25519 signature over the envelope with Sig/KeyID cleared, see codex_bus_auth.go
+	KeyID         string `json:"keyId,omitempty"`         // hex-encoded Ed25519 public key that produced Sig
 }
 
 type codexTurnResponse struct {
@@ -32,6 +36,8 @@ type codexTurnResponse struct {
 	FileChanges   []string `json:"fileChanges,omitempty"`
 	StartedAt     string   `json:"startedAt,omitempty"`
 	EndedAt       string   `json:"endedAt,omitempty"`
+	Sig           string   `json:"sig,omitempty"`   // see codexTurnRequest.Sig
+	KeyID         string   `json:"keyId,omitempty"` // see codexTurnRequest.KeyID
 }
 
 type codexTurnEvent struct {
@@ -39,9 +45,11 @@ type codexTurnEvent struct {
 	Type          string `json:"type"` // turn.event
 	CorrelationID string `json:"correlationId"`
 	At            string `json:"at"`
-	Kind          string `json:"kind"` // think|tool_use|step_start|step_finish|delta|info|error
+	Kind          string `json:"kind"` // think|tool_use|tool_call|step_start|step_finish|tool_result|delta|info|error|plan|plan.revision|plan.malformed
 	Message       string `json:"message"`
 	Tool          string `json:"tool,omitempty"`
+	Sig           string `json:"sig,omitempty"`   // see codexTurnRequest.Sig
+	KeyID         string `json:"keyId,omitempty"` // see codexTurnRequest.KeyID
 }
 
 func initCodexBus(responsesPath string, requestsPath string, eventsPath string) (responsesOffset int64, eventsOffset int64) {
@@ -67,60 +75,63 @@ func appendCodexRequest(path string, req codexTurnRequest) error {
 	if strings.TrimSpace(req.Type) == "" {
 		req.Type = "turn"
 	}
+	signCodexTurnRequest(path, &req)
 	b, err := json.Marshal(req)
 	if err != nil {
 		return err
 	}
-	_ = os.MkdirAll(filepath.Dir(path), 0o755)
-	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
-	if err != nil {
-		return err
-	}
-	defer f.Close()
-	_, err = f.Write(append(b, '\n'))
-	return err
+	return appendCodexBusLine(path, append(b, '\n'))
 }
 
-func readCodexResponses(path string, offset int64) ([]codexTurnResponse, int64) {
+// readCodexResponses returns the responses available at offset, the
+// advanced offset, and a non-nil *multiError of ErrCorruptLine/ErrUnauthorized
+// entries for any malformed or ACL-rejected lines (well-formed, authorized
+// responses are still returned).
+func readCodexResponses(path string, offset int64) ([]codexTurnResponse, int64, error) {
 	if strings.TrimSpace(path) == "" {
-		return nil, offset
-	}
-	f, err := os.Open(path)
-	if err != nil {
-		return nil, offset
+		return nil, offset, nil
+	}
+	acl := loadCodexACL(path)
+	var merr *multiError
+	out, cur, err := readJSONLFrom(path, offset, func(line []byte) (codexTurnResponse, bool) {
+		var r codexTurnResponse
+		if json.Unmarshal(line, &r) != nil || r.Version != 1 || strings.TrimSpace(r.Type) == "" {
+			return codexTurnResponse{}, false
+		}
+		if !verifyCodexTurnResponse(acl, r) {
+			merr = merr.add(newBusError(ErrUnauthorized, r.CorrelationID))
+			return codexTurnResponse{}, false
+		}
+		return r, true
+	})
+	var be *busError
+	if errors.As(err, &be) && be.Code == ErrIO {
+		return nil, offset, nil
 	}
-	defer f.Close()
+	return out, cur, mergeBusErrors(err, asMultiErrorOrNil(merr))
+}
 
-	st, err := f.Stat()
-	if err == nil && offset > st.Size() {
-		offset = st.Size()
+// appendCodexResponse signs and appends resp to the responses bus. It exists
+// for backends that don't write codex.responses.jsonl themselves (the stdio
+// and http Executor implementations) so executorBridgeTurn can fold their
+// TurnResult back onto the same file the file bus's readCodexResponses
+// already tails, keeping consumeCodexEvents backend-agnostic.
+func appendCodexResponse(path string, resp codexTurnResponse) error {
+	if strings.TrimSpace(path) == "" {
+		return nil
 	}
-	if offset > 0 {
-		if _, err := f.Seek(offset, 0); err != nil {
-			return nil, offset
-		}
+	if resp.Version == 0 {
+		resp.Version = 1
 	}
-
-	var out []codexTurnResponse
-	reader := bufio.NewReader(f)
-	cur := offset
-	for {
-		line, err := reader.ReadString('\n')
-		if line != "" {
-			cur += int64(len(line))
-			txt := strings.TrimSpace(line)
-			if txt != "" {
-				var r codexTurnResponse
-				if json.Unmarshal([]byte(txt), &r) == nil && r.Version == 1 && strings.TrimSpace(r.Type) != "" {
-					out = append(out, r)
-				}
-			}
-		}
-		if err != nil {
-			break
-		}
+	if strings.TrimSpace(resp.Type) == "" {
+		resp.Type = "turn.result"
+	}
+	signCodexTurnResponse(path, &resp)
+	b, err := json.Marshal(resp)
+	if err != nil {
+		return err
 	}
-	return out, cur
+	return appendCodexBusLine(path, append(b, '\n'))
 }
 
 func appendCodexEvent(path string, ev codexTurnEvent) error {
@@ -133,60 +144,36 @@ func appendCodexEvent(path string, ev codexTurnEvent) error {
 	if strings.TrimSpace(ev.Type) == "" {
 		ev.Type = "turn.event"
 	}
+	signCodexTurnEvent(path, &ev)
 	b, err := json.Marshal(ev)
 	if err != nil {
 		return err
 	}
-	_ = os.MkdirAll(filepath.Dir(path), 0o755)
-	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
-	if err != nil {
-		return err
-	}
-	defer f.Close()
-	_, err = f.Write(append(b, '\n'))
-	return err
+	return appendCodexBusLine(path, append(b, '\n'))
 }
 
-func readCodexEvents(path string, offset int64) ([]codexTurnEvent, int64) {
+func readCodexEvents(path string, offset int64) ([]codexTurnEvent, int64, error) {
 	if strings.TrimSpace(path) == "" {
-		return nil, offset
-	}
-	f, err := os.Open(path)
-	if err != nil {
-		return nil, offset
-	}
-	defer f.Close()
-
-	st, err := f.Stat()
-	if err == nil && offset > st.Size() {
-		offset = st.Size()
-	}
-	if offset > 0 {
-		if _, err := f.Seek(offset, 0); err != nil {
-			return nil, offset
+		return nil, offset, nil
+	}
+	acl := loadCodexACL(path)
+	var merr *multiError
+	out, cur, err := readJSONLFrom(path, offset, func(line []byte) (codexTurnEvent, bool) {
+		var ev codexTurnEvent
+		if json.Unmarshal(line, &ev) != nil || ev.Version != 1 || strings.TrimSpace(ev.Type) != "turn.event" {
+			return codexTurnEvent{}, false
 		}
-	}
-
-	var out []codexTurnEvent
-	reader := bufio.NewReader(f)
-	cur := offset
-	for {
-		line, err := reader.ReadString('\n')
-		if line != "" {
-			cur += int64(len(line))
-			txt := strings.TrimSpace(line)
-			if txt != "" {
-				var ev codexTurnEvent
-				if json.Unmarshal([]byte(txt), &ev) == nil && ev.Version == 1 && strings.TrimSpace(ev.Type) == "turn.event" {
-					out = append(out, ev)
-				}
-			}
-		}
-		if err != nil {
-			break
+		if !verifyCodexTurnEvent(acl, ev) {
+			merr = merr.add(newBusError(ErrUnauthorized, ev.CorrelationID))
+			return codexTurnEvent{}, false
 		}
+		return ev, true
+	})
+	var be *busError
+	if errors.As(err, &be) && be.Code == ErrIO {
+		return nil, offset, nil
 	}
-	return out, cur
+	return out, cur, mergeBusErrors(err, asMultiErrorOrNil(merr))
 }
 
 func codexExecutorReadyPath(stateDir string, sessionID string) string {
@@ -230,8 +217,24 @@ func codexExecutorDiagnostic(stateDir string, sessionID string, now time.Time) s
 
 	// Check if heartbeat is stale
 	if now.Sub(st.ModTime()) > 30*time.Second {
+		if detail := codexSupervisorDiagnostic(readCodexHeartbeat(p), now); detail != "" {
+			return detail + ". Check: .workbench/logs/codex-executor.log"
+		}
 		return "Codex executor heartbeat stale. Executor may have crashed. Check: .workbench/logs/codex-executor.log"
 	}
 
 	return ""
 }
+
+// readCodexHeartbeat best-effort parses a codexHeartbeat out of path,
+// returning a zero value if the file predates the structured heartbeat (a
+// bare mtime-touch) or isn't valid JSON.
+func readCodexHeartbeat(path string) codexHeartbeat {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return codexHeartbeat{}
+	}
+	var hb codexHeartbeat
+	_ = json.Unmarshal(raw, &hb)
+	return hb
+}
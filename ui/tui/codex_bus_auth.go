@@ -0,0 +1,271 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// codexACLEntry lists what a single Ed25519 public key (identified by its
+// hex-encoded KeyID, see loadOrCreateCodexSigningKey) is allowed to do on
+// the codex bus. An empty AllowedTypes means "no restriction", so an entry
+// of {} still grants full access to that KeyID — the allow-list only needs
+// to enumerate the keys that are *trusted at all*. There is deliberately no
+// cwd/permissionMode dimension here: codexTurnResponse/codexTurnEvent (the
+// only frames this ACL gates, see readCodexResponses/readCodexEvents) don't
+// carry either field, so there is nothing on the envelope to check them
+// against.
+type codexACLEntry struct {
+	AllowedTypes []string `json:"allowedTypes,omitempty"`
+}
+
+// codexACL maps KeyID to what that key may do. See codex.acl.json next to
+// the bus files it governs (codexACLPath).
+type codexACL map[string]codexACLEntry
+
+// codexACLPath returns the per-session allow-list sitting alongside the
+// given bus file (codex.requests/responses/events.jsonl all live in
+// stateDir/sessionID/, and so does codex.acl.json).
+func codexACLPath(busFilePath string) string {
+	return filepath.Join(filepath.Dir(busFilePath), "codex.acl.json")
+}
+
+var (
+	codexACLCacheMu sync.Mutex
+	codexACLCache   = map[string]codexACLCacheEntry{}
+)
+
+type codexACLCacheEntry struct {
+	acl     codexACL
+	modTime time.Time
+}
+
+// loadCodexACL returns the ACL next to busFilePath, or nil if no
+// codex.acl.json exists there — callers treat a nil ACL as "no restrictions
+// configured" (fail open), matching the repo's other opt-in-by-env-var knobs
+// (e.g. codexBusMaxBytes): signature verification is always enforced (an
+// unsigned frame fails it too), but authorization is only enforced once an
+// admin has actually written an allow-list.
+func loadCodexACL(busFilePath string) codexACL {
+	path := codexACLPath(busFilePath)
+	st, err := os.Stat(path)
+	if err != nil {
+		return nil
+	}
+
+	codexACLCacheMu.Lock()
+	if ce, ok := codexACLCache[path]; ok && ce.modTime.Equal(st.ModTime()) {
+		codexACLCacheMu.Unlock()
+		return ce.acl
+	}
+	codexACLCacheMu.Unlock()
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var acl codexACL
+	if json.Unmarshal(raw, &acl) != nil {
+		return nil
+	}
+
+	codexACLCacheMu.Lock()
+	codexACLCache[path] = codexACLCacheEntry{acl: acl, modTime: st.ModTime()}
+	codexACLCacheMu.Unlock()
+	return acl
+}
+
+var (
+	codexSigningKeysMu sync.Mutex
+	codexSigningKeys   = map[string]codexSigningKey{}
+)
+
+type codexSigningKey struct {
+	key   ed25519.PrivateKey
+	keyID string
+}
+
+// codexSigningKeyPath returns ~/.workbench/keys/<sessionID>.ed25519, the
+// per-session private key appendCodexRequest/appendCodexEvent sign with.
+// Falls back to a relative .workbench/keys if the home directory can't be
+// resolved (e.g. a minimal container without $HOME).
+func codexSigningKeyPath(sessionID string) string {
+	home, err := os.UserHomeDir()
+	if err != nil || strings.TrimSpace(home) == "" {
+		home = "."
+	}
+	return filepath.Join(home, ".workbench", "keys", sessionID+".ed25519")
+}
+
+// loadOrCreateCodexSigningKey loads the Ed25519 seed at
+// codexSigningKeyPath(sessionID), generating and persisting a new one on
+// first use. KeyID is the hex-encoded public key: since it's derived from
+// the key itself rather than assigned out-of-band, a codex.acl.json entry
+// can trust a specific KeyID without any separate key-registry step.
+func loadOrCreateCodexSigningKey(sessionID string) (codexSigningKey, error) {
+	codexSigningKeysMu.Lock()
+	defer codexSigningKeysMu.Unlock()
+	if sk, ok := codexSigningKeys[sessionID]; ok {
+		return sk, nil
+	}
+
+	path := codexSigningKeyPath(sessionID)
+	if seed, err := os.ReadFile(path); err == nil && len(seed) == ed25519.SeedSize {
+		key := ed25519.NewKeyFromSeed(seed)
+		sk := codexSigningKey{key: key, keyID: hex.EncodeToString(key.Public().(ed25519.PublicKey))}
+		codexSigningKeys[sessionID] = sk
+		return sk, nil
+	}
+
+	pub, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return codexSigningKey{}, err
+	}
+	_ = os.MkdirAll(filepath.Dir(path), 0o755)
+	if err := os.WriteFile(path, key.Seed(), 0o600); err != nil {
+		return codexSigningKey{}, err
+	}
+	sk := codexSigningKey{key: key, keyID: hex.EncodeToString(pub)}
+	codexSigningKeys[sessionID] = sk
+	return sk, nil
+}
+
+// signCodexTurnRequest signs req in place: it derives sessionID from path
+// (stateDir/sessionID/codex.requests.jsonl), loads or creates that
+// session's signing key, and sets Sig/KeyID over the canonical JSON of req
+// with Sig/KeyID cleared. A key-loading failure leaves req unsigned rather
+// than failing the append — an unsigned frame is simply dropped by any
+// reader enforcing ACLs, which is a safe default.
+func signCodexTurnRequest(path string, req *codexTurnRequest) {
+	sk, err := loadOrCreateCodexSigningKey(codexSessionIDFromPath(path))
+	if err != nil {
+		return
+	}
+	req.Sig, req.KeyID = "", ""
+	payload, err := json.Marshal(req)
+	if err != nil {
+		return
+	}
+	req.Sig = hex.EncodeToString(ed25519.Sign(sk.key, payload))
+	req.KeyID = sk.keyID
+}
+
+// signCodexTurnEvent mirrors signCodexTurnRequest for the events bus.
+func signCodexTurnEvent(path string, ev *codexTurnEvent) {
+	sk, err := loadOrCreateCodexSigningKey(codexSessionIDFromPath(path))
+	if err != nil {
+		return
+	}
+	ev.Sig, ev.KeyID = "", ""
+	payload, err := json.Marshal(ev)
+	if err != nil {
+		return
+	}
+	ev.Sig = hex.EncodeToString(ed25519.Sign(sk.key, payload))
+	ev.KeyID = sk.keyID
+}
+
+// signCodexTurnResponse mirrors signCodexTurnRequest for the responses bus.
+func signCodexTurnResponse(path string, resp *codexTurnResponse) {
+	sk, err := loadOrCreateCodexSigningKey(codexSessionIDFromPath(path))
+	if err != nil {
+		return
+	}
+	resp.Sig, resp.KeyID = "", ""
+	payload, err := json.Marshal(resp)
+	if err != nil {
+		return
+	}
+	resp.Sig = hex.EncodeToString(ed25519.Sign(sk.key, payload))
+	resp.KeyID = sk.keyID
+}
+
+// codexSessionIDFromPath recovers the session ID from a bus file path of
+// the form stateDir/sessionID/codex.*.jsonl.
+func codexSessionIDFromPath(path string) string {
+	return filepath.Base(filepath.Dir(path))
+}
+
+// verifyCodexTurnResponse checks r's signature against its own KeyID (the
+// hex-encoded public key IS the KeyID, so no separate trust store lookup is
+// needed) and, if acl is non-nil, that KeyID is both listed and permitted
+// the response's type. acl == nil means no allow-list is configured for
+// this session, so only the signature check applies.
+func verifyCodexTurnResponse(acl codexACL, r codexTurnResponse) bool {
+	if !verifyCodexSig(r.KeyID, r.Sig, withoutSig(r)) {
+		return false
+	}
+	if acl == nil {
+		return true
+	}
+	entry, ok := acl[r.KeyID]
+	if !ok {
+		return false
+	}
+	return aclAllowsType(entry, "turn.result")
+}
+
+// verifyCodexTurnEvent mirrors verifyCodexTurnResponse for turn.event frames.
+func verifyCodexTurnEvent(acl codexACL, ev codexTurnEvent) bool {
+	if !verifyCodexSig(ev.KeyID, ev.Sig, withoutSigEvent(ev)) {
+		return false
+	}
+	if acl == nil {
+		return true
+	}
+	entry, ok := acl[ev.KeyID]
+	if !ok {
+		return false
+	}
+	return aclAllowsType(entry, "turn.event")
+}
+
+func aclAllowsType(entry codexACLEntry, typ string) bool {
+	if len(entry.AllowedTypes) == 0 {
+		return true
+	}
+	for _, t := range entry.AllowedTypes {
+		if t == typ {
+			return true
+		}
+	}
+	return false
+}
+
+// verifyCodexSig decodes keyID back into an Ed25519 public key and verifies
+// sig over payload. Any malformed hex, wrong-length key, or missing
+// signature is treated as a verification failure, not a panic.
+func verifyCodexSig(keyID string, sig string, payload []byte) bool {
+	if strings.TrimSpace(keyID) == "" || strings.TrimSpace(sig) == "" {
+		return false
+	}
+	pub, err := hex.DecodeString(keyID)
+	if err != nil || len(pub) != ed25519.PublicKeySize {
+		return false
+	}
+	sigBytes, err := hex.DecodeString(sig)
+	if err != nil {
+		return false
+	}
+	return ed25519.Verify(ed25519.PublicKey(pub), payload, sigBytes)
+}
+
+// withoutSig re-marshals r with Sig/KeyID cleared, reproducing the exact
+// bytes signCodexTurnResponse signed on the producer side.
+func withoutSig(r codexTurnResponse) []byte {
+	r.Sig, r.KeyID = "", ""
+	b, _ := json.Marshal(r)
+	return b
+}
+
+func withoutSigEvent(ev codexTurnEvent) []byte {
+	ev.Sig, ev.KeyID = "", ""
+	b, _ := json.Marshal(ev)
+	return b
+}
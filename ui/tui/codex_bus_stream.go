@@ -0,0 +1,225 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// codexBus tails the codex responses and events NDJSON files with fsnotify
+// and reopens them transparently across rotation (see rotatingWriter),
+// replacing the naive offset-based polling readCodexResponses/readCodexEvents
+// do: those silently stop making progress the moment a file they're tracking
+// gets rotated out from under them.
+type codexBus struct {
+	responsesPath string
+	eventsPath    string
+
+	responses chan codexTurnResponse
+	events    chan codexTurnEvent
+	errs      chan error
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// newCodexBus starts tailing responsesPath and eventsPath in the background.
+// Call Close to stop both tailers; the channels are closed once their
+// tailer goroutine exits.
+func newCodexBus(ctx context.Context, responsesPath string, eventsPath string) *codexBus {
+	ctx, cancel := context.WithCancel(ctx)
+	b := &codexBus{
+		responsesPath: responsesPath,
+		eventsPath:    eventsPath,
+		responses:     make(chan codexTurnResponse, 32),
+		events:        make(chan codexTurnEvent, 32),
+		errs:          make(chan error, 4),
+		cancel:        cancel,
+	}
+	b.wg.Add(2)
+	go b.tailResponses(ctx)
+	go b.tailEvents(ctx)
+	return b
+}
+
+func (b *codexBus) Responses() <-chan codexTurnResponse { return b.responses }
+func (b *codexBus) Events() <-chan codexTurnEvent       { return b.events }
+func (b *codexBus) Errs() <-chan error                  { return b.errs }
+
+func (b *codexBus) Close() {
+	b.cancel()
+	b.wg.Wait()
+}
+
+func (b *codexBus) tailResponses(ctx context.Context) {
+	defer b.wg.Done()
+	defer close(b.responses)
+	rotatingTail(ctx, b.responsesPath, func(offset int64) ([]codexTurnResponse, int64, error) {
+		return readCodexResponses(b.responsesPath, offset)
+	}, b.responses, b.errs)
+}
+
+func (b *codexBus) tailEvents(ctx context.Context) {
+	defer b.wg.Done()
+	defer close(b.events)
+	rotatingTail(ctx, b.eventsPath, func(offset int64) ([]codexTurnEvent, int64, error) {
+		return readCodexEvents(b.eventsPath, offset)
+	}, b.events, b.errs)
+}
+
+// rotatingTail drives a read-until-empty loop over path using read, waking
+// on fsnotify events for either appends or rotation. When the tracked file
+// shrinks below the current offset (truncated, or swapped out from under us
+// by rotatingWriter.rotate), it resets to offset 0 and reopens from scratch
+// instead of trying to resume a now-meaningless byte position.
+func rotatingTail[T any](ctx context.Context, path string, read func(offset int64) ([]T, int64, error), out chan<- T, errs chan<- error) {
+	tailer, err := newDeadlineTailer(path, 250*time.Millisecond)
+	if err != nil {
+		select {
+		case errs <- err:
+		default:
+		}
+		return
+	}
+	defer tailer.Close()
+
+	var offset int64
+	for {
+		if st, statErr := os.Stat(path); statErr == nil && st.Size() < offset {
+			// Rotation (or truncation): the file shrank out from under our
+			// offset, so there's nothing left to resume; start clean.
+			offset = 0
+		}
+
+		items, next, err := read(offset)
+		offset = next
+		for _, it := range items {
+			select {
+			case out <- it:
+			case <-ctx.Done():
+				return
+			}
+		}
+		if err != nil {
+			select {
+			case errs <- err:
+			default:
+			}
+		}
+
+		lastSize := offset
+		if st, statErr := os.Stat(path); statErr == nil {
+			lastSize = st.Size()
+		}
+		if waitErr := tailer.waitForAppend(ctx, lastSize); waitErr != nil {
+			// Either ctx was canceled or the deadline (unset here, so this
+			// only fires via Close -> watcher teardown) expired.
+			return
+		}
+	}
+}
+
+// rotatingWriter is an append-only JSONL writer that rolls path to
+// path.1, path.2, ... once it exceeds maxBytes, pruning segments beyond
+// maxSegments. A maxBytes of 0 disables rotation entirely (single growing
+// file, today's behavior).
+type rotatingWriter struct {
+	path        string
+	maxBytes    int64
+	maxSegments int
+
+	mu sync.Mutex
+}
+
+func newRotatingWriter(path string, maxBytes int64, maxSegments int) *rotatingWriter {
+	if maxSegments <= 0 {
+		maxSegments = 5
+	}
+	return &rotatingWriter{path: path, maxBytes: maxBytes, maxSegments: maxSegments}
+}
+
+func (w *rotatingWriter) Append(line []byte) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	_ = os.MkdirAll(filepath.Dir(w.path), 0o755)
+
+	if w.maxBytes > 0 {
+		if st, err := os.Stat(w.path); err == nil && st.Size()+int64(len(line)) > w.maxBytes {
+			if err := w.rotate(); err != nil {
+				return err
+			}
+		}
+	}
+
+	f, err := os.OpenFile(w.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(line)
+	return err
+}
+
+// rotate shifts path.(n-1) -> path.n down to path.1, then moves path itself
+// to path.1, pruning anything beyond maxSegments.
+func (w *rotatingWriter) rotate() error {
+	for i := w.maxSegments - 1; i >= 1; i-- {
+		src := fmt.Sprintf("%s.%d", w.path, i)
+		dst := fmt.Sprintf("%s.%d", w.path, i+1)
+		if _, err := os.Stat(src); err == nil {
+			_ = os.Rename(src, dst)
+		}
+	}
+	if _, err := os.Stat(w.path); err == nil {
+		if err := os.Rename(w.path, w.path+".1"); err != nil {
+			return err
+		}
+	}
+	pruned := fmt.Sprintf("%s.%d", w.path, w.maxSegments+1)
+	if _, err := os.Stat(pruned); err == nil {
+		_ = os.Remove(pruned)
+	}
+	return nil
+}
+
+// codexBusMaxBytes reads WORKBENCH_CODEX_BUS_MAXBYTES, the rotation
+// threshold for appendCodexRequest/appendCodexEvent. 0 (the default) keeps
+// today's behavior of one unbounded file.
+func codexBusMaxBytes() int64 {
+	v := strings.TrimSpace(os.Getenv("WORKBENCH_CODEX_BUS_MAXBYTES"))
+	if v == "" {
+		return 0
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || n <= 0 {
+		return 0
+	}
+	return n
+}
+
+var (
+	codexBusWritersMu sync.Mutex
+	codexBusWriters   = map[string]*rotatingWriter{}
+)
+
+// appendCodexBusLine writes a single pre-encoded JSONL line to path through
+// the shared rotatingWriter for that path, so concurrent appenders (e.g. the
+// executor process and a retry goroutine) serialize on the same rotation
+// state instead of racing os.OpenFile calls directly. codexBusMaxBytes of 0
+// (the default) disables rotation, matching the pre-rotation behavior.
+func appendCodexBusLine(path string, line []byte) error {
+	codexBusWritersMu.Lock()
+	w, ok := codexBusWriters[path]
+	if !ok {
+		w = newRotatingWriter(path, codexBusMaxBytes(), 5)
+		codexBusWriters[path] = w
+	}
+	codexBusWritersMu.Unlock()
+	return w.Append(line)
+}
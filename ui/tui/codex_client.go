@@ -0,0 +1,388 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"io"
+	"math/rand"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// codexClient drives Codex chat requests with first-class deadlines,
+// cancellation, and hedged retries, modeled on codexTurnTracker's deadline
+// timer / cancel channel pattern: an idle read timer holds a cancel channel
+// that the in-flight SSE scan loop selects against alongside the next line,
+// so a connection that stalls mid-stream is abandoned cleanly instead of
+// hanging until the OS socket times out. Retries on 429/503 use a jittered
+// exponential backoff (honoring Retry-After when the server sends one); an
+// optional hedge fires a second attempt if the first hasn't returned within
+// hedgeAfter, keeping whichever finishes first and cancelling the other.
+type codexClient struct {
+	readDeadline  time.Duration
+	writeDeadline time.Duration
+
+	maxRetries     int
+	retryBaseDelay time.Duration
+	retryMaxDelay  time.Duration
+	hedgeAfter     time.Duration // 0 disables hedged retries
+}
+
+func newCodexClient() *codexClient {
+	return &codexClient{
+		readDeadline:   30 * time.Second,
+		writeDeadline:  70 * time.Second,
+		maxRetries:     2,
+		retryBaseDelay: 500 * time.Millisecond,
+		retryMaxDelay:  8 * time.Second,
+	}
+}
+
+// defaultCodexClient is the codexClient package-level helpers like
+// codexChatStream delegate to, so existing call sites keep working without
+// threading a client through every caller.
+var defaultCodexClient = newCodexClient()
+
+// SetReadDeadline bounds how long the SSE body scan may go between lines
+// before the in-flight attempt is abandoned; it applies to every call made
+// after it's set, mirroring net.Conn's per-connection (not per-call)
+// deadline semantics. Zero disables the idle timeout.
+func (c *codexClient) SetReadDeadline(d time.Duration) { c.readDeadline = d }
+
+// SetWriteDeadline bounds how long sending the request and receiving
+// response headers may take before the attempt is abandoned. Zero disables
+// it. It does not bound the body-streaming phase, which SetReadDeadline
+// governs instead.
+func (c *codexClient) SetWriteDeadline(d time.Duration) { c.writeDeadline = d }
+
+// ChatStream drives a Codex chat turn to completion, retrying retryable
+// failures (HTTP 429/503) with jittered exponential backoff up to
+// c.maxRetries, and hedging each attempt per c.hedgeAfter. The returned
+// codexChatResult.Attempts counts the initial attempt plus each retry; an
+// attempt and its hedge count as one. HedgedWon reports whether the hedge
+// (rather than the original) produced the winning response.
+func (c *codexClient) ChatStream(ctx context.Context, endpoint, model, accessToken, accountID, instructions string, input []chatMessage, onDelta func(delta string)) (codexChatResult, *codexHttpStatusError, error) {
+	maxRetries := c.maxRetries
+	if maxRetries < 0 {
+		maxRetries = 0
+	}
+
+	var lastStatusErr *codexHttpStatusError
+	var lastErr error
+	attempts := 0
+	for retry := 0; retry <= maxRetries; retry++ {
+		if retry > 0 {
+			select {
+			case <-time.After(c.backoffDelay(retry, lastStatusErr)):
+			case <-ctx.Done():
+				return codexChatResult{Attempts: attempts}, lastStatusErr, ctx.Err()
+			}
+		}
+
+		text, statusErr, hedgedWon, err := c.attemptWithHedge(ctx, endpoint, model, accessToken, accountID, instructions, input, onDelta)
+		attempts++
+		if err == nil && statusErr == nil {
+			return codexChatResult{Text: text, Attempts: attempts, HedgedWon: hedgedWon}, nil, nil
+		}
+		lastStatusErr, lastErr = statusErr, err
+		if statusErr == nil || !codexRetryableStatus(statusErr.Status) || retry == maxRetries {
+			return codexChatResult{Attempts: attempts}, statusErr, err
+		}
+	}
+	return codexChatResult{Attempts: attempts}, lastStatusErr, lastErr
+}
+
+func codexRetryableStatus(status int) bool {
+	return status == 429 || status == 503
+}
+
+func (c *codexClient) backoffDelay(retry int, statusErr *codexHttpStatusError) time.Duration {
+	if statusErr != nil && statusErr.RetryAfterMs > 0 {
+		return time.Duration(statusErr.RetryAfterMs) * time.Millisecond
+	}
+	base := c.retryBaseDelay
+	if base <= 0 {
+		base = 500 * time.Millisecond
+	}
+	maxDelay := c.retryMaxDelay
+	if maxDelay <= 0 {
+		maxDelay = 8 * time.Second
+	}
+	d := base * time.Duration(int64(1)<<uint(retry-1))
+	if d <= 0 || d > maxDelay {
+		d = maxDelay
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// attemptWithHedge runs a single (possibly hedged) attempt. With hedging
+// disabled it's just one chatStreamAttempt call; otherwise it starts a
+// second attempt after c.hedgeAfter if the first hasn't returned yet, and
+// returns whichever finishes first, cancelling the other.
+func (c *codexClient) attemptWithHedge(ctx context.Context, endpoint, model, accessToken, accountID, instructions string, input []chatMessage, onDelta func(delta string)) (string, *codexHttpStatusError, bool, error) {
+	if c.hedgeAfter <= 0 {
+		text, statusErr, err := c.chatStreamAttempt(ctx, endpoint, model, accessToken, accountID, instructions, input, onDelta)
+		return text, statusErr, false, err
+	}
+
+	type result struct {
+		text      string
+		statusErr *codexHttpStatusError
+		err       error
+		hedged    bool
+	}
+
+	primaryCtx, cancelPrimary := context.WithCancel(ctx)
+	defer cancelPrimary()
+	hedgeCtx, cancelHedge := context.WithCancel(ctx)
+	defer cancelHedge()
+
+	results := make(chan result, 2)
+	go func() {
+		text, statusErr, err := c.chatStreamAttempt(primaryCtx, endpoint, model, accessToken, accountID, instructions, input, onDelta)
+		results <- result{text: text, statusErr: statusErr, err: err}
+	}()
+
+	timer := time.NewTimer(c.hedgeAfter)
+	defer timer.Stop()
+
+	pending := 1
+	hedgeStarted := false
+	for {
+		select {
+		case r := <-results:
+			pending--
+			if r.err == nil && r.statusErr == nil {
+				if r.hedged {
+					cancelPrimary()
+				} else {
+					cancelHedge()
+				}
+				return r.text, r.statusErr, r.hedged, r.err
+			}
+			if pending == 0 {
+				return r.text, r.statusErr, r.hedged, r.err
+			}
+			// The other attempt (hedge, still racing) may yet succeed.
+		case <-timer.C:
+			if !hedgeStarted {
+				hedgeStarted = true
+				pending++
+				go func() {
+					// The hedge never drives onDelta: the UI only wants one
+					// stream of thought deltas, not two interleaved copies.
+					text, statusErr, err := c.chatStreamAttempt(hedgeCtx, endpoint, model, accessToken, accountID, instructions, input, nil)
+					results <- result{text: text, statusErr: statusErr, err: err, hedged: true}
+				}()
+			}
+		case <-ctx.Done():
+			return "", nil, false, ctx.Err()
+		}
+	}
+}
+
+// chatStreamAttempt performs exactly one HTTP round trip: it bounds the
+// request-send/headers phase with c.writeDeadline, then hands the body off
+// to scanSSE, which bounds idle gaps between SSE lines with c.readDeadline.
+func (c *codexClient) chatStreamAttempt(ctx context.Context, endpoint, model, accessToken, accountID, instructions string, input []chatMessage, onDelta func(delta string)) (string, *codexHttpStatusError, error) {
+	if strings.TrimSpace(accessToken) == "" {
+		return "", nil, errors.New("missing OAuth access token")
+	}
+	if strings.TrimSpace(endpoint) == "" {
+		endpoint = defaultCodexEndpoint
+	}
+	if strings.TrimSpace(model) == "" {
+		model = "gpt-5.2-codex"
+	}
+	if strings.TrimSpace(instructions) == "" {
+		instructions = "Workbench session."
+	}
+
+	msgs := make([]map[string]any, 0, len(input))
+	for _, m := range input {
+		role := strings.TrimSpace(m.Role)
+		if role == "" {
+			continue
+		}
+		msgs = append(msgs, map[string]any{"role": role, "content": m.Content})
+	}
+
+	body := map[string]any{
+		"model":        model,
+		"instructions": instructions,
+		"input":        msgs,
+		"store":        false,
+		"stream":       true,
+	}
+	b, err := json.Marshal(body)
+	if err != nil {
+		return "", nil, err
+	}
+
+	reqCtx, cancelReq := context.WithCancel(ctx)
+	defer cancelReq()
+
+	req, err := http.NewRequestWithContext(reqCtx, "POST", endpoint, bytes.NewReader(b))
+	if err != nil {
+		return "", nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("User-Agent", "MyLLMWorkbench/1.0")
+	if strings.TrimSpace(accountID) != "" {
+		req.Header.Set("ChatGPT-Account-Id", accountID)
+	}
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{MinVersion: tls.VersionTLS12},
+		},
+	}
+
+	type doResult struct {
+		resp *http.Response
+		err  error
+	}
+	doCh := make(chan doResult, 1)
+	go func() {
+		resp, err := client.Do(req)
+		doCh <- doResult{resp: resp, err: err}
+	}()
+
+	var writeTimeoutCh <-chan time.Time
+	if c.writeDeadline > 0 {
+		t := time.NewTimer(c.writeDeadline)
+		defer t.Stop()
+		writeTimeoutCh = t.C
+	}
+
+	var dr doResult
+	select {
+	case dr = <-doCh:
+	case <-writeTimeoutCh:
+		cancelReq()
+		<-doCh
+		return "", nil, errors.New("codex request timed out waiting for response headers")
+	case <-ctx.Done():
+		cancelReq()
+		<-doCh
+		return "", nil, ctx.Err()
+	}
+	if dr.err != nil {
+		return "", nil, dr.err
+	}
+	resp := dr.resp
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		raw, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return "", &codexHttpStatusError{
+			Status:       resp.StatusCode,
+			BodySnippet:  strings.TrimSpace(string(raw)),
+			RetryAfterMs: retryAfterMs(resp),
+		}, nil
+	}
+
+	return c.scanSSE(resp.Body, onDelta)
+}
+
+// scanSSE reads Codex's text/event-stream body on a background goroutine
+// so the main loop can select between the next line and an idle-timeout
+// cancel channel, closing the channel (and abandoning the attempt) if
+// c.readDeadline elapses without a new line arriving, the same way
+// codexTurnTracker's idle timer resets on every turn.event and fires a
+// cancel when it doesn't.
+func (c *codexClient) scanSSE(body io.Reader, onDelta func(delta string)) (string, *codexHttpStatusError, error) {
+	type lineResult struct {
+		line string
+		err  error
+	}
+	lines := make(chan lineResult)
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(body)
+		scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			select {
+			case lines <- lineResult{line: scanner.Text()}:
+			case <-done:
+				return
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			select {
+			case lines <- lineResult{err: err}:
+			case <-done:
+			}
+		}
+	}()
+
+	var cancelCh chan struct{}
+	var idleTimer *time.Timer
+	if c.readDeadline > 0 {
+		cancelCh = make(chan struct{})
+		idleTimer = time.AfterFunc(c.readDeadline, func() { close(cancelCh) })
+		defer idleTimer.Stop()
+	}
+
+	var textParts []string
+	for {
+		select {
+		case lr, ok := <-lines:
+			if !ok {
+				if len(textParts) == 0 {
+					return "", nil, errors.New("empty codex response")
+				}
+				return strings.Join(textParts, ""), nil, nil
+			}
+			if lr.err != nil {
+				return "", nil, lr.err
+			}
+			if idleTimer != nil {
+				idleTimer.Stop()
+				idleTimer.Reset(c.readDeadline)
+			}
+			line := strings.TrimSpace(lr.line)
+			if line == "" || !strings.HasPrefix(line, "data: ") {
+				continue
+			}
+			dataStr := strings.TrimSpace(strings.TrimPrefix(line, "data: "))
+			if dataStr == "[DONE]" {
+				if len(textParts) == 0 {
+					return "", nil, errors.New("empty codex response")
+				}
+				return strings.Join(textParts, ""), nil, nil
+			}
+			var event map[string]any
+			if json.Unmarshal([]byte(dataStr), &event) != nil {
+				continue
+			}
+			if eventType, _ := event["type"].(string); eventType == "response.output_text.delta" {
+				if delta, _ := event["delta"].(string); delta != "" {
+					textParts = append(textParts, delta)
+					if onDelta != nil {
+						onDelta(delta)
+					}
+				}
+			}
+			if eventType, _ := event["type"].(string); eventType == "response.completed" {
+				if respObj, ok := event["response"].(map[string]any); ok {
+					if outText, _ := respObj["output_text"].(string); strings.TrimSpace(outText) != "" {
+						return outText, nil, nil
+					}
+				}
+			}
+		case <-cancelCh:
+			return "", nil, errors.New("codex stream idle timeout exceeded")
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestCodexClientIdleTimeoutCancelsStalledStream drives a server that writes
+// one SSE delta then stalls forever (never closes the body), the same shape
+// a connection wedged mid-stream would take. With a short readDeadline the
+// in-flight scanSSE loop must abandon the attempt instead of hanging until
+// the test (or a real caller) times out on its own.
+func TestCodexClientIdleTimeoutCancelsStalledStream(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(http.StatusOK)
+		flusher := w.(http.Flusher)
+		w.Write([]byte("data: {\"type\":\"response.output_text.delta\",\"delta\":\"hi\"}\n\n"))
+		flusher.Flush()
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+
+	c := newCodexClient()
+	c.maxRetries = 0
+	c.writeDeadline = 2 * time.Second
+	c.readDeadline = 50 * time.Millisecond
+
+	start := time.Now()
+	result, statusErr, err := c.ChatStream(context.Background(), srv.URL, "", "token", "", "", nil, nil)
+	elapsed := time.Since(start)
+
+	if statusErr != nil {
+		t.Fatalf("statusErr = %+v, want nil", statusErr)
+	}
+	if err == nil {
+		t.Fatalf("err = nil, want an idle-timeout error")
+	}
+	if result.Attempts != 1 {
+		t.Fatalf("Attempts = %d, want 1", result.Attempts)
+	}
+	if elapsed > 2*time.Second {
+		t.Fatalf("ChatStream took %v, want it bounded by readDeadline rather than hanging", elapsed)
+	}
+}
+
+// TestCodexClientRetriesOnRetryAfter simulates a 429 with a short
+// Retry-After on the first attempt and a normal completed response on the
+// second, asserting both that the call eventually succeeds and that it
+// actually waited roughly the advertised Retry-After delay rather than the
+// default jittered backoff.
+func TestCodexClientRetriesOnRetryAfter(t *testing.T) {
+	var attempts int32
+	const retryAfterSecs = 1
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte("rate limited"))
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("data: {\"type\":\"response.output_text.delta\",\"delta\":\"ok\"}\n\n"))
+		w.Write([]byte("data: [DONE]\n\n"))
+	}))
+	defer srv.Close()
+
+	c := newCodexClient()
+	c.maxRetries = 1
+	c.writeDeadline = 2 * time.Second
+	c.readDeadline = 2 * time.Second
+
+	start := time.Now()
+	result, statusErr, err := c.ChatStream(context.Background(), srv.URL, "", "token", "", "", nil, nil)
+	elapsed := time.Since(start)
+
+	if err != nil {
+		t.Fatalf("err = %v, want nil after the retry succeeds", err)
+	}
+	if statusErr != nil {
+		t.Fatalf("statusErr = %+v, want nil after the retry succeeds", statusErr)
+	}
+	if result.Text != "ok" {
+		t.Fatalf("Text = %q, want %q", result.Text, "ok")
+	}
+	if result.Attempts != 2 {
+		t.Fatalf("Attempts = %d, want 2 (one 429, one success)", result.Attempts)
+	}
+	if elapsed < retryAfterSecs*time.Second {
+		t.Fatalf("elapsed = %v, want at least the %ds Retry-After delay before the retry", elapsed, retryAfterSecs)
+	}
+}
+
+// TestCodexClientGivesUpAfterMaxRetries checks that a persistently
+// retryable status (503, no Retry-After) is returned as-is once maxRetries
+// is exhausted, instead of retrying forever.
+func TestCodexClientGivesUpAfterMaxRetries(t *testing.T) {
+	var attempts int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte("unavailable"))
+	}))
+	defer srv.Close()
+
+	c := newCodexClient()
+	c.maxRetries = 2
+	c.retryBaseDelay = 5 * time.Millisecond
+	c.retryMaxDelay = 10 * time.Millisecond
+	c.writeDeadline = 2 * time.Second
+	c.readDeadline = 2 * time.Second
+
+	result, statusErr, err := c.ChatStream(context.Background(), srv.URL, "", "token", "", "", nil, nil)
+	if err != nil {
+		t.Fatalf("err = %v, want nil (a status error, not a transport error)", err)
+	}
+	if statusErr == nil || statusErr.Status != http.StatusServiceUnavailable {
+		t.Fatalf("statusErr = %+v, want status 503", statusErr)
+	}
+	if result.Attempts != 3 {
+		t.Fatalf("Attempts = %d, want 3 (1 initial + 2 retries)", result.Attempts)
+	}
+	if got := int(atomic.LoadInt32(&attempts)); got != 3 {
+		t.Fatalf("server saw %d requests, want 3", got)
+	}
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// codexHeartbeat is the JSON body written to codexExecutorReadyPath by
+// codexSupervisor. It supersedes the old bare mtime-touch: isCodexExecutorReady
+// still only cares about the file's ModTime, but codexExecutorDiagnostic now
+// unmarshals this to explain *why* the executor isn't ready instead of a
+// blanket "check the logs" string.
+type codexHeartbeat struct {
+	Pid          int    `json:"pid"`
+	StartedAt    string `json:"startedAt"`
+	RestartCount int    `json:"restartCount"`
+	LastError    string `json:"lastError,omitempty"`
+	LastCrashAt  string `json:"lastCrashAt,omitempty"`
+	RSSBytes     int64  `json:"rssBytes,omitempty"`
+}
+
+const (
+	supervisorMinBackoff    = 500 * time.Millisecond
+	supervisorMaxBackoff    = 30 * time.Second
+	supervisorRestartWindow = 60 * time.Second
+	supervisorHeartbeatTick = 5 * time.Second
+)
+
+// codexSupervisor owns the lifecycle of a `codex` executor child process:
+// spawn it, restart it with exponential backoff on crash, and keep
+// codex.executor.json current with a structured heartbeat (pid, restart
+// count, last error, sampled RSS) rather than a file whose only signal is
+// its mtime. Modeled on the gosuv/supervisord restart-with-backoff loop.
+type codexSupervisor struct {
+	command   string
+	args      []string
+	dir       string
+	readyPath string
+
+	mu                 sync.Mutex
+	restartCount       int
+	restartWindowStart time.Time
+	lastError          string
+	lastCrashAt        time.Time
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func newCodexSupervisor(command string, args []string, dir string, readyPath string) *codexSupervisor {
+	return &codexSupervisor{command: command, args: args, dir: dir, readyPath: readyPath}
+}
+
+// Start spawns the supervised loop in the background. Call Stop to shut the
+// child down and stop restarting it.
+func (s *codexSupervisor) Start(ctx context.Context) {
+	ctx, cancel := context.WithCancel(ctx)
+	s.cancel = cancel
+	s.wg.Add(1)
+	go s.run(ctx)
+}
+
+func (s *codexSupervisor) Stop() {
+	if s.cancel != nil {
+		s.cancel()
+	}
+	s.wg.Wait()
+}
+
+func (s *codexSupervisor) run(ctx context.Context) {
+	defer s.wg.Done()
+	backoff := supervisorMinBackoff
+	for ctx.Err() == nil {
+		cmd := exec.CommandContext(ctx, s.command, s.args...)
+		cmd.Dir = s.dir
+
+		if err := cmd.Start(); err != nil {
+			s.recordCrash(err)
+			if !s.sleepBackoff(ctx, &backoff) {
+				return
+			}
+			continue
+		}
+
+		hbDone := make(chan struct{})
+		go s.heartbeatLoop(ctx, cmd.Process.Pid, hbDone)
+		s.writeHeartbeat(cmd.Process.Pid, s.sampleRSS(cmd.Process.Pid))
+
+		waitErr := cmd.Wait()
+		close(hbDone)
+		if ctx.Err() != nil {
+			return
+		}
+		if waitErr != nil {
+			s.recordCrash(waitErr)
+		} else {
+			// Clean exit: don't punish the next attempt with backoff built
+			// up from earlier crashes.
+			backoff = supervisorMinBackoff
+		}
+		if !s.sleepBackoff(ctx, &backoff) {
+			return
+		}
+	}
+}
+
+// recordCrash bumps the restart counter (resetting it once more than
+// supervisorRestartWindow has passed since the first crash of the current
+// window, so a process that's been healthy for a while doesn't inherit a
+// stale high count) and records the failure for codexExecutorDiagnostic.
+func (s *codexSupervisor) recordCrash(err error) {
+	s.mu.Lock()
+	now := time.Now()
+	if s.restartWindowStart.IsZero() || now.Sub(s.restartWindowStart) > supervisorRestartWindow {
+		s.restartWindowStart = now
+		s.restartCount = 0
+	}
+	s.restartCount++
+	s.lastError = err.Error()
+	s.lastCrashAt = now
+	s.mu.Unlock()
+	s.writeHeartbeat(0, 0)
+}
+
+// sleepBackoff waits the current backoff duration (or until ctx is done,
+// returning false) and then doubles it, capped at supervisorMaxBackoff.
+func (s *codexSupervisor) sleepBackoff(ctx context.Context, backoff *time.Duration) bool {
+	select {
+	case <-time.After(*backoff):
+	case <-ctx.Done():
+		return false
+	}
+	*backoff *= 2
+	if *backoff > supervisorMaxBackoff {
+		*backoff = supervisorMaxBackoff
+	}
+	return true
+}
+
+func (s *codexSupervisor) heartbeatLoop(ctx context.Context, pid int, done <-chan struct{}) {
+	t := time.NewTicker(supervisorHeartbeatTick)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			s.writeHeartbeat(pid, s.sampleRSS(pid))
+		case <-done:
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (s *codexSupervisor) writeHeartbeat(pid int, rssBytes int64) {
+	if strings.TrimSpace(s.readyPath) == "" {
+		return
+	}
+	s.mu.Lock()
+	hb := codexHeartbeat{
+		Pid:          pid,
+		StartedAt:    time.Now().UTC().Format(time.RFC3339),
+		RestartCount: s.restartCount,
+		LastError:    s.lastError,
+	}
+	if !s.lastCrashAt.IsZero() {
+		hb.LastCrashAt = s.lastCrashAt.UTC().Format(time.RFC3339)
+	}
+	s.mu.Unlock()
+	hb.RSSBytes = rssBytes
+
+	b, err := json.Marshal(hb)
+	if err != nil {
+		return
+	}
+	_ = os.MkdirAll(parentDir(s.readyPath), 0o755)
+	_ = os.WriteFile(s.readyPath, b, 0o644)
+}
+
+// sampleRSS best-effort reads VmRSS for pid out of /proc; it returns 0 on
+// any failure (non-Linux, sandboxed pid namespace, process already gone)
+// rather than erroring, since RSS is diagnostic-only.
+func (s *codexSupervisor) sampleRSS(pid int) int64 {
+	if pid <= 0 {
+		return 0
+	}
+	raw, err := os.ReadFile(fmt.Sprintf("/proc/%d/status", pid))
+	if err != nil {
+		return 0
+	}
+	for _, line := range strings.Split(string(raw), "\n") {
+		if !strings.HasPrefix(line, "VmRSS:") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			return 0
+		}
+		kb, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			return 0
+		}
+		return kb * 1024
+	}
+	return 0
+}
+
+// codexSupervisorDiagnostic renders the restart/crash history from a
+// codexHeartbeat into the "restarted N× in Ws, last crash: ..." form
+// codexExecutorDiagnostic surfaces once the CLI/Node prerequisite checks
+// pass but the heartbeat is stale.
+func codexSupervisorDiagnostic(hb codexHeartbeat, now time.Time) string {
+	if hb.RestartCount == 0 && hb.LastError == "" {
+		return ""
+	}
+	msg := fmt.Sprintf("Codex executor restarted %d× in the last %.0fs", hb.RestartCount, supervisorRestartWindow.Seconds())
+	if hb.LastError != "" {
+		msg += fmt.Sprintf(", last crash: %s", hb.LastError)
+	}
+	return msg
+}
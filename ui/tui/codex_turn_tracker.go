@@ -0,0 +1,143 @@
+package main
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// codexTurnTracker watches pending codex turns for two kinds of timeout,
+// modeled on netstack/gonet's setDeadline pattern: an absolute DeadlineMs
+// from submission, and an IdleTimeoutMs that resets on every turn.event
+// carrying the same correlationID (so a turn that's actively streaming
+// thoughts/deltas isn't killed just because the whole thing runs long).
+// Either timer firing closes the turn's cancel channel, auto-emits a
+// cancel-type codexTurnRequest through appendCodexRequest, and hands a
+// synthetic Ok=false codexTurnResponse (Error "deadline exceeded" or "idle
+// timeout exceeded", by which timer fired) to Expired() for
+// the caller to fold in as if the executor itself had replied — closing the
+// gap where a hung executor otherwise starves the UI forever.
+type codexTurnTracker struct {
+	requestsPath string
+
+	mu      sync.Mutex
+	pending map[string]*trackedCodexTurn
+
+	expired chan codexTurnResponse
+}
+
+type trackedCodexTurn struct {
+	deadlineTimer *time.Timer
+	idleTimer     *time.Timer
+	idleTimeout   time.Duration
+	cancelCh      chan struct{}
+	done          bool
+}
+
+func newCodexTurnTracker(requestsPath string) *codexTurnTracker {
+	return &codexTurnTracker{
+		requestsPath: requestsPath,
+		pending:      make(map[string]*trackedCodexTurn),
+		expired:      make(chan codexTurnResponse, 8),
+	}
+}
+
+// Expired yields a synthetic turn.result for every correlationID that timed
+// out, in place of the real one the executor never sent.
+func (t *codexTurnTracker) Expired() <-chan codexTurnResponse { return t.expired }
+
+// Start registers correlationID as pending with the given deadline and idle
+// timeout (either may be zero to disable that timer) and returns a channel
+// that's closed the moment either timer fires.
+func (t *codexTurnTracker) Start(correlationID string, deadline time.Duration, idleTimeout time.Duration) <-chan struct{} {
+	correlationID = strings.TrimSpace(correlationID)
+	tt := &trackedCodexTurn{
+		idleTimeout: idleTimeout,
+		cancelCh:    make(chan struct{}),
+	}
+
+	t.mu.Lock()
+	if correlationID != "" {
+		t.pending[correlationID] = tt
+	}
+	t.mu.Unlock()
+
+	if deadline > 0 {
+		tt.deadlineTimer = time.AfterFunc(deadline, func() { t.expire(correlationID, tt, "deadline exceeded") })
+	}
+	if idleTimeout > 0 {
+		tt.idleTimer = time.AfterFunc(idleTimeout, func() { t.expire(correlationID, tt, "idle timeout exceeded") })
+	}
+	return tt.cancelCh
+}
+
+// Feed resets the idle timer for correlationID; call it for every
+// turn.event read off the events bus. A no-op if the turn isn't tracked or
+// has no idle timeout.
+func (t *codexTurnTracker) Feed(correlationID string) {
+	t.mu.Lock()
+	tt, ok := t.pending[strings.TrimSpace(correlationID)]
+	t.mu.Unlock()
+	if !ok || tt.idleTimer == nil || tt.idleTimeout <= 0 {
+		return
+	}
+	tt.idleTimer.Stop()
+	tt.idleTimer.Reset(tt.idleTimeout)
+}
+
+// Done stops both timers and forgets correlationID; call it once the real
+// turn.result for it arrives so a late response doesn't race an expiry.
+func (t *codexTurnTracker) Done(correlationID string) {
+	correlationID = strings.TrimSpace(correlationID)
+	t.mu.Lock()
+	tt, ok := t.pending[correlationID]
+	if ok {
+		delete(t.pending, correlationID)
+	}
+	t.mu.Unlock()
+	if !ok {
+		return
+	}
+	stopTrackedTurn(tt)
+}
+
+func (t *codexTurnTracker) expire(correlationID string, tt *trackedCodexTurn, reason string) {
+	t.mu.Lock()
+	cur, ok := t.pending[correlationID]
+	if !ok || cur != tt || tt.done {
+		t.mu.Unlock()
+		return
+	}
+	tt.done = true
+	delete(t.pending, correlationID)
+	t.mu.Unlock()
+
+	stopTrackedTurn(tt)
+	close(tt.cancelCh)
+
+	_ = appendCodexRequest(t.requestsPath, codexTurnRequest{
+		Version:       1,
+		Type:          "cancel",
+		CorrelationID: correlationID,
+	})
+
+	select {
+	case t.expired <- codexTurnResponse{
+		Version:       1,
+		Type:          "turn.result",
+		CorrelationID: correlationID,
+		Ok:            false,
+		Error:         reason,
+	}:
+	default:
+	}
+}
+
+func stopTrackedTurn(tt *trackedCodexTurn) {
+	if tt.deadlineTimer != nil {
+		tt.deadlineTimer.Stop()
+	}
+	if tt.idleTimer != nil {
+		tt.idleTimer.Stop()
+	}
+}
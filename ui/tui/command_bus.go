@@ -2,13 +2,15 @@ package main
 
 import (
 	"bufio"
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
-	"path/filepath"
 	"strings"
 
 	tea "github.com/charmbracelet/bubbletea"
+
+	"workbench/ui/tui/bus"
 )
 
 type busCommand struct {
@@ -25,24 +27,13 @@ type busCommand struct {
 	Source  string `json:"source,omitempty"` // cli|tui|system
 }
 
-func initCommandBus(path string) int64 {
-	if strings.TrimSpace(path) == "" {
-		return 0
-	}
-	_ = os.MkdirAll(filepath.Dir(path), 0o755)
-	if _, err := os.Stat(path); err != nil {
-		_ = os.WriteFile(path, []byte{}, 0o644)
-		return 0
-	}
-	return 0
-}
-
 func (m appModel) consumeCommandBus() (appModel, tea.Cmd) {
-	if strings.TrimSpace(m.commandBusPath) == "" {
+	if strings.TrimSpace(m.commandBusPath) == "" || m.commandBusConsumer == nil {
 		return m, nil
 	}
-	cmds, newOffset := readBusCommands(m.commandBusPath, m.commandBusOffset)
-	m.commandBusOffset = newOffset
+	cmds, newOffset := readBusCommands(m.commandBusPath, m.commandBusConsumer.Offset())
+	_ = m.commandBusConsumer.Advance(newOffset)
+	_ = bus.MaybeCompact(m.commandBusPath)
 	var outCmds []tea.Cmd
 	for _, c := range cmds {
 		var cmd tea.Cmd
@@ -109,6 +100,11 @@ func (m appModel) applyBusCommand(c busCommand) (appModel, tea.Cmd) {
 	m.actionSource = src
 	defer func() { m.actionSource = prevSource }()
 
+	m.audit.Append("command.busCommand", src, c, "")
+	if strings.TrimSpace(m.tapePath) != "" {
+		_ = appendTapeRecord(m.tapePath, m.tapeStartedAt, c, src)
+	}
+
 	switch strings.TrimSpace(strings.ToLower(c.Type)) {
 	case "stop":
 		m.systemAlert(alertInfo, "session.stop", "Stop requested", map[string]any{"source": src})
@@ -132,7 +128,7 @@ func (m appModel) applyBusCommand(c busCommand) (appModel, tea.Cmd) {
 		}
 		if r := strings.TrimSpace(c.Runtime); r != "" {
 			found := false
-			for _, opt := range runtimeOptionsUnified() {
+			for _, opt := range m.runtimeOptionsUnified() {
 				if opt.ID == r {
 					found = true
 					break
@@ -141,7 +137,7 @@ func (m appModel) applyBusCommand(c busCommand) (appModel, tea.Cmd) {
 			if found {
 				m.selectedRuntime = r
 				changed = true
-				compat := getCompatibilityLabel(m.selectedProvider, m.selectedRuntime)
+				compat := m.getCompatibilityLabel(m.selectedProvider, m.selectedRuntime)
 				m.systemAlert(alertInfo, "dev.set.runtime", fmt.Sprintf("Runtime set to %s (%s)", m.selectedRuntimeLabel(), compat), map[string]any{"runtime": r, "compatibility": compat, "source": src})
 			} else {
 				m.systemAlert(alertWarn, "dev.set.runtime.invalid", "Unknown runtime id", map[string]any{"runtime": r, "source": src})
@@ -222,6 +218,7 @@ func (m appModel) applySyntheticKey(token string) (appModel, tea.Cmd) {
 	if t == "" {
 		return m, nil
 	}
+	m.audit.Append("command.key", m.actionSource, map[string]any{"key": t}, "")
 	lt := strings.ToLower(t)
 
 	var msg tea.KeyMsg
@@ -277,68 +274,40 @@ func (m appModel) executeCommandText(text string) (appModel, tea.Cmd) {
 		return m, nil
 	}
 
-	// System commands can take arguments (e.g. //verify full, //docker probe).
-	// For all other // commands, fall through to the system command palette registry.
-	if ns == "//" {
-		name := cmdName[0]
-		args := cmdName[1:]
-		switch name {
-		case "verify":
-			full := false
-			if len(args) > 0 {
-				a := strings.ToLower(strings.TrimSpace(args[0]))
-				full = a == "full" || a == "--full"
-			}
-			next, cmd := m.submitSystemVerify(full, "")
-			if am, ok := next.(appModel); ok {
-				m = am
-			}
-			return m, cmd
-		case "docker":
-			// Default subcommand is probe/status-equivalent.
-			sub := "probe"
-			if len(args) > 0 && strings.TrimSpace(args[0]) != "" {
-				sub = strings.ToLower(strings.TrimSpace(args[0]))
-			}
-			if sub == "probe" || sub == "status" {
-				next, cmd := m.submitSystemDockerProbe("")
-				if am, ok := next.(appModel); ok {
-					m = am
-				}
-				return m, cmd
-			}
-			m.systemAlert(alertError, "command.invalid", "Unknown //docker subcommand", map[string]any{"subcommand": sub})
+	// "help" and "help <cmd>" render a registered command's long help
+	// instead of dispatching it, matching /help <cmd> introspection.
+	if cmdName[0] == "help" {
+		target := ""
+		if len(cmdName) > 1 {
+			target = cmdName[1]
+		}
+		if target == "" {
+			m.systemAlert(alertInfo, "command.help", "Available commands", map[string]any{"commands": strings.Join(m.cmdRegistry.Names(ns, ""), ", ")})
 			return m, nil
 		}
-	}
-
-	m.commandPaletteNamespace = ns
-	item, ok := findPaletteItem(ns, cmdName[0])
-	if !ok {
-		m.systemAlert(alertError, "command.not_found", "Command not found", map[string]any{"namespace": ns, "cmd": cmdName[0]})
+		help, ok := m.cmdRegistry.Help(target)
+		if !ok {
+			m.systemAlert(alertWarn, "command.not_found", "No help for command", map[string]any{"cmd": target})
+			return m, nil
+		}
+		m.systemAlert(alertInfo, "command.help", help, map[string]any{"cmd": target})
 		return m, nil
 	}
 
-	next, cmd := m.applyCommandPalette(item)
-	if am, ok := next.(appModel); ok {
-		m = am
+	m.commandPaletteNamespace = ns
+	// docker/verify emit their own command.submitted with a correlation ID
+	// from inside their Run handlers, and only when they actually submit a
+	// system request (so "//verify audit", an unknown docker subcommand, or
+	// a busy or unready executor records none); every other command is
+	// recorded here.
+	if cmdName[0] != "docker" && cmdName[0] != "verify" {
+		m.emitEvent("command.submitted", m.actionSource, map[string]any{"namespace": ns, "text": txt}, "", "")
 	}
-	return m, cmd
-}
 
-func findPaletteItem(namespace string, cmd string) (paletteItem, bool) {
-	name := strings.TrimSpace(cmd)
-	if name == "" {
-		return paletteItem{}, false
-	}
-	items := commandPaletteItems()
-	if namespace == "//" {
-		items = systemCommandPaletteItems()
-	}
-	for _, it := range items {
-		if it.cmd == name {
-			return it, true
-		}
+	next, cmd, err := m.cmdRegistry.Dispatch(context.Background(), m, ns, cmdText)
+	if err != nil {
+		m.systemAlert(alertError, "command.not_found", "Command not found", map[string]any{"namespace": ns, "cmd": cmdName[0]})
+		return m, nil
 	}
-	return paletteItem{}, false
+	return next, cmd
 }
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestUpdateCommandPaletteNamespacePromotion(t *testing.T) {
+	m := appModel{commandPaletteNamespace: "/", commandPaletteQuery: ""}
+
+	next, _ := m.updateCommandPalette(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'/'}})
+	got, ok := next.(appModel)
+	if !ok {
+		t.Fatalf("updateCommandPalette returned %T, want appModel", next)
+	}
+	if got.commandPaletteNamespace != "//" {
+		t.Fatalf("commandPaletteNamespace = %q, want %q after a second '/' on an empty query", got.commandPaletteNamespace, "//")
+	}
+	if got.commandPaletteQuery != "" {
+		t.Fatalf("commandPaletteQuery = %q, want empty after promotion", got.commandPaletteQuery)
+	}
+}
+
+func TestUpdateCommandPaletteSlashDoesNotPromoteWithQuery(t *testing.T) {
+	m := appModel{commandPaletteNamespace: "/", commandPaletteQuery: "th", cmdRegistry: buildCommandRegistry()}
+
+	next, _ := m.updateCommandPalette(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'/'}})
+	got := next.(appModel)
+	if got.commandPaletteNamespace != "/" {
+		t.Fatalf("commandPaletteNamespace = %q, want unchanged %q once a query is already typed", got.commandPaletteNamespace, "/")
+	}
+	if got.commandPaletteQuery != "th/" {
+		t.Fatalf("commandPaletteQuery = %q, want the '/' appended as a literal character", got.commandPaletteQuery)
+	}
+}
+
+func TestFilteredCommandPaletteItemsEmptyQueryOrdersByRecencyThenName(t *testing.T) {
+	reg := buildCommandRegistry()
+	// "diff" was used before "theme", so "theme" is more recent; the
+	// remaining "/" commands (never mentioned) fall back to alphabetical.
+	items := filteredCommandPaletteItems(reg, "/", "", []string{"diff", "theme"})
+	if len(items) < 5 {
+		t.Fatalf("len(items) = %d, want at least 5 registered \"/\" commands", len(items))
+	}
+	if items[0].cmd != "theme" {
+		t.Fatalf("items[0].cmd = %q, want %q (most recently used)", items[0].cmd, "theme")
+	}
+	if items[1].cmd != "diff" {
+		t.Fatalf("items[1].cmd = %q, want %q (second most recently used)", items[1].cmd, "diff")
+	}
+	for i := 2; i < len(items)-1; i++ {
+		if items[i].cmd > items[i+1].cmd {
+			t.Fatalf("items not alphabetical past the recency-ranked prefix: %q > %q", items[i].cmd, items[i+1].cmd)
+		}
+	}
+}
+
+func TestFilteredCommandPaletteItemsTypoTolerance(t *testing.T) {
+	reg := buildCommandRegistry()
+	items := filteredCommandPaletteItems(reg, "//", "provdr", nil)
+	if len(items) == 0 {
+		t.Fatalf("filteredCommandPaletteItems(%q) returned nothing, want the \"provider\" command to still match", "provdr")
+	}
+	if items[0].cmd != "provider" {
+		t.Fatalf("items[0].cmd = %q, want %q for a typo of it", items[0].cmd, "provider")
+	}
+}
@@ -0,0 +1,217 @@
+// Package commands provides a small IRC-style command table (modeled after
+// senpai's command dispatch) so the "/" and "//" overlays and the CLI
+// action-source bus can share one declarative source of truth instead of
+// matching literal strings against a hand-rolled switch in appModel.Update.
+//
+// A Registry is generic over the application model type M so this package
+// has no dependency on the tui package; the tui package instantiates
+// Registry[appModel] and registers its commands against it.
+package commands
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Cmd is a single command declaration. Run follows the bubbletea Update
+// convention: it receives the current model by value and returns the
+// (possibly mutated) model plus an optional tea.Cmd.
+type Cmd[M any] struct {
+	Name      string
+	Aliases   []string
+	Namespace string // "/" (session) or "//" (system); empty means both
+	MinArgs   int
+	MaxArgs   int // -1 means unbounded
+	Short     string
+	Long      string
+	AdminOnly bool
+	// Hidden keeps a command out of palette listings (e.g. permission_mode
+	// and cancel, which are primarily reached via key bindings) while still
+	// letting it be typed or looked up via /help.
+	Hidden bool
+	Run    func(ctx context.Context, m M, args []string) (M, tea.Cmd, error)
+}
+
+// Registry is a lookup table of Cmd by canonical name and alias.
+type Registry[M any] struct {
+	cmds   []Cmd[M]
+	lookup map[string]int // lowercased name or alias -> index into cmds
+}
+
+// NewRegistry returns an empty Registry ready for Register calls.
+func NewRegistry[M any]() *Registry[M] {
+	return &Registry[M]{lookup: make(map[string]int)}
+}
+
+// Register adds a command. Duplicate names/aliases are a programming error
+// caught at registration time rather than at dispatch time.
+func (r *Registry[M]) Register(c Cmd[M]) {
+	name := strings.ToLower(strings.TrimSpace(c.Name))
+	if name == "" {
+		panic("commands: command registered with empty name")
+	}
+	if _, exists := r.lookup[name]; exists {
+		panic("commands: duplicate command name " + name)
+	}
+	idx := len(r.cmds)
+	r.cmds = append(r.cmds, c)
+	r.lookup[name] = idx
+	for _, a := range c.Aliases {
+		alias := strings.ToLower(strings.TrimSpace(a))
+		if alias == "" {
+			continue
+		}
+		if _, exists := r.lookup[alias]; exists {
+			panic("commands: duplicate command alias " + alias)
+		}
+		r.lookup[alias] = idx
+	}
+}
+
+// Lookup resolves a name or alias (case-insensitively) to its Cmd.
+func (r *Registry[M]) Lookup(name string) (Cmd[M], bool) {
+	idx, ok := r.lookup[strings.ToLower(strings.TrimSpace(name))]
+	if !ok {
+		return Cmd[M]{}, false
+	}
+	return r.cmds[idx], true
+}
+
+// All returns every registered command in registration order.
+func (r *Registry[M]) All() []Cmd[M] {
+	out := make([]Cmd[M], len(r.cmds))
+	copy(out, r.cmds)
+	return out
+}
+
+// ForNamespace returns commands registered for ns, plus any registered for
+// both namespaces (Namespace == "").
+func (r *Registry[M]) ForNamespace(ns string) []Cmd[M] {
+	var out []Cmd[M]
+	for _, c := range r.cmds {
+		if c.Namespace == "" || c.Namespace == ns {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// Names returns canonical command names (not aliases) whose name has the
+// given prefix, sorted, for tab-completion.
+func (r *Registry[M]) Names(ns string, prefix string) []string {
+	p := strings.ToLower(strings.TrimSpace(prefix))
+	var out []string
+	for _, c := range r.ForNamespace(ns) {
+		if p == "" || strings.HasPrefix(strings.ToLower(c.Name), p) {
+			out = append(out, c.Name)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Help renders the long-form help text for a command, falling back to its
+// short description when no long help was registered. Reports !ok if the
+// command does not exist.
+func (r *Registry[M]) Help(name string) (string, bool) {
+	c, ok := r.Lookup(name)
+	if !ok {
+		return "", false
+	}
+	long := strings.TrimSpace(c.Long)
+	if long == "" {
+		long = c.Short
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s", c.Name)
+	if len(c.Aliases) > 0 {
+		fmt.Fprintf(&b, " (aliases: %s)", strings.Join(c.Aliases, ", "))
+	}
+	b.WriteString("\n")
+	b.WriteString(long)
+	return b.String(), true
+}
+
+// Dispatch tokenizes line (which must already have its leading "/" or "//"
+// stripped), looks up the command in ns, validates arity, and runs it.
+func (r *Registry[M]) Dispatch(ctx context.Context, m M, ns string, line string) (M, tea.Cmd, error) {
+	tokens := Tokenize(line)
+	if len(tokens) == 0 {
+		return m, nil, fmt.Errorf("commands: empty command")
+	}
+	name, args := tokens[0], tokens[1:]
+	c, ok := r.Lookup(name)
+	if !ok || (c.Namespace != "" && c.Namespace != ns) {
+		return m, nil, fmt.Errorf("commands: unknown command %q", name)
+	}
+	if len(args) < c.MinArgs || (c.MaxArgs >= 0 && len(args) > c.MaxArgs) {
+		if c.MaxArgs < 0 {
+			return m, nil, fmt.Errorf("%s expects at least %d arg(s)", c.Name, c.MinArgs)
+		}
+		if c.MinArgs == c.MaxArgs {
+			return m, nil, fmt.Errorf("%s expects exactly %d arg(s)", c.Name, c.MinArgs)
+		}
+		return m, nil, fmt.Errorf("%s expects %d-%d args", c.Name, c.MinArgs, c.MaxArgs)
+	}
+	return c.Run(ctx, m, args)
+}
+
+// Tokenize splits a command line into arguments, honoring single and double
+// quotes so that e.g. `docker probe --label="my session"` yields
+// ["docker", "probe", `--label=my session`].
+func Tokenize(line string) []string {
+	var out []string
+	var cur strings.Builder
+	hasCur := false
+	var quote rune
+	for _, r := range line {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+				continue
+			}
+			cur.WriteRune(r)
+		case r == '\'' || r == '"':
+			quote = r
+			hasCur = true
+		case r == ' ' || r == '\t':
+			if hasCur {
+				out = append(out, cur.String())
+				cur.Reset()
+				hasCur = false
+			}
+		default:
+			cur.WriteRune(r)
+			hasCur = true
+		}
+	}
+	if hasCur {
+		out = append(out, cur.String())
+	}
+	return out
+}
+
+// ParseFlags splits tokens (as returned by Tokenize) into positional
+// arguments and --flag / --flag=value pairs. A bare --flag is recorded with
+// value "true".
+func ParseFlags(tokens []string) (positional []string, flags map[string]string) {
+	flags = make(map[string]string)
+	for _, t := range tokens {
+		if !strings.HasPrefix(t, "--") {
+			positional = append(positional, t)
+			continue
+		}
+		body := strings.TrimPrefix(t, "--")
+		if eq := strings.IndexByte(body, '='); eq >= 0 {
+			flags[body[:eq]] = body[eq+1:]
+		} else {
+			flags[body] = "true"
+		}
+	}
+	return positional, flags
+}
@@ -0,0 +1,493 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"workbench/ui/tui/commands"
+)
+
+// buildCommandRegistry declares every "/" and "//" command once, in the
+// style of senpai's command table: name, aliases, arity, short/long help,
+// and a Run handler. appModel.cmdRegistry is the single source of truth the
+// command palette, tab-completion, /help, and the CLI command bus all look
+// through instead of matching literal strings in Update.
+func buildCommandRegistry() *commands.Registry[appModel] {
+	r := commands.NewRegistry[appModel]()
+
+	r.Register(commands.Cmd[appModel]{
+		Name:      "theme",
+		Namespace: "/",
+		MinArgs:   0,
+		MaxArgs:   1,
+		Short:     "View or switch the TUI color theme",
+		Long:      "With no argument, lists the active theme and every theme available (builtin \"default-dark\"/\"default-light\" plus any *.toml file under $XDG_CONFIG_HOME/workbench/themes). With a name, switches to it, downgrading colors to the terminal's detected depth.",
+		Run: func(_ context.Context, m appModel, args []string) (appModel, tea.Cmd, error) {
+			if len(args) == 0 {
+				m.systemAlert(alertInfo, "theme.list", fmt.Sprintf("Active theme: %s", m.themeName), map[string]any{"active": m.themeName, "available": themeNames()})
+				return m, nil, nil
+			}
+			name := strings.TrimSpace(args[0])
+			th, resolved, err := loadTheme(name)
+			if err != nil {
+				m.systemAlert(alertWarn, "theme.invalid", "Unknown theme", map[string]any{"theme": name, "available": themeNames()})
+				return m, nil, nil
+			}
+			return m, func() tea.Msg { return themeChangedMsg{Name: resolved, Theme: th} }, nil
+		},
+	})
+
+	r.Register(commands.Cmd[appModel]{
+		Name:      "clear",
+		Namespace: "/",
+		MinArgs:   0,
+		MaxArgs:   0,
+		Short:     "Clear Context Window",
+		Long:      "Clears chat scrollback and in-memory message history for the current session. Does not affect the underlying runtime's context.",
+		Run: func(_ context.Context, m appModel, _ []string) (appModel, tea.Cmd, error) {
+			m.chatLines = []string{}
+			m.chatRoleLines = []chatRoleLine{}
+			m.chatMessages = []chatMessage{}
+			m.chatScrollOffset = 0
+			m.systemAlert(alertInfo, "chat.cleared", "Chat cleared", nil)
+			m = m.closeAllOverlays()
+			return m, nil, nil
+		},
+	})
+
+	r.Register(commands.Cmd[appModel]{
+		Name:      "provider",
+		Namespace: "//",
+		MaxArgs:   0,
+		Short:     "Switch LLM Provider (OpenAI/Anthropic/Google/Ollama)",
+		Long:      "Opens the provider selector overlay. Use up/down and Enter to choose, Esc to cancel.",
+		Run: func(_ context.Context, m appModel, _ []string) (appModel, tea.Cmd, error) {
+			m = m.openOverlay(overlayProviderSelect)
+			m.providerSelectIndex = 0
+			return m, nil, nil
+		},
+	})
+
+	r.Register(commands.Cmd[appModel]{
+		Name:      "runtime",
+		Namespace: "//",
+		MaxArgs:   0,
+		Short:     "Switch Runtime (Codex Chat/CLI, Claude Code, Direct API)",
+		Long:      "Opens the runtime selector overlay. Runtime compatibility with the current provider is shown inline.",
+		Run: func(_ context.Context, m appModel, _ []string) (appModel, tea.Cmd, error) {
+			m = m.openOverlay(overlayRuntimeSelect)
+			m.runtimeSelectIndex = 0
+			return m, nil, nil
+		},
+	})
+
+	r.Register(commands.Cmd[appModel]{
+		Name:      "model",
+		Namespace: "//",
+		MaxArgs:   0,
+		Short:     "Switch AI Model",
+		Long:      "Opens the model selector overlay for the currently selected provider/runtime.",
+		Run: func(_ context.Context, m appModel, _ []string) (appModel, tea.Cmd, error) {
+			m = m.openOverlay(overlayModelSelect)
+			m.modelSelectIndex = 0
+			return m, nil, nil
+		},
+	})
+
+	r.Register(commands.Cmd[appModel]{
+		Name:      "auth",
+		Namespace: "//",
+		MinArgs:   0,
+		MaxArgs:   2,
+		Short:     "Manage OAuth Accounts",
+		Long:      "With no args, opens the OAuth account overlay: add, select, or inspect usage for pooled accounts. \"cooldown clear\" resets the in-memory rate-limit cooldown tracked per profile, so a profile that 429'd/5xx'd is offered again immediately instead of waiting out its retry-after.",
+		Run: func(_ context.Context, m appModel, args []string) (appModel, tea.Cmd, error) {
+			if len(args) >= 2 && strings.EqualFold(args[0], "cooldown") && strings.EqualFold(args[1], "clear") {
+				m.profileCooldowns = map[string]time.Time{}
+				m.systemAlert(alertInfo, "auth.cooldown.cleared", "Cleared OAuth profile cooldowns", nil)
+				return m, nil, nil
+			}
+			m = m.openOverlay(overlayAuthSelect)
+			m.authSelectIndex = 0
+			return m, nil, nil
+		},
+	})
+
+	r.Register(commands.Cmd[appModel]{
+		Name:      "mode",
+		Namespace: "//",
+		MaxArgs:   0,
+		Short:     "Switch Session Mode (A <-> B)",
+		Long:      "Toggles between mode A and mode B for the current session.",
+		Run: func(_ context.Context, m appModel, _ []string) (appModel, tea.Cmd, error) {
+			if m.mode == modeA {
+				m.mode = modeB
+			} else {
+				m.mode = modeA
+			}
+			m.systemAlert(alertInfo, "mode.switched", fmt.Sprintf("Mode switched to %s", m.mode.String()), nil)
+			m = m.closeAllOverlays()
+			return m, nil, nil
+		},
+	})
+
+	r.Register(commands.Cmd[appModel]{
+		Name:      "session",
+		Aliases:   []string{"session.new"},
+		Namespace: "//",
+		MinArgs:   0,
+		MaxArgs:   2,
+		Short:     "Start a new session, or snapshot/fork the current one",
+		Long:      "With no args, allocates a fresh session id, repoints the command/codex/opencode/system buses at it, and clears chat scrollback. \"snapshot [name]\" content-addresses the current scrollback under stateDir/snapshots, optionally naming it for \"fork\" to address later. \"fork <name-or-hash>\" starts a new session and replays the named snapshot's messages into it.",
+		Run: func(_ context.Context, m appModel, args []string) (appModel, tea.Cmd, error) {
+			if len(args) == 0 {
+				m = m.startNewSession()
+				m = m.closeAllOverlays()
+				return m, nil, nil
+			}
+			switch strings.ToLower(strings.TrimSpace(args[0])) {
+			case "snapshot":
+				name := ""
+				if len(args) > 1 {
+					name = args[1]
+				}
+				hash, err := m.snapshotSession(name)
+				if err != nil {
+					m.systemAlert(alertError, "session.snapshot.failed", "Failed to snapshot session", map[string]any{"error": err.Error()})
+					return m, nil, nil
+				}
+				m.systemAlert(alertInfo, "session.snapshotted", "Snapshotted session as "+shortHash(hash), map[string]any{"hash": hash})
+				return m, nil, nil
+			case "fork":
+				if len(args) < 2 || strings.TrimSpace(args[1]) == "" {
+					m.systemAlert(alertWarn, "command.invalid", "//session fork requires a snapshot name or hash", nil)
+					return m, nil, nil
+				}
+				hash, err := resolveSnapshotRef(m.cfg.stateDir, args[1])
+				if err != nil {
+					m.systemAlert(alertError, "session.fork.failed", "Unknown snapshot", map[string]any{"ref": args[1]})
+					return m, nil, nil
+				}
+				forked, err := m.forkSession(hash)
+				if err != nil {
+					m.systemAlert(alertError, "session.fork.failed", "Failed to fork session from snapshot", map[string]any{"error": err.Error()})
+					return m, nil, nil
+				}
+				m = forked
+				m = m.closeAllOverlays()
+				return m, nil, nil
+			default:
+				m.systemAlert(alertWarn, "command.invalid", "Unknown //session subcommand", map[string]any{"subcommand": args[0]})
+				return m, nil, nil
+			}
+		},
+	})
+
+	r.Register(commands.Cmd[appModel]{
+		Name:      "snapshots",
+		Namespace: "//",
+		MaxArgs:   0,
+		Short:     "Browse session snapshots",
+		Long:      "Opens the snapshot browser overlay: lists every snapshot under stateDir/snapshots with its timestamp, parent hash, and message count. Press 'd' to mark one and diff it against another, or Enter to fork a session from the highlighted snapshot.",
+		Run: func(_ context.Context, m appModel, _ []string) (appModel, tea.Cmd, error) {
+			m.snapshotBrowserItems = listSnapshots(m.cfg.stateDir)
+			m.snapshotBrowserIndex = 0
+			m.snapshotBrowserMarkedHash = ""
+			m.snapshotBrowserDiffLines = nil
+			m = m.openOverlay(overlaySnapshotBrowser)
+			return m, nil, nil
+		},
+	})
+
+	r.Register(commands.Cmd[appModel]{
+		Name:      "sessions",
+		Namespace: "//",
+		MaxArgs:   0,
+		Short:     "Browse and resume prior sessions",
+		Long:      "Opens the session browser overlay: fuzzy-search prior sessions by their first message, preview the highlighted one's last few turns, and press Enter to resume it.",
+		Run: func(_ context.Context, m appModel, _ []string) (appModel, tea.Cmd, error) {
+			m.sessionBrowserSessions = listSessions(m.cfg.stateDir, m.sessionID)
+			m.sessionBrowserQuery = ""
+			m.sessionBrowserIndex = 0
+			m = m.openOverlay(overlaySessionBrowser)
+			return m, nil, nil
+		},
+	})
+
+	r.Register(commands.Cmd[appModel]{
+		Name:      "mcp",
+		Namespace: "//",
+		MaxArgs:   0,
+		Short:     "Browse MCP servers and toggle them per session",
+		Long:      "Opens the MCP servers overlay: lists every server declared in mcp.json with its connection status and tool count, and Space toggles one on/off for the mcp-agent runtime in the current session.",
+		Run: func(_ context.Context, m appModel, _ []string) (appModel, tea.Cmd, error) {
+			m.mcpServersIndex = 0
+			m = m.openOverlay(overlayMCPServers)
+			return m, nil, nil
+		},
+	})
+
+	r.Register(commands.Cmd[appModel]{
+		Name:      "layout",
+		Namespace: "//",
+		MaxArgs:   0,
+		Short:     "Reset the chat/footer split to its default size",
+		Long:      "Resets footerExtraRows to 0, undoing any Alt+Up/Alt+Down resizing or boundary-row dragging for this session.",
+		Run: func(_ context.Context, m appModel, _ []string) (appModel, tea.Cmd, error) {
+			m = m.resetFooterExtraRows()
+			return m, nil, nil
+		},
+	})
+
+	r.Register(commands.Cmd[appModel]{
+		Name:      "stats",
+		Namespace: "//",
+		MaxArgs:   0,
+		Short:     "View Detailed Statistics",
+		Long:      "Opens the statistics overlay (OAuth pool ranking, usage, last system result).",
+		Run: func(_ context.Context, m appModel, _ []string) (appModel, tea.Cmd, error) {
+			m = m.openOverlay(overlayStats)
+			return m, nil, nil
+		},
+	})
+
+	r.Register(commands.Cmd[appModel]{
+		Name:      "rewind",
+		Namespace: "//",
+		MinArgs:   1,
+		MaxArgs:   1,
+		Short:     "Rewind chat history to a turn sequence number",
+		Long:      "Truncates the session's history.wal to the given sequence number and branches the conversation from there, discarding any turns after it. Sequence numbers are the ones recorded in history.wal entries.",
+		Run: func(_ context.Context, m appModel, args []string) (appModel, tea.Cmd, error) {
+			seq, err := strconv.ParseInt(strings.TrimSpace(args[0]), 10, 64)
+			if err != nil {
+				m.systemAlert(alertWarn, "history.rewind.invalid", "Invalid sequence number", map[string]any{"arg": args[0]})
+				return m, nil, nil
+			}
+			m = m.rewindTo(seq)
+			return m, nil, nil
+		},
+	})
+
+	r.Register(commands.Cmd[appModel]{
+		Name:      "docker",
+		Namespace: "//",
+		MinArgs:   0,
+		MaxArgs:   1,
+		Short:     "Docker status/probe",
+		Long:      "Runs a Docker daemon probe via the system executor. Subcommand defaults to \"probe\"; \"status\" is an alias for it.",
+		Run: func(_ context.Context, m appModel, args []string) (appModel, tea.Cmd, error) {
+			sub := "probe"
+			if len(args) > 0 && strings.TrimSpace(args[0]) != "" {
+				sub = strings.ToLower(strings.TrimSpace(args[0]))
+			}
+			if sub != "probe" && sub != "status" {
+				m.systemAlert(alertError, "command.invalid", "Unknown //docker subcommand", map[string]any{"subcommand": sub})
+				return m, nil, nil
+			}
+			next, cmd := m.submitSystemDockerProbe("")
+			if am, ok := next.(appModel); ok {
+				m = am
+			}
+			return m, cmd, nil
+		},
+	})
+
+	r.Register(commands.Cmd[appModel]{
+		Name:      "verify",
+		Namespace: "//",
+		MinArgs:   0,
+		MaxArgs:   1,
+		Short:     "Run verification gates, or \"audit\" to check the audit-log hash chain",
+		Long:      "Runs the project's verification gates via the system executor. Pass \"full\" (or \"--full\") to run the full suite instead of the quick gate, or \"audit\" to walk stateDir/<sessionID>/audit.jsonl's hash chain and report the first break instead of running the test gates.",
+		Run: func(_ context.Context, m appModel, args []string) (appModel, tea.Cmd, error) {
+			if len(args) > 0 && strings.EqualFold(strings.TrimSpace(args[0]), "audit") {
+				res := verifyAuditChain(m.cfg.stateDir, m.sessionID)
+				if res.OK {
+					m.systemAlert(alertInfo, "verify.audit.ok", fmt.Sprintf("Audit chain verified (%d records)", res.Total), map[string]any{"total": res.Total})
+				} else {
+					m.systemAlert(alertError, "verify.audit.broken", fmt.Sprintf("Audit chain broken at record %d: %s", res.BrokenAt, res.Reason), map[string]any{"total": res.Total, "brokenAt": res.BrokenAt, "reason": res.Reason})
+				}
+				return m, nil, nil
+			}
+			full := false
+			if len(args) > 0 {
+				a := strings.ToLower(strings.TrimSpace(args[0]))
+				full = a == "full" || a == "--full"
+			}
+			next, cmd := m.submitSystemVerify(full, "")
+			if am, ok := next.(appModel); ok {
+				m = am
+			}
+			return m, cmd, nil
+		},
+	})
+
+	r.Register(commands.Cmd[appModel]{
+		Name:      "permission_mode",
+		Aliases:   []string{"permission"},
+		Namespace: "//",
+		MinArgs:   0,
+		MaxArgs:   1,
+		Hidden:    true,
+		Short:     "View or set the permission mode (plan/bypass)",
+		Long:      "With no argument, toggles between plan and bypass. With \"plan\" or \"bypass\", sets it directly. Shift+Tab does the same toggle from the cockpit screen.",
+		Run: func(_ context.Context, m appModel, args []string) (appModel, tea.Cmd, error) {
+			next := ""
+			if len(args) > 0 {
+				next = strings.ToLower(strings.TrimSpace(args[0]))
+				if next != "plan" && next != "bypass" {
+					m.systemAlert(alertWarn, "dev.set.permission_mode.invalid", "Invalid permissionMode (expected plan or bypass)", map[string]any{"permissionMode": args[0]})
+					return m, nil, nil
+				}
+			} else if m.permissionMode == "bypass" {
+				next = "plan"
+			} else {
+				next = "bypass"
+			}
+			m = m.setPermissionMode(next)
+			return m, nil, nil
+		},
+	})
+
+	r.Register(commands.Cmd[appModel]{
+		Name:      "split",
+		Namespace: "/",
+		MinArgs:   0,
+		MaxArgs:   1,
+		Short:     "Split-screen a second runtime side by side (mode C)",
+		Long:      "With a runtime id, opens a second pane running that runtime (or OAuth profile, for codex-chat) in parallel with the current one against the same input line. With no argument or \"off\", closes the split and returns to a single pane.",
+		Run: func(_ context.Context, m appModel, args []string) (appModel, tea.Cmd, error) {
+			if len(args) == 0 || strings.TrimSpace(args[0]) == "" || strings.EqualFold(strings.TrimSpace(args[0]), "off") {
+				m = m.closeSplitRuntime()
+				return m, nil, nil
+			}
+			id := strings.TrimSpace(args[0])
+			found := false
+			for _, opt := range m.runtimeOptionsUnified() {
+				if opt.ID == id {
+					found = true
+					break
+				}
+			}
+			if !found {
+				m.systemAlert(alertWarn, "split.invalid", "Unknown runtime id", map[string]any{"runtime": id})
+				return m, nil, nil
+			}
+			m.splitRuntime = id
+			m.splitFocus = ""
+			m.chatRoleLinesRight = nil
+			m.chatMessagesRight = nil
+			m.chatScrollOffsetRight = 0
+			m.systemAlert(alertInfo, "split.started", fmt.Sprintf("Split screen: %s vs %s", m.selectedRuntimeLabel(), m.runtimeLabelForID(id)), map[string]any{"runtime": id})
+			m = m.closeAllOverlays()
+			return m, nil, nil
+		},
+	})
+
+	r.Register(commands.Cmd[appModel]{
+		Name:      "diff",
+		Namespace: "/",
+		MaxArgs:   0,
+		Short:     "Compare the split-screen panes' latest replies",
+		Long:      "Shows the most recent assistant reply from each pane side by side. Only meaningful after /split is active.",
+		Run: func(_ context.Context, m appModel, _ []string) (appModel, tea.Cmd, error) {
+			if strings.TrimSpace(m.splitRuntime) == "" {
+				m.systemAlert(alertWarn, "diff.inactive", "No split screen active", nil)
+				return m, nil, nil
+			}
+			left := lastAssistantText(m.chatMessages)
+			right := lastAssistantText(m.chatMessagesRight)
+			m.systemAlert(alertInfo, "diff.compare", fmt.Sprintf("Left (%s): %s\n\nRight (%s): %s", m.selectedRuntimeLabel(), summarizeForAlert(left), m.runtimeLabelForID(m.splitRuntime), summarizeForAlert(right)), map[string]any{"left": left, "right": right})
+			return m, nil, nil
+		},
+	})
+
+	r.Register(commands.Cmd[appModel]{
+		Name:      "pick",
+		Namespace: "/",
+		MinArgs:   1,
+		MaxArgs:   1,
+		Short:     "Promote one split-screen pane into the canonical chat for the next turn",
+		Long:      "Pick \"left\" or \"right\": the chosen pane's messages/role-lines become the canonical chatMessages/chatRoleLines going forward, and the split closes.",
+		Run: func(_ context.Context, m appModel, args []string) (appModel, tea.Cmd, error) {
+			side := strings.ToLower(strings.TrimSpace(args[0]))
+			if side != "left" && side != "right" {
+				m.systemAlert(alertWarn, "pick.invalid", "Expected left or right", map[string]any{"arg": args[0]})
+				return m, nil, nil
+			}
+			if strings.TrimSpace(m.splitRuntime) == "" {
+				m.systemAlert(alertWarn, "pick.inactive", "No split screen active", nil)
+				return m, nil, nil
+			}
+			if side == "right" {
+				m.chatMessages = m.chatMessagesRight
+				m.chatRoleLines = m.chatRoleLinesRight
+			}
+			m.systemAlert(alertInfo, "pick.promoted", fmt.Sprintf("Promoted %s pane into canonical chat", side), map[string]any{"side": side})
+			m = m.closeSplitRuntime()
+			return m, nil, nil
+		},
+	})
+
+	r.Register(commands.Cmd[appModel]{
+		Name:      "cancel",
+		Namespace: "",
+		MaxArgs:   0,
+		Hidden:    true,
+		Short:     "Cancel the in-flight chat turn",
+		Long:      "Requests cancellation of whatever chat turn is currently in flight, the same action Esc performs from the cockpit while a turn is running.",
+		Run: func(_ context.Context, m appModel, _ []string) (appModel, tea.Cmd, error) {
+			m, cmd := m.cancelInFlightChat()
+			return m, cmd, nil
+		},
+	})
+
+	r.Register(commands.Cmd[appModel]{
+		Name:      "replay",
+		Namespace: "//",
+		MinArgs:   1,
+		MaxArgs:   1,
+		Short:     "Control an in-progress --replay tape",
+		Long:      "\"pause\"/\"resume\" gate cadence-driven playback; \"step\" applies exactly one more tape record regardless of pause state or --speed timing. No-op if workbench wasn't started with --replay.",
+		Run: func(_ context.Context, m appModel, args []string) (appModel, tea.Cmd, error) {
+			if m.replay == nil {
+				m.systemAlert(alertWarn, "replay.inactive", "No replay in progress (start with --replay)", nil)
+				return m, nil, nil
+			}
+			switch strings.ToLower(strings.TrimSpace(args[0])) {
+			case "pause":
+				m.replay.Pause()
+				m.systemAlert(alertInfo, "replay.paused", "Replay paused", nil)
+			case "resume":
+				m.replay.Resume()
+				m.systemAlert(alertInfo, "replay.resumed", "Replay resumed", nil)
+			case "step":
+				m.replay.Step()
+				m.systemAlert(alertInfo, "replay.step", "Stepping one tape record", nil)
+			default:
+				m.systemAlert(alertWarn, "command.invalid", "Unknown //replay subcommand", map[string]any{"subcommand": args[0]})
+			}
+			return m, nil, nil
+		},
+	})
+
+	r.Register(commands.Cmd[appModel]{
+		Name:      "exit",
+		Aliases:   []string{"quit"},
+		Namespace: "//",
+		MaxArgs:   0,
+		Short:     "Close Session",
+		Long:      "Quits the workbench TUI immediately, without a confirmation prompt (Esc on the launcher asks first).",
+		Run: func(_ context.Context, m appModel, _ []string) (appModel, tea.Cmd, error) {
+			return m, tea.Quit, nil
+		},
+	})
+
+	return r
+}
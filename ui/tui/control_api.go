@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"workbench/ui/tui/controlapi"
+	"workbench/ui/tui/hooks"
+)
+
+// startControlAPI binds listen (from --listen/WORKBENCH_BUS_LISTEN, see
+// app.go) if non-empty and subscribes the resulting Server to hookBus's
+// catch-all pattern so GET /events mirrors exactly what the hooks socket
+// already publishes (chat deltas, system.* alerts), without controlapi
+// needing any notion of where those events come from. A failed bind or
+// token write is non-fatal and just leaves the control API disabled, the
+// same degrade-gracefully policy hooks.Bus uses for its own socket.
+func startControlAPI(stateDir string, listen string, disableNetwork bool, hookBus *hooks.Bus) *controlapi.Server {
+	if disableNetwork || strings.TrimSpace(listen) == "" {
+		return nil
+	}
+	srv, err := controlapi.NewServer(controlTokenPath(stateDir))
+	if err != nil {
+		return nil
+	}
+	if err := srv.ListenAll(listen); err != nil {
+		return nil
+	}
+	subscribeControlAPI(hookBus, srv)
+	return srv
+}
+
+func controlTokenPath(stateDir string) string {
+	return stateDir + "/state/control.token"
+}
+
+// subscribeControlAPI fans every hookBus event into capi's SSE stream. It is
+// called again each time hookBus is recreated (session rotation), since a
+// Bus subscription doesn't carry over to its replacement; capi itself is
+// long-lived across session rotation and keeps its listeners and token.
+func subscribeControlAPI(hookBus *hooks.Bus, capi *controlapi.Server) {
+	if capi == nil {
+		return
+	}
+	hookBus.Subscribe("*", func(ev hooks.Event) *hooks.Rejection {
+		capi.Broadcast(ev)
+		return nil
+	})
+}
+
+// consumeControlAPICalls drains calls accepted by the control API's
+// JSON-RPC endpoint and applies each one through the same applyBusCommand
+// switch the file bus and hooks socket use, so a POST to /rpc does exactly
+// what the same verb does from commands.jsonl. Every accepted call is
+// journaled into commands.jsonl first, with the local bus consumer advanced
+// past it, so replaying that file reconstructs control-API-driven turns too
+// without consumeCommandBus re-applying them a second time.
+func (m appModel) consumeControlAPICalls() (appModel, tea.Cmd) {
+	if m.controlAPI == nil {
+		return m, nil
+	}
+	calls := m.controlAPI.DrainCalls()
+	if len(calls) == 0 {
+		return m, nil
+	}
+	var outCmds []tea.Cmd
+	for _, call := range calls {
+		c, err := busCommandFromRPC(call.Method, call.Params)
+		if err != nil {
+			call.Complete(nil, &controlapi.Error{Code: -32602, Message: err.Error()})
+			continue
+		}
+		c.Source = "controlapi"
+		m.appendCommandJournal(c)
+
+		before := len(m.alerts)
+		var cmd tea.Cmd
+		m, cmd = m.applyBusCommand(c)
+		var alerts []map[string]any
+		for _, a := range m.alerts[before:] {
+			alerts = append(alerts, map[string]any{"severity": a.Severity, "code": a.Code, "message": a.Message})
+		}
+		call.Complete(map[string]any{"accepted": true, "alerts": alerts}, nil)
+
+		if cmd != nil {
+			outCmds = append(outCmds, cmd)
+		}
+		if m.quitRequested {
+			break
+		}
+	}
+	if len(outCmds) == 0 {
+		return m, nil
+	}
+	return m, tea.Batch(outCmds...)
+}
+
+// busCommandFromRPC unmarshals an RPC call's params into a busCommand and
+// stamps its Type from the JSON-RPC method name, which controlapi.Server has
+// already constrained to the busCommand verb set.
+func busCommandFromRPC(method string, params json.RawMessage) (busCommand, error) {
+	var c busCommand
+	if len(params) > 0 {
+		if err := json.Unmarshal(params, &c); err != nil {
+			return busCommand{}, fmt.Errorf("invalid params: %w", err)
+		}
+	}
+	c.Version = 1
+	c.Type = method
+	return c, nil
+}
+
+// appendCommandJournal appends c to commands.jsonl the same way an external
+// CLI writer would, then advances the in-process consumer past it so
+// consumeCommandBus's next tail doesn't re-apply a call this method already
+// ran through applyBusCommand.
+func (m appModel) appendCommandJournal(c busCommand) {
+	b, err := json.Marshal(c)
+	if err != nil {
+		return
+	}
+	b = append(b, '\n')
+	f, err := os.OpenFile(m.cfg.commandsPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return
+	}
+	_, werr := f.Write(b)
+	_ = f.Close()
+	if werr != nil || m.commandBusConsumer == nil {
+		return
+	}
+	if st, err := os.Stat(m.cfg.commandsPath); err == nil {
+		_ = m.commandBusConsumer.Advance(st.Size())
+	}
+}
@@ -0,0 +1,327 @@
+// Package controlapi implements an embedded JSON-RPC 2.0 control surface,
+// the request/response counterpart to the JSONL command bus in
+// command_bus.go: a caller that wants to know "did my send actually land,
+// and what was the reply" can POST a busCommand-shaped call over a Unix
+// socket and/or TCP endpoint and get a synchronous result back, instead of
+// appending to commands.jsonl and polling for effects.
+//
+// Server only owns transport, auth, and pending-call/SSE bookkeeping; it has
+// no notion of busCommand or appModel. The TUI's single-threaded Update loop
+// drains accepted calls with DrainCalls and applies them through
+// applyBusCommand the same way the file bus and hooks socket already do
+// (see consumeControlAPICalls in control_api.go), then unblocks the waiting
+// HTTP response with Call.Complete. GET /events is a Server-Sent-Events
+// stream fed by Broadcast, which callers wire to hooks.Bus so it mirrors
+// exactly what that bus already publishes (chat deltas, system.* alerts)
+// without this package needing to know where those events come from.
+package controlapi
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// callTimeout bounds how long a POST /rpc request waits for the TUI loop to
+// drain and apply it before returning a timeout error; the loop ticks every
+// 100ms (see tickCmd), so this is generous headroom for a stalled turn
+// rather than a realistic steady-state wait.
+const callTimeout = 10 * time.Second
+
+// Methods accepted over JSON-RPC, one per busCommand verb (see
+// command_bus.go's applyBusCommand switch).
+var methods = map[string]bool{
+	"stop": true,
+	"set":  true,
+	"send": true,
+	"cmd":  true,
+	"key":  true,
+}
+
+// Error is a JSON-RPC 2.0 error object.
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string { return e.Message }
+
+// Call is one accepted JSON-RPC request queued for the TUI loop to apply.
+// Method is the JSON-RPC method name, which doubles as the busCommand verb;
+// Params is the command's raw JSON body. Complete must be called exactly
+// once to unblock the waiting HTTP response.
+type Call struct {
+	Method string
+	Params json.RawMessage
+
+	done chan callResult
+}
+
+type callResult struct {
+	result any
+	err    *Error
+}
+
+// Complete unblocks the HTTP handler waiting on this call with either a
+// JSON-RPC result or an application error. Calling it more than once or not
+// at all is a caller bug; the done channel is buffered so the first call
+// never blocks.
+func (c *Call) Complete(result any, err *Error) {
+	select {
+	case c.done <- callResult{result: result, err: err}:
+	default:
+	}
+}
+
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Result  any             `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+}
+
+// Server is the embedded control surface for one workbench process. It is
+// safe for concurrent use: HTTP handlers run on their own goroutines and
+// hand accepted calls to the TUI loop through DrainCalls rather than
+// mutating any session state themselves.
+type Server struct {
+	token string
+
+	httpSrv   *http.Server
+	listeners []net.Listener
+
+	mu      sync.Mutex
+	pending []*Call
+
+	sseMu   sync.Mutex
+	sseSubs map[chan []byte]struct{}
+}
+
+// NewServer writes a fresh shared secret to tokenPath (mode 0600, like the
+// rest of stateDir/state/*) and returns a Server ready to Listen on; it does
+// not bind anything itself so a caller with an empty --listen value can skip
+// that step entirely.
+func NewServer(tokenPath string) (*Server, error) {
+	token, err := writeToken(tokenPath)
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{token: token, sseSubs: map[chan []byte]struct{}{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/rpc", s.handleRPC)
+	mux.HandleFunc("/events", s.handleEvents)
+	s.httpSrv = &http.Server{Handler: mux}
+	return s, nil
+}
+
+func writeToken(path string) (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(buf)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(path, []byte(token+"\n"), 0o600); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// ListenAll binds every comma-separated address in spec and starts serving
+// each on its own goroutine. An address is a Unix socket path if it starts
+// with "unix:" or "/", otherwise TCP ("tcp:host:port" or bare "host:port").
+func (s *Server) ListenAll(spec string) error {
+	for _, addr := range strings.Split(spec, ",") {
+		addr = strings.TrimSpace(addr)
+		if addr == "" {
+			continue
+		}
+		if err := s.listen(addr); err != nil {
+			return fmt.Errorf("controlapi: listen %s: %w", addr, err)
+		}
+	}
+	return nil
+}
+
+func (s *Server) listen(addr string) error {
+	network, address := splitAddr(addr)
+	if network == "unix" {
+		_ = os.Remove(address)
+		if err := os.MkdirAll(filepath.Dir(address), 0o755); err != nil {
+			return err
+		}
+	}
+	lis, err := net.Listen(network, address)
+	if err != nil {
+		return err
+	}
+	s.listeners = append(s.listeners, lis)
+	go s.httpSrv.Serve(lis)
+	return nil
+}
+
+func splitAddr(addr string) (network string, address string) {
+	switch {
+	case strings.HasPrefix(addr, "unix:"):
+		return "unix", strings.TrimPrefix(addr, "unix:")
+	case strings.HasPrefix(addr, "tcp:"):
+		return "tcp", strings.TrimPrefix(addr, "tcp:")
+	case strings.HasPrefix(addr, "/"):
+		return "unix", addr
+	default:
+		return "tcp", addr
+	}
+}
+
+// Close stops every listener; in-flight calls that are already queued for
+// DrainCalls are left for the TUI loop to finish draining.
+func (s *Server) Close() {
+	if s == nil {
+		return
+	}
+	for _, lis := range s.listeners {
+		_ = lis.Close()
+	}
+}
+
+func (s *Server) authOK(r *http.Request) bool {
+	const prefix = "Bearer "
+	h := r.Header.Get("Authorization")
+	if !strings.HasPrefix(h, prefix) {
+		return false
+	}
+	given := strings.TrimPrefix(h, prefix)
+	return subtle.ConstantTimeCompare([]byte(given), []byte(s.token)) == 1
+}
+
+func (s *Server) handleRPC(w http.ResponseWriter, r *http.Request) {
+	if !s.authOK(r) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req rpcRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeRPCResponse(w, nil, callResult{err: &Error{Code: -32700, Message: "parse error"}})
+		return
+	}
+	method := strings.ToLower(strings.TrimSpace(req.Method))
+	if !methods[method] {
+		writeRPCResponse(w, req.ID, callResult{err: &Error{Code: -32601, Message: "method not found"}})
+		return
+	}
+
+	call := &Call{Method: method, Params: req.Params, done: make(chan callResult, 1)}
+	s.mu.Lock()
+	s.pending = append(s.pending, call)
+	s.mu.Unlock()
+
+	select {
+	case res := <-call.done:
+		writeRPCResponse(w, req.ID, res)
+	case <-time.After(callTimeout):
+		writeRPCResponse(w, req.ID, callResult{err: &Error{Code: -32000, Message: "timed out waiting for workbench to apply the command"}})
+	}
+}
+
+func writeRPCResponse(w http.ResponseWriter, id json.RawMessage, res callResult) {
+	resp := rpcResponse{JSONRPC: "2.0", ID: id, Result: res.result, Error: res.err}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// DrainCalls returns and clears every call accepted since the last call, in
+// arrival order.
+func (s *Server) DrainCalls() []*Call {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.pending) == 0 {
+		return nil
+	}
+	out := s.pending
+	s.pending = nil
+	return out
+}
+
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	if !s.authOK(r) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	ch := make(chan []byte, 32)
+	s.sseMu.Lock()
+	s.sseSubs[ch] = struct{}{}
+	s.sseMu.Unlock()
+	defer func() {
+		s.sseMu.Lock()
+		delete(s.sseSubs, ch)
+		s.sseMu.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		select {
+		case line := <-ch:
+			fmt.Fprintf(w, "data: %s\n\n", line)
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// Broadcast JSON-encodes ev and fans it out to every connected /events
+// client; a client whose buffer is full is dropped a delta rather than
+// blocking the publisher.
+func (s *Server) Broadcast(ev any) {
+	if s == nil {
+		return
+	}
+	b, err := json.Marshal(ev)
+	if err != nil {
+		return
+	}
+	s.sseMu.Lock()
+	defer s.sseMu.Unlock()
+	for ch := range s.sseSubs {
+		select {
+		case ch <- b:
+		default:
+		}
+	}
+}
@@ -0,0 +1,237 @@
+package controlapi
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestServer starts a Server bound to a Unix socket under t.TempDir() and
+// returns it along with an *http.Client dialed to that socket, so tests
+// don't need to guess or scrape a dynamically assigned TCP port.
+func newTestServer(t *testing.T) (*Server, *http.Client, string, string) {
+	t.Helper()
+	dir := t.TempDir()
+	srv, err := NewServer(filepath.Join(dir, "control.token"))
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	sockPath := filepath.Join(dir, "control.sock")
+	if err := srv.ListenAll("unix:" + sockPath); err != nil {
+		t.Fatalf("ListenAll: %v", err)
+	}
+	t.Cleanup(srv.Close)
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _ string, _ string) (net.Conn, error) {
+				return net.Dial("unix", sockPath)
+			},
+		},
+	}
+	return srv, client, "http://unix", sockPath
+}
+
+// drainOne waits up to 2s for exactly one call to show up via DrainCalls,
+// the same way consumeControlAPICalls polls it from the TUI's tick loop.
+func drainOne(t *testing.T, srv *Server) *Call {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if calls := srv.DrainCalls(); len(calls) > 0 {
+			if len(calls) != 1 {
+				t.Fatalf("DrainCalls returned %d calls, want 1", len(calls))
+			}
+			return calls[0]
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatal("timed out waiting for a call to be queued")
+	return nil
+}
+
+// TestControlAPIFullCycle drives start -> send -> observe delta -> stop
+// without a TTY: a "send" RPC is accepted, queued, and completed the way
+// consumeControlAPICalls/applyBusCommand would; a Broadcast fans out over
+// /events the way subscribeControlAPI wires hookBus; and a final "stop" RPC
+// plus Close tear the server down cleanly.
+func TestControlAPIFullCycle(t *testing.T) {
+	srv, client, base, sockPath := newTestServer(t)
+
+	// start: /events is live and streams whatever Broadcast publishes.
+	eventsReq, err := http.NewRequest(http.MethodGet, base+"/events", nil)
+	if err != nil {
+		t.Fatalf("NewRequest: %v", err)
+	}
+	eventsReq.Header.Set("Authorization", "Bearer "+srv.token)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	eventsResp, err := client.Do(eventsReq.WithContext(ctx))
+	if err != nil {
+		t.Fatalf("GET /events: %v", err)
+	}
+	defer eventsResp.Body.Close()
+	if eventsResp.StatusCode != http.StatusOK {
+		t.Fatalf("GET /events status = %d, want 200", eventsResp.StatusCode)
+	}
+
+	// send: POST /rpc with method "send" is accepted and queued for the
+	// TUI loop, which completes it asynchronously via DrainCalls+Complete.
+	reqBody := `{"jsonrpc":"2.0","id":1,"method":"send","params":{"text":"hello"}}`
+	type postResult struct {
+		resp *http.Response
+		err  error
+	}
+	postDone := make(chan postResult, 1)
+	go func() {
+		rpcReq, err := http.NewRequest(http.MethodPost, base+"/rpc", strings.NewReader(reqBody))
+		if err != nil {
+			postDone <- postResult{nil, err}
+			return
+		}
+		rpcReq.Header.Set("Content-Type", "application/json")
+		rpcReq.Header.Set("Authorization", "Bearer "+srv.token)
+		resp, err := client.Do(rpcReq)
+		postDone <- postResult{resp, err}
+	}()
+
+	call := drainOne(t, srv)
+	if call.Method != "send" {
+		t.Fatalf("call.Method = %q, want %q", call.Method, "send")
+	}
+	var params map[string]string
+	if err := json.Unmarshal(call.Params, &params); err != nil {
+		t.Fatalf("unmarshal params: %v", err)
+	}
+	if params["text"] != "hello" {
+		t.Fatalf("params[text] = %q, want %q", params["text"], "hello")
+	}
+	call.Complete(map[string]any{"accepted": true}, nil)
+
+	pr := <-postDone
+	if pr.err != nil {
+		t.Fatalf("POST /rpc: %v", pr.err)
+	}
+	defer pr.resp.Body.Close()
+	var rpcResp rpcResponse
+	if err := json.NewDecoder(pr.resp.Body).Decode(&rpcResp); err != nil {
+		t.Fatalf("decode rpc response: %v", err)
+	}
+	if rpcResp.Error != nil {
+		t.Fatalf("rpc response error = %+v, want none", rpcResp.Error)
+	}
+
+	// observe delta: the event published via Broadcast arrives over the
+	// already-open /events stream as an SSE "data: " line.
+	srv.Broadcast(map[string]string{"kind": "chat.delta", "text": "hi"})
+	reader := bufio.NewReader(eventsResp.Body)
+	var line string
+	for i := 0; i < 10; i++ {
+		l, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("reading /events stream: %v", err)
+		}
+		if strings.HasPrefix(l, "data: ") {
+			line = l
+			break
+		}
+	}
+	if !strings.Contains(line, "chat.delta") {
+		t.Fatalf("events stream line = %q, want it to contain the broadcast delta", line)
+	}
+
+	// stop: a "stop" RPC round-trips the same way "send" did.
+	stopDone := make(chan postResult, 1)
+	go func() {
+		rpcReq, err := http.NewRequest(http.MethodPost, base+"/rpc", strings.NewReader(`{"jsonrpc":"2.0","id":2,"method":"stop"}`))
+		if err != nil {
+			stopDone <- postResult{nil, err}
+			return
+		}
+		rpcReq.Header.Set("Content-Type", "application/json")
+		rpcReq.Header.Set("Authorization", "Bearer "+srv.token)
+		resp, err := client.Do(rpcReq)
+		stopDone <- postResult{resp, err}
+	}()
+	stopCall := drainOne(t, srv)
+	if stopCall.Method != "stop" {
+		t.Fatalf("stopCall.Method = %q, want %q", stopCall.Method, "stop")
+	}
+	stopCall.Complete(map[string]any{"stopped": true}, nil)
+	spr := <-stopDone
+	if spr.err != nil {
+		t.Fatalf("POST /rpc (stop): %v", spr.err)
+	}
+	spr.resp.Body.Close()
+
+	// stop (server): after Close, the socket no longer accepts connections.
+	cancel()
+	srv.Close()
+	if _, err := net.Dial("unix", sockPath); err == nil {
+		t.Fatalf("dialing the control socket after Close unexpectedly succeeded")
+	}
+}
+
+func TestControlAPIRejectsBadToken(t *testing.T) {
+	_, client, base, _ := newTestServer(t)
+
+	req, err := http.NewRequest(http.MethodPost, base+"/rpc", bytes.NewReader([]byte(`{"jsonrpc":"2.0","id":1,"method":"send"}`)))
+	if err != nil {
+		t.Fatalf("NewRequest: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer wrong-token")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("POST /rpc: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusUnauthorized)
+	}
+}
+
+func TestControlAPIUnknownMethod(t *testing.T) {
+	srv, client, base, _ := newTestServer(t)
+
+	req, err := http.NewRequest(http.MethodPost, base+"/rpc", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"bogus"}`))
+	if err != nil {
+		t.Fatalf("NewRequest: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+srv.token)
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("POST /rpc: %v", err)
+	}
+	defer resp.Body.Close()
+	var rpcResp rpcResponse
+	if err := json.NewDecoder(resp.Body).Decode(&rpcResp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if rpcResp.Error == nil || rpcResp.Error.Code != -32601 {
+		t.Fatalf("rpcResp.Error = %+v, want code -32601", rpcResp.Error)
+	}
+}
+
+func TestWriteTokenPersists(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "state", "control.token")
+	token, err := writeToken(path)
+	if err != nil {
+		t.Fatalf("writeToken: %v", err)
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if strings.TrimSpace(string(raw)) != token {
+		t.Fatalf("persisted token = %q, want %q", strings.TrimSpace(string(raw)), token)
+	}
+}
@@ -1,19 +1,65 @@
 package main
 
 import (
+	"bufio"
+	"compress/gzip"
 	"crypto/rand"
 	"encoding/hex"
 	"encoding/json"
+	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 )
 
+// EventSink lets eventLogger push each Append to an external destination
+// (OTLP, a queue, ...) alongside its JSONL write, for operators who want
+// session events wired into existing observability pipelines instead of
+// only tailing events.jsonl. Emit is expected to return quickly (batching
+// or otherwise queuing slow I/O itself, the way otlpEventSink does) since
+// it runs inline with Append; the JSONL write remains the source of truth
+// regardless of whether the sink succeeds.
+type EventSink interface {
+	Emit(rec eventRecord) error
+}
+
+// eventRotateMaxBytes and eventRotateMaxAge bound events.jsonl the way
+// history.snapshotInterval bounds how far an Open has to replay the WAL:
+// a long TUI session emits one record per bus command, delta, and alert,
+// and left unrotated that file grows for as long as the process runs.
+// Whichever limit is hit first triggers rotation.
+const (
+	eventRotateMaxBytes = 8 * 1024 * 1024
+	eventRotateMaxAge   = 30 * time.Minute
+)
+
+// eventSyncEvery is how many Appends elapse between fsyncs, the buffered
+// counterpart to history.WAL's fsync-every-Append: WAL entries gate chat
+// state a crash must not lose, while events.jsonl is a best-effort
+// observability trail, so batching the fsync (and the file open it used
+// to pay on every Append) is the right trade for the volume of records
+// this log carries.
+const eventSyncEvery = 20
+
 type eventLogger struct {
+	dir  string
 	path string
-	mu   sync.Mutex
-	seq  uint64
+
+	mu       sync.Mutex
+	seq      uint64
+	sink     EventSink
+	f        *os.File
+	w        *bufio.Writer
+	size     int64
+	openedAt time.Time
+	unsynced int
+	segment  uint64
+	lastHash string
 }
 
 type eventRecord struct {
@@ -24,6 +70,10 @@ type eventRecord struct {
 	Payload       any    `json:"payload"`
 	CorrelationID string `json:"correlation_id,omitempty"`
 	CausationID   string `json:"causation_id,omitempty"`
+	PrevHash      string `json:"prev_hash,omitempty"`
+	RecordHash    string `json:"record_hash,omitempty"`
+	Sig           string `json:"sig,omitempty"`
+	KeyID         string `json:"keyId,omitempty"`
 }
 
 func newEventLogger(stateDir string, sessionID string) *eventLogger {
@@ -32,7 +82,150 @@ func newEventLogger(stateDir string, sessionID string) *eventLogger {
 	}
 	dir := filepath.Join(stateDir, sessionID)
 	_ = os.MkdirAll(dir, 0o755)
-	return &eventLogger{path: filepath.Join(dir, "events.jsonl")}
+	l := &eventLogger{dir: dir, path: filepath.Join(dir, "events.jsonl")}
+	l.segment = latestEventSegment(dir)
+	l.seq, l.lastHash = eventChainTail(dir, l.path, sessionID)
+	if err := l.openCurrent(); err != nil {
+		return l
+	}
+	return l
+}
+
+// openCurrent (re)opens events.jsonl for append and wraps it in a buffered
+// writer, recording the size and open time rotate() needs to decide when
+// to roll the file over.
+func (l *eventLogger) openCurrent() error {
+	fi, _ := os.Stat(l.path)
+	f, err := os.OpenFile(l.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	l.f = f
+	l.w = bufio.NewWriter(f)
+	l.openedAt = time.Now()
+	if fi != nil {
+		l.size = fi.Size()
+	} else {
+		l.size = 0
+	}
+	return nil
+}
+
+// eventSegmentPath is where rotate() moves the current events.jsonl
+// before compressing it; seq is the segment's own counter, independent of
+// eventRecord.Seq, so a session that rotates many times doesn't collide
+// on segment names.
+func eventSegmentPath(dir string, seq uint64) string {
+	return filepath.Join(dir, fmt.Sprintf("events-%d.jsonl.gz", seq))
+}
+
+// latestEventSegment scans dir for events-<seq>.jsonl.gz segments left by
+// a prior process and returns the highest seq found, so a resumed session
+// keeps numbering segments forward instead of restarting at 0 and
+// clobbering one.
+func latestEventSegment(dir string) uint64 {
+	var best uint64
+	for _, name := range eventSegmentNames(dir) {
+		n := strings.TrimSuffix(strings.TrimPrefix(name, "events-"), ".jsonl.gz")
+		seq, err := strconv.ParseUint(n, 10, 64)
+		if err == nil && seq > best {
+			best = seq
+		}
+	}
+	return best
+}
+
+// eventChainTail returns the highest eventRecord.Seq and the RecordHash of
+// the last record already committed across every rotated segment plus the
+// current events.jsonl, so a resumed eventLogger keeps both the sequence
+// and the hash chain continuous instead of restarting the former at 0
+// (duplicating numbers a ReplayEvents consumer already saw) or the latter
+// at the session seed (forking the chain verifyEventChain walks).
+func eventChainTail(dir string, currentPath string, sessionID string) (uint64, string) {
+	seq := uint64(0)
+	hash := eventChainSeedHash(sessionID)
+	scan := func(rc io.Reader) {
+		scanner := bufio.NewScanner(rc)
+		scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+		for scanner.Scan() {
+			var rec eventRecord
+			if json.Unmarshal(scanner.Bytes(), &rec) != nil {
+				continue
+			}
+			seq = rec.Seq
+			if rec.RecordHash != "" {
+				hash = rec.RecordHash
+			}
+		}
+	}
+	for _, name := range eventSegmentNames(dir) {
+		f, err := os.Open(filepath.Join(dir, name))
+		if err != nil {
+			continue
+		}
+		if gz, err := gzip.NewReader(f); err == nil {
+			scan(gz)
+			_ = gz.Close()
+		}
+		_ = f.Close()
+	}
+	if f, err := os.Open(currentPath); err == nil {
+		scan(f)
+		_ = f.Close()
+	}
+	return seq, hash
+}
+
+// eventSegmentNames lists dir's events-<seq>.jsonl.gz rotated segments,
+// oldest first, shared by eventChainTail, latestEventSegment, and
+// ReplayEvents so the three don't each re-derive the same filter.
+func eventSegmentNames(dir string) []string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	var names []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasPrefix(e.Name(), "events-") && strings.HasSuffix(e.Name(), ".jsonl.gz") {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SetSink attaches sink (or, given nil, detaches one) for every subsequent
+// Append. Safe to call before any Append.
+func (l *eventLogger) SetSink(sink EventSink) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	l.sink = sink
+	l.mu.Unlock()
+}
+
+// Close stops an attached sink's background work (e.g. otlpEventSink's
+// flush loop), flushing whatever batch is pending, and flushes/syncs/
+// closes the current segment's file handle. Safe to call on a nil
+// *eventLogger.
+func (l *eventLogger) Close() {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	sink := l.sink
+	if l.w != nil {
+		_ = l.w.Flush()
+	}
+	if l.f != nil {
+		_ = l.f.Sync()
+		_ = l.f.Close()
+	}
+	l.mu.Unlock()
+	if closer, ok := sink.(interface{ Close() }); ok {
+		closer.Close()
+	}
 }
 
 func (l *eventLogger) Append(source string, eventType string, payload any, correlationID string, causationID string) {
@@ -51,18 +244,142 @@ func (l *eventLogger) Append(source string, eventType string, payload any, corre
 		Payload:       payload,
 		CorrelationID: correlationID,
 		CausationID:   causationID,
+		PrevHash:      l.lastHash,
+	}
+	rec.RecordHash = eventRecordHash(rec)
+	signEventRecord(l.dir, &rec)
+	l.lastHash = rec.RecordHash
+
+	if l.sink != nil {
+		_ = l.sink.Emit(rec)
 	}
 	b, err := json.Marshal(rec)
 	if err != nil {
 		return
 	}
-	_ = os.MkdirAll(filepath.Dir(l.path), 0o755)
-	f, err := os.OpenFile(l.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if l.w == nil {
+		if err := l.openCurrent(); err != nil {
+			return
+		}
+	}
+	b = append(b, '\n')
+	n, err := l.w.Write(b)
 	if err != nil {
 		return
 	}
-	_, _ = f.Write(append(b, '\n'))
-	_ = f.Close()
+	l.size += int64(n)
+	l.unsynced++
+	if l.unsynced >= eventSyncEvery {
+		_ = l.w.Flush()
+		_ = l.f.Sync()
+		l.unsynced = 0
+	}
+	if l.size >= eventRotateMaxBytes || time.Since(l.openedAt) >= eventRotateMaxAge {
+		l.rotate()
+	}
+}
+
+// rotate flushes and closes the current events.jsonl, gzips it into the
+// next events-<seq>.jsonl.gz segment, and opens a fresh events.jsonl for
+// subsequent Appends. Called with l.mu already held.
+func (l *eventLogger) rotate() {
+	if l.w != nil {
+		_ = l.w.Flush()
+	}
+	if l.f != nil {
+		_ = l.f.Sync()
+		_ = l.f.Close()
+	}
+	l.segment++
+	if err := compressEventSegment(l.path, eventSegmentPath(l.dir, l.segment)); err != nil {
+		l.segment--
+	}
+	_ = os.Remove(l.path)
+	_ = l.openCurrent()
+}
+
+// compressEventSegment gzips src into dst, removing a partial dst if any
+// step fails. It leaves src in place; rotate removes it afterwards.
+func compressEventSegment(src string, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(out)
+	if _, err := io.Copy(gz, in); err != nil {
+		_ = gz.Close()
+		_ = out.Close()
+		_ = os.Remove(dst)
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		_ = out.Close()
+		_ = os.Remove(dst)
+		return err
+	}
+	return out.Close()
+}
+
+// ReplayEvents streams every eventRecord with Seq > from for sessionID
+// under stateDir, oldest first, across every rotated events-<seq>.jsonl.gz
+// segment followed by the live events.jsonl, closing the returned channel
+// once exhausted. It lets a new process rehydrate appModel state (mode,
+// screen, selectedProvider, alerts, recentCommands) from the event log
+// instead of only the lossy summary.json snapshot writeSessionSummary
+// produces.
+func ReplayEvents(stateDir string, sessionID string, from uint64) (<-chan eventRecord, error) {
+	dir := filepath.Join(stateDir, sessionID)
+	if _, err := os.Stat(dir); err != nil {
+		return nil, err
+	}
+
+	segments := eventSegmentNames(dir)
+
+	out := make(chan eventRecord)
+	go func() {
+		defer close(out)
+		for _, name := range segments {
+			f, err := os.Open(filepath.Join(dir, name))
+			if err != nil {
+				continue
+			}
+			gz, err := gzip.NewReader(f)
+			if err != nil {
+				_ = f.Close()
+				continue
+			}
+			replayEventLines(gz, from, out)
+			_ = gz.Close()
+			_ = f.Close()
+		}
+		if f, err := os.Open(filepath.Join(dir, "events.jsonl")); err == nil {
+			replayEventLines(f, from, out)
+			_ = f.Close()
+		}
+	}()
+	return out, nil
+}
+
+// replayEventLines scans JSONL eventRecords from r, sending every one
+// with Seq > from onto out.
+func replayEventLines(r io.Reader, from uint64, out chan<- eventRecord) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		var rec eventRecord
+		if json.Unmarshal(scanner.Bytes(), &rec) != nil {
+			continue
+		}
+		if rec.Seq > from {
+			out <- rec
+		}
+	}
 }
 
 type alertSeverity string
@@ -75,12 +392,12 @@ const (
 )
 
 type systemAlert struct {
-	At            string        `json:"at"`
-	Severity      alertSeverity `json:"severity"`
-	Code          string        `json:"code"`
-	Message       string        `json:"message"`
+	At            string         `json:"at"`
+	Severity      alertSeverity  `json:"severity"`
+	Code          string         `json:"code"`
+	Message       string         `json:"message"`
 	Context       map[string]any `json:"context,omitempty"`
-	CorrelationID string        `json:"correlation_id"`
+	CorrelationID string         `json:"correlation_id"`
 }
 
 func newCorrelationID() string {
@@ -88,4 +405,3 @@ func newCorrelationID() string {
 	_, _ = rand.Read(buf[:])
 	return hex.EncodeToString(buf[:])
 }
-
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// eventChainSeedHash is the PrevHash the first record of a session's
+// events.jsonl chain seeds from, derived from the session ID the same way
+// auditSeedHash seeds audit.jsonl's chain, so two empty event logs for
+// different sessions don't start identically.
+func eventChainSeedHash(sessionID string) string {
+	sum := sha256.Sum256([]byte("workbench.events.v1:" + sessionID))
+	return hex.EncodeToString(sum[:])
+}
+
+// eventRecordHash hashes rec's canonical JSON with RecordHash, Sig, and
+// KeyID blanked, so the stored RecordHash is exactly "sha256(everything
+// else, including PrevHash)". Mirrors auditRecordHash.
+func eventRecordHash(rec eventRecord) string {
+	rec.RecordHash, rec.Sig, rec.KeyID = "", "", ""
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+var (
+	eventSigningKeysMu sync.Mutex
+	eventSigningKeys   = map[string]ed25519.PrivateKey{}
+)
+
+// eventSigningKeyPath returns dir/events.key, the per-session Ed25519 key
+// eventLogger.Append signs each record's RecordHash with. Named distinctly
+// from audit.jsonl (which isn't signed — see auditRecord) so a reader
+// doesn't assume the two hash chains share a key.
+func eventSigningKeyPath(dir string) string {
+	return filepath.Join(dir, "events.key")
+}
+
+// loadOrCreateEventSigningKey loads the Ed25519 seed at
+// eventSigningKeyPath(dir), generating and persisting a new one on first
+// use. Mirrors loadOrCreateCodexSigningKey's resume-or-create shape, keyed
+// by dir instead of session ID since events.jsonl's chain already lives
+// under stateDir/<sessionID>.
+func loadOrCreateEventSigningKey(dir string) (ed25519.PrivateKey, error) {
+	eventSigningKeysMu.Lock()
+	defer eventSigningKeysMu.Unlock()
+	if key, ok := eventSigningKeys[dir]; ok {
+		return key, nil
+	}
+
+	path := eventSigningKeyPath(dir)
+	if seed, err := os.ReadFile(path); err == nil && len(seed) == ed25519.SeedSize {
+		key := ed25519.NewKeyFromSeed(seed)
+		eventSigningKeys[dir] = key
+		return key, nil
+	}
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	_ = os.MkdirAll(dir, 0o755)
+	if err := os.WriteFile(path, key.Seed(), 0o600); err != nil {
+		return nil, err
+	}
+	eventSigningKeys[dir] = key
+	return key, nil
+}
+
+// eventAuditPublicKey returns the hex-encoded Ed25519 public key
+// eventLogger signs with for dir, generating the underlying key pair on
+// first call if it doesn't exist yet. writeSessionSummary publishes this
+// in summary.json so an offline verifier can confirm KeyID against a
+// known identity without filesystem access to the private key.
+func eventAuditPublicKey(dir string) string {
+	key, err := loadOrCreateEventSigningKey(dir)
+	if err != nil {
+		return ""
+	}
+	return hex.EncodeToString(key.Public().(ed25519.PublicKey))
+}
+
+// signEventRecord signs rec.RecordHash in place using dir's signing key,
+// best-effort: a key-loading failure leaves Sig/KeyID empty rather than
+// failing the Append, the same trade signCodexTurnRequest makes.
+func signEventRecord(dir string, rec *eventRecord) {
+	key, err := loadOrCreateEventSigningKey(dir)
+	if err != nil {
+		return
+	}
+	rec.KeyID = hex.EncodeToString(key.Public().(ed25519.PublicKey))
+	rec.Sig = hex.EncodeToString(ed25519.Sign(key, []byte(rec.RecordHash)))
+}
+
+// verifyEventSig checks rec.Sig against rec.KeyID (itself the hex-encoded
+// public key, so no separate trust store lookup is needed) over
+// rec.RecordHash. Mirrors verifyCodexSig.
+func verifyEventSig(rec eventRecord) bool {
+	if rec.Sig == "" || rec.KeyID == "" {
+		return false
+	}
+	pub, err := hex.DecodeString(rec.KeyID)
+	if err != nil || len(pub) != ed25519.PublicKeySize {
+		return false
+	}
+	sig, err := hex.DecodeString(rec.Sig)
+	if err != nil {
+		return false
+	}
+	return ed25519.Verify(ed25519.PublicKey(pub), []byte(rec.RecordHash), sig)
+}
+
+// eventVerifyResult is what "workbench events verify" reports.
+type eventVerifyResult struct {
+	OK       bool
+	Total    int
+	Signed   int
+	BrokenAt int // 1-based record number of the first break, 0 if OK
+	Reason   string
+}
+
+// verifyEventChain walks every events-<seq>.jsonl.gz segment followed by
+// the live events.jsonl for stateDir/<sessionID>, oldest first,
+// recomputing each record's hash and checking it both matches what's
+// stored and chains from the previous record, and verifying any embedded
+// signature along the way. A missing session directory is a trivially
+// valid (empty) chain.
+func verifyEventChain(stateDir string, sessionID string) eventVerifyResult {
+	dir := filepath.Join(stateDir, sessionID)
+	if _, err := os.Stat(dir); err != nil {
+		return eventVerifyResult{OK: true}
+	}
+
+	prevHash := eventChainSeedHash(sessionID)
+	total, signed := 0, 0
+	broken := ""
+	ok := true
+
+	check := func(rec eventRecord) bool {
+		total++
+		if rec.PrevHash != prevHash {
+			broken = "prev_hash does not match the preceding record"
+			return false
+		}
+		if want := eventRecordHash(rec); want != rec.RecordHash {
+			broken = "record_hash does not match record contents"
+			return false
+		}
+		if rec.Sig != "" {
+			if !verifyEventSig(rec) {
+				broken = "signature does not match record_hash"
+				return false
+			}
+			signed++
+		}
+		prevHash = rec.RecordHash
+		return true
+	}
+
+	scan := func(r io.Reader) bool {
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+		for scanner.Scan() {
+			var rec eventRecord
+			if json.Unmarshal(scanner.Bytes(), &rec) != nil {
+				continue
+			}
+			if !check(rec) {
+				return false
+			}
+		}
+		return true
+	}
+
+	for _, name := range eventSegmentNames(dir) {
+		if !ok {
+			break
+		}
+		f, err := os.Open(filepath.Join(dir, name))
+		if err != nil {
+			continue
+		}
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			_ = f.Close()
+			continue
+		}
+		ok = scan(gz)
+		_ = gz.Close()
+		_ = f.Close()
+	}
+	if ok {
+		if f, err := os.Open(filepath.Join(dir, "events.jsonl")); err == nil {
+			ok = scan(f)
+			_ = f.Close()
+		}
+	}
+
+	if !ok {
+		return eventVerifyResult{OK: false, Total: total, Signed: signed, BrokenAt: total, Reason: broken}
+	}
+	return eventVerifyResult{OK: true, Total: total, Signed: signed}
+}
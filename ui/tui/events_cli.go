@@ -0,0 +1,41 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// runEventsCLI handles "workbench events verify --session <id>", walking
+// the session's hash-chained events.jsonl (and any rotated
+// events-<seq>.jsonl.gz segments) and reporting the first broken link, the
+// events.jsonl analog of runAuditCLI's audit verification. It is
+// dispatched directly from main() before flag.Parse() touches the TUI's
+// own flags, since "events" is a subcommand rather than one of them.
+func runEventsCLI(args []string) int {
+	if len(args) == 0 || args[0] != "verify" {
+		fmt.Fprintln(os.Stderr, "usage: workbench events verify --session <id>")
+		return 2
+	}
+	fs := flag.NewFlagSet("events verify", flag.ContinueOnError)
+	sessionID := fs.String("session", "", "session id to verify")
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2
+	}
+	if strings.TrimSpace(*sessionID) == "" {
+		fmt.Fprintln(os.Stderr, "events verify: --session is required")
+		return 2
+	}
+	stateDir := os.Getenv("WORKBENCH_STATE_DIR")
+	if strings.TrimSpace(stateDir) == "" {
+		stateDir = ".workbench"
+	}
+	res := verifyEventChain(stateDir, *sessionID)
+	if !res.OK {
+		fmt.Printf("broken at record %d: %s (total=%d, signed=%d)\n", res.BrokenAt, res.Reason, res.Total, res.Signed)
+		return 1
+	}
+	fmt.Printf("ok: %d records verified (%d signed)\n", res.Total, res.Signed)
+	return 0
+}
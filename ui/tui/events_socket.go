@@ -0,0 +1,47 @@
+package main
+
+import (
+	"encoding/json"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// consumeEventsSocketCommands drains any busCommand-shaped JSONL lines
+// queued by clients connected to m.hookBus's socket (see
+// hooks.Bus.DrainCommands) and applies them through the same
+// applyBusCommand switch the file-based command bus uses, so
+// "{"version":1,"type":"cmd","text":"//verify"}" over --events-socket does
+// exactly what it does from commands.jsonl. Malformed lines are reported
+// and skipped rather than aborting the batch.
+func (m appModel) consumeEventsSocketCommands() (appModel, tea.Cmd) {
+	if m.hookBus == nil {
+		return m, nil
+	}
+	lines := m.hookBus.DrainCommands()
+	if len(lines) == 0 {
+		return m, nil
+	}
+	var outCmds []tea.Cmd
+	for _, line := range lines {
+		var c busCommand
+		if err := json.Unmarshal(line, &c); err != nil || c.Version != 1 || c.Type == "" {
+			m.systemAlert(alertWarn, "events_socket.invalid", "Ignored malformed events-socket command", map[string]any{"raw": string(line)})
+			continue
+		}
+		if c.Source == "" {
+			c.Source = "socket"
+		}
+		var cmd tea.Cmd
+		m, cmd = m.applyBusCommand(c)
+		if cmd != nil {
+			outCmds = append(outCmds, cmd)
+		}
+		if m.quitRequested {
+			break
+		}
+	}
+	if len(outCmds) == 0 {
+		return m, nil
+	}
+	return m, tea.Batch(outCmds...)
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+)
+
+// TurnRequest, TurnEvent, and TurnResult are the executor-agnostic wire
+// shapes every Executor backend is driven with. They mirror
+// codexTurnRequest/codexTurnEvent/codexTurnResponse field-for-field (the
+// NDJSON bus remains the wire format stdioExecutor and fileBusExecutor both
+// speak) but drop the "codex" name so the same interface can eventually
+// front an opencode or system backend too.
+type TurnRequest struct {
+	CorrelationID  string
+	Prompt         string
+	Cwd            string
+	Model          string
+	Think          bool
+	PermissionMode string
+	DeadlineMs     int64
+	IdleTimeoutMs  int64
+}
+
+type TurnEvent struct {
+	CorrelationID string
+	At            string
+	Kind          string // think|tool_use|step_start|step_finish|delta|info|error
+	Message       string
+	Tool          string
+}
+
+type TurnResult struct {
+	CorrelationID string
+	Ok            bool
+	Content       string
+	Error         string
+	FileChanges   []string
+	StartedAt     string
+	EndedAt       string
+}
+
+// Executor is the seam every codex backend implements, whether it's the
+// NDJSON file bus, a child process on stdio, or a remote HTTP runner.
+// SubmitTurn returns per-turn event/result channels scoped to req's
+// CorrelationID: both are closed once the result lands (or the context is
+// canceled), so callers can range over TurnEvent without filtering by ID
+// themselves.
+type Executor interface {
+	SubmitTurn(ctx context.Context, req TurnRequest) (<-chan TurnEvent, <-chan TurnResult, error)
+	Cancel(correlationID string) error
+	Ready() (bool, string)
+}
+
+// NewExecutor builds an Executor from a URL whose scheme selects the
+// backend:
+//
+//	file://<stateDir>              the existing NDJSON append-only bus under <stateDir>/<sessionID>
+//	stdio:<command> [args...]      speak the same JSON frames over a child's stdin/stdout
+//	http(s)://<host>/<path>        POST turns, consume SSE for events/result
+//
+// An empty rawURL defaults to the file bus rooted at stateDir/sessionID, so
+// existing callers that never set WORKBENCH_EXECUTOR_URL keep today's
+// behavior unchanged.
+func NewExecutor(rawURL string, stateDir string, sessionID string) (Executor, error) {
+	rawURL = strings.TrimSpace(rawURL)
+	if rawURL == "" {
+		return newFileBusExecutor(stateDir, sessionID), nil
+	}
+
+	if rest, ok := strings.CutPrefix(rawURL, "stdio:"); ok {
+		return newStdioExecutor(rest)
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("executor: parsing %q: %w", rawURL, err)
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "", "file":
+		dir := u.Path
+		if dir == "" {
+			dir = stateDir
+		}
+		return newFileBusExecutor(dir, sessionID), nil
+	case "http", "https":
+		return newHTTPExecutor(u.String()), nil
+	default:
+		return nil, fmt.Errorf("executor: unsupported scheme %q in %q", u.Scheme, rawURL)
+	}
+}
+
+// executorURLFromEnv reads WORKBENCH_EXECUTOR_URL, the knob NewExecutor is
+// keyed from. Unset (the default) keeps today's file-bus behavior.
+func executorURLFromEnv() string {
+	return strings.TrimSpace(os.Getenv("WORKBENCH_EXECUTOR_URL"))
+}
+
+var (
+	codexExecutorsMu sync.Mutex
+	codexExecutors   = map[string]Executor{}
+)
+
+// cachedCodexExecutor returns the Executor for (rawURL, stateDir, sessionID),
+// constructing and caching it on first use. Needed because a stdioExecutor
+// owns a long-lived child process (newStdioExecutor starts it once): calling
+// NewExecutor fresh on every turn/cancel would spawn a new, unrelated child
+// each time, so a cancel could never reach the process actually running the
+// turn it's meant to interrupt. Mirrors loadOrCreateCodexSigningKey's
+// cache-by-key shape.
+func cachedCodexExecutor(rawURL string, stateDir string, sessionID string) (Executor, error) {
+	key := rawURL + "\x00" + stateDir + "\x00" + sessionID
+	codexExecutorsMu.Lock()
+	defer codexExecutorsMu.Unlock()
+	if e, ok := codexExecutors[key]; ok {
+		return e, nil
+	}
+	e, err := NewExecutor(rawURL, stateDir, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	codexExecutors[key] = e
+	return e, nil
+}
+
+// bridgeCodexExecutorTurn drains a non-file Executor's per-turn TurnEvent and
+// TurnResult channels and folds them onto eventsPath/responsesPath, signing
+// each frame the same way the file bus's own writers do. This is what lets
+// model.go's existing tick-based consumeCodexEvents (tailing those two
+// files) stay backend-agnostic: a stdio or http Executor just needs its
+// output mirrored onto the files, not a second consumption path.
+func bridgeCodexExecutorTurn(events <-chan TurnEvent, results <-chan TurnResult, eventsPath string, responsesPath string) {
+	go func() {
+		for ev := range events {
+			_ = appendCodexEvent(eventsPath, codexTurnEvent{
+				Version:       1,
+				Type:          "turn.event",
+				CorrelationID: ev.CorrelationID,
+				At:            ev.At,
+				Kind:          ev.Kind,
+				Message:       ev.Message,
+				Tool:          ev.Tool,
+			})
+		}
+	}()
+	go func() {
+		for r := range results {
+			_ = appendCodexResponse(responsesPath, codexTurnResponse{
+				Version:       1,
+				Type:          "turn.result",
+				CorrelationID: r.CorrelationID,
+				Ok:            r.Ok,
+				Content:       r.Content,
+				Error:         r.Error,
+				FileChanges:   r.FileChanges,
+				StartedAt:     r.StartedAt,
+				EndedAt:       r.EndedAt,
+			})
+		}
+	}()
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"time"
+)
+
+// fileBusExecutor adapts the existing codex NDJSON append-only bus
+// (codex_bus.go, codex_bus_stream.go) to the Executor interface. It's the
+// default backend and the only one that existed before this file: an empty
+// WORKBENCH_EXECUTOR_URL still resolves here via NewExecutor.
+type fileBusExecutor struct {
+	stateDir      string
+	sessionID     string
+	requestsPath  string
+	responsesPath string
+	eventsPath    string
+}
+
+func newFileBusExecutor(stateDir string, sessionID string) *fileBusExecutor {
+	return &fileBusExecutor{
+		stateDir:      stateDir,
+		sessionID:     sessionID,
+		requestsPath:  filepath.Join(stateDir, sessionID, "codex.requests.jsonl"),
+		responsesPath: filepath.Join(stateDir, sessionID, "codex.responses.jsonl"),
+		eventsPath:    filepath.Join(stateDir, sessionID, "codex.events.jsonl"),
+	}
+}
+
+func (e *fileBusExecutor) Ready() (bool, string) {
+	now := time.Now()
+	if isCodexExecutorReady(e.stateDir, e.sessionID, now) {
+		return true, ""
+	}
+	return false, codexExecutorDiagnostic(e.stateDir, e.sessionID, now)
+}
+
+func (e *fileBusExecutor) Cancel(correlationID string) error {
+	return appendCodexRequest(e.requestsPath, codexTurnRequest{
+		Version:       1,
+		Type:          "cancel",
+		CorrelationID: correlationID,
+	})
+}
+
+// SubmitTurn appends req to the requests bus and tails the responses/events
+// buses (via codexBus) for frames carrying req.CorrelationID, demuxing the
+// shared tail down to per-turn channels that close once the result lands.
+func (e *fileBusExecutor) SubmitTurn(ctx context.Context, req TurnRequest) (<-chan TurnEvent, <-chan TurnResult, error) {
+	if err := appendCodexRequest(e.requestsPath, codexTurnRequest{
+		Version:        1,
+		Type:           "turn",
+		CorrelationID:  req.CorrelationID,
+		Prompt:         req.Prompt,
+		Cwd:            req.Cwd,
+		Model:          req.Model,
+		Think:          req.Think,
+		PermissionMode: req.PermissionMode,
+		DeadlineMs:     req.DeadlineMs,
+		IdleTimeoutMs:  req.IdleTimeoutMs,
+	}); err != nil {
+		return nil, nil, err
+	}
+
+	events := make(chan TurnEvent, 16)
+	results := make(chan TurnResult, 1)
+	bus := newCodexBus(ctx, e.responsesPath, e.eventsPath)
+
+	go func() {
+		defer close(events)
+		defer close(results)
+		defer bus.Close()
+		for {
+			select {
+			case ev, ok := <-bus.Events():
+				if !ok {
+					return
+				}
+				if ev.CorrelationID != req.CorrelationID {
+					continue
+				}
+				select {
+				case events <- TurnEvent{CorrelationID: ev.CorrelationID, At: ev.At, Kind: ev.Kind, Message: ev.Message, Tool: ev.Tool}:
+				case <-ctx.Done():
+					return
+				}
+			case r, ok := <-bus.Responses():
+				if !ok {
+					return
+				}
+				if r.CorrelationID != req.CorrelationID {
+					continue
+				}
+				select {
+				case results <- TurnResult{CorrelationID: r.CorrelationID, Ok: r.Ok, Content: r.Content, Error: r.Error, FileChanges: r.FileChanges, StartedAt: r.StartedAt, EndedAt: r.EndedAt}:
+				case <-ctx.Done():
+				}
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return events, results, nil
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// httpExecutor drives a remote model runner over plain HTTP: POST /turns to
+// submit, consume the response body as Server-Sent Events for turn.event and
+// the final turn.result, and POST /cancel to abort. It exists so workbench
+// can point at a hosted runner without any of the other backends' local
+// process/file assumptions.
+type httpExecutor struct {
+	endpoint string
+	client   *http.Client
+}
+
+func newHTTPExecutor(endpoint string) *httpExecutor {
+	return &httpExecutor{
+		endpoint: strings.TrimRight(endpoint, "/"),
+		client:   &http.Client{Timeout: 0}, // streaming response; per-turn deadlines are the caller's job (codexTurnTracker)
+	}
+}
+
+func (e *httpExecutor) Ready() (bool, string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, e.endpoint+"/healthz", nil)
+	if err != nil {
+		return false, err.Error()
+	}
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return false, err.Error()
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Sprintf("remote executor returned %d", resp.StatusCode)
+	}
+	return true, ""
+}
+
+func (e *httpExecutor) Cancel(correlationID string) error {
+	body, err := json.Marshal(codexTurnRequest{Version: 1, Type: "cancel", CorrelationID: correlationID})
+	if err != nil {
+		return err
+	}
+	resp, err := e.client.Post(e.endpoint+"/cancel", "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("executor: remote cancel returned %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (e *httpExecutor) SubmitTurn(ctx context.Context, req TurnRequest) (<-chan TurnEvent, <-chan TurnResult, error) {
+	body, err := json.Marshal(codexTurnRequest{
+		Version:        1,
+		Type:           "turn",
+		CorrelationID:  req.CorrelationID,
+		Prompt:         req.Prompt,
+		Cwd:            req.Cwd,
+		Model:          req.Model,
+		Think:          req.Think,
+		PermissionMode: req.PermissionMode,
+		DeadlineMs:     req.DeadlineMs,
+		IdleTimeoutMs:  req.IdleTimeoutMs,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, e.endpoint+"/turns", bytes.NewReader(body))
+	if err != nil {
+		return nil, nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Accept", "text/event-stream")
+
+	resp, err := e.client.Do(httpReq)
+	if err != nil {
+		return nil, nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, nil, fmt.Errorf("executor: remote turn submit returned %d", resp.StatusCode)
+	}
+
+	events := make(chan TurnEvent, 16)
+	results := make(chan TurnResult, 1)
+	go consumeTurnSSE(resp.Body, req.CorrelationID, events, results)
+	return events, results, nil
+}
+
+// consumeTurnSSE reads an SSE stream of "data: <json>\n\n" frames, decoding
+// each payload as a stdioFrame (the same union shape stdioExecutor reads off
+// a child's stdout) and routing it to events or results by Type. It returns
+// once the stream closes, which the server is expected to do right after the
+// turn.result frame.
+func consumeTurnSSE(body io.ReadCloser, correlationID string, events chan<- TurnEvent, results chan<- TurnResult) {
+	defer body.Close()
+	defer close(events)
+	defer close(results)
+
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	var dataLines []string
+	flush := func() {
+		if len(dataLines) == 0 {
+			return
+		}
+		payload := strings.Join(dataLines, "\n")
+		dataLines = nil
+
+		var frame stdioFrame
+		if json.Unmarshal([]byte(payload), &frame) != nil {
+			return
+		}
+		if frame.CorrelationID != "" && frame.CorrelationID != correlationID {
+			return
+		}
+		switch frame.Type {
+		case "turn.event":
+			events <- TurnEvent{CorrelationID: frame.CorrelationID, At: frame.At, Kind: frame.Kind, Message: frame.Message, Tool: frame.Tool}
+		case "turn.result":
+			results <- TurnResult{
+				CorrelationID: frame.CorrelationID,
+				Ok:            frame.Ok,
+				Content:       frame.Content,
+				Error:         frame.Error,
+				FileChanges:   frame.FileChanges,
+				StartedAt:     frame.StartedAt,
+				EndedAt:       frame.EndedAt,
+			}
+		}
+	}
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			flush()
+			continue
+		}
+		if data, ok := strings.CutPrefix(line, "data:"); ok {
+			dataLines = append(dataLines, strings.TrimPrefix(data, " "))
+		}
+	}
+	flush()
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+// stdioExecutor speaks the same JSON frames the file bus uses (one
+// codexTurnRequest/codexTurnEvent/codexTurnResponse per line) over a child
+// process's stdin/stdout instead of NDJSON files on disk, sidestepping file
+// rotation entirely for local use. The child is expected to read turn/cancel
+// request frames from stdin and write turn.event/turn.result frames to
+// stdout, same shape as codex.requests.jsonl/codex.responses.jsonl today.
+type stdioExecutor struct {
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+
+	writeMu sync.Mutex
+
+	mu   sync.Mutex
+	subs map[string]stdioSub
+}
+
+type stdioSub struct {
+	events  chan TurnEvent
+	results chan TurnResult
+}
+
+// stdioFrame is the union of every field any codex*Turn* type can carry,
+// enough to decode a line without knowing in advance whether it's an event
+// or a result.
+type stdioFrame struct {
+	Type          string   `json:"type"`
+	CorrelationID string   `json:"correlationId"`
+	Ok            bool     `json:"ok"`
+	Content       string   `json:"content"`
+	Error         string   `json:"error"`
+	FileChanges   []string `json:"fileChanges"`
+	StartedAt     string   `json:"startedAt"`
+	EndedAt       string   `json:"endedAt"`
+	At            string   `json:"at"`
+	Kind          string   `json:"kind"`
+	Message       string   `json:"message"`
+	Tool          string   `json:"tool"`
+}
+
+// newStdioExecutor parses commandLine as a program plus arguments (e.g.
+// "codex --json-bus") and starts it immediately; the child stays up for the
+// lifetime of the executor, serving however many turns are submitted to it.
+func newStdioExecutor(commandLine string) (*stdioExecutor, error) {
+	fields := strings.Fields(commandLine)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("executor: empty stdio command")
+	}
+	cmd := exec.Command(fields[0], fields[1:]...)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	e := &stdioExecutor{cmd: cmd, stdin: stdin, subs: make(map[string]stdioSub)}
+	go e.readLoop(stdout)
+	return e, nil
+}
+
+func (e *stdioExecutor) readLoop(stdout io.Reader) {
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var frame stdioFrame
+		if json.Unmarshal([]byte(line), &frame) != nil {
+			continue
+		}
+
+		e.mu.Lock()
+		sub, ok := e.subs[frame.CorrelationID]
+		e.mu.Unlock()
+		if !ok {
+			continue
+		}
+
+		switch frame.Type {
+		case "turn.event":
+			select {
+			case sub.events <- TurnEvent{CorrelationID: frame.CorrelationID, At: frame.At, Kind: frame.Kind, Message: frame.Message, Tool: frame.Tool}:
+			default:
+			}
+		case "turn.result":
+			sub.results <- TurnResult{
+				CorrelationID: frame.CorrelationID,
+				Ok:            frame.Ok,
+				Content:       frame.Content,
+				Error:         frame.Error,
+				FileChanges:   frame.FileChanges,
+				StartedAt:     frame.StartedAt,
+				EndedAt:       frame.EndedAt,
+			}
+			e.mu.Lock()
+			delete(e.subs, frame.CorrelationID)
+			e.mu.Unlock()
+			close(sub.events)
+			close(sub.results)
+		}
+	}
+}
+
+func (e *stdioExecutor) Ready() (bool, string) {
+	if e.cmd == nil || e.cmd.Process == nil {
+		return false, "stdio executor not started"
+	}
+	if e.cmd.ProcessState != nil && e.cmd.ProcessState.Exited() {
+		return false, "stdio executor process exited"
+	}
+	return true, ""
+}
+
+func (e *stdioExecutor) writeFrame(v any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	e.writeMu.Lock()
+	defer e.writeMu.Unlock()
+	_, err = e.stdin.Write(append(b, '\n'))
+	return err
+}
+
+func (e *stdioExecutor) SubmitTurn(ctx context.Context, req TurnRequest) (<-chan TurnEvent, <-chan TurnResult, error) {
+	sub := stdioSub{events: make(chan TurnEvent, 16), results: make(chan TurnResult, 1)}
+	e.mu.Lock()
+	e.subs[req.CorrelationID] = sub
+	e.mu.Unlock()
+
+	err := e.writeFrame(codexTurnRequest{
+		Version:        1,
+		Type:           "turn",
+		CorrelationID:  req.CorrelationID,
+		Prompt:         req.Prompt,
+		Cwd:            req.Cwd,
+		Model:          req.Model,
+		Think:          req.Think,
+		PermissionMode: req.PermissionMode,
+		DeadlineMs:     req.DeadlineMs,
+		IdleTimeoutMs:  req.IdleTimeoutMs,
+	})
+	if err != nil {
+		e.mu.Lock()
+		delete(e.subs, req.CorrelationID)
+		e.mu.Unlock()
+		return nil, nil, err
+	}
+	return sub.events, sub.results, nil
+}
+
+func (e *stdioExecutor) Cancel(correlationID string) error {
+	return e.writeFrame(codexTurnRequest{Version: 1, Type: "cancel", CorrelationID: correlationID})
+}
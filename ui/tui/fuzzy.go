@@ -0,0 +1,117 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Tunables for fuzzyMatch's v2-style scoring: a flat per-rune match score,
+// a compounding bonus for runs of consecutive matched runes, a bonus for
+// matches that land on a word boundary (start of string, after a
+// '.'/'-'/'_'/' '/'/' separator, or a lower-to-upper CamelCase transition),
+// and a flat penalty charged when a match isn't contiguous with the one
+// before it.
+const (
+	fuzzyScoreMatch       = 16
+	fuzzyBonusBoundary    = 8
+	fuzzyBonusCamel       = 6
+	fuzzyBonusConsecutive = 4
+	fuzzyGapPenalty       = 6
+	fuzzyNegInf           = -1 << 30
+)
+
+// fuzzyMatch reports how well query fuzzy-matches target: every rune of
+// query must appear in target in order (a subsequence match). Among all
+// subsequence alignments it picks the highest-scoring one via dynamic
+// programming, preferring contiguous runs, boundary-aligned matches, and
+// few gaps. Returns the score, the 0-indexed rune positions in target that
+// were matched (ascending, one per query rune, for highlighting), and
+// whether query matched at all. An empty query always matches with score 0
+// and no positions.
+func fuzzyMatch(query, target string) (int, []int, bool) {
+	q := []rune(strings.ToLower(strings.TrimSpace(query)))
+	if len(q) == 0 {
+		return 0, nil, true
+	}
+	t := []rune(target)
+	lt := []rune(strings.ToLower(target))
+	n, m := len(t), len(q)
+	if m > n {
+		return 0, nil, false
+	}
+
+	// h[i][j] is the best score matching q[:j] against t[:i]; c[i][j] is
+	// the length of the consecutive matched run ending at t[i-1] if h[i][j]
+	// was achieved by matching there, else 0; matched[i][j] records whether
+	// that was the case, for traceback.
+	h := make([][]int, n+1)
+	c := make([][]int, n+1)
+	matched := make([][]bool, n+1)
+	for i := range h {
+		h[i] = make([]int, m+1)
+		c[i] = make([]int, m+1)
+		matched[i] = make([]bool, m+1)
+	}
+	for j := 1; j <= m; j++ {
+		h[0][j] = fuzzyNegInf
+	}
+
+	for i := 1; i <= n; i++ {
+		for j := 1; j <= m; j++ {
+			h[i][j] = h[i-1][j]
+			if lt[i-1] != q[j-1] {
+				continue
+			}
+			prev := h[i-1][j-1]
+			if prev <= fuzzyNegInf {
+				continue
+			}
+			run := c[i-1][j-1] + 1
+			score := prev + fuzzyScoreMatch + fuzzyBoundaryBonus(t, i-1) + fuzzyBonusConsecutive*(run-1)
+			if run == 1 && j > 1 {
+				score -= fuzzyGapPenalty
+			}
+			if score >= h[i][j] {
+				h[i][j] = score
+				c[i][j] = run
+				matched[i][j] = true
+			}
+		}
+	}
+
+	if h[n][m] <= fuzzyNegInf {
+		return 0, nil, false
+	}
+
+	positions := make([]int, 0, m)
+	for i, j := n, m; j > 0; {
+		if matched[i][j] {
+			positions = append(positions, i-1)
+			i--
+			j--
+		} else {
+			i--
+		}
+	}
+	for l, r := 0, len(positions)-1; l < r; l, r = l+1, r-1 {
+		positions[l], positions[r] = positions[r], positions[l]
+	}
+	return h[n][m], positions, true
+}
+
+// fuzzyBoundaryBonus returns the word-boundary bonus for a match landing at
+// target rune index i: the start of the string, right after a separator
+// rune, or a CamelCase transition from the previous rune all count.
+func fuzzyBoundaryBonus(t []rune, i int) int {
+	if i == 0 {
+		return fuzzyBonusBoundary
+	}
+	switch t[i-1] {
+	case '.', '-', '_', ' ', '/':
+		return fuzzyBonusBoundary
+	}
+	if unicode.IsLower(t[i-1]) && unicode.IsUpper(t[i]) {
+		return fuzzyBonusCamel
+	}
+	return 0
+}
@@ -0,0 +1,94 @@
+package main
+
+import "testing"
+
+func TestFuzzyMatchEmptyQueryAlwaysMatches(t *testing.T) {
+	score, positions, ok := fuzzyMatch("", "anything")
+	if !ok || score != 0 || positions != nil {
+		t.Fatalf("fuzzyMatch(\"\", ...) = (%d, %v, %v), want (0, nil, true)", score, positions, ok)
+	}
+}
+
+func TestFuzzyMatchCaseFolds(t *testing.T) {
+	// ToLower-based case folding doubles as "Unicode normalization" here:
+	// an uppercase query and an uppercase target both still match their
+	// lowercase counterparts, including outside ASCII (accented runes).
+	cases := []struct{ query, target string }{
+		{"PROVIDER", "provider"},
+		{"provider", "PROVIDER"},
+		{"CAFÉ", "café"},
+		{"café", "CAFÉ MENU"},
+	}
+	for _, c := range cases {
+		if _, _, ok := fuzzyMatch(c.query, c.target); !ok {
+			t.Errorf("fuzzyMatch(%q, %q) did not match, want case-insensitive match", c.query, c.target)
+		}
+	}
+}
+
+func TestFuzzyMatchNoSubsequenceFails(t *testing.T) {
+	if _, _, ok := fuzzyMatch("xyz", "provider"); ok {
+		t.Fatalf("fuzzyMatch(\"xyz\", \"provider\") matched, want false (no subsequence)")
+	}
+}
+
+func TestFuzzyMatchPositionsAreAscendingAndInBounds(t *testing.T) {
+	score, positions, ok := fuzzyMatch("swrt", "switch runtime")
+	if !ok {
+		t.Fatalf("fuzzyMatch(\"swrt\", \"switch runtime\") did not match")
+	}
+	if score <= 0 {
+		t.Fatalf("score = %d, want > 0 for a real match", score)
+	}
+	if len(positions) != 4 {
+		t.Fatalf("len(positions) = %d, want 4 (one per query rune)", len(positions))
+	}
+	for i := 1; i < len(positions); i++ {
+		if positions[i] <= positions[i-1] {
+			t.Fatalf("positions not strictly ascending: %v", positions)
+		}
+	}
+	for _, p := range positions {
+		if p < 0 || p >= len([]rune("switch runtime")) {
+			t.Fatalf("position %d out of bounds for target", p)
+		}
+	}
+}
+
+func TestFuzzyMatchBoundaryBeatsMidWord(t *testing.T) {
+	// "s" landing at the start of "session" (a word boundary: the start of
+	// the string) should outscore the same rune landing mid-word.
+	boundaryScore, _, ok := fuzzyMatch("s", "session")
+	if !ok {
+		t.Fatalf("fuzzyMatch(\"s\", \"session\") did not match")
+	}
+	midWordScore, _, ok := fuzzyMatch("s", "classic")
+	if !ok {
+		t.Fatalf("fuzzyMatch(\"s\", \"classic\") did not match")
+	}
+	if boundaryScore <= midWordScore {
+		t.Fatalf("boundary score %d should be greater than mid-word score %d", boundaryScore, midWordScore)
+	}
+}
+
+func TestFilteredCommandPaletteItemsTieStableOrdering(t *testing.T) {
+	reg := buildCommandRegistry()
+	// With no recency list, every "/" command ties at score 0; the result
+	// must still come back in the same, purely alphabetical order on every
+	// call instead of varying with map iteration order.
+	first := filteredCommandPaletteItems(reg, "/", "", nil)
+	second := filteredCommandPaletteItems(reg, "/", "", nil)
+	if len(first) != len(second) {
+		t.Fatalf("len(first)=%d, len(second)=%d, want equal", len(first), len(second))
+	}
+	for i := range first {
+		if first[i].cmd != second[i].cmd {
+			t.Fatalf("item %d differs across repeated calls: %q vs %q (sort not stable)", i, first[i].cmd, second[i].cmd)
+		}
+	}
+	for i := 1; i < len(first); i++ {
+		if first[i-1].cmd > first[i].cmd {
+			t.Fatalf("empty-query, no-recency order not alphabetical: %q before %q", first[i-1].cmd, first[i].cmd)
+		}
+	}
+}
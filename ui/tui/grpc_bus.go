@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	"google.golang.org/grpc"
+
+	"workbench/ui/tui/busproto"
+)
+
+// grpcBusServer hosts the WorkbenchBus gRPC service so an executor process
+// can attach over a socket instead of tailing JSONL files. Selected by
+// setting WORKBENCH_BUS=grpc; the listen address is written into the
+// executor-ready sentinel file (see opencodeExecutorReadyPath) so the TUI
+// side can dial it. The executor supplies the actual request handling by
+// implementing busproto.WorkbenchBusServer and passing it to listenGrpcBus.
+type grpcBusServer struct {
+	listener net.Listener
+	server   *grpc.Server
+	addr     string
+}
+
+// listenGrpcBus starts a gRPC listener on an OS-assigned loopback port,
+// registers impl's RPC handlers on it, and returns the address it bound to.
+// The caller is responsible for calling grpc.Server.Serve on a goroutine and
+// for stopping it on shutdown.
+func listenGrpcBus(impl busproto.WorkbenchBusServer) (*grpcBusServer, error) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	server := grpc.NewServer()
+	busproto.RegisterWorkbenchBusServer(server, impl)
+	s := &grpcBusServer{
+		listener: lis,
+		server:   server,
+		addr:     lis.Addr().String(),
+	}
+	return s, nil
+}
+
+func (s *grpcBusServer) Addr() string {
+	if s == nil {
+		return ""
+	}
+	return s.addr
+}
+
+func (s *grpcBusServer) Serve() error {
+	if s == nil || s.server == nil {
+		return fmt.Errorf("grpc bus server not initialized")
+	}
+	return s.server.Serve(s.listener)
+}
+
+func (s *grpcBusServer) Stop() {
+	if s == nil || s.server == nil {
+		return
+	}
+	s.server.GracefulStop()
+}
+
+// grpcBusClient implements busClient over a gRPC connection to the address
+// published by the executor's ready sentinel.
+type grpcBusClient struct {
+	addr string
+	conn *grpc.ClientConn
+	rpc  busproto.WorkbenchBusClient
+}
+
+func dialGrpcBus(addr string) (*grpcBusClient, error) {
+	conn, err := grpc.Dial(addr, grpc.WithInsecure())
+	if err != nil {
+		return nil, err
+	}
+	return &grpcBusClient{addr: addr, conn: conn, rpc: busproto.NewWorkbenchBusClient(conn)}, nil
+}
+
+func (c *grpcBusClient) SubmitTurn(ctx context.Context, req opencodeTurnRequest) error {
+	if c == nil || c.rpc == nil {
+		return fmt.Errorf("grpc bus client not connected")
+	}
+	stream, err := c.rpc.SubmitTurn(ctx, &busproto.OpencodeTurnRequest{
+		Version:        int32(req.Version),
+		Type:           req.Type,
+		CorrelationID:  req.CorrelationID,
+		Prompt:         req.Prompt,
+		Cwd:            req.Cwd,
+		Model:          req.Model,
+		Agent:          req.Agent,
+		Think:          req.Think,
+		PermissionMode: req.PermissionMode,
+	})
+	if err != nil {
+		return err
+	}
+	// Events are delivered to the caller via the tea.Cmd polling loop in
+	// model.go (consumeOpencodeEvents); draining here just keeps the
+	// server-streaming RPC alive until the executor sends the terminal event.
+	for {
+		if _, err := stream.Recv(); err != nil {
+			return nil
+		}
+	}
+}
+
+func (c *grpcBusClient) Cancel(ctx context.Context, correlationID string) error {
+	if c == nil || c.rpc == nil {
+		return fmt.Errorf("grpc bus client not connected")
+	}
+	_, err := c.rpc.Cancel(ctx, &busproto.CancelRequest{CorrelationID: correlationID})
+	return err
+}
+
+func (c *grpcBusClient) Close() error {
+	if c == nil || c.conn == nil {
+		return nil
+	}
+	return c.conn.Close()
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"workbench/ui/tui/busproto"
+)
+
+// grpcBusStub is the minimal WorkbenchBusServer TestGrpcBusRoundTrip dials
+// against: one fixed turn.event per SubmitTurn, and Cancel just records the
+// correlation ID it was asked to cancel.
+type grpcBusStub struct {
+	busproto.WorkbenchBusServer
+
+	cancelled chan string
+}
+
+func (s *grpcBusStub) SubmitTurn(req *busproto.OpencodeTurnRequest, stream busproto.WorkbenchBus_SubmitTurnServer) error {
+	return stream.Send(&busproto.OpencodeTurnEvent{
+		Version:       1,
+		Type:          "turn.event",
+		CorrelationID: req.CorrelationID,
+		Kind:          "info",
+		Message:       "echo:" + req.Prompt,
+	})
+}
+
+func (s *grpcBusStub) Cancel(ctx context.Context, req *busproto.CancelRequest) (*busproto.CancelResult, error) {
+	s.cancelled <- req.CorrelationID
+	return &busproto.CancelResult{Ok: true}, nil
+}
+
+// TestGrpcBusRoundTrip starts a live grpcBusServer backed by grpcBusStub,
+// dials it with dialGrpcBus the same way the TUI does when WORKBENCH_BUS=grpc,
+// and exercises both SubmitTurn (draining the event stream) and Cancel,
+// proving the hand-rolled busproto client/server actually round-trip.
+func TestGrpcBusRoundTrip(t *testing.T) {
+	stub := &grpcBusStub{cancelled: make(chan string, 1)}
+	srv, err := listenGrpcBus(stub)
+	if err != nil {
+		t.Fatalf("listenGrpcBus: %v", err)
+	}
+	go srv.Serve()
+	defer srv.Stop()
+
+	client, err := dialGrpcBus(srv.Addr())
+	if err != nil {
+		t.Fatalf("dialGrpcBus: %v", err)
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := client.SubmitTurn(ctx, opencodeTurnRequest{
+		Version:       1,
+		Type:          "turn",
+		CorrelationID: "cid-1",
+		Prompt:        "hello",
+	}); err != nil {
+		t.Fatalf("SubmitTurn: %v", err)
+	}
+
+	if err := client.Cancel(ctx, "cid-1"); err != nil {
+		t.Fatalf("Cancel: %v", err)
+	}
+
+	select {
+	case got := <-stub.cancelled:
+		if got != "cid-1" {
+			t.Fatalf("cancelled correlation ID = %q, want %q", got, "cid-1")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for the server to observe Cancel")
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"os"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"golang.org/x/term"
+)
+
+// defaultTmuxHeightSpec is what --tui-height/WORKBENCH_TUI_HEIGHT falls back
+// to when running under tmux with neither set: fzf-style height mode avoids
+// stealing the whole pane, at the cost of a bit less room than a dedicated
+// full-screen session would have.
+const defaultTmuxHeightSpec = "60%"
+
+// resolveTUIHeightSpec picks the --tui-height value, falling back to
+// WORKBENCH_TUI_HEIGHT, then (only under tmux) defaultTmuxHeightSpec.
+// Returns "" when workbench should run full-screen as before.
+func resolveTUIHeightSpec(flagValue string) string {
+	if strings.TrimSpace(flagValue) != "" {
+		return strings.TrimSpace(flagValue)
+	}
+	if v := strings.TrimSpace(os.Getenv("WORKBENCH_TUI_HEIGHT")); v != "" {
+		return v
+	}
+	if strings.TrimSpace(os.Getenv("TMUX")) != "" {
+		return defaultTmuxHeightSpec
+	}
+	return ""
+}
+
+// parseTUIHeightRows resolves spec ("30%" or a plain row count like "20")
+// against termHeight, clamped to [1, termHeight] the same way fzf's
+// --height never exceeds the terminal. ok is false for an empty or
+// unparseable spec, in which case workbench should run full-screen.
+func parseTUIHeightRows(spec string, termHeight int) (rows int, ok bool) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return 0, false
+	}
+	if termHeight < 1 {
+		termHeight = 24
+	}
+	if strings.HasSuffix(spec, "%") {
+		pct, err := strconv.Atoi(strings.TrimSuffix(spec, "%"))
+		if err != nil {
+			return 0, false
+		}
+		rows = termHeight * pct / 100
+	} else {
+		n, err := strconv.Atoi(spec)
+		if err != nil {
+			return 0, false
+		}
+		rows = n
+	}
+	if rows < 1 {
+		rows = 1
+	}
+	if rows > termHeight {
+		rows = termHeight
+	}
+	return rows, true
+}
+
+// terminalSize queries the real terminal dimensions backing stdout, falling
+// back to 80x24 (the same default effectiveSize assumes) when stdout isn't a
+// TTY, e.g. under --smoke or --serve.
+func terminalSize() (width, height int) {
+	w, h, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil || w <= 0 || h <= 0 {
+		return 80, 24
+	}
+	return w, h
+}
+
+// heightModeInitCmd seeds the synthetic tea.WindowSizeMsg{Height: rows}
+// height mode needs at startup: Bubble Tea's own real WindowSizeMsg (full
+// terminal height) would otherwise arrive first, and Update's clamp only
+// applies to messages it has already seen.
+func heightModeInitCmd(width, rows int) tea.Cmd {
+	return func() tea.Msg {
+		return tea.WindowSizeMsg{Width: width, Height: rows}
+	}
+}
+
+// clearHeightModeRegion restores the cursor and erases the rows height mode
+// rendered into, the cleanup a full tea.WithAltScreen() session gets for
+// free when the terminal switches back to the primary screen.
+func clearHeightModeRegion(rows int) {
+	if rows < 1 {
+		return
+	}
+	var b strings.Builder
+	b.WriteString("\r")
+	for i := 0; i < rows; i++ {
+		b.WriteString("\x1b[2K")
+		if i < rows-1 {
+			b.WriteString("\x1b[1A")
+		}
+	}
+	os.Stdout.WriteString(b.String())
+}
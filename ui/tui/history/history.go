@@ -0,0 +1,365 @@
+// Package history is the etcd-style applier/backend split applied to chat
+// scrollback: every user turn, assistant reply, tool call, and system alert
+// is appended to a per-session write-ahead log (<stateDir>/<id>/history.wal)
+// with a monotonically increasing sequence number, and periodic snapshots
+// (history.snap.<seq>) let Open reconstruct state without always walking
+// the WAL from entry zero. A session that crashes mid-turn, or is hard
+// killed, picks back up from the last committed entry the next time
+// newAppModel calls Open instead of starting blank.
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// EntryKind classifies one WAL record.
+type EntryKind string
+
+const (
+	KindUser      EntryKind = "user"
+	KindAssistant EntryKind = "assistant"
+	KindTool      EntryKind = "tool"
+	KindSystem    EntryKind = "system"
+	KindAlert     EntryKind = "alert"
+)
+
+// Entry is one WAL record, in the order it was committed.
+type Entry struct {
+	Seq           int64     `json:"seq"`
+	Kind          EntryKind `json:"kind"`
+	Role          string    `json:"role,omitempty"`
+	Text          string    `json:"text,omitempty"`
+	CorrelationID string    `json:"correlationId,omitempty"`
+	At            string    `json:"at"`
+}
+
+// Message mirrors the tui package's chatMessage shape without this package
+// depending on it, the same way commands.Cmd is generic over the tui model
+// type rather than importing it.
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// RoleLine mirrors the tui package's chatRoleLine shape.
+type RoleLine struct {
+	Role string `json:"role"`
+	Text string `json:"text"`
+}
+
+// State is what Open/RewindTo reconstruct: the scrollback, plus whichever
+// correlation ID was still in flight when the WAL ends (an unresolved user
+// turn with no matching assistant entry after it).
+type State struct {
+	Messages              []Message  `json:"messages"`
+	RoleLines             []RoleLine `json:"roleLines"`
+	InFlightCorrelationID string     `json:"inFlightCorrelationId,omitempty"`
+}
+
+// snapshotInterval is how many appended entries elapse between snapshots.
+const snapshotInterval = 200
+
+// WAL is a per-session, append-only, sequence-numbered chat log with
+// periodic full-state snapshots.
+type WAL struct {
+	dir  string
+	path string
+
+	mu    sync.Mutex
+	f     *os.File
+	seq   int64
+	state State
+}
+
+func walPath(dir string) string {
+	return filepath.Join(dir, "history.wal")
+}
+
+func snapshotPath(dir string, seq int64) string {
+	return filepath.Join(dir, fmt.Sprintf("history.snap.%d", seq))
+}
+
+// Open replays the latest snapshot plus the WAL tail for sessionID under
+// stateDir (if any exist yet) and returns a WAL ready for further Append
+// calls, along with the reconstructed State.
+func Open(stateDir string, sessionID string) (*WAL, State, error) {
+	dir := filepath.Join(stateDir, sessionID)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, State{}, err
+	}
+	path := walPath(dir)
+	if _, err := os.Stat(path); err != nil {
+		if err := os.WriteFile(path, []byte{}, 0o644); err != nil {
+			return nil, State{}, err
+		}
+	}
+
+	state, seq, err := replay(dir, path)
+	if err != nil {
+		return nil, State{}, err
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, State{}, err
+	}
+	return &WAL{dir: dir, path: path, f: f, seq: seq, state: state}, state, nil
+}
+
+// replay reconstructs State for the session at dir by applying its latest
+// snapshot (if any) plus every WAL entry committed after it, returning the
+// resulting state and the highest sequence number applied. Shared by Open
+// (which then keeps the WAL file open for further appends) and Peek (which
+// doesn't).
+func replay(dir string, path string) (State, int64, error) {
+	state, seq := loadLatestSnapshot(dir)
+	entries, err := readEntries(path)
+	if err != nil {
+		return State{}, 0, err
+	}
+	for _, e := range entries {
+		if e.Seq <= seq {
+			continue
+		}
+		apply(&state, e)
+		seq = e.Seq
+	}
+	return state, seq, nil
+}
+
+// Peek reconstructs State for sessionID under stateDir the same way Open
+// does, without creating the session directory or holding its WAL file
+// open. Used by the session browser to list and preview past sessions
+// without disturbing whichever session is currently in progress.
+func Peek(stateDir string, sessionID string) (State, error) {
+	dir := filepath.Join(stateDir, sessionID)
+	state, _, err := replay(dir, walPath(dir))
+	return state, err
+}
+
+func loadLatestSnapshot(dir string) (State, int64) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return State{}, 0
+	}
+	bestSeq := int64(-1)
+	bestName := ""
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasPrefix(e.Name(), "history.snap.") {
+			continue
+		}
+		seq, err := strconv.ParseInt(strings.TrimPrefix(e.Name(), "history.snap."), 10, 64)
+		if err != nil {
+			continue
+		}
+		if seq > bestSeq {
+			bestSeq = seq
+			bestName = e.Name()
+		}
+	}
+	if bestName == "" {
+		return State{}, 0
+	}
+	raw, err := os.ReadFile(filepath.Join(dir, bestName))
+	if err != nil {
+		return State{}, 0
+	}
+	var snap State
+	if json.Unmarshal(raw, &snap) != nil {
+		return State{}, 0
+	}
+	return snap, bestSeq
+}
+
+func readEntries(path string) ([]Entry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var out []Entry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var e Entry
+		if json.Unmarshal([]byte(line), &e) == nil {
+			out = append(out, e)
+		}
+	}
+	return out, scanner.Err()
+}
+
+func apply(s *State, e Entry) {
+	switch e.Kind {
+	case KindUser:
+		s.Messages = append(s.Messages, Message{Role: "user", Content: e.Text})
+		s.RoleLines = append(s.RoleLines, RoleLine{Role: "user", Text: e.Text})
+		s.InFlightCorrelationID = e.CorrelationID
+	case KindAssistant:
+		s.Messages = append(s.Messages, Message{Role: "assistant", Content: e.Text})
+		s.RoleLines = append(s.RoleLines, RoleLine{Role: "assistant", Text: e.Text})
+		s.InFlightCorrelationID = ""
+	case KindTool, KindSystem, KindAlert:
+		role := e.Role
+		if role == "" {
+			role = "system"
+		}
+		s.RoleLines = append(s.RoleLines, RoleLine{Role: role, Text: e.Text})
+	}
+}
+
+// Append commits one entry with the next sequence number and fsyncs it,
+// taking a snapshot every snapshotInterval entries so a later Open doesn't
+// have to replay the whole file.
+func (w *WAL) Append(kind EntryKind, role string, text string, correlationID string) (Entry, error) {
+	if w == nil {
+		return Entry{}, nil
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	seq := w.seq + 1
+	e := Entry{
+		Seq:           seq,
+		Kind:          kind,
+		Role:          role,
+		Text:          text,
+		CorrelationID: correlationID,
+		At:            time.Now().UTC().Format(time.RFC3339Nano),
+	}
+	b, err := json.Marshal(e)
+	if err != nil {
+		return Entry{}, err
+	}
+	if _, err := w.f.Write(append(b, '\n')); err != nil {
+		return Entry{}, err
+	}
+	if err := w.f.Sync(); err != nil {
+		return Entry{}, err
+	}
+	w.seq = seq
+	apply(&w.state, e)
+
+	if w.seq%snapshotInterval == 0 {
+		_ = w.writeSnapshot()
+	}
+	return e, nil
+}
+
+func (w *WAL) writeSnapshot() error {
+	b, err := json.Marshal(w.state)
+	if err != nil {
+		return err
+	}
+	path := snapshotPath(w.dir, w.seq)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Seq returns the sequence number of the last committed entry.
+func (w *WAL) Seq() int64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.seq
+}
+
+// Close closes the underlying file handle. The WAL itself is durable on
+// disk and needs no other teardown.
+func (w *WAL) Close() error {
+	if w == nil {
+		return nil
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.f.Close()
+}
+
+// RewindTo truncates the WAL to entries with Seq <= seq, discarding
+// anything after as if those turns never happened, and returns the
+// reconstructed State as of that point. A later Append continues the
+// sequence from seq, so rewinding and then sending a new turn branches the
+// conversation instead of replaying what was discarded. Snapshots taken
+// past the rewind point are removed so a later Open can't resume from one
+// that references entries this call just dropped.
+func (w *WAL) RewindTo(seq int64) (State, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	entries, err := readEntries(w.path)
+	if err != nil {
+		return State{}, err
+	}
+	var kept []Entry
+	var state State
+	for _, e := range entries {
+		if e.Seq > seq {
+			break
+		}
+		kept = append(kept, e)
+		apply(&state, e)
+	}
+
+	if err := w.f.Close(); err != nil {
+		return State{}, err
+	}
+	tmp := w.path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return State{}, err
+	}
+	for _, e := range kept {
+		b, err := json.Marshal(e)
+		if err != nil {
+			f.Close()
+			return State{}, err
+		}
+		if _, err := f.Write(append(b, '\n')); err != nil {
+			f.Close()
+			return State{}, err
+		}
+	}
+	if err := f.Close(); err != nil {
+		return State{}, err
+	}
+	if err := os.Rename(tmp, w.path); err != nil {
+		return State{}, err
+	}
+
+	nf, err := os.OpenFile(w.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return State{}, err
+	}
+
+	if snaps, err := os.ReadDir(w.dir); err == nil {
+		for _, e := range snaps {
+			if e.IsDir() || !strings.HasPrefix(e.Name(), "history.snap.") {
+				continue
+			}
+			snapSeq, err := strconv.ParseInt(strings.TrimPrefix(e.Name(), "history.snap."), 10, 64)
+			if err == nil && snapSeq > seq {
+				_ = os.Remove(filepath.Join(w.dir, e.Name()))
+			}
+		}
+	}
+
+	w.f = nf
+	w.seq = seq
+	w.state = state
+	return state, nil
+}
@@ -0,0 +1,395 @@
+// Package hooks implements an event-hook bus modeled after the Cwtch
+// autobindings "event hooks" pattern: subscribers register against a glob
+// over dot-separated event types (e.g. "chat.*", "auth.swap",
+// "permission_mode.toggled") and receive the event's structured payload
+// plus its correlation/causation IDs. Two out-of-process transports are
+// layered on top of in-process subscriptions: an on-disk manifest of
+// subprocess hooks under stateDir/hooks/, and a Unix-domain-socket JSONL
+// fan-out for real-time external listeners.
+//
+// A manifest hook marked "blocking" runs synchronously and can veto the
+// event by writing a rejection back on stdout; Bus.Publish surfaces that as
+// a Rejection the caller can turn into a user-facing alert.
+//
+// The socket transport is bidirectional: besides fanning Publish out to
+// every connected client, Bus reads newline-delimited JSON back from each
+// client and queues it for DrainCommands, so an external process can both
+// watch and drive a session over the one connection.
+package hooks
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Event is the payload handed to every matching subscriber and transport.
+type Event struct {
+	Type          string `json:"type"`
+	Source        string `json:"source"`
+	Payload       any    `json:"payload,omitempty"`
+	CorrelationID string `json:"correlation_id,omitempty"`
+	CausationID   string `json:"causation_id,omitempty"`
+	At            string `json:"at"`
+}
+
+// Rejection is returned by a Handler (or parsed from a blocking manifest
+// hook's stdout) to veto the action that triggered the event.
+type Rejection struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	By      string `json:"by,omitempty"` // hook name or subscriber pattern that vetoed
+}
+
+// Handler observes (and may veto) an event. A non-nil Rejection stops
+// Publish from notifying any remaining in-process handlers.
+type Handler func(ev Event) *Rejection
+
+type subscription struct {
+	id      uint64
+	pattern string
+	handler Handler
+}
+
+// manifestHook is one entry loaded from stateDir/hooks/*.json.
+type manifestHook struct {
+	Name     string   `json:"name"`
+	Pattern  string   `json:"pattern"`
+	Exec     []string `json:"exec"`
+	Blocking bool     `json:"blocking"`
+}
+
+// Bus is the hook dispatcher for a single session. It fans every Publish
+// out to in-process subscribers, on-disk manifest subprocess hooks, and any
+// connected Unix-domain-socket listeners.
+type Bus struct {
+	manifestDir string
+	socketPath  string
+
+	mu     sync.RWMutex
+	subs   []subscription
+	nextID uint64
+
+	listener  net.Listener
+	connMu    sync.Mutex
+	conns     []net.Conn
+	closeOnce sync.Once
+
+	cmdMu  sync.Mutex
+	cmdBuf []json.RawMessage
+}
+
+// NewBus returns a Bus rooted at stateDir. If disableNetwork is false, it
+// also starts listening on stateDir/<sessionID>/hooks.sock for external
+// JSONL subscribers; listen failures are non-fatal (hooks degrade to
+// in-process + manifest subprocess only), mirroring how the other buses in
+// this package tolerate a missing state directory.
+func NewBus(stateDir string, sessionID string, disableNetwork bool) *Bus {
+	return NewBusAt(stateDir, sessionID, "", disableNetwork)
+}
+
+// NewBusAt is NewBus with an explicit socketPath override (e.g. from
+// --events-socket=unix:/tmp/workbench.sock), used instead of the default
+// stateDir/<sessionID>/hooks.sock location when non-empty.
+func NewBusAt(stateDir string, sessionID string, socketPath string, disableNetwork bool) *Bus {
+	b := &Bus{
+		manifestDir: filepath.Join(stateDir, "hooks"),
+	}
+	if strings.TrimSpace(socketPath) != "" {
+		b.socketPath = socketPath
+	} else {
+		if strings.TrimSpace(sessionID) == "" {
+			sessionID = "sess_unknown"
+		}
+		b.socketPath = filepath.Join(stateDir, sessionID, "hooks.sock")
+	}
+	if !disableNetwork {
+		b.startSocket()
+	}
+	return b
+}
+
+// Subscribe registers an in-process handler against a glob pattern over
+// event types (a trailing "*" matches any run of characters; "chat.*"
+// matches "chat.send" and "chat.cancelled" but not "chat"). It returns an
+// unsubscribe function.
+func (b *Bus) Subscribe(pattern string, h Handler) func() {
+	b.mu.Lock()
+	b.nextID++
+	id := b.nextID
+	b.subs = append(b.subs, subscription{id: id, pattern: pattern, handler: h})
+	b.mu.Unlock()
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		for i := range b.subs {
+			if b.subs[i].id == id {
+				b.subs = append(b.subs[:i], b.subs[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// Publish notifies every subscriber and hook whose pattern matches ev.Type.
+// In-process subscribers run first and synchronously, in registration
+// order; the first Rejection returned is returned at once, so the remaining
+// in-process subscribers, every manifest hook, and the socket fan-out are
+// all skipped. Otherwise manifest hooks run next in directory order:
+// blocking ones synchronously, each able to veto, and non-blocking ones
+// fire-and-forget. A blocking veto stops the manifest loop, so hooks after
+// it do not run. The socket fan-out runs whenever no in-process subscriber
+// vetoed.
+func (b *Bus) Publish(ev Event) *Rejection {
+	if b == nil {
+		return nil
+	}
+	if ev.At == "" {
+		ev.At = time.Now().UTC().Format(time.RFC3339Nano)
+	}
+
+	b.mu.RLock()
+	subs := make([]subscription, len(b.subs))
+	copy(subs, b.subs)
+	b.mu.RUnlock()
+
+	for _, s := range subs {
+		if !matchGlob(s.pattern, ev.Type) {
+			continue
+		}
+		if rej := s.handler(ev); rej != nil {
+			if rej.By == "" {
+				rej.By = s.pattern
+			}
+			return rej
+		}
+	}
+
+	hooks := b.loadManifest()
+	var rejection *Rejection
+	for _, h := range hooks {
+		if !matchGlob(h.Pattern, ev.Type) {
+			continue
+		}
+		if h.Blocking {
+			if rej := b.runManifestHook(h, ev); rej != nil {
+				rejection = rej
+				break
+			}
+			continue
+		}
+		go b.runManifestHook(h, ev)
+	}
+
+	b.fanOutSocket(ev)
+	return rejection
+}
+
+// matchGlob supports a single trailing "*" (e.g. "chat.*") or an exact
+// match; that covers every pattern this codebase's event-type taxonomy
+// needs ("chat.*", "auth.swap", "system.alert", "*" for catch-all).
+func matchGlob(pattern string, eventType string) bool {
+	if pattern == "" || pattern == "*" {
+		return true
+	}
+	if strings.HasSuffix(pattern, "*") {
+		return strings.HasPrefix(eventType, strings.TrimSuffix(pattern, "*"))
+	}
+	return pattern == eventType
+}
+
+func (b *Bus) loadManifest() []manifestHook {
+	entries, err := os.ReadDir(b.manifestDir)
+	if err != nil {
+		return nil
+	}
+	var out []manifestHook
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+		raw, err := os.ReadFile(filepath.Join(b.manifestDir, e.Name()))
+		if err != nil {
+			continue
+		}
+		var h manifestHook
+		if json.Unmarshal(raw, &h) != nil || len(h.Exec) == 0 {
+			continue
+		}
+		if strings.TrimSpace(h.Name) == "" {
+			h.Name = strings.TrimSuffix(e.Name(), ".json")
+		}
+		out = append(out, h)
+	}
+	return out
+}
+
+// runManifestHook execs the hook's command with the event JSON on stdin and
+// a bounded timeout, so a hung subprocess can't wedge the TUI. A blocking
+// hook vetoes by writing {"reject": true, "reason": "..."} as its last
+// line of stdout.
+func (b *Bus) runManifestHook(h manifestHook, ev Event) *Rejection {
+	payload, err := json.Marshal(ev)
+	if err != nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, h.Exec[0], h.Exec[1:]...)
+	cmd.Stdin = bytes.NewReader(payload)
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+	if err := cmd.Run(); err != nil {
+		return nil
+	}
+	if !h.Blocking {
+		return nil
+	}
+	return parseRejection(h.Name, stdout.Bytes())
+}
+
+func parseRejection(hookName string, out []byte) *Rejection {
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	var last struct {
+		Reject bool   `json:"reject"`
+		Reason string `json:"reason"`
+		Code   string `json:"code"`
+	}
+	found := false
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var cur struct {
+			Reject bool   `json:"reject"`
+			Reason string `json:"reason"`
+			Code   string `json:"code"`
+		}
+		if json.Unmarshal([]byte(line), &cur) == nil {
+			last = cur
+			found = true
+		}
+	}
+	if !found || !last.Reject {
+		return nil
+	}
+	code := last.Code
+	if code == "" {
+		code = "hook_rejected"
+	}
+	return &Rejection{Code: code, Message: last.Reason, By: hookName}
+}
+
+func (b *Bus) startSocket() {
+	_ = os.Remove(b.socketPath)
+	_ = os.MkdirAll(filepath.Dir(b.socketPath), 0o755)
+	lis, err := net.Listen("unix", b.socketPath)
+	if err != nil {
+		return
+	}
+	b.listener = lis
+	go func() {
+		for {
+			conn, err := lis.Accept()
+			if err != nil {
+				return
+			}
+			b.connMu.Lock()
+			b.conns = append(b.conns, conn)
+			b.connMu.Unlock()
+			go b.readCommands(conn)
+		}
+	}()
+}
+
+// readCommands drains newline-delimited JSON sent by a connected client into
+// cmdBuf until the connection closes; fanOutSocket separately prunes conns
+// on write failure, so this goroutine just exits quietly on read error.
+func (b *Bus) readCommands(conn net.Conn) {
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		raw := make(json.RawMessage, len(line))
+		copy(raw, line)
+		b.cmdMu.Lock()
+		b.cmdBuf = append(b.cmdBuf, raw)
+		b.cmdMu.Unlock()
+	}
+}
+
+// DrainCommands returns and clears every inbound JSONL line queued by
+// connected socket clients since the last call, in arrival order.
+func (b *Bus) DrainCommands() []json.RawMessage {
+	if b == nil {
+		return nil
+	}
+	b.cmdMu.Lock()
+	defer b.cmdMu.Unlock()
+	if len(b.cmdBuf) == 0 {
+		return nil
+	}
+	out := b.cmdBuf
+	b.cmdBuf = nil
+	return out
+}
+
+func (b *Bus) fanOutSocket(ev Event) {
+	line, err := json.Marshal(ev)
+	if err != nil {
+		return
+	}
+	line = append(line, '\n')
+
+	b.connMu.Lock()
+	defer b.connMu.Unlock()
+	live := b.conns[:0]
+	for _, c := range b.conns {
+		if _, err := c.Write(line); err != nil {
+			_ = c.Close()
+			continue
+		}
+		live = append(live, c)
+	}
+	b.conns = live
+}
+
+// Close stops the socket listener and disconnects any fan-out clients.
+func (b *Bus) Close() {
+	if b == nil {
+		return
+	}
+	b.closeOnce.Do(func() {
+		if b.listener != nil {
+			_ = b.listener.Close()
+		}
+		b.connMu.Lock()
+		for _, c := range b.conns {
+			_ = c.Close()
+		}
+		b.conns = nil
+		b.connMu.Unlock()
+		_ = os.Remove(b.socketPath)
+	})
+}
+
+// SocketPath returns the Unix-domain-socket path external subscribers
+// should dial (empty if the bus failed to bind one).
+func (b *Bus) SocketPath() string {
+	if b == nil || b.listener == nil {
+		return ""
+	}
+	return b.socketPath
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Footer row accounting: footerBaseRowsFull is the row count of the
+// blank-separator/input/info/footer-hint block at its default size (see
+// viewChatFull), footerBaseRowsMin is what's left once it's collapsed all
+// the way down to just the input line, and footerDefaultAlertCap is how
+// many alert lines show at the default size (unchanged from before
+// footerExtraRows existed).
+const (
+	footerBaseRowsFull    = 4
+	footerBaseRowsMin     = 1
+	footerDefaultAlertCap = 3
+)
+
+// footerBaseRows is how many of the blank/input/info/footer-hint rows are
+// currently shown: footerExtraRows >= 0 keeps all four (and instead grows
+// the alert cap / padding, see footerAlertCap and footerPadRows); negative
+// values shrink this block, dropping the footer hint first, then the
+// blank separator, then the info line, bottoming out at the input line
+// alone.
+func (m appModel) footerBaseRows() int {
+	if m.footerExtraRows >= 0 {
+		return footerBaseRowsFull
+	}
+	rows := footerBaseRowsFull + m.footerExtraRows
+	if rows < footerBaseRowsMin {
+		rows = footerBaseRowsMin
+	}
+	return rows
+}
+
+// footerAlertCap is how many alert lines may show: growing footerExtraRows
+// past 0 raises this so a taller footer can surface more than the default
+// 3 recent alerts.
+func (m appModel) footerAlertCap() int {
+	if m.footerExtraRows > 0 {
+		return footerDefaultAlertCap + m.footerExtraRows
+	}
+	return footerDefaultAlertCap
+}
+
+// footerPadRows is the count of blank filler rows appended after the alert
+// section: a positive footerExtraRows grows the footer even when there
+// aren't enough alerts to fill the extra alert cap on their own, so the
+// boundary still visibly moves up to the half-of-chatHeight maximum.
+func (m appModel) footerPadRows() int {
+	if m.footerExtraRows > 0 {
+		return m.footerExtraRows
+	}
+	return 0
+}
+
+// footerExtraRowsBounds returns the [min, max] footerExtraRows may take for
+// the given chatHeight: shrinking bottoms out at footerBaseRowsMin (the "1
+// input line only" floor from chunk5-4), growing is capped so the full base
+// rows plus padding plus the boundary row never exceed half of
+// chatHeight-2; alert lines are not counted against that cap.
+func footerExtraRowsBounds(chatHeight int) (int, int) {
+	innerHeight := chatHeight - 2
+	if innerHeight < 1 {
+		innerHeight = 1
+	}
+	half := innerHeight / 2
+	max := half - footerBaseRowsFull - 1 // -1 for the always-on boundary row
+	if max < 0 {
+		max = 0
+	}
+	min := footerBaseRowsMin - footerBaseRowsFull
+	return min, max
+}
+
+func clampFooterExtraRows(rows int, chatHeight int) int {
+	min, max := footerExtraRowsBounds(chatHeight)
+	if rows < min {
+		rows = min
+	}
+	if rows > max {
+		rows = max
+	}
+	return rows
+}
+
+// layoutState is the per-session footerExtraRows setting, persisted so a
+// resized split survives a restart the same way history.WAL's state does.
+type layoutState struct {
+	FooterExtraRows int `json:"footerExtraRows"`
+}
+
+func layoutPath(stateDir string, sessionID string) string {
+	return filepath.Join(stateDir, sessionID, "layout.json")
+}
+
+// loadLayoutState reads sessionID's saved footerExtraRows, defaulting to 0
+// (the original, unresized layout) if none was ever saved.
+func loadLayoutState(stateDir string, sessionID string) layoutState {
+	raw, err := os.ReadFile(layoutPath(stateDir, sessionID))
+	if err != nil {
+		return layoutState{}
+	}
+	var st layoutState
+	_ = json.Unmarshal(raw, &st)
+	return st
+}
+
+// adjustFooterExtraRows changes footerExtraRows by delta (clamped to what
+// currentChatHeight() allows) and persists the result, the same "mutate
+// then save" flow //rewind and startNewSession use for their own per-session
+// state.
+func (m appModel) adjustFooterExtraRows(delta int) appModel {
+	chatHeight := m.currentChatHeight()
+	rows := clampFooterExtraRows(m.footerExtraRows+delta, chatHeight)
+	if rows == m.footerExtraRows {
+		return m
+	}
+	m.footerExtraRows = rows
+	if err := saveLayoutState(m.cfg.stateDir, m.sessionID, layoutState{FooterExtraRows: rows}); err != nil {
+		m.systemAlert(alertWarn, "layout.save_failed", "Failed to save layout", map[string]any{"error": err.Error()})
+	}
+	return m
+}
+
+// resetFooterExtraRows restores the default (unresized) footer split,
+// surfaced as the //layout command.
+func (m appModel) resetFooterExtraRows() appModel {
+	if m.footerExtraRows == 0 {
+		return m
+	}
+	m.footerExtraRows = 0
+	if err := saveLayoutState(m.cfg.stateDir, m.sessionID, layoutState{FooterExtraRows: 0}); err != nil {
+		m.systemAlert(alertWarn, "layout.save_failed", "Failed to save layout", map[string]any{"error": err.Error()})
+	}
+	return m
+}
+
+// footerBoundaryRow is the absolute terminal row (0-indexed) of the
+// draggable boundary line between chat history and the footer: the
+// header, then the chat panel's top border, then every history line above
+// it.
+func (m appModel) footerBoundaryRow() int {
+	header := renderHeader(m.th, m.cfg.applicationV, m.mcpConnected, m.sessionID)
+	chatHeight := m.currentChatHeight()
+	historyMax := m.chatHistoryMaxLines(chatHeight)
+	return lipgloss.Height(header) + 1 + historyMax
+}
+
+// handleFooterMouse tracks hover/drag of the boundary row: a left-button
+// press on the boundary starts a drag, motion while dragging adjusts
+// footerExtraRows by the row delta (moving the mouse up grows the footer),
+// and any release ends it.
+func (m appModel) handleFooterMouse(ev tea.MouseMsg) appModel {
+	boundary := m.footerBoundaryRow()
+	m.footerBoundaryHovered = ev.Y >= boundary-1 && ev.Y <= boundary+1
+
+	switch ev.Action {
+	case tea.MouseActionPress:
+		if ev.Button == tea.MouseButtonLeft && m.footerBoundaryHovered {
+			m.footerDragging = true
+			m.footerDragLastY = ev.Y
+		}
+	case tea.MouseActionMotion:
+		if m.footerDragging {
+			delta := m.footerDragLastY - ev.Y
+			if delta != 0 {
+				m = m.adjustFooterExtraRows(delta)
+				m.footerDragLastY = ev.Y
+			}
+		}
+	case tea.MouseActionRelease:
+		m.footerDragging = false
+	}
+	return m
+}
+
+// saveLayoutState persists footerExtraRows for sessionID.
+func saveLayoutState(stateDir string, sessionID string, st layoutState) error {
+	dir := filepath.Join(stateDir, sessionID)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(layoutPath(stateDir, sessionID), append(b, '\n'), 0o644)
+}
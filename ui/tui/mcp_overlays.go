@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"workbench/ui/tui/mcpclient"
+)
+
+// updateMCPServers drives the overlayMCPServers list: Up/Down moves the
+// highlighted server, Space toggles it enabled/disabled for the current
+// session (dispatchMCPAgent refuses a disabled server's tool calls).
+func (m appModel) updateMCPServers(k tea.KeyMsg) (tea.Model, tea.Cmd) {
+	servers := m.mcpServerStatuses()
+	if len(servers) == 0 {
+		return m, nil
+	}
+	if m.mcpServersIndex >= len(servers) {
+		m.mcpServersIndex = len(servers) - 1
+	}
+	switch k.Type {
+	case tea.KeyUp:
+		if m.mcpServersIndex > 0 {
+			m.mcpServersIndex--
+		}
+	case tea.KeyDown:
+		if m.mcpServersIndex < len(servers)-1 {
+			m.mcpServersIndex++
+		}
+	case tea.KeySpace:
+		name := servers[m.mcpServersIndex].Name
+		if m.mcpServerEnabled == nil {
+			m.mcpServerEnabled = map[string]bool{}
+		}
+		m.mcpServerEnabled[name] = !m.mcpServerEnabled[name]
+		m.systemAlert(alertInfo, "mcp.server.toggled", fmt.Sprintf("Server %q %s for this session", name, enabledDisabledLabel(m.mcpServerEnabled[name])), map[string]any{"server": name, "enabled": m.mcpServerEnabled[name]})
+	}
+	return m, nil
+}
+
+func enabledDisabledLabel(enabled bool) string {
+	if enabled {
+		return "enabled"
+	}
+	return "disabled"
+}
+
+// viewMCPServers renders every configured MCP server, its connection
+// status, and its per-session enabled/disabled toggle.
+func (m appModel) viewMCPServers() string {
+	servers := m.mcpServerStatuses()
+	lines := []string{
+		m.th.Accent.Render("//mcp  MCP SERVERS"),
+		m.th.Muted.Render("Esc: back    Space: toggle enabled for this session"),
+		"",
+	}
+	if len(servers) == 0 {
+		lines = append(lines, m.th.Muted.Render("(no servers configured - add one to "+mcpclient.DefaultConfigPath()+")"))
+		return m.th.OverlayBox.Render(strings.Join(lines, "\n"))
+	}
+	for i, s := range servers {
+		prefix := "  "
+		status := "✓ connected"
+		if !s.Connected {
+			status = "✗ " + s.Error
+		}
+		enabled := "on"
+		if !m.mcpServerEnabled[s.Name] {
+			enabled = "off"
+		}
+		row := fmt.Sprintf("%-20s %-4s %s (%d tools)", s.Name, enabled, status, len(s.Tools))
+		if i == m.mcpServersIndex {
+			prefix = m.th.Accent.Render("> ")
+			row = m.th.Accent.Render(row)
+		}
+		lines = append(lines, prefix+row)
+	}
+	return m.th.OverlayBox.Render(strings.Join(lines, "\n"))
+}
+
+// updateToolApprove drives overlayToolApprove: "y"/Enter runs the pending
+// tool call (mirroring dispatchMCPAgent's bypass-mode path), "n" discards
+// it. A stray approval with nothing pending (e.g. a delayed keystroke after
+// Esc already dismissed it) just closes the overlay.
+func (m appModel) updateToolApprove(k tea.KeyMsg) (tea.Model, tea.Cmd) {
+	pending := m.pendingToolCall
+	if pending == nil {
+		m = m.closeOverlay()
+		return m, nil
+	}
+	switch strings.ToLower(k.String()) {
+	case "y", "enter":
+		m.pendingToolCall = nil
+		m = m.closeOverlay()
+		m.chatInFlight = true
+		m.chatCorrelationID = pending.CorrelationID
+		m.chatActiveProfile = ""
+		m.chatStreamText = ""
+		return m, m.runMCPToolCall(pending.CorrelationID, pending.Server, pending.Tool, pending.Args)
+	case "n":
+		m.pendingToolCall = nil
+		m = m.closeOverlay()
+		m.systemAlert(alertInfo, "mcp.tool.denied", fmt.Sprintf("Denied tool call %s.%s", pending.Server, pending.Tool), map[string]any{"server": pending.Server, "tool": pending.Tool})
+	}
+	return m, nil
+}
+
+// viewToolApprove shows the pending server/tool/args for the user to
+// approve or deny before it runs.
+func (m appModel) viewToolApprove() string {
+	pending := m.pendingToolCall
+	if pending == nil {
+		return m.th.OverlayBox.Render(m.th.Muted.Render("(no pending tool call)"))
+	}
+	argsJSON, _ := json.Marshal(pending.Args)
+	lines := []string{
+		m.th.Accent.Render("APPROVE TOOL CALL"),
+		m.th.Muted.Render("Permission: " + m.permissionModeLabel()),
+		"",
+		fmt.Sprintf("server: %s", pending.Server),
+		fmt.Sprintf("tool:   %s", pending.Tool),
+		fmt.Sprintf("args:   %s", string(argsJSON)),
+		"",
+		m.th.Muted.Render("y/Enter: approve and run    n: deny    Esc: dismiss"),
+	}
+	return m.th.OverlayBox.Render(strings.Join(lines, "\n"))
+}
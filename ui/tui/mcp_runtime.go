@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"workbench/ui/tui/mcpclient"
+)
+
+// pendingMCPToolCall is a tool invocation parsed out of the user's input
+// while permissionMode requires approval: dispatchMCPAgent stashes it here
+// and opens overlayToolApprove instead of running it, and updateToolApprove
+// either runs it (via runMCPToolCall) or discards it.
+type pendingMCPToolCall struct {
+	CorrelationID string
+	Server        string
+	Tool          string
+	Args          map[string]any
+}
+
+// loadMCPServers reads ~/.workbench/mcp.json and connects to every server it
+// names, skipped entirely when the network is disabled (smoke/serve mode),
+// the same way hooks.NewBus skips its socket listener. Results
+// are also written to stateDir/registry/mcp.json in the shape
+// readMcpConnectedCount already expects, so the header's "N Connected" badge
+// reflects these real connections instead of whatever a prior process left
+// behind.
+func (m appModel) loadMCPServers() appModel {
+	m.mcpServerEnabled = map[string]bool{}
+	if m.cfg.disableNetwork {
+		return m
+	}
+	cfg, err := mcpclient.LoadConfig(mcpclient.DefaultConfigPath())
+	if err != nil {
+		m.systemAlert(alertWarn, "mcp.config.failed", "Failed to load MCP config", map[string]any{"error": err.Error()})
+		return m
+	}
+	if len(cfg.Servers) == 0 {
+		return m
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	mgr := mcpclient.NewManager(ctx, cfg)
+	m.mcpManager = mgr
+
+	connected := 0
+	for _, st := range mgr.Servers() {
+		m.mcpServerEnabled[st.Name] = true
+		if st.Connected {
+			connected++
+		} else {
+			m.systemAlert(alertWarn, "mcp.server.failed", fmt.Sprintf("MCP server %q failed to connect", st.Name), map[string]any{"server": st.Name, "error": st.Error})
+		}
+	}
+	writeMcpRegistry(m.cfg.stateDir, mgr.Servers())
+	m.mcpConnected = connected
+	m.cfg.mcpConnected = connected
+	return m
+}
+
+// writeMcpRegistry records each server's connection status to
+// stateDir/registry/mcp.json, the file readMcpConnectedCount already reads
+// for the header badge.
+func writeMcpRegistry(stateDir string, servers []mcpclient.ServerStatus) {
+	type entry struct {
+		LastHandshakeOk bool `json:"lastHandshakeOk"`
+	}
+	reg := struct {
+		Servers map[string]entry `json:"servers"`
+	}{Servers: map[string]entry{}}
+	for _, s := range servers {
+		reg.Servers[s.Name] = entry{LastHandshakeOk: s.Connected}
+	}
+	path := filepath.Join(stateDir, "registry", "mcp.json")
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return
+	}
+	b, err := json.MarshalIndent(reg, "", "  ")
+	if err != nil {
+		return
+	}
+	_ = os.WriteFile(path, b, 0o644)
+}
+
+// mcpServerStatuses returns the configured servers' statuses, or nil if no
+// Manager was ever built (network disabled, or mcp.json names no servers).
+func (m appModel) mcpServerStatuses() []mcpclient.ServerStatus {
+	if m.mcpManager == nil {
+		return nil
+	}
+	return m.mcpManager.Servers()
+}
+
+// parseMCPInvocation reads "@server.tool [json args]" out of a chat line -
+// the convention dispatchMCPAgent expects for the "mcp-agent" runtime, since
+// MCP itself has no chat/completion API of its own to route free-form text
+// through.
+func parseMCPInvocation(txt string) (server string, tool string, args map[string]any, err error) {
+	txt = strings.TrimSpace(txt)
+	if !strings.HasPrefix(txt, "@") {
+		return "", "", nil, fmt.Errorf(`expected "@server.tool [json args]", e.g. @filesystem.read_file {"path":"README.md"}`)
+	}
+	rest := txt[1:]
+	head := rest
+	argsRaw := ""
+	if idx := strings.IndexAny(rest, " \t"); idx >= 0 {
+		head = rest[:idx]
+		argsRaw = strings.TrimSpace(rest[idx+1:])
+	}
+	dot := strings.Index(head, ".")
+	if dot <= 0 || dot == len(head)-1 {
+		return "", "", nil, fmt.Errorf("expected \"server.tool\", got %q", head)
+	}
+	server = head[:dot]
+	tool = head[dot+1:]
+	args = map[string]any{}
+	if argsRaw != "" {
+		if err := json.Unmarshal([]byte(argsRaw), &args); err != nil {
+			return "", "", nil, fmt.Errorf("invalid JSON arguments: %w", err)
+		}
+	}
+	return server, tool, args, nil
+}
+
+// dispatchMCPAgent parses txt as an MCP tool invocation and either runs it
+// immediately (permissionMode "bypass") or stashes it as m.pendingToolCall
+// and opens overlayToolApprove for the user to approve/deny.
+func (m appModel) dispatchMCPAgent(cid string, txt string) (appModel, tea.Cmd) {
+	if m.mcpManager == nil {
+		m.systemAlert(alertError, "mcp.unavailable", "No MCP servers configured", map[string]any{"hint": "add a server to " + mcpclient.DefaultConfigPath() + " and restart, or check //mcp"})
+		return m, nil
+	}
+	server, tool, args, err := parseMCPInvocation(txt)
+	if err != nil {
+		m.systemAlert(alertWarn, "mcp.invocation.invalid", err.Error(), nil)
+		return m, nil
+	}
+	if enabled, ok := m.mcpServerEnabled[server]; ok && !enabled {
+		m.systemAlert(alertWarn, "mcp.server.disabled", fmt.Sprintf("Server %q is disabled for this session", server), map[string]any{"hint": "use //mcp to re-enable it"})
+		return m, nil
+	}
+
+	if strings.ToLower(strings.TrimSpace(m.permissionMode)) != "bypass" {
+		m.pendingToolCall = &pendingMCPToolCall{CorrelationID: cid, Server: server, Tool: tool, Args: args}
+		m = m.openOverlay(overlayToolApprove)
+		return m, nil
+	}
+
+	m.chatInFlight = true
+	m.chatCorrelationID = cid
+	m.chatActiveProfile = ""
+	m.chatStreamText = ""
+	return m, m.runMCPToolCall(cid, server, tool, args)
+}
+
+// runMCPToolCall invokes server.tool through mcpManager, surfacing it to
+// chat scrollback as a codexTurnEvent tool_call/tool_result pair written to
+// codexEventsPath - the same wire shape codex-cli's real tool_use/
+// step_finish events use, so consumeCodexEvents renders it as the same
+// collapsible chatToolCall block (see the tool_call/tool_result cases added
+// there) - before resolving with a chatReplyMsg carrying the tool's result.
+func (m appModel) runMCPToolCall(cid string, server string, tool string, args map[string]any) tea.Cmd {
+	mgr := m.mcpManager
+	eventsPath := m.codexEventsPath
+	argsJSON, _ := json.Marshal(args)
+	label := server + "." + tool
+	m.emitEvent("llm.request", "system", map[string]any{"provider": "mcp-agent", "server": server, "tool": tool}, cid, "")
+
+	return func() tea.Msg {
+		_ = appendCodexEvent(eventsPath, codexTurnEvent{
+			Version:       1,
+			Type:          "turn.event",
+			CorrelationID: cid,
+			At:            time.Now().UTC().Format(time.RFC3339Nano),
+			Kind:          "tool_call",
+			Tool:          label,
+			Message:       string(argsJSON),
+		})
+
+		ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+		defer cancel()
+		result, err := mgr.CallTool(ctx, server, tool, args)
+
+		_ = appendCodexEvent(eventsPath, codexTurnEvent{
+			Version:       1,
+			Type:          "turn.event",
+			CorrelationID: cid,
+			At:            time.Now().UTC().Format(time.RFC3339Nano),
+			Kind:          "tool_result",
+			Tool:          label,
+			Message:       result,
+		})
+
+		if err != nil {
+			return chatReplyMsg{CorrelationID: cid, Provider: "mcp-agent", Error: err.Error()}
+		}
+		return chatReplyMsg{CorrelationID: cid, Provider: "mcp-agent", Text: result}
+	}
+}
@@ -0,0 +1,487 @@
+// Package mcpclient is a minimal Model Context Protocol (MCP) client: it
+// speaks JSON-RPC 2.0 to servers declared in ~/.workbench/mcp.json, either by
+// spawning them as a stdio subprocess or by POSTing to an HTTP+SSE endpoint,
+// and exposes the handful of calls chat/tool-use wiring needs - listing a
+// server's tools/resources/prompts and invoking one tool. It deliberately
+// doesn't implement the rest of the MCP spec (sampling, roots, a fully
+// duplexed SSE stream): those aren't needed to drive a chat turn's tool
+// calls, the same scope reduction executor_http.go's consumeTurnSSE makes
+// for the codex-chat HTTP transport.
+package mcpclient
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Tool, Resource, and Prompt mirror the shapes MCP's tools/list,
+// resources/list, and prompts/list results carry.
+type Tool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	InputSchema json.RawMessage `json:"inputSchema,omitempty"`
+}
+
+type Resource struct {
+	URI         string `json:"uri"`
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
+	MimeType    string `json:"mimeType,omitempty"`
+}
+
+type Prompt struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+// ServerConfig is one entry in mcp.json: a stdio server names Command (plus
+// Args), an HTTP+SSE server names URL instead.
+type ServerConfig struct {
+	Name    string   `json:"name"`
+	Command string   `json:"command,omitempty"`
+	Args    []string `json:"args,omitempty"`
+	URL     string   `json:"url,omitempty"`
+}
+
+// Config is the parsed shape of mcp.json.
+type Config struct {
+	Servers []ServerConfig `json:"servers"`
+}
+
+// DefaultConfigPath returns ~/.workbench/mcp.json, falling back to
+// ./.workbench/mcp.json if the user's home directory can't be resolved.
+func DefaultConfigPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil || strings.TrimSpace(home) == "" {
+		return filepath.Join(".workbench", "mcp.json")
+	}
+	return filepath.Join(home, ".workbench", "mcp.json")
+}
+
+// LoadConfig reads path and parses it as Config. A missing file isn't an
+// error - it just means no MCP servers are configured yet - but a malformed
+// one is, so a typo surfaces instead of silently running with zero servers.
+func LoadConfig(path string) (Config, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return Config{}, nil
+		}
+		return Config{}, err
+	}
+	var cfg Config
+	if err := json.Unmarshal(raw, &cfg); err != nil {
+		return Config{}, fmt.Errorf("mcpclient: parse %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+type rpcRequest struct {
+	JSONRPC string `json:"jsonrpc"`
+	ID      int64  `json:"id"`
+	Method  string `json:"method"`
+	Params  any    `json:"params,omitempty"`
+}
+
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      int64           `json:"id"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Client is one connected MCP server, reachable over whichever transport its
+// ServerConfig named.
+type Client interface {
+	ListTools(ctx context.Context) ([]Tool, error)
+	ListResources(ctx context.Context) ([]Resource, error)
+	ListPrompts(ctx context.Context) ([]Prompt, error)
+	CallTool(ctx context.Context, tool string, args map[string]any) (string, error)
+	Close() error
+}
+
+// caller is the one JSON-RPC primitive both transports implement; the
+// listTools/listResources/listPrompts/callTool helpers below build the
+// tools/list, resources/list, prompts/list, and tools/call methods on top of
+// it once instead of duplicating them per transport.
+type caller interface {
+	call(ctx context.Context, method string, params any) (json.RawMessage, error)
+}
+
+func listTools(ctx context.Context, c caller) ([]Tool, error) {
+	raw, err := c.call(ctx, "tools/list", nil)
+	if err != nil {
+		return nil, err
+	}
+	var out struct {
+		Tools []Tool `json:"tools"`
+	}
+	if err := json.Unmarshal(raw, &out); err != nil {
+		return nil, err
+	}
+	return out.Tools, nil
+}
+
+func listResources(ctx context.Context, c caller) ([]Resource, error) {
+	raw, err := c.call(ctx, "resources/list", nil)
+	if err != nil {
+		return nil, err
+	}
+	var out struct {
+		Resources []Resource `json:"resources"`
+	}
+	if err := json.Unmarshal(raw, &out); err != nil {
+		return nil, err
+	}
+	return out.Resources, nil
+}
+
+func listPrompts(ctx context.Context, c caller) ([]Prompt, error) {
+	raw, err := c.call(ctx, "prompts/list", nil)
+	if err != nil {
+		return nil, err
+	}
+	var out struct {
+		Prompts []Prompt `json:"prompts"`
+	}
+	if err := json.Unmarshal(raw, &out); err != nil {
+		return nil, err
+	}
+	return out.Prompts, nil
+}
+
+func callTool(ctx context.Context, c caller, tool string, args map[string]any) (string, error) {
+	raw, err := c.call(ctx, "tools/call", map[string]any{"name": tool, "arguments": args})
+	if err != nil {
+		return "", err
+	}
+	var out struct {
+		Content []struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		} `json:"content"`
+		IsError bool `json:"isError"`
+	}
+	if err := json.Unmarshal(raw, &out); err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	for i, part := range out.Content {
+		if part.Type != "text" {
+			continue
+		}
+		if i > 0 && sb.Len() > 0 {
+			sb.WriteString("\n")
+		}
+		sb.WriteString(part.Text)
+	}
+	if out.IsError {
+		return sb.String(), fmt.Errorf("mcpclient: tool %q reported an error", tool)
+	}
+	return sb.String(), nil
+}
+
+// stdioClient speaks JSON-RPC 2.0 over a spawned child process's
+// stdin/stdout, one JSON object per line, the same framing stdioExecutor
+// uses for the codex bus. Requests are correlated to responses by id instead
+// of by correlation ID, since a server can field several concurrent calls
+// from the same client.
+type stdioClient struct {
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+
+	writeMu sync.Mutex
+
+	mu      sync.Mutex
+	nextID  int64
+	pending map[int64]chan rpcResponse
+}
+
+func dialStdio(sc ServerConfig) (*stdioClient, error) {
+	if strings.TrimSpace(sc.Command) == "" {
+		return nil, fmt.Errorf("mcpclient: server %q has no command", sc.Name)
+	}
+	cmd := exec.Command(sc.Command, sc.Args...)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	c := &stdioClient{cmd: cmd, stdin: stdin, pending: map[int64]chan rpcResponse{}}
+	go c.readLoop(stdout)
+	return c, nil
+}
+
+func (c *stdioClient) readLoop(stdout io.Reader) {
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var resp rpcResponse
+		if json.Unmarshal([]byte(line), &resp) != nil {
+			continue
+		}
+		c.mu.Lock()
+		ch, ok := c.pending[resp.ID]
+		if ok {
+			delete(c.pending, resp.ID)
+		}
+		c.mu.Unlock()
+		if ok {
+			ch <- resp
+		}
+	}
+}
+
+func (c *stdioClient) call(ctx context.Context, method string, params any) (json.RawMessage, error) {
+	c.mu.Lock()
+	c.nextID++
+	id := c.nextID
+	ch := make(chan rpcResponse, 1)
+	c.pending[id] = ch
+	c.mu.Unlock()
+
+	b, err := json.Marshal(rpcRequest{JSONRPC: "2.0", ID: id, Method: method, Params: params})
+	if err != nil {
+		return nil, err
+	}
+
+	c.writeMu.Lock()
+	_, err = c.stdin.Write(append(b, '\n'))
+	c.writeMu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	select {
+	case resp := <-ch:
+		if resp.Error != nil {
+			return nil, fmt.Errorf("mcpclient: %s: %s", method, resp.Error.Message)
+		}
+		return resp.Result, nil
+	case <-ctx.Done():
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+		return nil, ctx.Err()
+	}
+}
+
+func (c *stdioClient) ListTools(ctx context.Context) ([]Tool, error) { return listTools(ctx, c) }
+func (c *stdioClient) ListResources(ctx context.Context) ([]Resource, error) {
+	return listResources(ctx, c)
+}
+func (c *stdioClient) ListPrompts(ctx context.Context) ([]Prompt, error) { return listPrompts(ctx, c) }
+func (c *stdioClient) CallTool(ctx context.Context, tool string, args map[string]any) (string, error) {
+	return callTool(ctx, c, tool, args)
+}
+
+func (c *stdioClient) Close() error {
+	_ = c.stdin.Close()
+	if c.cmd != nil && c.cmd.Process != nil {
+		return c.cmd.Process.Kill()
+	}
+	return nil
+}
+
+// httpClient speaks JSON-RPC 2.0 by POSTing one request per call to a fixed
+// URL and reading one response back - either a plain JSON body, or a single
+// "data: <json>" SSE frame, whichever the server answers with. It does not
+// keep a long-lived SSE connection open for server-initiated notifications;
+// see the package doc for why that's out of scope here.
+type httpClient struct {
+	url    string
+	client *http.Client
+
+	mu     sync.Mutex
+	nextID int64
+}
+
+func dialHTTP(sc ServerConfig) (*httpClient, error) {
+	if strings.TrimSpace(sc.URL) == "" {
+		return nil, fmt.Errorf("mcpclient: server %q has no url", sc.Name)
+	}
+	return &httpClient{url: sc.URL, client: &http.Client{Timeout: 30 * time.Second}}, nil
+}
+
+func (c *httpClient) call(ctx context.Context, method string, params any) (json.RawMessage, error) {
+	c.mu.Lock()
+	c.nextID++
+	id := c.nextID
+	c.mu.Unlock()
+
+	b, err := json.Marshal(rpcRequest{JSONRPC: "2.0", ID: id, Method: method, Params: params})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+
+	httpResp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer httpResp.Body.Close()
+
+	resp, err := decodeRPCResponse(httpResp.Body, httpResp.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, err
+	}
+	if resp.Error != nil {
+		return nil, fmt.Errorf("mcpclient: %s: %s", method, resp.Error.Message)
+	}
+	return resp.Result, nil
+}
+
+// decodeRPCResponse reads body as either a bare JSON-RPC response or one SSE
+// "data:" frame wrapping one, enough to get a call's single result back.
+func decodeRPCResponse(body io.Reader, contentType string) (rpcResponse, error) {
+	raw, err := io.ReadAll(body)
+	if err != nil {
+		return rpcResponse{}, err
+	}
+	if strings.Contains(contentType, "text/event-stream") {
+		for _, line := range strings.Split(string(raw), "\n") {
+			line = strings.TrimSpace(line)
+			data, ok := strings.CutPrefix(line, "data:")
+			if !ok {
+				continue
+			}
+			raw = []byte(strings.TrimSpace(data))
+			break
+		}
+	}
+	var resp rpcResponse
+	if err := json.Unmarshal(raw, &resp); err != nil {
+		return rpcResponse{}, err
+	}
+	return resp, nil
+}
+
+func (c *httpClient) ListTools(ctx context.Context) ([]Tool, error) { return listTools(ctx, c) }
+func (c *httpClient) ListResources(ctx context.Context) ([]Resource, error) {
+	return listResources(ctx, c)
+}
+func (c *httpClient) ListPrompts(ctx context.Context) ([]Prompt, error) { return listPrompts(ctx, c) }
+func (c *httpClient) CallTool(ctx context.Context, tool string, args map[string]any) (string, error) {
+	return callTool(ctx, c, tool, args)
+}
+func (c *httpClient) Close() error { return nil }
+
+func dial(sc ServerConfig) (Client, error) {
+	if strings.TrimSpace(sc.URL) != "" {
+		return dialHTTP(sc)
+	}
+	if strings.TrimSpace(sc.Command) != "" {
+		return dialStdio(sc)
+	}
+	return nil, fmt.Errorf("mcpclient: server %q has neither command nor url", sc.Name)
+}
+
+// ServerStatus summarizes one configured server for the MCP servers overlay
+// and the header's "N Connected" badge.
+type ServerStatus struct {
+	Name      string
+	Connected bool
+	Error     string
+	Tools     []Tool
+}
+
+// Manager aggregates every server declared in a Config behind its name, so
+// callers route a tool call by server name without holding transport details
+// themselves.
+type Manager struct {
+	mu      sync.Mutex
+	clients map[string]Client
+	status  []ServerStatus
+}
+
+// NewManager connects to every server in cfg, best-effort: a server that
+// fails to start or answer tools/list is recorded in the returned Manager's
+// status with its error instead of aborting the rest, the same "one bad
+// entry doesn't block the others" handling the OAuth pool snapshot gives a
+// malformed profile.
+func NewManager(ctx context.Context, cfg Config) *Manager {
+	mgr := &Manager{clients: map[string]Client{}}
+	for _, sc := range cfg.Servers {
+		st := ServerStatus{Name: sc.Name}
+		client, err := dial(sc)
+		if err != nil {
+			st.Error = err.Error()
+			mgr.status = append(mgr.status, st)
+			continue
+		}
+		tools, err := client.ListTools(ctx)
+		if err != nil {
+			st.Error = err.Error()
+			_ = client.Close()
+			mgr.status = append(mgr.status, st)
+			continue
+		}
+		st.Connected = true
+		st.Tools = tools
+		mgr.clients[sc.Name] = client
+		mgr.status = append(mgr.status, st)
+	}
+	return mgr
+}
+
+// Servers returns each configured server's last-known connection status.
+func (m *Manager) Servers() []ServerStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]ServerStatus{}, m.status...)
+}
+
+// CallTool invokes tool on server with args, failing fast if server isn't
+// currently connected.
+func (m *Manager) CallTool(ctx context.Context, server string, tool string, args map[string]any) (string, error) {
+	m.mu.Lock()
+	client, ok := m.clients[server]
+	m.mu.Unlock()
+	if !ok {
+		return "", fmt.Errorf("mcpclient: server %q is not connected", server)
+	}
+	return client.CallTool(ctx, tool, args)
+}
+
+// Close shuts down every connected client.
+func (m *Manager) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, c := range m.clients {
+		_ = c.Close()
+	}
+	return nil
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
@@ -14,6 +15,15 @@ import (
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+
+	"workbench/ui/tui/bus"
+	"workbench/ui/tui/commands"
+	"workbench/ui/tui/controlapi"
+	"workbench/ui/tui/history"
+	"workbench/ui/tui/hooks"
+	"workbench/ui/tui/mcpclient"
+	"workbench/ui/tui/providers"
+	"workbench/ui/tui/ratelimit"
 )
 
 type screen int
@@ -68,6 +78,10 @@ const (
 	overlaySystemInfo
 	overlayProviderSelect
 	overlayRuntimeSelect
+	overlaySessionBrowser
+	overlayMCPServers
+	overlayToolApprove
+	overlaySnapshotBrowser
 )
 
 func (o overlay) String() string {
@@ -92,6 +106,14 @@ func (o overlay) String() string {
 		return "provider_select"
 	case overlayRuntimeSelect:
 		return "runtime_select"
+	case overlaySessionBrowser:
+		return "session_browser"
+	case overlayMCPServers:
+		return "mcp_servers"
+	case overlayToolApprove:
+		return "tool_approve"
+	case overlaySnapshotBrowser:
+		return "snapshot_browser"
 	default:
 		return "unknown"
 	}
@@ -114,11 +136,53 @@ type appConfig struct {
 	opencodeRequestsPath  string
 	opencodeResponsesPath string
 	opencodeEventsPath    string
+
+	// tuiHeightRows is the resolved --tui-height/WORKBENCH_TUI_HEIGHT row
+	// count (see height.go); 0 means workbench runs full-screen as before.
+	tuiHeightRows int
+
+	// eventsSocketPath overrides hookBus's Unix-domain-socket location (see
+	// --events-socket/--json in app.go); empty keeps the default
+	// stateDir/<sessionID>/hooks.sock.
+	eventsSocketPath string
+
+	// controlListen is the comma-separated unix:/tcp: address list the
+	// embedded JSON-RPC control API binds to (see --listen/WORKBENCH_BUS_LISTEN
+	// in app.go); empty leaves the control API disabled.
+	controlListen string
+
+	// tapePath is the --record destination every accepted busCommand is
+	// appended to as a JSONL tape (see replay.go); empty disables recording.
+	tapePath string
+
+	// replayRecords, replaySpeed, and replayUntil seed an in-progress replay
+	// loaded from --replay/--speed/--until in app.go; replayRecords is nil
+	// when no replay was requested.
+	replayRecords []tapeRecord
+	replaySpeed   float64
+	replayUntil   string
+
+	// otlpEndpoint, when non-empty, is an OTLP/HTTP logs endpoint (see
+	// --otlp-endpoint/WORKBENCH_OTLP_ENDPOINT in app.go) eventLogger mirrors
+	// every Append to via otlpEventSink, alongside its events.jsonl write.
+	otlpEndpoint string
 }
 
 type appModel struct {
 	cfg appConfig
 	th  theme
+	// themeName is the name loadTheme resolved th from (a builtinThemeSpecs
+	// key or a user themes/*.toml file's name), for the "/theme" command and
+	// summary.json to report the active theme.
+	themeName string
+
+	// summaryNodeID and summaryLamport are this process's identity and
+	// logical clock in summary.deltas.jsonl's CRDT (see summary_crdt.go):
+	// every delta writeSessionSummary appends is keyed by (summaryNodeID,
+	// summaryLamport) so two workbench processes attached to the same
+	// sessionID merge instead of clobbering each other's summary.json.
+	summaryNodeID  string
+	summaryLamport uint64
 
 	width  int
 	height int
@@ -154,6 +218,9 @@ type appModel struct {
 	runtimeSelectIndex int
 
 	authSelectIndex int
+	// authPrompt drives viewAuthSelect's "n" (new profile) flow; see
+	// auth_prompt.go.
+	authPrompt authPromptState
 
 	input string
 
@@ -162,33 +229,168 @@ type appModel struct {
 	chatMessages []chatMessage
 	chatInFlight bool
 	chatCancel context.CancelFunc
+	// chatCancelPending is set while a cancellation has been requested but
+	// the backend hasn't yet confirmed the turn stopped (cleared alongside
+	// chatInFlight once its reply, Cancelled or not, arrives). viewStatusBar
+	// shows a "⏸ cancelling…" hint while it's true.
+	chatCancelPending bool
 	chatCorrelationID string
 	chatActiveProfile string
 	chatScrollOffset int // lines from bottom; 0 = follow
 	alerts    []systemAlert
 
+	// Scrollback search (Ctrl-R, see chat_search.go): chatScrollback is the
+	// on-disk ring buffer appendChatRoleLine mirrors every line into.
+	// openChatSearch swaps chatRoleLines for that persisted scrollback (so
+	// a query can reach turns chatRoleLinesMax already trimmed) and saves
+	// the original here to restore on close.
+	chatScrollback           *scrollback
+	chatSearchActive         bool
+	chatSearchCommitted      bool
+	chatSearchQuery          string
+	chatSearchMatches        []int
+	chatSearchMatchIndex     int
+	chatSearchSavedRoleLines []chatRoleLine
+	chatSearchSavedOffset    int
+
+	// Resizable chat/footer split (chunk5-4, see layout.go): footerExtraRows
+	// shrinks (negative, down to "1 input line only") or grows (positive,
+	// up to half of chatHeight) the footer via Alt+Up/Alt+Down or a mouse
+	// drag on the boundary row. footerBoundaryHovered/footerDragging/
+	// footerDragLastY track an in-progress drag between MouseMsg events.
+	footerExtraRows       int
+	footerBoundaryHovered bool
+	footerDragging        bool
+	footerDragLastY       int
+
+	// Streaming metrics for the in-flight left-pane turn: chatMetricsStartedAt
+	// is set once in sendChat, and chatMetricsTokens accumulates an estimated
+	// token count off each delta event so viewStatusBar can show a live
+	// tokens/s rate. emitChatMetrics reports the final tally as an
+	// llm.metrics event and zeroes chatMetricsStartedAt for the next turn.
+	chatMetricsStartedAt time.Time
+	chatMetricsTokens    int
+
+	// Tool calls made during the in-flight turn are collapsed into a single
+	// block (see chatToolCall) instead of flooding chatRoleLines with one
+	// "Codex/<tool>: ..." line per event. chatActiveToolCallID is the call a
+	// tool_use event opened that hasn't yet seen its step_finish; expanded
+	// state is keyed by call ID (not transcript position) so it survives
+	// trimChatRoleLines dropping old entries from the front.
+	chatToolCalls        map[string]*chatToolCall
+	chatToolCallExpanded map[string]bool
+	chatActiveToolCallID string
+	chatToolCallSeq      int
+	chatToolCallFocus    string
+
+	// Split-screen "mode C": splitRuntime, when non-empty, names a second
+	// runtime (or, for codex-chat, a second OAuth profile) run in parallel
+	// with selectedRuntime against the same input line. The right pane
+	// mirrors every chat* field above with its own independent state so a
+	// 429/backoff on one side never blocks the other. splitFocus picks
+	// which pane PgUp/PgDn/Home/End scroll ("" or "left" = left pane).
+	splitRuntime string
+	splitFocus   string
+
+	chatRoleLinesRight     []chatRoleLine
+	chatMessagesRight      []chatMessage
+	chatInFlightRight      bool
+	chatCancelRight        context.CancelFunc
+	chatCorrelationIDRight string
+	chatActiveProfileRight string
+	chatStreamTextRight    string
+	chatScrollOffsetRight  int
+
 	recentCommands []string
 
+	// Session browser overlay (overlaySessionBrowser): sessionBrowserSessions
+	// is loaded once when the overlay opens (listSessions is a directory scan
+	// plus a WAL replay per session, too heavy to redo on every keystroke),
+	// then filteredSessionSummaries re-ranks that cached slice against
+	// sessionBrowserQuery on each render.
+	sessionBrowserSessions []sessionSummary
+	sessionBrowserQuery    string
+	sessionBrowserIndex    int
+
+	// originSnapshotHash is set by forkSession to the snapshot this session
+	// branched from, so a later snapshotSession call of this session chains
+	// back to it as ParentHash; empty for a session that was never forked.
+	originSnapshotHash string
+
+	// Snapshot browser overlay (overlaySnapshotBrowser): snapshotBrowserItems
+	// is loaded once when the overlay opens (listSnapshots scans
+	// stateDir/snapshots). markedHash holds the first snapshot picked with
+	// 'd'; diffLines holds the rendered diff once a second snapshot is
+	// picked, and is cleared to go back to the list.
+	snapshotBrowserItems     []snapshotSummary
+	snapshotBrowserIndex     int
+	snapshotBrowserMarkedHash string
+	snapshotBrowserDiffLines []string
+
+	// MCP tool-use runtime ("mcp-agent"): mcpManager dials every server in
+	// mcp.json once at startup, mcpServerEnabled gates CallTool per server
+	// for the current session (the overlayMCPServers overlay toggles it),
+	// and pendingToolCall holds a call parsed from the user's input while
+	// permissionMode != "bypass" is waiting on overlayToolApprove.
+	mcpManager       *mcpclient.Manager
+	mcpServerEnabled map[string]bool
+	mcpServersIndex  int
+	pendingToolCall  *pendingMCPToolCall
+
+	// providerRegistry holds the provider/runtime metadata that used to be
+	// hard-coded switch statements (providerOptions, getCompatibility,
+	// defaultRuntimeForProvider): which runtimes each provider drives
+	// natively, its default runtime, and its auth requirements. Populated
+	// once in newAppModel, optionally layered with stateDir/providers.json.
+	providerRegistry *providers.Registry
+	// runtimeHandlers is the registry.Lookup(runtime).Handle(...) table
+	// dispatchChatRuntime uses in place of its old if/else chain; see
+	// registerRuntimeHandlers. Built once in newAppModel since the bindings
+	// are stateless (every per-call value comes from the appModel and Turn
+	// arguments Handle receives).
+	runtimeHandlers *providers.Handlers[appModel]
+
 	lastOAuthProfile string
 	oauthFlashUntil time.Time
 	oauthPool       oauthPoolSnapshot
+	// profileCooldowns tracks, per OAuth profile, the time its last 429/5xx
+	// reply's retry-after expires. It's an in-memory fast path consulted
+	// alongside the pool snapshot's persisted RateLimitedUntilMs so
+	// pickOAuthProfile and viewAuthSelect don't have to wait on the next
+	// snapshot refresh to stop offering a profile that just got rate
+	// limited. An entry stops counting once its time has passed but is not
+	// removed; "//auth cooldown clear" empties the whole map.
+	profileCooldowns map[string]time.Time
 
 	usageByProfile          map[string]*usageData
 	usageFetchInFlight      map[string]bool
 	usageLastCacheNotified  map[string]int64
 	usageLastErrorNotified  map[string]time.Time
 
-	events *eventLogger
+	events          *eventLogger
+	audit           *auditLogger
+	hookBus         *hooks.Bus
+	controlAPI      *controlapi.Server
+	historyWAL      *history.WAL
+	oauthLimiter    *ratelimit.Limiter
+	chatRenderCache *chatRenderCache
+
+	// tapePath/tapeStartedAt back --record (see replay.go); tapePath empty
+	// disables recording. replay is non-nil only when workbench was started
+	// with --replay.
+	tapePath      string
+	tapeStartedAt time.Time
+	replay        *replayState
 
 	now                 time.Time
 	slashPending        bool
 	slashPendingUntil   time.Time
 	commandPaletteNamespace string // "/" or "//"
 
-	commandBusPath   string
-	commandBusOffset int64
-	actionSource     string // tui|cli
-	quitRequested    bool
+	commandBusPath     string
+	commandBusConsumer *bus.Consumer
+	actionSource       string // tui|cli
+	quitRequested      bool
 
 	codexRequestsPath  string
 	codexResponsesPath string
@@ -205,6 +407,13 @@ type appModel struct {
 	opencodeExecutorReady    bool
 
 	permissionMode string // plan|bypass
+	// bypassAllowed gates setPermissionMode's transition to "bypass": true
+	// for a local run (there's no ACL to consult), or for an SSH session
+	// whose connecting key serve.acl.json actually grants bypass (see
+	// sshAllowsBypass). Without this, a connection that merely started in
+	// "plan" could still reach "bypass" via Shift+Tab or //permission_mode,
+	// since both only ever checked serve.acl.json for the starting mode.
+	bypassAllowed bool
 	thoughtStream bool
 	chatStreamText string
 
@@ -215,6 +424,8 @@ type appModel struct {
 	systemInFlight bool
 	systemCorrelationID string
 	systemLastResult *systemResponse
+
+	cmdRegistry *commands.Registry[appModel]
 }
 
 func (m appModel) chatRoleLinesMax() int {
@@ -236,6 +447,18 @@ func (m appModel) chatRoleLinesMax() int {
 	return max
 }
 
+// appendChatRoleLine appends role to the left pane's transcript and mirrors
+// it into the on-disk scrollback ring buffer (see scrollback.go), so a line
+// trimChatRoleLines later drops from memory is still reachable by Ctrl-R
+// search. Call sites that replay from history on open/resume/rewind go
+// straight through chatRoleLinesFromHistory instead, since those lines are
+// already in scrollback.jsonl from the run that originally appended them.
+func (m appModel) appendChatRoleLine(role chatRoleLine) appModel {
+	m.chatRoleLines = append(m.chatRoleLines, role)
+	m.chatScrollback.Append(role.Role, role.Text)
+	return m
+}
+
 func (m appModel) trimChatRoleLines() appModel {
 	max := m.chatRoleLinesMax()
 	if len(m.chatRoleLines) > max {
@@ -245,9 +468,14 @@ func (m appModel) trimChatRoleLines() appModel {
 }
 
 func newAppModel(cfg appConfig) appModel {
+	th, themeName, err := loadTheme("")
+	if err != nil {
+		th, themeName = defaultTheme(), "default-dark"
+	}
 	m := appModel{
 		cfg:          cfg,
-		th:           defaultTheme(),
+		th:           th,
+		themeName:    themeName,
 		sessionID:    cfg.sessionID,
 		mcpConnected: cfg.mcpConnected,
 		screens:      []screen{screenLauncher},
@@ -261,6 +489,8 @@ func newAppModel(cfg appConfig) appModel {
 		alerts:       []systemAlert{},
 		recentCommands: []string{},
 		events:       newEventLogger(cfg.stateDir, cfg.sessionID),
+		audit:        newAuditLogger(cfg.stateDir, cfg.sessionID),
+		hookBus:      hooks.NewBusAt(cfg.stateDir, cfg.sessionID, cfg.eventsSocketPath, cfg.disableNetwork),
 		commandPaletteNamespace: "/",
 		commandBusPath: cfg.commandsPath,
 		actionSource:   "tui",
@@ -268,6 +498,10 @@ func newAppModel(cfg appConfig) appModel {
 		usageFetchInFlight: map[string]bool{},
 		usageLastCacheNotified: map[string]int64{},
 		usageLastErrorNotified: map[string]time.Time{},
+		profileCooldowns:   map[string]time.Time{},
+		chatRenderCache:        newChatRenderCache(),
+		chatToolCalls:          map[string]*chatToolCall{},
+		chatToolCallExpanded:   map[string]bool{},
 
 		codexRequestsPath:  cfg.codexRequestsPath,
 		codexResponsesPath: cfg.codexResponsesPath,
@@ -281,16 +515,83 @@ func newAppModel(cfg appConfig) appModel {
 		opencodeEventsPath:    cfg.opencodeEventsPath,
 
 		permissionMode: "plan",
+		bypassAllowed: true,
 		thoughtStream: thoughtStreamEnabled(),
+		cmdRegistry:   buildCommandRegistry(),
+
+		summaryNodeID: newCorrelationID(),
+
+		tapePath: strings.TrimSpace(cfg.tapePath),
 	}
-	m.commandBusOffset = initCommandBus(cfg.commandsPath)
+	m = mergeSessionSummary(m)
+	if m.tapePath != "" {
+		m.tapeStartedAt = time.Now()
+	}
+	if len(cfg.replayRecords) > 0 {
+		m.replay = newReplayState(cfg.replayRecords, cfg.replaySpeed, cfg.replayUntil)
+	}
+	if strings.TrimSpace(cfg.otlpEndpoint) != "" {
+		m.events.SetSink(newOTLPEventSink(cfg.otlpEndpoint))
+	}
+	consumer, err := bus.OpenConsumer(cfg.commandsPath, "tui")
+	if err != nil {
+		m.systemAlert(alertWarn, "command_bus.open.failed", "Failed to open command bus consumer", map[string]any{"error": err.Error()})
+	}
+	m.commandBusConsumer = consumer
+	m.controlAPI = startControlAPI(cfg.stateDir, cfg.controlListen, cfg.disableNetwork, m.hookBus)
 	m.codexResponsesOffset, m.codexEventsOffset = initCodexBus(cfg.codexResponsesPath, cfg.codexRequestsPath, cfg.codexEventsPath)
 	m.opencodeResponsesOffset, m.opencodeEventsOffset = initOpencodeBus(cfg.opencodeResponsesPath, cfg.opencodeRequestsPath, cfg.opencodeEventsPath)
 	m.systemResponsesOffset = initSystemBus(cfg.systemResponsesPath, cfg.systemRequestsPath)
+
+	m.chatScrollback = openScrollback(cfg.stateDir, cfg.sessionID)
+	m.footerExtraRows = loadLayoutState(cfg.stateDir, cfg.sessionID).FooterExtraRows
+
+	wal, state, err := history.Open(cfg.stateDir, cfg.sessionID)
+	if err != nil {
+		m.systemAlert(alertWarn, "history.open.failed", "Failed to open history WAL", map[string]any{"error": err.Error()})
+	} else {
+		m.historyWAL = wal
+		m.chatMessages = chatMessagesFromHistory(state.Messages)
+		m.chatRoleLines = chatRoleLinesFromHistory(state.RoleLines)
+		m = m.trimChatRoleLines()
+		if state.InFlightCorrelationID != "" {
+			m.systemAlert(alertWarn, "history.resumed.incomplete", "Resumed a session with an unfinished turn; it was not replayed", map[string]any{"correlationId": state.InFlightCorrelationID})
+		}
+	}
+
+	limiter, err := ratelimit.Open(cfg.stateDir)
+	if err != nil {
+		m.systemAlert(alertWarn, "ratelimit.open.failed", "Failed to open OAuth rate limiter state", map[string]any{"error": err.Error()})
+	}
+	m.oauthLimiter = limiter
+
+	m.providerRegistry = providers.NewRegistry()
+	if err := m.providerRegistry.LoadOverrides(cfg.stateDir); err != nil {
+		m.systemAlert(alertWarn, "providers.overrides.failed", "Failed to load provider overrides", map[string]any{"error": err.Error()})
+	}
+	m.runtimeHandlers = registerRuntimeHandlers()
+
+	m = m.loadMCPServers()
 	m.systemAlert(alertInfo, "workbench.started", "Workbench shell started", nil)
 	return m
 }
 
+func chatMessagesFromHistory(msgs []history.Message) []chatMessage {
+	out := make([]chatMessage, 0, len(msgs))
+	for _, msg := range msgs {
+		out = append(out, chatMessage{Role: msg.Role, Content: msg.Content})
+	}
+	return out
+}
+
+func chatRoleLinesFromHistory(lines []history.RoleLine) []chatRoleLine {
+	out := make([]chatRoleLine, 0, len(lines))
+	for _, l := range lines {
+		out = append(out, chatRoleLine{Role: l.Role, Text: l.Text})
+	}
+	return out
+}
+
 func (m appModel) startNewSession() appModel {
 	id, err := createNewSessionID(m.cfg.stateDir)
 	if err != nil {
@@ -300,6 +601,7 @@ func (m appModel) startNewSession() appModel {
 	if err := setCurrentSessionID(m.cfg.stateDir, id); err != nil {
 		m.systemAlert(alertWarn, "session.current.failed", "Failed to update current session pointer", map[string]any{"error": err.Error()})
 	}
+	pruneOldSessions(m.cfg.stateDir, id)
 
 	m.sessionID = id
 	m.cfg.sessionID = id
@@ -327,7 +629,14 @@ func (m appModel) startNewSession() appModel {
 	m.opencodeResponsesPath = m.cfg.opencodeResponsesPath
 	m.opencodeEventsPath = m.cfg.opencodeEventsPath
 
-	m.commandBusOffset = initCommandBus(m.commandBusPath)
+	if m.commandBusConsumer != nil {
+		_ = m.commandBusConsumer.Close()
+	}
+	consumer, err := bus.OpenConsumer(m.commandBusPath, "tui")
+	if err != nil {
+		m.systemAlert(alertWarn, "command_bus.open.failed", "Failed to open command bus consumer", map[string]any{"error": err.Error()})
+	}
+	m.commandBusConsumer = consumer
 	m.codexResponsesOffset, m.codexEventsOffset = initCodexBus(m.codexResponsesPath, m.codexRequestsPath, m.codexEventsPath)
 	m.opencodeResponsesOffset, m.opencodeEventsOffset = initOpencodeBus(m.opencodeResponsesPath, m.opencodeRequestsPath, m.opencodeEventsPath)
 	m.systemResponsesOffset = initSystemBus(m.systemResponsesPath, m.systemRequestsPath)
@@ -347,17 +656,45 @@ func (m appModel) startNewSession() appModel {
 	m.chatRoleLines = []chatRoleLine{}
 	m.chatMessages = []chatMessage{}
 	m.alerts = []systemAlert{}
+	m.chatToolCalls = map[string]*chatToolCall{}
+	m.chatToolCallExpanded = map[string]bool{}
+	m.chatActiveToolCallID = ""
+	m.chatToolCallFocus = ""
 	m.recentCommands = []string{}
 	m.systemInFlight = false
 	m.systemCorrelationID = ""
 	m.systemLastResult = nil
 
+	m.events.Close()
 	m.events = newEventLogger(m.cfg.stateDir, id)
+	if strings.TrimSpace(m.cfg.otlpEndpoint) != "" {
+		m.events.SetSink(newOTLPEventSink(m.cfg.otlpEndpoint))
+	}
+	m.audit = newAuditLogger(m.cfg.stateDir, id)
+	if m.hookBus != nil {
+		m.hookBus.Close()
+	}
+	m.hookBus = hooks.NewBusAt(m.cfg.stateDir, id, m.cfg.eventsSocketPath, m.cfg.disableNetwork)
+	subscribeControlAPI(m.hookBus, m.controlAPI)
+
+	_ = m.historyWAL.Close()
+	wal, _, err := history.Open(m.cfg.stateDir, id)
+	if err != nil {
+		m.systemAlert(alertWarn, "history.open.failed", "Failed to open history WAL", map[string]any{"error": err.Error()})
+	}
+	m.historyWAL = wal
+	m.chatScrollback = openScrollback(m.cfg.stateDir, id)
+	m.footerExtraRows = loadLayoutState(m.cfg.stateDir, id).FooterExtraRows
+
 	m.systemAlert(alertInfo, "session.new", "New session started", map[string]any{"sessionId": id})
 	return m
 }
 
 func (m appModel) Init() tea.Cmd {
+	if m.cfg.tuiHeightRows > 0 {
+		w, _ := terminalSize()
+		return tea.Batch(tickCmd(), heightModeInitCmd(w, m.cfg.tuiHeightRows))
+	}
 	return tickCmd()
 }
 
@@ -366,103 +703,27 @@ func (m appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case tea.WindowSizeMsg:
 		m.width = t.Width
 		m.height = t.Height
-		return m, nil
-	case chatReplyMsg:
-		if m.chatCorrelationID != "" && t.CorrelationID != "" && t.CorrelationID != m.chatCorrelationID {
-			return m, nil
+		if m.cfg.tuiHeightRows > 0 && m.height > m.cfg.tuiHeightRows {
+			m.height = m.cfg.tuiHeightRows
 		}
-		m.chatInFlight = false
-		m.chatCorrelationID = ""
-		m.chatActiveProfile = ""
-		m.chatStreamText = ""
-		if m.chatCancel != nil {
-			m.chatCancel()
-			m.chatCancel = nil
-		}
-		if t.Cancelled {
-			m.systemAlert(alertInfo, "chat.cancelled", "Chat request cancelled", nil)
-			return m, nil
+		if m.chatRenderCache != nil {
+			m.chatRenderCache.reset()
 		}
-		if strings.TrimSpace(t.Text) != "" {
-			if m.chatScrollOffset > 0 {
-				m.chatScrollOffset += m.chatWrappedLineCount("assistant", t.Text)
-			}
-			m.chatRoleLines = append(m.chatRoleLines, chatRoleLine{Role: "assistant", Text: strings.TrimRight(t.Text, "\n")})
-			m = m.trimChatRoleLines()
-			m.chatMessages = append(m.chatMessages, chatMessage{Role: "assistant", Content: t.Text})
-			m.emitEvent("llm.response", "system", map[string]any{"provider": t.Provider, "profile": t.Profile, "status": t.Status}, t.CorrelationID, "")
-			m.emitEvent("chat.reply", "system", map[string]any{"provider": t.Provider, "text": t.Text}, t.CorrelationID, "")
-			return m, nil
-		}
-		if strings.TrimSpace(t.Error) != "" {
-			if t.Status == 429 && t.Provider == "openai-oauth-codex" && t.Attempt == 0 {
-				retryMs := t.RetryAfterMs
-				if retryMs <= 0 {
-					retryMs = 10_000
-				}
-				if err := setOAuthProfileRateLimitedUntil(m.cfg.stateDir, t.Profile, time.Now().Add(time.Duration(retryMs)*time.Millisecond).UnixMilli()); err == nil {
-					// Choose next candidate deterministically (best-effort).
-					if snap, ok := readOAuthPoolSnapshot(m.cfg.stateDir, time.Now()); ok {
-						next, ok2 := firstNonLimitedExcept(snap.Ranked, t.Profile)
-						if ok2 {
-							_ = setOAuthPoolLastUsedProfile(m.cfg.stateDir, next.Profile)
-							m.oauthFlashUntil = time.Now().Add(1 * time.Second)
-							m.lastOAuthProfile = next.Email
-							m.systemAlert(alertWarn, "auth.swap", fmt.Sprintf("Swapped OAuth Account -> %s (reason=rate_limit)", next.Email), map[string]any{"fromProfile": t.Profile, "toProfile": next.Profile})
-							m.emitEvent("auth.swap", "system", map[string]any{
-								"from":    t.Profile,
-								"to":      next.Email,
-								"reason":  "rate_limit",
-								"ranking": snap.Ranked,
-							}, t.CorrelationID, "")
-
-							// Retry once with the same messages.
-							if !m.cfg.disableNetwork {
-								m.chatInFlight = true
-								retryCID := newCorrelationID()
-								m.chatCorrelationID = retryCID
-								m.chatActiveProfile = next.Profile
-								ctx, cancel := context.WithCancel(context.Background())
-								m.chatCancel = cancel
-
-								endpoint := strings.TrimSpace(snap.CodexEndpoint)
-								model := codexModelForSelection(strings.TrimSpace(m.selectedModel))
-								instructions := strings.TrimSpace(os.Getenv("WORKBENCH_SYSTEM_PROMPT"))
-								msgs := append([]chatMessage{}, m.chatMessages...)
-								profileCopy := next
-
-								m.emitEvent("llm.request", "system", map[string]any{"provider": "openai-oauth-codex", "model": model, "profile": profileCopy.Profile, "retry": true}, retryCID, t.CorrelationID)
-								return m, func() tea.Msg {
-									text, statusErr, err := codexChatStream(ctx, endpoint, model, profileCopy.accessToken, profileCopy.accountID, instructions, msgs, func(delta string) {
-										if strings.TrimSpace(delta) == "" {
-											return
-										}
-										_ = appendCodexEvent(m.codexEventsPath, codexTurnEvent{
-											Version:       1,
-											Type:          "turn.event",
-											CorrelationID: retryCID,
-											At:            time.Now().UTC().Format(time.RFC3339Nano),
-											Kind:          "delta",
-											Message:       delta,
-										})
-									})
-									if err != nil {
-										cancelled := errorsIsContextCanceled(err)
-										return chatReplyMsg{CorrelationID: retryCID, Provider: "openai-oauth-codex", Profile: profileCopy.Profile, Error: err.Error(), Cancelled: cancelled, Attempt: 1}
-									}
-									if statusErr != nil {
-										return chatReplyMsg{CorrelationID: retryCID, Provider: "openai-oauth-codex", Profile: profileCopy.Profile, Error: statusErr.Error(), Status: statusErr.Status, RetryAfterMs: statusErr.RetryAfterMs, Attempt: 1}
-									}
-									return chatReplyMsg{CorrelationID: retryCID, Provider: "openai-oauth-codex", Profile: profileCopy.Profile, Text: text, Attempt: 1}
-								}
-							}
-						}
-					}
-				}
-			}
-			m.systemAlert(alertError, "chat.failed", "Chat request failed", map[string]any{"provider": t.Provider, "error": t.Error, "status": t.Status})
+		return m, nil
+	case tea.MouseMsg:
+		if m.currentScreen() == screenCockpit && m.currentOverlay() == overlayNone && strings.TrimSpace(m.splitRuntime) == "" {
+			m = m.handleFooterMouse(t)
 		}
 		return m, nil
+	case chatReplyMsg:
+		var cmd tea.Cmd
+		m, cmd = m.handleChatReply(t)
+		return m, cmd
+	case themeChangedMsg:
+		m.th = t.Theme
+		m.themeName = t.Name
+		m.systemAlert(alertInfo, "theme.changed", fmt.Sprintf("Theme changed (%s)", t.Name), map[string]any{"theme": t.Name})
+		return m, nil
 	case usageFetchedMsg:
 		m.usageFetchInFlight[t.Profile] = false
 		if t.Data != nil {
@@ -486,13 +747,11 @@ func (m appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case tea.KeyMsg:
 		// Global permission-mode toggle (cockpit only): Shift+Tab.
 		if t.Type == tea.KeyShiftTab && m.currentOverlay() == overlayNone && m.currentScreen() == screenCockpit {
+			next := "bypass"
 			if m.permissionMode == "bypass" {
-				m.permissionMode = "plan"
-			} else {
-				m.permissionMode = "bypass"
+				next = "plan"
 			}
-			m.systemAlert(alertInfo, "permission_mode.toggled", "Permission mode: "+m.permissionModeLabel(), map[string]any{"permissionMode": m.permissionMode})
-			m.emitEvent("permission_mode.toggled", m.actionSource, map[string]any{"permissionMode": m.permissionMode}, "", "")
+			m = m.setPermissionMode(next)
 			return m, nil
 		}
 		if t.String() == "esc" {
@@ -502,6 +761,9 @@ func (m appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, tea.Quit
 		}
 		if t.Type == tea.KeyCtrlC {
+			if m.currentScreen() == screenCockpit && m.chatInFlight {
+				return m.submitCancelTurn()
+			}
 			return m, tea.Quit
 		}
 
@@ -524,6 +786,14 @@ func (m appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m.updateProviderSelect(t)
 		case overlayRuntimeSelect:
 			return m.updateRuntimeSelect(t)
+		case overlaySessionBrowser:
+			return m.updateSessionBrowser(t)
+		case overlayMCPServers:
+			return m.updateMCPServers(t)
+		case overlayToolApprove:
+			return m.updateToolApprove(t)
+		case overlaySnapshotBrowser:
+			return m.updateSnapshotBrowser(t)
 		default:
 			// fallthrough to screen
 		}
@@ -552,16 +822,53 @@ func (m appModel) permissionModeLabel() string {
 	}
 }
 
-func firstNonLimitedExcept(ranked []oauthPoolProfile, exceptProfile string) (oauthPoolProfile, bool) {
+// pickOAuthProfile filters ranked down to non-LIMITED profiles other than
+// exceptProfile, in ranked order, then asks oauthLimiter for whichever of
+// those has its circuit breaker closed and the most tokens available. If
+// the limiter rejects every candidate (all breakers open) or isn't
+// available yet, it falls back to ranked's own best-first order.
+func (m appModel) pickOAuthProfile(ranked []oauthPoolProfile, exceptProfile string) (oauthPoolProfile, bool) {
+	names := make([]string, 0, len(ranked))
+	byName := make(map[string]oauthPoolProfile, len(ranked))
 	for _, p := range ranked {
-		if p.Profile == "" || p.Profile == exceptProfile {
+		if p.Profile == "" || p.Profile == exceptProfile || p.Status == "LIMITED" {
 			continue
 		}
-		if p.Status != "LIMITED" {
-			return p, true
+		names = append(names, p.Profile)
+		byName[p.Profile] = p
+	}
+	if len(names) == 0 {
+		return oauthPoolProfile{}, false
+	}
+	if m.oauthLimiter != nil {
+		if picked, ok := m.oauthLimiter.Pick(names, m.now); ok {
+			return byName[picked], true
+		}
+	}
+	return byName[names[0]], true
+}
+
+// pickNonCoolingOAuthProfile wraps pickOAuthProfile with the in-memory
+// profileCooldowns map, so a profile that just 429'd/5xx'd this session
+// isn't handed right back out before its cooldown (or, failing that, the
+// persisted RateLimitedUntilMs the pool snapshot was built from) expires.
+// Falls back to pickOAuthProfile's own candidate if every profile is
+// cooling down - better to retry a cooling profile than fail the turn
+// outright.
+func (m appModel) pickNonCoolingOAuthProfile(ranked []oauthPoolProfile, exceptProfile string) (oauthPoolProfile, bool) {
+	filtered := make([]oauthPoolProfile, 0, len(ranked))
+	for _, p := range ranked {
+		if until, ok := m.profileCooldowns[p.Profile]; ok && m.now.Before(until) {
+			continue
 		}
+		filtered = append(filtered, p)
 	}
-	return oauthPoolProfile{}, false
+	if len(filtered) > 0 {
+		if picked, ok := m.pickOAuthProfile(filtered, exceptProfile); ok {
+			return picked, true
+		}
+	}
+	return m.pickOAuthProfile(ranked, exceptProfile)
 }
 
 func (m appModel) activeOAuthProfile() (oauthPoolProfile, bool) {
@@ -569,8 +876,10 @@ func (m appModel) activeOAuthProfile() (oauthPoolProfile, bool) {
 		return oauthPoolProfile{}, false
 	}
 	active := strings.TrimSpace(m.oauthPool.ActiveProfile)
-	if active == "" && len(m.oauthPool.Ranked) > 0 {
-		active = m.oauthPool.Ranked[0].Profile
+	if active == "" {
+		if p, ok := m.pickOAuthProfile(m.oauthPool.Ranked, ""); ok {
+			return p, true
+		}
 	}
 	for _, p := range m.oauthPool.Profiles {
 		if p.Profile == active {
@@ -591,6 +900,210 @@ func errorsIsContextCanceled(err error) bool {
 	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
 }
 
+// observeOAuthTurn feeds one completed codex-chat turn's outcome into
+// oauthLimiter so its token bucket and circuit breaker reflect real
+// traffic rather than just the pool's cached rateLimitedUntilMs, emitting
+// auth.breaker.open/close whenever Observe reports the breaker tripped or
+// recovered.
+func (m appModel) observeOAuthTurn(profile string, status int, retryAfterMs int64, side string) appModel {
+	if m.oauthLimiter == nil || strings.TrimSpace(profile) == "" {
+		return m
+	}
+	tr := m.oauthLimiter.Observe(profile, status, retryAfterMs, m.now)
+	if tr.Opened {
+		m.systemAlert(alertWarn, "auth.breaker.open", fmt.Sprintf("OAuth profile %s tripped its rate-limit breaker", profile), map[string]any{"profile": profile, "status": status, "side": sideLabel(side)})
+		m.emitEvent("auth.breaker.open", "system", map[string]any{"profile": profile, "status": status, "side": sideLabel(side)}, "", "")
+	} else if tr.Closed {
+		m.systemAlert(alertInfo, "auth.breaker.close", fmt.Sprintf("OAuth profile %s's rate-limit breaker closed", profile), map[string]any{"profile": profile, "side": sideLabel(side)})
+		m.emitEvent("auth.breaker.close", "system", map[string]any{"profile": profile, "side": sideLabel(side)}, "", "")
+	}
+	return m
+}
+
+// sideLabel names a chatReplyMsg.Side value for alerts/events; "" means left.
+func sideLabel(side string) string {
+	if side == "right" {
+		return "right"
+	}
+	return "left"
+}
+
+// handleChatReply applies one completed (or failed) chat turn to whichever
+// pane it belongs to: the left pane (Side == "") or, in split-runtime mode,
+// the right pane (Side == "right"). The OAuth rate-limit swap-and-retry
+// logic runs independently per pane, so one side backing off on a 429 never
+// blocks the other side's turn from completing.
+func (m appModel) handleChatReply(t chatReplyMsg) (appModel, tea.Cmd) {
+	right := t.Side == "right"
+
+	curCID := m.chatCorrelationID
+	if right {
+		curCID = m.chatCorrelationIDRight
+	}
+	if curCID != "" && t.CorrelationID != "" && t.CorrelationID != curCID {
+		return m, nil
+	}
+
+	if right {
+		m.chatInFlightRight = false
+		m.chatCorrelationIDRight = ""
+		m.chatActiveProfileRight = ""
+		m.chatStreamTextRight = ""
+		if m.chatCancelRight != nil {
+			m.chatCancelRight()
+			m.chatCancelRight = nil
+		}
+	} else {
+		m.chatInFlight = false
+		m.chatCancelPending = false
+		m.chatCorrelationID = ""
+		m.chatActiveProfile = ""
+		m.chatStreamText = ""
+		if m.chatCancel != nil {
+			m.chatCancel()
+			m.chatCancel = nil
+		}
+	}
+
+	if t.Cancelled {
+		m.systemAlert(alertInfo, "chat.cancelled", "Chat request cancelled", map[string]any{"side": sideLabel(t.Side)})
+		return m, nil
+	}
+
+	if strings.TrimSpace(t.Text) != "" {
+		role := chatRoleLine{Role: "assistant", Text: strings.TrimRight(t.Text, "\n")}
+		msg := chatMessage{Role: "assistant", Content: t.Text}
+		if right {
+			w, _ := m.effectiveSize()
+			if m.chatScrollOffsetRight > 0 {
+				m.chatScrollOffsetRight += m.chatWrappedLineCountWidth("assistant", t.Text, m.chatPaneInnerWidth(w))
+			}
+			m.chatRoleLinesRight = append(m.chatRoleLinesRight, role)
+			m.chatMessagesRight = append(m.chatMessagesRight, msg)
+		} else {
+			if m.chatScrollOffset > 0 {
+				m.chatScrollOffset += m.chatWrappedLineCount("assistant", t.Text)
+			}
+			m = m.appendChatRoleLine(role)
+			m = m.trimChatRoleLines()
+			m.chatMessages = append(m.chatMessages, msg)
+			_, _ = m.historyWAL.Append(history.KindAssistant, "assistant", t.Text, t.CorrelationID)
+		}
+		m.emitEvent("llm.response", "system", map[string]any{"provider": t.Provider, "profile": t.Profile, "status": t.Status, "side": sideLabel(t.Side)}, t.CorrelationID, "")
+		m.emitEvent("chat.reply", "system", map[string]any{"provider": t.Provider, "text": t.Text, "side": sideLabel(t.Side)}, t.CorrelationID, "")
+		if t.Provider == "openai-oauth-codex" {
+			m = m.observeOAuthTurn(t.Profile, 200, 0, t.Side)
+		}
+		return m, nil
+	}
+
+	if strings.TrimSpace(t.Error) == "" {
+		return m, nil
+	}
+
+	rateLimited := t.Status == 429 || t.Status >= 500
+	if rateLimited && t.Provider == "openai-oauth-codex" && t.Attempt < 2 {
+		retryMs := t.RetryAfterMs
+		if retryMs <= 0 {
+			retryMs = 10_000
+		}
+		m = m.observeOAuthTurn(t.Profile, t.Status, t.RetryAfterMs, t.Side)
+		until := time.Now().Add(time.Duration(retryMs) * time.Millisecond)
+		if m.profileCooldowns == nil {
+			m.profileCooldowns = map[string]time.Time{}
+		}
+		m.profileCooldowns[t.Profile] = until
+		if err := setOAuthProfileRateLimitedUntil(m.cfg.stateDir, t.Profile, until.UnixMilli()); err == nil {
+			// Choose next candidate deterministically (best-effort).
+			if snap, ok := readOAuthPoolSnapshot(m.cfg.stateDir, time.Now()); ok {
+				next, ok2 := m.pickNonCoolingOAuthProfile(snap.Ranked, t.Profile)
+				if ok2 {
+					_ = setOAuthPoolLastUsedProfile(m.cfg.stateDir, next.Profile)
+					m.oauthFlashUntil = time.Now().Add(1 * time.Second)
+					m.lastOAuthProfile = next.Email
+					m.systemAlert(alertWarn, "auth.swap", fmt.Sprintf("Swapped OAuth Account -> %s (reason=rate_limit, side=%s)", next.Email, sideLabel(t.Side)), map[string]any{"fromProfile": t.Profile, "toProfile": next.Profile, "side": sideLabel(t.Side)})
+					m.emitEvent("auth.swap", "system", map[string]any{
+						"from":    t.Profile,
+						"to":      next.Email,
+						"reason":  "rate_limit",
+						"ranking": snap.Ranked,
+						"side":    sideLabel(t.Side),
+					}, t.CorrelationID, "")
+
+					// Retry once with the same messages.
+					if !m.cfg.disableNetwork {
+						retryCID := newCorrelationID()
+						msgs := m.chatMessages
+						if right {
+							msgs = m.chatMessagesRight
+						}
+						msgs = append([]chatMessage{}, msgs...)
+						ctx, cancel := context.WithCancel(context.Background())
+						if right {
+							m.chatInFlightRight = true
+							m.chatCorrelationIDRight = retryCID
+							m.chatActiveProfileRight = next.Profile
+							m.chatCancelRight = cancel
+						} else {
+							m.chatInFlight = true
+							m.chatCorrelationID = retryCID
+							m.chatActiveProfile = next.Profile
+							m.chatCancel = cancel
+						}
+
+						endpoint := strings.TrimSpace(snap.CodexEndpoint)
+						model := codexModelForSelection(strings.TrimSpace(m.selectedModel))
+						instructions := strings.TrimSpace(os.Getenv("WORKBENCH_SYSTEM_PROMPT"))
+						profileCopy := next
+						eventsPath := m.codexEventsPath
+						side := t.Side
+						attempt := t.Attempt
+						audit := m.audit
+
+						m.emitEvent("llm.request", "system", map[string]any{"provider": "openai-oauth-codex", "model": model, "profile": profileCopy.Profile, "retry": true, "side": sideLabel(side)}, retryCID, t.CorrelationID)
+						audit.Append("codex.request", "system", map[string]any{"provider": "openai-oauth-codex", "model": model, "profile": profileCopy.Profile, "retry": true, "side": sideLabel(side)}, retryCID)
+						return m, func() tea.Msg {
+							res, statusErr, err := codexChatStreamDetailed(ctx, endpoint, model, profileCopy.accessToken, profileCopy.accountID, instructions, msgs, func(delta string) {
+								if strings.TrimSpace(delta) == "" {
+									return
+								}
+								_ = appendCodexEvent(eventsPath, codexTurnEvent{
+									Version:       1,
+									Type:          "turn.event",
+									CorrelationID: retryCID,
+									At:            time.Now().UTC().Format(time.RFC3339Nano),
+									Kind:          "delta",
+									Message:       delta,
+								})
+							})
+							if err != nil {
+								cancelled := errorsIsContextCanceled(err)
+								audit.Append("codex.response", "system", map[string]any{"error": err.Error(), "cancelled": cancelled, "clientAttempts": res.Attempts}, retryCID)
+								return chatReplyMsg{CorrelationID: retryCID, Provider: "openai-oauth-codex", Profile: profileCopy.Profile, Error: err.Error(), Cancelled: cancelled, Attempt: attempt + 1, Side: side}
+							}
+							if statusErr != nil {
+								audit.Append("codex.response", "system", map[string]any{"error": statusErr.Error(), "status": statusErr.Status, "clientAttempts": res.Attempts}, retryCID)
+								return chatReplyMsg{CorrelationID: retryCID, Provider: "openai-oauth-codex", Profile: profileCopy.Profile, Error: statusErr.Error(), Status: statusErr.Status, RetryAfterMs: statusErr.RetryAfterMs, Attempt: attempt + 1, Side: side}
+							}
+							if res.Attempts > 1 || res.HedgedWon {
+								audit.Append("codex.retry", "system", map[string]any{"clientAttempts": res.Attempts, "hedgedWon": res.HedgedWon}, retryCID)
+							}
+							audit.Append("codex.response", "system", map[string]any{"text": res.Text, "clientAttempts": res.Attempts}, retryCID)
+							return chatReplyMsg{CorrelationID: retryCID, Provider: "openai-oauth-codex", Profile: profileCopy.Profile, Text: res.Text, Attempt: attempt + 1, Side: side}
+						}
+					}
+				}
+			}
+		}
+	}
+	alreadyObserved := (t.Status == 429 || t.Status >= 500) && t.Provider == "openai-oauth-codex" && t.Attempt < 2
+	if !alreadyObserved && t.Status != 0 && t.Provider == "openai-oauth-codex" {
+		m = m.observeOAuthTurn(t.Profile, t.Status, t.RetryAfterMs, t.Side)
+	}
+	m.systemAlert(alertError, "chat.failed", "Chat request failed", map[string]any{"provider": t.Provider, "error": t.Error, "status": t.Status, "side": sideLabel(t.Side)})
+	return m, nil
+}
+
 func tickCmd() tea.Cmd {
 	return tea.Tick(100*time.Millisecond, func(t time.Time) tea.Msg { return t })
 }
@@ -601,8 +1114,9 @@ type chatMessage struct {
 }
 
 type chatRoleLine struct {
-	Role string // user|assistant|system
-	Text string
+	Role       string // user|assistant|system|tool
+	Text       string
+	ToolCallID string // set when Role == "tool"; looks up appModel.chatToolCalls
 }
 
 type chatReplyMsg struct {
@@ -615,6 +1129,7 @@ type chatReplyMsg struct {
 	RetryAfterMs  int64
 	Cancelled     bool
 	Attempt       int
+	Side          string // ""(left)|"right"; which split-screen pane this reply belongs to
 }
 
 func (m appModel) currentScreen() screen {
@@ -673,6 +1188,120 @@ func (m appModel) closeAllOverlays() appModel {
 	return m
 }
 
+// cancelInFlightChat requests cancellation of whatever chat turn is
+// currently running, routing through whichever backend (opencode runtime,
+// codex runtime, or in-process context.CancelFunc) owns it. A no-op if no
+// turn is in flight. Shared by the Esc key binding and the registered
+// "cancel" command.
+func (m appModel) cancelInFlightChat() (appModel, tea.Cmd) {
+	if !m.chatInFlight {
+		return m, nil
+	}
+	m.chatCancelPending = true
+	_ = appendCodexEvent(m.codexEventsPath, codexTurnEvent{
+		CorrelationID: m.chatCorrelationID,
+		At:            m.now.UTC().Format(time.RFC3339),
+		Kind:          "cancelled",
+		Message:       "turn cancelled by user",
+	})
+	if m.opencodeExecutorReady && m.selectedRuntime == "opencode-run" && strings.TrimSpace(m.chatCorrelationID) != "" {
+		bus := newOpencodeBusClient(m.cfg.stateDir, m.sessionID, m.opencodeRequestsPath)
+		correlationID := m.chatCorrelationID
+		m.systemAlert(alertInfo, "chat.cancel.requested", "Cancellation requested", map[string]any{"backend": "opencode-runtime"})
+		m.emitEvent("command.cancel.requested", m.actionSource, map[string]any{"kind": "chat", "backend": "opencode-runtime"}, "", "")
+		m = m.emitChatMetrics(m.chatCorrelationID, "cancelled")
+		return m, func() tea.Msg {
+			defer bus.Close()
+			_ = bus.Cancel(context.Background(), correlationID)
+			return nil
+		}
+	}
+	if m.codexExecutorReady && m.selectedRuntime == "codex-cli" && strings.TrimSpace(m.chatCorrelationID) != "" {
+		correlationID := m.chatCorrelationID
+		stateDir, sessionID := m.cfg.stateDir, m.sessionID
+		m.systemAlert(alertInfo, "chat.cancel.requested", "Cancellation requested", map[string]any{"backend": "codex-runtime"})
+		m.emitEvent("command.cancel.requested", m.actionSource, map[string]any{"kind": "chat", "backend": "codex-runtime"}, "", "")
+		m = m.emitChatMetrics(m.chatCorrelationID, "cancelled")
+		return m, func() tea.Msg {
+			executor, err := cachedCodexExecutor(executorURLFromEnv(), stateDir, sessionID)
+			if err != nil {
+				return nil
+			}
+			_ = executor.Cancel(correlationID)
+			return nil
+		}
+	}
+	if m.chatCancel != nil {
+		m.chatCancel()
+		m.chatCancel = nil
+	}
+	m.chatInFlight = false
+	m.chatCancelPending = false
+	m.systemAlert(alertInfo, "chat.cancel.requested", "Cancellation requested", nil)
+	m.emitEvent("command.cancel.requested", m.actionSource, map[string]any{"kind": "chat"}, "", "")
+	m = m.emitChatMetrics(m.chatCorrelationID, "cancelled")
+	m.chatCorrelationID = ""
+	m.chatActiveProfile = ""
+	return m, nil
+}
+
+// submitCancelTurn is the Ctrl+C binding's entry point: it's a thin wrapper
+// over cancelInFlightChat so Ctrl+C and Esc share one cancellation path
+// instead of drifting apart; only this Ctrl+C path also emits a
+// chat.cancelled event.
+func (m appModel) submitCancelTurn() (appModel, tea.Cmd) {
+	m, cmd := m.cancelInFlightChat()
+	m.emitEvent("chat.cancelled", m.actionSource, map[string]any{"correlationId": m.chatCorrelationID}, "", "")
+	return m, cmd
+}
+
+// closeSplitRuntime cancels any in-flight right-pane turn and clears every
+// right-pane field, returning the cockpit to a single pane. Used by the
+// /split off form as well as /pick, which closes the split after promoting
+// a side.
+func (m appModel) closeSplitRuntime() appModel {
+	if m.chatCancelRight != nil {
+		m.chatCancelRight()
+		m.chatCancelRight = nil
+	}
+	m.splitRuntime = ""
+	m.splitFocus = ""
+	m.chatInFlightRight = false
+	m.chatCorrelationIDRight = ""
+	m.chatActiveProfileRight = ""
+	m.chatStreamTextRight = ""
+	m.chatRoleLinesRight = nil
+	m.chatMessagesRight = nil
+	m.chatScrollOffsetRight = 0
+	return m
+}
+
+// lastAssistantText returns the most recent assistant message's content, or
+// "" if msgs has none yet (e.g. the pane hasn't replied for this turn).
+func lastAssistantText(msgs []chatMessage) string {
+	for i := len(msgs) - 1; i >= 0; i-- {
+		if msgs[i].Role == "assistant" {
+			return msgs[i].Content
+		}
+	}
+	return ""
+}
+
+// summarizeForAlert renders text for inclusion in a systemAlert message,
+// which is a single status line: collapse it to one line and cap its length
+// so a long reply doesn't blow out the alert panel.
+func summarizeForAlert(text string) string {
+	s := strings.Join(strings.Fields(text), " ")
+	if s == "" {
+		return "(no reply yet)"
+	}
+	const maxLen = 200
+	if len(s) > maxLen {
+		return s[:maxLen] + "…"
+	}
+	return s
+}
+
 func (m appModel) handleEsc() (tea.Model, tea.Cmd) {
 	// Priority:
 	// 1) Close top overlay
@@ -684,37 +1313,11 @@ func (m appModel) handleEsc() (tea.Model, tea.Cmd) {
 		m.slashPendingUntil = time.Time{}
 		return m, nil
 	}
+	if m.chatSearchActive {
+		return m.closeChatSearch(), nil
+	}
 	if m.currentScreen() == screenCockpit && m.chatInFlight {
-		if m.opencodeExecutorReady && m.selectedRuntime == "opencode-run" && strings.TrimSpace(m.chatCorrelationID) != "" {
-			_ = appendOpencodeRequest(m.opencodeRequestsPath, opencodeTurnRequest{
-				Version:       1,
-				Type:          "cancel",
-				CorrelationID: m.chatCorrelationID,
-			})
-			m.systemAlert(alertInfo, "chat.cancel.requested", "Cancellation requested", map[string]any{"backend": "opencode-runtime"})
-			m.emitEvent("command.cancel.requested", m.actionSource, map[string]any{"kind": "chat", "backend": "opencode-runtime"}, "", "")
-			return m, nil
-		}
-		if m.codexExecutorReady && m.selectedRuntime == "codex-cli" && strings.TrimSpace(m.chatCorrelationID) != "" {
-			_ = appendCodexRequest(m.codexRequestsPath, codexTurnRequest{
-				Version:       1,
-				Type:          "cancel",
-				CorrelationID: m.chatCorrelationID,
-			})
-			m.systemAlert(alertInfo, "chat.cancel.requested", "Cancellation requested", map[string]any{"backend": "codex-runtime"})
-			m.emitEvent("command.cancel.requested", m.actionSource, map[string]any{"kind": "chat", "backend": "codex-runtime"}, "", "")
-			return m, nil
-		}
-		if m.chatCancel != nil {
-			m.chatCancel()
-			m.chatCancel = nil
-		}
-		m.chatInFlight = false
-		m.chatCorrelationID = ""
-		m.chatActiveProfile = ""
-		m.systemAlert(alertInfo, "chat.cancel.requested", "Cancellation requested", nil)
-		m.emitEvent("command.cancel.requested", m.actionSource, map[string]any{"kind": "chat"}, "", "")
-		return m, nil
+		return m.cancelInFlightChat()
 	}
 	if m.currentScreen() == screenCockpit && m.slashPending {
 		m.slashPending = false
@@ -730,11 +1333,57 @@ func (m appModel) handleEsc() (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
-func (m appModel) emitEvent(eventType string, source string, payload any, correlationID string, causationID string) {
-	if m.events == nil {
-		return
+func (m appModel) emitEvent(eventType string, source string, payload any, correlationID string, causationID string) *hooks.Rejection {
+	if m.events != nil {
+		m.events.Append(source, eventType, payload, correlationID, causationID)
+	}
+	return m.hookBus.Publish(hooks.Event{
+		Type:          eventType,
+		Source:        source,
+		Payload:       payload,
+		CorrelationID: correlationID,
+		CausationID:   causationID,
+	})
+}
+
+// estimateTokenCount gives a cheap tokens/s proxy for streaming deltas:
+// roughly 4 characters per token, the same rule of thumb lmcli's chat model
+// uses, without pulling in a real tokenizer just for a status-bar estimate.
+func estimateTokenCount(s string) int {
+	if s == "" {
+		return 0
 	}
-	m.events.Append(source, eventType, payload, correlationID, causationID)
+	n := len(s) / 4
+	if n == 0 {
+		n = 1
+	}
+	return n
+}
+
+// emitChatMetrics reports the just-finished turn's token count, duration,
+// and average rate as an llm.metrics event, then zeroes chatMetricsStartedAt
+// so viewStatusBar stops rendering a rate until the next sendChat. status is
+// "ok", "error", or "cancelled". A no-op if no timer was started, e.g. a
+// stale response arriving after the turn already reported.
+func (m appModel) emitChatMetrics(correlationID string, status string) appModel {
+	if m.chatMetricsStartedAt.IsZero() {
+		return m
+	}
+	elapsed := m.now.Sub(m.chatMetricsStartedAt)
+	tokens := m.chatMetricsTokens
+	rate := 0.0
+	if elapsed > 0 {
+		rate = float64(tokens) / elapsed.Seconds()
+	}
+	m.emitEvent("llm.metrics", "system", map[string]any{
+		"status":       status,
+		"tokens":       tokens,
+		"durationMs":   elapsed.Milliseconds(),
+		"tokensPerSec": rate,
+	}, correlationID, "")
+	m.chatMetricsStartedAt = time.Time{}
+	m.chatMetricsTokens = 0
+	return m
 }
 
 func (m *appModel) systemAlert(sev alertSeverity, code string, message string, context map[string]any) {
@@ -758,6 +1407,80 @@ func (m *appModel) systemAlert(sev alertSeverity, code string, message string, c
 		"context":        context,
 		"correlation_id": cid,
 	}, cid, "")
+	_, _ = m.historyWAL.Append(history.KindAlert, string(sev), code+": "+message, cid)
+	m.audit.Append("system.alert", "system", map[string]any{
+		"severity": string(sev),
+		"code":     code,
+		"message":  message,
+		"context":  context,
+	}, cid)
+}
+
+// setPermissionMode applies a permission-mode switch, giving hook
+// subscribers a chance to veto it first via a "permission_mode.pre_toggle"
+// event (the primary use case being a policy hook that blocks switches to
+// "bypass"). A rejection leaves permissionMode unchanged and surfaces the
+// hook's reason as a warning alert instead of the usual toggled alert.
+// Escalating to "bypass" is additionally gated on m.bypassAllowed, which an
+// SSH connection only gets from serve.acl.json (see sshAllowsBypass) —
+// otherwise Shift+Tab or //permission_mode would let any connected client
+// reach bypass regardless of the ACL.
+func (m appModel) setPermissionMode(next string) appModel {
+	if next == "bypass" && !m.bypassAllowed {
+		m.systemAlert(alertWarn, "permission_mode.rejected", "Permission mode change blocked: bypass is not permitted for this connection", map[string]any{
+			"permissionMode": m.permissionMode,
+			"requested":      next,
+		})
+		return m
+	}
+	cid := newCorrelationID()
+	if rej := m.emitEvent("permission_mode.pre_toggle", m.actionSource, map[string]any{
+		"from": m.permissionMode,
+		"to":   next,
+	}, cid, ""); rej != nil {
+		m.systemAlert(alertWarn, "permission_mode.rejected", "Permission mode change blocked: "+rej.Message, map[string]any{
+			"permissionMode": m.permissionMode,
+			"requested":      next,
+			"by":             rej.By,
+			"hookCode":       rej.Code,
+		})
+		return m
+	}
+	m.permissionMode = next
+	m.systemAlert(alertInfo, "permission_mode.toggled", "Permission mode: "+m.permissionModeLabel(), map[string]any{"permissionMode": m.permissionMode})
+	m.emitEvent("permission_mode.toggled", m.actionSource, map[string]any{"permissionMode": m.permissionMode}, cid, "")
+	return m
+}
+
+// rewindTo truncates the session's history WAL to seq, reconstructs
+// chatMessages/chatRoleLines from what's left, and drops any in-flight
+// turn, so a later sendChat branches the conversation from that point
+// instead of replaying what was discarded. Surfaced as the //rewind
+// command.
+func (m appModel) rewindTo(seq int64) appModel {
+	if m.historyWAL == nil {
+		m.systemAlert(alertWarn, "history.rewind.unavailable", "History WAL is not available", nil)
+		return m
+	}
+	state, err := m.historyWAL.RewindTo(seq)
+	if err != nil {
+		m.systemAlert(alertError, "history.rewind.failed", "Failed to rewind history", map[string]any{"seq": seq, "error": err.Error()})
+		return m
+	}
+	if m.chatCancel != nil {
+		m.chatCancel()
+		m.chatCancel = nil
+	}
+	m.chatInFlight = false
+	m.chatCorrelationID = ""
+	m.chatActiveProfile = ""
+	m.chatStreamText = ""
+	m.chatScrollOffset = 0
+	m.chatMessages = chatMessagesFromHistory(state.Messages)
+	m.chatRoleLines = chatRoleLinesFromHistory(state.RoleLines)
+	m = m.trimChatRoleLines()
+	m.systemAlert(alertInfo, "history.rewound", fmt.Sprintf("Rewound to turn %d", seq), map[string]any{"seq": seq})
+	return m
 }
 
 func (m appModel) onTick(now time.Time) (appModel, tea.Cmd) {
@@ -807,12 +1530,36 @@ func (m appModel) onTick(now time.Time) (appModel, tea.Cmd) {
 	if m.quitRequested {
 		return m, tea.Quit
 	}
+	var socketCmd tea.Cmd
+	m, socketCmd = m.consumeEventsSocketCommands()
+	if m.quitRequested {
+		return m, tea.Quit
+	}
+	var controlCmd tea.Cmd
+	m, controlCmd = m.consumeControlAPICalls()
+	if m.quitRequested {
+		return m, tea.Quit
+	}
+	var replayCmd tea.Cmd
+	m, replayCmd = m.consumeReplay()
+	if m.quitRequested {
+		return m, tea.Quit
+	}
 	var usageCmd tea.Cmd
 	m, usageCmd = m.maybeScheduleUsageFetch(now)
 	cmds := []tea.Cmd{tickCmd()}
 	if busCmd != nil {
 		cmds = append(cmds, busCmd)
 	}
+	if socketCmd != nil {
+		cmds = append(cmds, socketCmd)
+	}
+	if controlCmd != nil {
+		cmds = append(cmds, controlCmd)
+	}
+	if replayCmd != nil {
+		cmds = append(cmds, replayCmd)
+	}
 	if usageCmd != nil {
 		cmds = append(cmds, usageCmd)
 	}
@@ -822,12 +1569,24 @@ func (m appModel) onTick(now time.Time) (appModel, tea.Cmd) {
 	return m, tea.Batch(cmds...)
 }
 
+// badgeBusReadError surfaces a *multiError of ErrCorruptLine entries from a
+// bus read as a single warn-level alert instead of failing the read; the
+// well-formed lines were already processed by the caller.
+func (m *appModel) badgeBusReadError(bus string, err error) {
+	var merr *multiError
+	if !errors.As(err, &merr) || merr == nil || len(merr.Errs) == 0 {
+		return
+	}
+	m.systemAlert(alertWarn, "bus.partial_read", fmt.Sprintf("%s bus: %d line(s) failed to parse", bus, len(merr.Errs)), map[string]any{"bus": bus, "count": len(merr.Errs)})
+}
+
 func (m appModel) consumeSystemResponses(now time.Time) appModel {
 	if strings.TrimSpace(m.systemResponsesPath) == "" {
 		return m
 	}
-	rs, newOffset := readSystemResponses(m.systemResponsesPath, m.systemResponsesOffset)
+	rs, newOffset, rerr := readSystemResponses(m.systemResponsesPath, m.systemResponsesOffset)
 	m.systemResponsesOffset = newOffset
+	m.badgeBusReadError("system", rerr)
 	for _, r := range rs {
 		if r.Type != "system.result" {
 			continue
@@ -863,8 +1622,9 @@ func (m appModel) consumeCodexResponses(now time.Time) appModel {
 	if strings.TrimSpace(m.codexResponsesPath) == "" {
 		return m
 	}
-	rs, newOffset := readCodexResponses(m.codexResponsesPath, m.codexResponsesOffset)
+	rs, newOffset, rerr := readCodexResponses(m.codexResponsesPath, m.codexResponsesOffset)
 	m.codexResponsesOffset = newOffset
+	m.badgeBusReadError("codex", rerr)
 	for _, r := range rs {
 		if r.Type != "turn.result" {
 			continue
@@ -889,9 +1649,11 @@ func (m appModel) consumeCodexResponses(now time.Time) appModel {
 			if m.chatScrollOffset > 0 {
 				m.chatScrollOffset += m.chatWrappedLineCount("system", msg)
 			}
-			m.chatRoleLines = append(m.chatRoleLines, chatRoleLine{Role: "system", Text: msg})
+			m = m.appendChatRoleLine(chatRoleLine{Role: "system", Text: msg})
 			m = m.trimChatRoleLines()
 			m.systemAlert(alertError, "codex.runtime.error", msg, map[string]any{"correlationId": r.CorrelationID})
+			m = m.emitChatMetrics(r.CorrelationID, "error")
+			m.chatCancelPending = false
 			continue
 		}
 
@@ -899,18 +1661,22 @@ func (m appModel) consumeCodexResponses(now time.Time) appModel {
 			if m.chatScrollOffset > 0 {
 				m.chatScrollOffset += m.chatWrappedLineCount("assistant", r.Content)
 			}
-			m.chatRoleLines = append(m.chatRoleLines, chatRoleLine{Role: "assistant", Text: strings.TrimRight(r.Content, "\n")})
+			m = m.appendChatRoleLine(chatRoleLine{Role: "assistant", Text: strings.TrimRight(r.Content, "\n")})
 		} else {
 			if m.chatScrollOffset > 0 {
 				m.chatScrollOffset += 1
 			}
-			m.chatRoleLines = append(m.chatRoleLines, chatRoleLine{Role: "assistant", Text: "(no content)"})
+			m = m.appendChatRoleLine(chatRoleLine{Role: "assistant", Text: "(no content)"})
 		}
 		m = m.trimChatRoleLines()
 		if len(r.FileChanges) > 0 {
-			m.systemAlert(alertInfo, "codex.runtime.file_changes", fmt.Sprintf("Codex changed %d file(s)", len(r.FileChanges)), map[string]any{"files": r.FileChanges})
+			if !m.attachFileChangesToLastToolCall(r.CorrelationID, r.FileChanges) {
+				m.systemAlert(alertInfo, "codex.runtime.file_changes", fmt.Sprintf("Codex changed %d file(s)", len(r.FileChanges)), map[string]any{"files": r.FileChanges})
+			}
 		}
 		m.emitEvent("llm.response", "system", map[string]any{"provider": "codex-runtime", "ok": true, "filesChanged": len(r.FileChanges)}, r.CorrelationID, "")
+		m = m.emitChatMetrics(r.CorrelationID, "ok")
+		m.chatCancelPending = false
 	}
 	_ = now
 	return m
@@ -920,8 +1686,9 @@ func (m appModel) consumeCodexEvents(now time.Time) appModel {
 	if strings.TrimSpace(m.codexEventsPath) == "" {
 		return m
 	}
-	evs, newOffset := readCodexEvents(m.codexEventsPath, m.codexEventsOffset)
+	evs, newOffset, everr := readCodexEvents(m.codexEventsPath, m.codexEventsOffset)
 	m.codexEventsOffset = newOffset
+	m.badgeBusReadError("codex.events", everr)
 	for _, ev := range evs {
 		if strings.TrimSpace(ev.CorrelationID) == "" {
 			continue
@@ -941,6 +1708,7 @@ func (m appModel) consumeCodexEvents(now time.Time) appModel {
 			}
 
 			m.chatStreamText += ev.Message
+			m.chatMetricsTokens += estimateTokenCount(ev.Message)
 			if len(m.chatStreamText) > 4000 {
 				m.chatStreamText = m.chatStreamText[len(m.chatStreamText)-4000:]
 			}
@@ -955,6 +1723,14 @@ func (m appModel) consumeCodexEvents(now time.Time) appModel {
 			}
 			continue
 		}
+		if (kind == "tool_use" || kind == "tool_call") && strings.TrimSpace(ev.Tool) != "" {
+			m = m.beginChatToolCall(ev.CorrelationID, strings.TrimSpace(ev.Tool), strings.TrimSpace(ev.Message), ev.At)
+			continue
+		}
+		if (kind == "step_finish" || kind == "tool_result") && m.chatActiveToolCallID != "" {
+			m = m.finishChatToolCall(strings.TrimSpace(ev.Message), ev.At)
+			continue
+		}
 
 		msg := strings.TrimSpace(ev.Message)
 		if msg == "" {
@@ -970,7 +1746,7 @@ func (m appModel) consumeCodexEvents(now time.Time) appModel {
 		if m.chatScrollOffset > 0 {
 			m.chatScrollOffset += m.chatWrappedLineCount("system", prefix+": "+msg)
 		}
-		m.chatRoleLines = append(m.chatRoleLines, chatRoleLine{Role: "system", Text: prefix + ": " + msg})
+		m = m.appendChatRoleLine(chatRoleLine{Role: "system", Text: prefix + ": " + msg})
 		m = m.trimChatRoleLines()
 	}
 	_ = now
@@ -981,8 +1757,9 @@ func (m appModel) consumeOpencodeEvents(now time.Time) appModel {
 	if strings.TrimSpace(m.opencodeEventsPath) == "" {
 		return m
 	}
-	evs, newOffset := readOpencodeEvents(m.opencodeEventsPath, m.opencodeEventsOffset)
+	evs, newOffset, everr := readOpencodeEvents(m.opencodeEventsPath, m.opencodeEventsOffset)
 	m.opencodeEventsOffset = newOffset
+	m.badgeBusReadError("opencode.events", everr)
 	for _, ev := range evs {
 		if strings.TrimSpace(ev.CorrelationID) == "" {
 			continue
@@ -998,6 +1775,7 @@ func (m appModel) consumeOpencodeEvents(now time.Time) appModel {
 			}
 
 			m.chatStreamText += ev.Message
+			m.chatMetricsTokens += estimateTokenCount(ev.Message)
 			if len(m.chatStreamText) > 4000 {
 				m.chatStreamText = m.chatStreamText[len(m.chatStreamText)-4000:]
 			}
@@ -1012,6 +1790,14 @@ func (m appModel) consumeOpencodeEvents(now time.Time) appModel {
 			}
 			continue
 		}
+		if strings.TrimSpace(ev.Kind) == "tool_use" && strings.TrimSpace(ev.Tool) != "" {
+			m = m.beginChatToolCall(ev.CorrelationID, strings.TrimSpace(ev.Tool), strings.TrimSpace(ev.Message), ev.At)
+			continue
+		}
+		if strings.TrimSpace(ev.Kind) == "step_finish" && m.chatActiveToolCallID != "" {
+			m = m.finishChatToolCall(strings.TrimSpace(ev.Message), ev.At)
+			continue
+		}
 		msg := strings.TrimSpace(ev.Message)
 		if msg == "" {
 			continue
@@ -1026,7 +1812,7 @@ func (m appModel) consumeOpencodeEvents(now time.Time) appModel {
 		if m.chatScrollOffset > 0 {
 			m.chatScrollOffset += m.chatWrappedLineCount("system", prefix+": "+msg)
 		}
-		m.chatRoleLines = append(m.chatRoleLines, chatRoleLine{Role: "system", Text: prefix + ": " + msg})
+		m = m.appendChatRoleLine(chatRoleLine{Role: "system", Text: prefix + ": " + msg})
 		m = m.trimChatRoleLines()
 	}
 	_ = now
@@ -1037,8 +1823,9 @@ func (m appModel) consumeOpencodeResponses(now time.Time) appModel {
 	if strings.TrimSpace(m.opencodeResponsesPath) == "" {
 		return m
 	}
-	rs, newOffset := readOpencodeResponses(m.opencodeResponsesPath, m.opencodeResponsesOffset)
+	rs, newOffset, rerr := readOpencodeResponses(m.opencodeResponsesPath, m.opencodeResponsesOffset)
 	m.opencodeResponsesOffset = newOffset
+	m.badgeBusReadError("opencode", rerr)
 	for _, r := range rs {
 		if r.Type != "turn.result" {
 			continue
@@ -1063,9 +1850,11 @@ func (m appModel) consumeOpencodeResponses(now time.Time) appModel {
 			if m.chatScrollOffset > 0 {
 				m.chatScrollOffset += m.chatWrappedLineCount("system", msg)
 			}
-			m.chatRoleLines = append(m.chatRoleLines, chatRoleLine{Role: "system", Text: msg})
+			m = m.appendChatRoleLine(chatRoleLine{Role: "system", Text: msg})
 			m = m.trimChatRoleLines()
 			m.systemAlert(alertError, "opencode.runtime.error", msg, map[string]any{"correlationId": r.CorrelationID})
+			m = m.emitChatMetrics(r.CorrelationID, "error")
+			m.chatCancelPending = false
 			continue
 		}
 
@@ -1073,18 +1862,22 @@ func (m appModel) consumeOpencodeResponses(now time.Time) appModel {
 			if m.chatScrollOffset > 0 {
 				m.chatScrollOffset += m.chatWrappedLineCount("assistant", r.Content)
 			}
-			m.chatRoleLines = append(m.chatRoleLines, chatRoleLine{Role: "assistant", Text: strings.TrimRight(r.Content, "\n")})
+			m = m.appendChatRoleLine(chatRoleLine{Role: "assistant", Text: strings.TrimRight(r.Content, "\n")})
 		} else {
 			if m.chatScrollOffset > 0 {
 				m.chatScrollOffset += 1
 			}
-			m.chatRoleLines = append(m.chatRoleLines, chatRoleLine{Role: "assistant", Text: "(no content)"})
+			m = m.appendChatRoleLine(chatRoleLine{Role: "assistant", Text: "(no content)"})
 		}
 		m = m.trimChatRoleLines()
 		if len(r.FileChanges) > 0 {
-			m.systemAlert(alertInfo, "opencode.runtime.file_changes", fmt.Sprintf("OpenCode changed %d file(s)", len(r.FileChanges)), map[string]any{"files": r.FileChanges})
+			if !m.attachFileChangesToLastToolCall(r.CorrelationID, r.FileChanges) {
+				m.systemAlert(alertInfo, "opencode.runtime.file_changes", fmt.Sprintf("OpenCode changed %d file(s)", len(r.FileChanges)), map[string]any{"files": r.FileChanges})
+			}
 		}
 		m.emitEvent("llm.response", "system", map[string]any{"provider": "opencode-runtime", "ok": true, "filesChanged": len(r.FileChanges)}, r.CorrelationID, "")
+		m = m.emitChatMetrics(r.CorrelationID, "ok")
+		m.chatCancelPending = false
 	}
 	_ = now
 	return m
@@ -1148,6 +1941,13 @@ func (m appModel) maybeScheduleUsageFetch(now time.Time) (appModel, tea.Cmd) {
 		return m, func() tea.Msg {
 			u, err := fetchUsage(token, accountID)
 			if err != nil {
+				var be *busError
+				// ErrRateLimited still carries a usable usageData snapshot
+				// (parseUsageResponse only errors after filling it in), so
+				// keep the data and just surface the rate-limited reason.
+				if errors.As(err, &be) && be.Code == ErrRateLimited && u != nil {
+					return usageFetchedMsg{Profile: profile, Data: u, Error: err.Error()}
+				}
 				return usageFetchedMsg{Profile: profile, Data: nil, Error: err.Error()}
 			}
 			return usageFetchedMsg{Profile: profile, Data: u, Error: ""}
@@ -1196,7 +1996,7 @@ func (m appModel) updateProviderConfig(k tea.KeyMsg) (tea.Model, tea.Cmd) {
 }
 
 func (m appModel) bumpProvider(delta int) appModel {
-	opts := providerOptions()
+	opts := m.providerOptions()
 	max := len(opts) - 1
 	if m.mode == modeA {
 		if m.providerFocus == 0 {
@@ -1208,11 +2008,55 @@ func (m appModel) bumpProvider(delta int) appModel {
 	}
 	m.providerSelected = clamp(m.providerSelected+delta, 0, max)
 	m.selectedProvider = opts[m.providerSelected]
-	m.selectedRuntime = defaultRuntimeForProvider(m.selectedProvider)
+	m.selectedRuntime = m.defaultRuntimeForProvider(m.selectedProvider)
 	return m
 }
 
 func (m appModel) updateCockpit(k tea.KeyMsg) (tea.Model, tea.Cmd) {
+	// Ctrl-R opens (or, while committed, re-edits) scrollback search mode;
+	// once active it owns all further key input until Esc closes it.
+	if m.chatSearchActive {
+		return m.updateChatSearch(k)
+	}
+	if k.Type == tea.KeyCtrlR && strings.TrimSpace(m.splitRuntime) == "" {
+		return m.openChatSearch(), nil
+	}
+
+	// Tab swaps which split-screen pane PgUp/PgDn/Home/End scroll; outside
+	// split mode it falls through to ordinary input handling below.
+	if k.Type == tea.KeyTab && strings.TrimSpace(m.splitRuntime) != "" {
+		if m.splitFocus == "right" {
+			m.splitFocus = "left"
+		} else {
+			m.splitFocus = "right"
+		}
+		return m, nil
+	}
+
+	// Outside split mode, with no text entered, Tab cycles focus between
+	// tool-call blocks in the current transcript (see chatToolCall) and
+	// Space/Enter toggle the focused block's collapsed/expanded state.
+	if strings.TrimSpace(m.splitRuntime) == "" && strings.TrimSpace(m.input) == "" {
+		if k.Type == tea.KeyTab {
+			m = m.cycleChatToolCallFocus(m.chatRoleLines)
+			return m, nil
+		}
+		if m.chatToolCallFocus != "" && (k.Type == tea.KeySpace || k.Type == tea.KeyEnter) {
+			m = m.toggleChatToolCallFocus()
+			return m, nil
+		}
+	}
+
+	// Resize the chat/footer split (chunk5-4, see layout.go): Alt+Up grows
+	// the footer, Alt+Down shrinks it back toward "1 input line only".
+	if k.Alt && (k.Type == tea.KeyUp || k.Type == tea.KeyDown) {
+		delta := 1
+		if k.Type == tea.KeyDown {
+			delta = -1
+		}
+		return m.adjustFooterExtraRows(delta), nil
+	}
+
 	// Scrollback controls (do not interfere with text entry).
 	switch k.Type {
 	case tea.KeyPgUp, tea.KeyCtrlU, tea.KeyPgDown, tea.KeyCtrlD, tea.KeyHome, tea.KeyEnd, tea.KeyUp, tea.KeyDown:
@@ -1227,24 +2071,42 @@ func (m appModel) updateCockpit(k tea.KeyMsg) (tea.Model, tea.Cmd) {
 			chatHeight = 6
 		}
 		innerW := chatInnerWidth(w)
-		maxOff := m.chatMaxScrollOffset(chatHeight, innerW)
 		step := m.chatHistoryMaxLines(chatHeight) / 2
 		if step < 1 {
 			step = 1
 		}
+
+		right := m.splitFocus == "right" && strings.TrimSpace(m.splitRuntime) != ""
+		offset := m.chatScrollOffset
+		maxOff := m.chatMaxScrollOffset(chatHeight, innerW)
+		if right {
+			rightInnerW := m.chatPaneInnerWidth(w)
+			offset = m.chatScrollOffsetRight
+			rightLines := len(m.chatHistoryLinesWrappedFor(m.chatRoleLinesRight, m.chatStreamTextRight, m.chatInFlightRight, rightInnerW))
+			maxOff = rightLines - m.chatHistoryMaxLines(chatHeight)
+			if maxOff < 0 {
+				maxOff = 0
+			}
+		}
+
 		switch k.Type {
 		case tea.KeyPgUp, tea.KeyCtrlU:
-			m.chatScrollOffset = clamp(m.chatScrollOffset+step, 0, maxOff)
+			offset = clamp(offset+step, 0, maxOff)
 		case tea.KeyPgDown, tea.KeyCtrlD:
-			m.chatScrollOffset = clamp(m.chatScrollOffset-step, 0, maxOff)
+			offset = clamp(offset-step, 0, maxOff)
 		case tea.KeyUp:
-			m.chatScrollOffset = clamp(m.chatScrollOffset+1, 0, maxOff)
+			offset = clamp(offset+1, 0, maxOff)
 		case tea.KeyDown:
-			m.chatScrollOffset = clamp(m.chatScrollOffset-1, 0, maxOff)
+			offset = clamp(offset-1, 0, maxOff)
 		case tea.KeyHome:
-			m.chatScrollOffset = maxOff
+			offset = maxOff
 		case tea.KeyEnd:
-			m.chatScrollOffset = 0
+			offset = 0
+		}
+		if right {
+			m.chatScrollOffsetRight = offset
+		} else {
+			m.chatScrollOffset = offset
 		}
 		return m, nil
 	}
@@ -1287,6 +2149,7 @@ func (m appModel) updateCockpit(k tea.KeyMsg) (tea.Model, tea.Cmd) {
 		if line != "" {
 			// When submitting, return to follow mode so the response is visible.
 			m.chatScrollOffset = 0
+			m.chatScrollOffsetRight = 0
 			if strings.HasPrefix(line, "/") {
 				var cmd tea.Cmd
 				m, cmd = m.executeCommandText(line)
@@ -1311,6 +2174,16 @@ func countVisualLines(raw string) int {
 	return len(strings.Split(s, "\n"))
 }
 
+// chatPaneInnerWidth is chatInnerWidth for one pane: the full chat panel
+// width when splitRuntime is off, or half of it (minus the 1-col divider
+// between panes) when the cockpit is split left/right.
+func (m appModel) chatPaneInnerWidth(totalWidth int) int {
+	if strings.TrimSpace(m.splitRuntime) == "" {
+		return chatInnerWidth(totalWidth)
+	}
+	return chatInnerWidth((totalWidth - 1) / 2)
+}
+
 func chatInnerWidth(totalWidth int) int {
 	// Chat panel uses:
 	// - overall width (totalWidth)
@@ -1358,10 +2231,58 @@ func wrapChatBlock(prefixStyled string, indent string, raw string, innerWidth in
 func styleChatContent(th theme, raw string) string {
 	raw = strings.ReplaceAll(raw, "\r\n", "\n")
 	lines := strings.Split(raw, "\n")
-	for i := range lines {
-		lines[i] = styleChatLine(th, lines[i])
+	out := make([]string, 0, len(lines))
+	for i := 0; i < len(lines); {
+		if lang, ok := fenceOpen(lines[i]); ok {
+			j := i + 1
+			for j < len(lines) && !fenceClose(lines[j]) {
+				j++
+			}
+			out = append(out, renderCodeFence(th, lang, lines[i+1:j])...)
+			if j < len(lines) {
+				j++ // consume the closing fence line
+			}
+			i = j
+			continue
+		}
+		out = append(out, styleChatLine(th, lines[i]))
+		i++
 	}
-	return strings.Join(lines, "\n")
+	return strings.Join(out, "\n")
+}
+
+// fenceOpen reports whether line opens a ``` fence and, if so, its language
+// tag (empty if the fence has none, e.g. bare ```).
+func fenceOpen(line string) (string, bool) {
+	trimmed := strings.TrimSpace(line)
+	if !strings.HasPrefix(trimmed, "```") {
+		return "", false
+	}
+	return strings.TrimSpace(strings.TrimPrefix(trimmed, "```")), true
+}
+
+func fenceClose(line string) bool {
+	return strings.HasPrefix(strings.TrimSpace(line), "```")
+}
+
+// renderCodeFence renders one fenced block's body as a bordered,
+// syntax-highlighted block: a top rule naming the language, each line
+// tokenized by highlightCodeLine and prefixed with a border column, and a
+// closing rule. This runs inside styleChatContent, before wrapChatBlock
+// ever sees the text, so chatWrappedLineCount's count comes from the same
+// final strings the wrap step reflows - no separate bookkeeping needed.
+func renderCodeFence(th theme, lang string, body []string) []string {
+	label := strings.TrimSpace(lang)
+	if label == "" {
+		label = "code"
+	}
+	out := make([]string, 0, len(body)+2)
+	out = append(out, th.Muted.Render("┌─ "+label))
+	for _, l := range body {
+		out = append(out, th.Muted.Render("│ ")+highlightCodeLine(th, lang, l))
+	}
+	out = append(out, th.Muted.Render("└─"))
+	return out
 }
 
 func styleChatLine(th theme, line string) string {
@@ -1487,17 +2408,41 @@ func looksLikePath(s string) bool {
 
 func (m appModel) chatWrappedLineCount(role string, raw string) int {
 	w, _ := m.effectiveSize()
-	innerW := chatInnerWidth(w)
+	return m.chatWrappedLineCountWidth(role, raw, chatInnerWidth(w))
+}
+
+func (m appModel) chatWrappedLineCountWidth(role string, raw string, innerW int) int {
+	return len(m.cachedWrappedChatBlock(role, raw, innerW))
+}
+
+// wrapChatBlockForRole applies the role-specific prefix/indent and wraps
+// text at innerWidth; factored out so the line-count path and the render
+// path share both the wrapping logic and chatRenderCache's entries.
+func wrapChatBlockForRole(th theme, role string, text string, innerWidth int) []string {
 	switch role {
 	case "user":
-		return len(wrapChatBlock(m.th.Accent.Render("You: "), "     ", raw, innerW))
+		return wrapChatBlock(th.Accent.Render("You: "), "     ", text, innerWidth)
 	case "assistant":
-		return len(wrapChatBlock(m.th.Success.Render("AI: "), "    ", raw, innerW))
+		return wrapChatBlock(th.Success.Render("AI: "), "    ", text, innerWidth)
 	default:
-		return len(wrapChatBlock(m.th.Muted.Render("[SYSTEM] "), "         ", raw, innerW))
+		return wrapChatBlock(th.Muted.Render("[SYSTEM] "), "         ", text, innerWidth)
 	}
 }
 
+// cachedWrappedChatBlock wraps raw through wrapChatBlockForRole (after
+// markdown-ish styling), routed through chatRenderCache so repeated calls
+// for the same (role, raw, innerWidth) - every stream delta, every
+// PgUp/PgDn - skip both the styling and the wrapping on a hit.
+func (m appModel) cachedWrappedChatBlock(role string, raw string, innerWidth int) []string {
+	render := func() []string {
+		return wrapChatBlockForRole(m.th, role, styleChatContent(m.th, raw), innerWidth)
+	}
+	if m.chatRenderCache == nil {
+		return render()
+	}
+	return m.chatRenderCache.wrap(role, raw, innerWidth, render)
+}
+
 func (m appModel) updateCommandPalette(k tea.KeyMsg) (tea.Model, tea.Cmd) {
 	if k.Type == tea.KeyRunes && len(k.Runes) == 1 && k.Runes[0] == '/' && m.commandPaletteQuery == "" && m.commandPaletteNamespace == "/" {
 		// Deterministic promotion: a second '/' with an empty query always enters the system namespace.
@@ -1529,8 +2474,19 @@ func (m appModel) updateCommandPalette(k tea.KeyMsg) (tea.Model, tea.Cmd) {
 		m.commandPaletteIndex = 0
 		return m, nil
 	}
+	if k.Type == tea.KeyTab {
+		ns := m.commandPaletteNamespace
+		if ns != "//" {
+			ns = "/"
+		}
+		if names := m.cmdRegistry.Names(ns, m.commandPaletteQuery); len(names) > 0 {
+			m.commandPaletteQuery = names[0]
+			m.commandPaletteIndex = 0
+		}
+		return m, nil
+	}
 
-	items := filteredCommandPaletteItems(m.commandPaletteNamespace, m.commandPaletteQuery)
+	items := filteredCommandPaletteItems(m.cmdRegistry, m.commandPaletteNamespace, m.commandPaletteQuery, m.recentCommands)
 	if len(items) == 0 {
 		if k.Type == tea.KeyEnter {
 			m = m.closeOverlay()
@@ -1571,95 +2527,22 @@ func (m appModel) applyCommandPalette(item paletteItem) (tea.Model, tea.Cmd) {
 		m.recentCommands = m.recentCommands[len(m.recentCommands)-20:]
 	}
 
-	if ns == "//" {
-		// Record command submission for system commands too (except docker/verify which emit in their helpers with correlation IDs).
-		if item.action != "docker" && item.action != "verify" {
-			m.emitEvent("command.submitted", m.actionSource, map[string]any{"namespace": ns, "text": text}, "", "")
-		}
-		switch item.action {
-		case "provider":
-			m = m.openOverlay(overlayProviderSelect)
-			m.providerSelectIndex = 0
-			return m, nil
-		case "runtime":
-			m = m.openOverlay(overlayRuntimeSelect)
-			m.runtimeSelectIndex = 0
-			return m, nil
-		case "model":
-			m = m.openOverlay(overlayModelSelect)
-			m.modelSelectIndex = 0
-			return m, nil
-		case "auth":
-			m = m.openOverlay(overlayAuthSelect)
-			m.authSelectIndex = 0
-			return m, nil
-		case "mode":
-			if m.mode == modeA {
-				m.mode = modeB
-			} else {
-				m.mode = modeA
-			}
-			m.systemAlert(alertInfo, "mode.switched", fmt.Sprintf("Mode switched to %s", m.mode.String()), nil)
-			m = m.closeAllOverlays()
-			return m, nil
-		case "session":
-			m = m.startNewSession()
-			m = m.closeAllOverlays()
-			return m, nil
-		case "stats":
-			m = m.openOverlay(overlayStats)
-			return m, nil
-		case "docker":
-			return m.submitSystemDockerProbe("")
-		case "verify":
-			return m.submitSystemVerify(false, "")
-		case "exit":
-			return m, tea.Quit
-		default:
-			m.systemAlert(alertError, "system.command", "System command not implemented", map[string]any{"cmd": item.cmd})
-			m = m.closeAllOverlays()
-			return m, nil
-		}
+	// docker/verify emit their own command.submitted with a correlation ID
+	// from inside their Run handlers, and only when they actually submit a
+	// system request (so "//verify audit", an unknown docker subcommand, or
+	// a busy or unready executor records none); every other command is
+	// recorded here.
+	if item.cmd != "docker" && item.cmd != "verify" {
+		m.emitEvent("command.submitted", m.actionSource, map[string]any{"namespace": ns, "text": text}, "", "")
 	}
 
-	m.emitEvent("command.submitted", m.actionSource, map[string]any{"namespace": ns, "text": text}, "", "")
-
-	switch item.action {
-	case "auth":
-		m = m.openOverlay(overlayAuthSelect)
-		m.authSelectIndex = 0
-		return m, nil
-	case "exit":
-		return m, tea.Quit
-	case "model":
-		m = m.openOverlay(overlayModelSelect)
-		m.modelSelectIndex = 0
-		return m, nil
-	case "mode":
-		if m.mode == modeA {
-			m.mode = modeB
-		} else {
-			m.mode = modeA
-		}
-		m.systemAlert(alertInfo, "mode.switched", fmt.Sprintf("Mode switched to %s", m.mode.String()), nil)
-		m = m.closeAllOverlays()
-		return m, nil
-	case "stats":
-		m = m.openOverlay(overlayStats)
-		return m, nil
-	case "clear":
-		m.chatLines = []string{}
-		m.chatRoleLines = []chatRoleLine{}
-		m.chatMessages = []chatMessage{}
-		m.chatScrollOffset = 0
-		m.systemAlert(alertInfo, "chat.cleared", "Chat cleared", nil)
+	next, cmd, err := m.cmdRegistry.Dispatch(context.Background(), m, ns, item.cmd)
+	if err != nil {
+		m.systemAlert(alertError, "system.command", "Command not implemented", map[string]any{"cmd": item.cmd, "error": err.Error()})
 		m = m.closeAllOverlays()
 		return m, nil
-	default:
-		m.systemAlert(alertInfo, "command.executed", item.label, map[string]any{"cmd": item.cmd})
-		m = m.closeOverlay()
-		return m, nil
 	}
+	return next, cmd
 }
 
 func (m appModel) submitSystemVerify(full bool, correlationID string) (tea.Model, tea.Cmd) {
@@ -1734,60 +2617,147 @@ func (m appModel) sendChat(line string) (appModel, tea.Cmd) {
 	if txt == "" {
 		return m, nil
 	}
-	if m.chatInFlight {
+	splitRuntime := strings.TrimSpace(m.splitRuntime)
+	if m.chatInFlight || (splitRuntime != "" && m.chatInFlightRight) {
 		m.systemAlert(alertWarn, "chat.busy", "A chat request is already in flight", nil)
 		return m, nil
 	}
 
 	cid := newCorrelationID()
-	m.chatRoleLines = append(m.chatRoleLines, chatRoleLine{Role: "user", Text: txt})
+	m.chatMetricsStartedAt = m.now
+	m.chatMetricsTokens = 0
+	m = m.appendChatRoleLine(chatRoleLine{Role: "user", Text: txt})
 	m = m.trimChatRoleLines()
 	m.chatMessages = append(m.chatMessages, chatMessage{Role: "user", Content: txt})
+	_, _ = m.historyWAL.Append(history.KindUser, "user", txt, cid)
 	m.emitEvent("chat.send", m.actionSource, map[string]any{"text": txt, "provider": m.selectedProvider, "runtime": m.selectedRuntime, "permissionMode": m.permissionMode}, cid, "")
 
-	runtime := strings.TrimSpace(m.selectedRuntime)
-	provider := m.selectedProviderLabel()
-	compatLabel := getCompatibilityLabel(provider, runtime)
+	var rightCID string
+	excludeProfile := ""
+	if splitRuntime != "" {
+		rightCID = newCorrelationID()
+		m.chatRoleLinesRight = append(m.chatRoleLinesRight, chatRoleLine{Role: "user", Text: txt})
+		m.chatMessagesRight = append(m.chatMessagesRight, chatMessage{Role: "user", Content: txt})
+		m.emitEvent("chat.send", m.actionSource, map[string]any{"text": txt, "provider": m.selectedProvider, "runtime": splitRuntime, "side": "right"}, rightCID, "")
+		excludeProfile = m.chatActiveProfile
+	}
+
+	var leftCmd tea.Cmd
+	m, leftCmd = m.dispatchChatRuntime(cid, txt, m.selectedRuntime, "", "")
+	if splitRuntime == "" {
+		return m, leftCmd
+	}
 
-	// Claude Code runtime (native TTY)
-	if runtime == "claude-code" {
-		m.systemAlert(alertWarn, "claude.native.surface", "Claude Code is a native TTY surface and is not wired into this managed cockpit yet", map[string]any{"hint": "Run `claude` in a terminal (or use tmux integration via other UI surfaces)"})
+	var rightCmd tea.Cmd
+	m, rightCmd = m.dispatchChatRuntime(rightCID, txt, splitRuntime, "right", excludeProfile)
+	if leftCmd == nil && rightCmd == nil {
 		return m, nil
 	}
+	return m, tea.Batch(leftCmd, rightCmd)
+}
 
-	// Direct API runtime - not implemented (requires API keys we don't have)
-	if runtime == "direct-api" {
-		m.systemAlert(alertWarn, "direct.api.wip", "Direct API runtime is not yet implemented", map[string]any{"hint": "Use Codex – Chat Mode or Codex – CLI Mode instead"})
+// dispatchChatRuntime submits txt to runtime for one split-screen pane
+// ("" = left, "right" = the splitRuntime pane). codex-cli and opencode-run
+// stream through this process' own executor bus (one request/response pair
+// per session) rather than a parallel per-pane call, so they can only run
+// as the left pane for now; codex-chat talks to the OAuth API directly and
+// so is the one runtime dispatchCodexChat can run twice in parallel,
+// letting splitRuntime pair two different OAuth profiles against the same
+// prompt (excludeProfile keeps the right pane off whatever profile the
+// left pane just picked).
+func (m appModel) dispatchChatRuntime(cid string, txt string, runtime string, side string, excludeProfile string) (appModel, tea.Cmd) {
+	runtime = strings.TrimSpace(runtime)
+	handler, ok := m.runtimeHandlers.Lookup(runtime)
+	if !ok {
+		provider := m.selectedProviderLabel()
+		compatLabel := m.getCompatibilityLabel(provider, runtime)
+		m.systemAlert(alertWarn, "chat.unavailable", fmt.Sprintf("Runtime '%s' with provider '%s' is not wired in the managed cockpit yet", runtime, provider), map[string]any{"provider": provider, "runtime": runtime, "compatibility": compatLabel, "side": sideLabel(side)})
 		return m, nil
 	}
+	return handler(context.Background(), m, providers.Turn{CorrelationID: cid, Text: txt, Side: side, ExcludeProfile: excludeProfile})
+}
+
+// registerRuntimeHandlers builds the runtime ID -> Handler table
+// dispatchChatRuntime looks up against, one binding per entry in
+// providers.NewRegistry's Runtimes(). Adding a runtime that needs genuinely
+// new dispatch logic (rather than just new registry metadata) still means
+// adding a case here - this removes the if/else chain's string matching,
+// not the per-runtime Go code behind it.
+func registerRuntimeHandlers() *providers.Handlers[appModel] {
+	h := providers.NewHandlers[appModel]()
+
+	h.Register("claude-code", func(_ context.Context, m appModel, turn providers.Turn) (appModel, tea.Cmd) {
+		m.systemAlert(alertWarn, "claude.native.surface", "Claude Code is a native TTY surface and is not wired into this managed cockpit yet", map[string]any{"hint": "Run `claude` in a terminal (or use tmux integration via other UI surfaces)", "side": sideLabel(turn.Side)})
+		return m, nil
+	})
 
-	// Codex CLI Mode - uses local Codex executor for file edits
-	if runtime == "codex-cli" {
+	h.Register("direct-api", func(_ context.Context, m appModel, turn providers.Turn) (appModel, tea.Cmd) {
+		m.systemAlert(alertWarn, "direct.api.wip", "Direct API runtime is not yet implemented", map[string]any{"hint": "Use Codex – Chat Mode or Codex – CLI Mode instead", "side": sideLabel(turn.Side)})
+		return m, nil
+	})
+
+	h.Register("codex-cli", func(_ context.Context, m appModel, turn providers.Turn) (appModel, tea.Cmd) {
+		if turn.Side == "right" {
+			m.systemAlert(alertWarn, "chat.split.unavailable", "Codex – CLI Mode can't run as the split-screen right pane yet (it streams through this session's single executor bus, not a parallel call)", map[string]any{"hint": "pick codex-chat for the right pane"})
+			return m, nil
+		}
+		compatLabel := m.getCompatibilityLabel(m.selectedProviderLabel(), "codex-cli")
 		ready := m.codexExecutorReady || isCodexExecutorReady(m.cfg.stateDir, m.sessionID, m.now)
 		if ready {
 			m.codexExecutorReady = true
 			m.chatInFlight = true
-			m.chatCorrelationID = cid
+			m.chatCorrelationID = turn.CorrelationID
 			m.chatActiveProfile = m.oauthPool.ActiveProfile
 			m.chatStreamText = ""
 			model := codexModelForSelection(strings.TrimSpace(m.selectedModel))
-			cwd := extractCwdFromPrompt(txt)
+			cwd := extractCwdFromPrompt(turn.Text)
 			if strings.TrimSpace(cwd) == "" {
 				cwd = "."
 			}
-			_ = appendCodexRequest(m.codexRequestsPath, codexTurnRequest{
-				Version:       1,
-				Type:          "turn",
-				CorrelationID: cid,
-				Prompt:        txt,
-				Cwd:           cwd,
-				Model:         model,
-				NoShell:       false,
-				Think:         m.thoughtStream,
-				PermissionMode: strings.ToLower(strings.TrimSpace(m.permissionMode)),
-			})
+			permissionMode := strings.ToLower(strings.TrimSpace(m.permissionMode))
 			m.systemAlert(alertInfo, "codex.cli.turn", "Submitted to Codex CLI", map[string]any{"cwd": cwd, "model": model, "think": m.thoughtStream, "permissionMode": m.permissionMode, "runtime": "codex-cli", "compatibility": compatLabel})
-			return m, nil
+			executorURL := executorURLFromEnv()
+			if strings.TrimSpace(executorURL) == "" {
+				_ = appendCodexRequest(m.codexRequestsPath, codexTurnRequest{
+					Version:        1,
+					Type:           "turn",
+					CorrelationID:  turn.CorrelationID,
+					Prompt:         turn.Text,
+					Cwd:            cwd,
+					Model:          model,
+					NoShell:        false,
+					Think:          m.thoughtStream,
+					PermissionMode: permissionMode,
+				})
+				return m, nil
+			}
+			// WORKBENCH_EXECUTOR_URL selects a stdio/http backend: submit
+			// through the Executor interface and fold its TurnEvent/TurnResult
+			// channels back onto codex.events.jsonl/codex.responses.jsonl so
+			// the existing file-tailing consumeCodexEvents loop keeps working
+			// unchanged regardless of which backend produced them.
+			stateDir, sessionID := m.cfg.stateDir, m.sessionID
+			eventsPath, responsesPath := m.codexEventsPath, m.codexResponsesPath
+			turnReq := TurnRequest{
+				CorrelationID:  turn.CorrelationID,
+				Prompt:         turn.Text,
+				Cwd:            cwd,
+				Model:          model,
+				Think:          m.thoughtStream,
+				PermissionMode: permissionMode,
+			}
+			return m, func() tea.Msg {
+				executor, err := cachedCodexExecutor(executorURL, stateDir, sessionID)
+				if err != nil {
+					return nil
+				}
+				events, results, err := executor.SubmitTurn(context.Background(), turnReq)
+				if err != nil {
+					return nil
+				}
+				bridgeCodexExecutorTurn(events, results, eventsPath, responsesPath)
+				return nil
+			}
 		}
 		diag := codexExecutorDiagnostic(m.cfg.stateDir, m.sessionID, m.now)
 		if diag == "" {
@@ -1795,134 +2765,313 @@ func (m appModel) sendChat(line string) (appModel, tea.Cmd) {
 		}
 		m.systemAlert(alertError, "codex.cli.unavailable", diag, map[string]any{"hint": "switch runtime to Codex – Chat Mode if this persists"})
 		return m, nil
-	}
+	})
 
-	// OpenCode Run Mode - uses host-side OpenCode executor (streams tool/step events)
-	if runtime == "opencode-run" {
+	h.Register("opencode-run", func(_ context.Context, m appModel, turn providers.Turn) (appModel, tea.Cmd) {
+		if turn.Side == "right" {
+			m.systemAlert(alertWarn, "chat.split.unavailable", "OpenCode – Run Mode can't run as the split-screen right pane yet (it streams through this session's single executor bus, not a parallel call)", map[string]any{"hint": "pick codex-chat for the right pane"})
+			return m, nil
+		}
+		provider := m.selectedProviderLabel()
+		compatLabel := m.getCompatibilityLabel(provider, "opencode-run")
 		ready := m.opencodeExecutorReady || isOpencodeExecutorReady(m.cfg.stateDir, m.sessionID, m.now)
 		if ready {
 			m.opencodeExecutorReady = true
 			m.chatInFlight = true
-			m.chatCorrelationID = cid
+			m.chatCorrelationID = turn.CorrelationID
 			m.chatActiveProfile = ""
 			m.chatStreamText = ""
 			model := opencodeModelForSelection(provider, strings.TrimSpace(m.selectedModel))
 			agent := opencodeAgent()
 			think := m.thoughtStream
-			cwd := extractCwdFromPrompt(txt)
+			cwd := extractCwdFromPrompt(turn.Text)
 			if strings.TrimSpace(cwd) == "" {
 				cwd = "."
 			}
-			_ = appendOpencodeRequest(m.opencodeRequestsPath, opencodeTurnRequest{
-				Version:       1,
-				Type:          "turn",
-				CorrelationID: cid,
-				Prompt:        txt,
-				Cwd:           cwd,
-				Model:         model,
-				Agent:         agent,
-				Think:         think,
+			bus := newOpencodeBusClient(m.cfg.stateDir, m.sessionID, m.opencodeRequestsPath)
+			req := opencodeTurnRequest{
+				Version:        1,
+				Type:           "turn",
+				CorrelationID:  turn.CorrelationID,
+				Prompt:         turn.Text,
+				Cwd:            cwd,
+				Model:          model,
+				Agent:          agent,
+				Think:          think,
 				PermissionMode: strings.ToLower(strings.TrimSpace(m.permissionMode)),
-			})
+			}
 			m.systemAlert(alertInfo, "opencode.run.turn", "Submitted to OpenCode", map[string]any{"cwd": cwd, "model": model, "agent": agent, "think": think, "permissionMode": m.permissionMode, "runtime": "opencode-run", "compatibility": compatLabel})
-			return m, nil
+			return m, func() tea.Msg {
+				defer bus.Close()
+				_ = bus.SubmitTurn(context.Background(), req)
+				return nil
+			}
 		}
 		m.systemAlert(alertError, "opencode.run.unavailable", "OpenCode executor not ready", map[string]any{"hint": "Install `opencode` and restart workbench, or switch runtime"})
 		return m, nil
-	}
+	})
 
-	// Codex Chat Mode - uses OAuth API (chat-only, no file edits)
-	if runtime == "codex-chat" {
-		p, ok := m.activeOAuthProfile()
-		if !ok {
-			m.systemAlert(alertError, "auth.pool.empty", "OpenAI OAuth pool is empty/unavailable", nil)
+	h.Register("codex-chat", func(_ context.Context, m appModel, turn providers.Turn) (appModel, tea.Cmd) {
+		return m.dispatchCodexChat(turn.CorrelationID, turn.Text, turn.Side, turn.ExcludeProfile)
+	})
+
+	// MCP Agent - invokes a tool on a connected MCP server, gated by
+	// permissionMode. Like codex-cli/opencode-run, it streams through this
+	// session's single codex bus rather than a parallel per-pane call.
+	h.Register("mcp-agent", func(_ context.Context, m appModel, turn providers.Turn) (appModel, tea.Cmd) {
+		if turn.Side == "right" {
+			m.systemAlert(alertWarn, "chat.split.unavailable", "MCP Agent can't run as the split-screen right pane yet (it streams through this session's single codex bus, not a parallel call)", map[string]any{"hint": "pick codex-chat for the right pane"})
 			return m, nil
 		}
-		m.chatInFlight = true
-		m.chatCorrelationID = cid
-		m.chatActiveProfile = p.Profile
-		m.chatStreamText = ""
-		ctx, cancel := context.WithCancel(context.Background())
-		m.chatCancel = cancel
+		return m.dispatchMCPAgent(turn.CorrelationID, turn.Text)
+	})
 
-		endpoint := strings.TrimSpace(m.oauthPool.CodexEndpoint)
-		model := codexModelForSelection(strings.TrimSpace(m.selectedModel))
-		instructions := strings.TrimSpace(os.Getenv("WORKBENCH_SYSTEM_PROMPT"))
-		msgs := append([]chatMessage{}, m.chatMessages...)
-		eventsPath := m.codexEventsPath
+	return h
+}
 
-		m.emitEvent("llm.request", "system", map[string]any{"provider": "openai-oauth-codex", "model": model, "profile": p.Profile, "runtime": "codex-chat"}, cid, "")
-		return m, func() tea.Msg {
-			if m.thoughtStream {
-				planInstructions := strings.TrimSpace(instructions + "\n\nYou are in planning mode. Output only a concise bullet plan of steps. Do not produce the final answer.")
-				planText, planStatusErr, planErr := codexChatOnce(ctx, endpoint, model, p.accessToken, p.accountID, planInstructions, msgs)
-				if planErr != nil {
-					cancelled := errorsIsContextCanceled(planErr)
-					return chatReplyMsg{CorrelationID: cid, Provider: "openai-oauth-codex", Profile: p.Profile, Error: planErr.Error(), Cancelled: cancelled, Attempt: 0}
-				}
-				if planStatusErr != nil {
-					return chatReplyMsg{
-						CorrelationID: cid,
-						Provider:      "openai-oauth-codex",
-						Profile:       p.Profile,
-						Error:         planStatusErr.Error(),
-						Status:        planStatusErr.Status,
-						RetryAfterMs:  planStatusErr.RetryAfterMs,
-						Cancelled:     false,
-						Attempt:       0,
-					}
-				}
-				for _, line := range strings.Split(planText, "\n") {
-					l := strings.TrimSpace(line)
-					if l == "" {
-						continue
-					}
-					_ = appendCodexEvent(eventsPath, codexTurnEvent{
-						Version:       1,
-						Type:          "turn.event",
-						CorrelationID: cid,
-						At:            time.Now().UTC().Format(time.RFC3339Nano),
-						Kind:          "think",
-						Message:       l,
-					})
-				}
+// planStep is one step of a thought-stream plan revision, per the strict
+// JSON schema runThoughtStreamPlan's planning prompt is instructed to
+// reply with.
+type planStep struct {
+	ID        string `json:"id"`
+	Action    string `json:"action"`
+	Rationale string `json:"rationale"`
+}
+
+// planRevision is the strict JSON shape the planning prompt must reply
+// with. NeedsMoreInfo, or any step with Action == "tool", keeps
+// runThoughtStreamPlan's loop going for another revision.
+type planRevision struct {
+	Steps         []planStep `json:"steps"`
+	NeedsMoreInfo bool       `json:"needs_more_info"`
+}
+
+// planMaxSteps caps runThoughtStreamPlan's ReAct iterations (env
+// WORKBENCH_PLAN_MAX_STEPS, default 4) so a model that keeps asking for
+// more info can't stall the turn indefinitely.
+func planMaxSteps() int {
+	max := 4
+	if v := strings.TrimSpace(os.Getenv("WORKBENCH_PLAN_MAX_STEPS")); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			max = n
+		}
+	}
+	return max
+}
+
+// stripJSONFence trims a leading/trailing ``` or ```json code fence off s,
+// since planning replies sometimes wrap their JSON in one despite being
+// told not to.
+func stripJSONFence(s string) string {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "```") {
+		return s
+	}
+	s = strings.TrimPrefix(s, "```json")
+	s = strings.TrimPrefix(s, "```")
+	s = strings.TrimSuffix(s, "```")
+	return strings.TrimSpace(s)
+}
+
+// runThoughtStreamPlan drives the bounded ReAct-style planning loop that
+// replaced dispatchCodexChat's old single-shot bullet plan: each revision
+// must reply in the strict JSON planRevision schema; a revision whose
+// NeedsMoreInfo is true, or that contains an action:"tool" step, has its
+// steps appended to msgs as a synthetic assistant turn and is re-prompted,
+// up to planMaxSteps revisions. Every well-formed revision is persisted as
+// its own turn.event (Kind "plan.revision", Message the revision's JSON
+// tagged with a monotonic "index") so the UI can render collapsible plan
+// history; continuing past a revision additionally emits a Kind "plan"
+// event. A revision that fails to parse as JSON falls back to the old
+// bullet-plan behavior (split into "think" events) for that revision, logs
+// a Kind "plan.malformed" event, and ends the loop early. Returns the
+// (possibly plan-augmented) messages to finalize with codexChatStream, or
+// a non-nil chatReplyMsg if a transport/status error means the turn should
+// stop here instead (Profile is left for the caller to fill in).
+func runThoughtStreamPlan(ctx context.Context, endpoint, model, accessToken, accountID, instructions string, msgs []chatMessage, eventsPath, cid, side string) ([]chatMessage, *chatReplyMsg) {
+	planInstructions := strings.TrimSpace(instructions + "\n\nYou are in planning mode. Respond with strict JSON only, no prose or code fences: {\"steps\":[{\"id\":string,\"action\":string,\"rationale\":string}],\"needs_more_info\":bool}. Set needs_more_info true, or give a step action of \"tool\", if you need another planning pass before the final answer.")
+
+	for index := 0; index < planMaxSteps(); index++ {
+		planText, planStatusErr, planErr := codexChatOnce(ctx, endpoint, model, accessToken, accountID, planInstructions, msgs)
+		if planErr != nil {
+			cancelled := errorsIsContextCanceled(planErr)
+			return msgs, &chatReplyMsg{CorrelationID: cid, Provider: "openai-oauth-codex", Error: planErr.Error(), Cancelled: cancelled, Side: side}
+		}
+		if planStatusErr != nil {
+			return msgs, &chatReplyMsg{
+				CorrelationID: cid,
+				Provider:      "openai-oauth-codex",
+				Error:         planStatusErr.Error(),
+				Status:        planStatusErr.Status,
+				RetryAfterMs:  planStatusErr.RetryAfterMs,
+				Side:          side,
 			}
+		}
 
-			text, statusErr, err := codexChatStream(ctx, endpoint, model, p.accessToken, p.accountID, instructions, msgs, func(delta string) {
-				if strings.TrimSpace(delta) == "" {
-					return
+		var plan planRevision
+		if err := json.Unmarshal([]byte(stripJSONFence(planText)), &plan); err != nil {
+			_ = appendCodexEvent(eventsPath, codexTurnEvent{
+				Version:       1,
+				Type:          "turn.event",
+				CorrelationID: cid,
+				At:            time.Now().UTC().Format(time.RFC3339Nano),
+				Kind:          "plan.malformed",
+				Message:       planText,
+			})
+			for _, line := range strings.Split(planText, "\n") {
+				l := strings.TrimSpace(line)
+				if l == "" {
+					continue
 				}
 				_ = appendCodexEvent(eventsPath, codexTurnEvent{
 					Version:       1,
 					Type:          "turn.event",
 					CorrelationID: cid,
 					At:            time.Now().UTC().Format(time.RFC3339Nano),
-					Kind:          "delta",
-					Message:       delta,
+					Kind:          "think",
+					Message:       l,
 				})
-			})
-			if err != nil {
-				cancelled := errorsIsContextCanceled(err)
-				return chatReplyMsg{CorrelationID: cid, Provider: "openai-oauth-codex", Profile: p.Profile, Error: err.Error(), Cancelled: cancelled, Attempt: 0}
 			}
-			if statusErr != nil {
-				return chatReplyMsg{
-					CorrelationID: cid,
-					Provider:      "openai-oauth-codex",
-					Profile:       p.Profile,
-					Error:         statusErr.Error(),
-					Status:        statusErr.Status,
-					RetryAfterMs:  statusErr.RetryAfterMs,
-					Cancelled:     false,
-					Attempt:       0,
-				}
+			return msgs, nil
+		}
+
+		revisionPayload, _ := json.Marshal(struct {
+			Index int `json:"index"`
+			planRevision
+		}{Index: index, planRevision: plan})
+		_ = appendCodexEvent(eventsPath, codexTurnEvent{
+			Version:       1,
+			Type:          "turn.event",
+			CorrelationID: cid,
+			At:            time.Now().UTC().Format(time.RFC3339Nano),
+			Kind:          "plan.revision",
+			Message:       string(revisionPayload),
+		})
+
+		needsMore := plan.NeedsMoreInfo
+		for _, s := range plan.Steps {
+			if strings.EqualFold(s.Action, "tool") {
+				needsMore = true
+			}
+		}
+		if !needsMore {
+			return msgs, nil
+		}
+
+		_ = appendCodexEvent(eventsPath, codexTurnEvent{
+			Version:       1,
+			Type:          "turn.event",
+			CorrelationID: cid,
+			At:            time.Now().UTC().Format(time.RFC3339Nano),
+			Kind:          "plan",
+			Message:       fmt.Sprintf("Revision %d needs another planning pass; re-prompting with accumulated steps", index),
+		})
+		msgs = append(msgs, chatMessage{Role: "assistant", Content: string(revisionPayload)})
+	}
+	return msgs, nil
+}
+
+// dispatchCodexChat submits txt to the OAuth-backed codex-chat runtime for
+// one pane. When excludeProfile is set (the split-screen right pane), it
+// prefers the next-ranked non-limited profile other than excludeProfile so
+// the two panes compare two different accounts instead of racing the same
+// one; it falls back to the ordinary active-profile pick if none is free.
+func (m appModel) dispatchCodexChat(cid string, txt string, side string, excludeProfile string) (appModel, tea.Cmd) {
+	right := side == "right"
+
+	var p oauthPoolProfile
+	ok := false
+	if strings.TrimSpace(excludeProfile) != "" {
+		p, ok = m.pickOAuthProfile(m.oauthPool.Ranked, excludeProfile)
+	}
+	if !ok {
+		p, ok = m.activeOAuthProfile()
+	}
+	if !ok {
+		m.systemAlert(alertError, "auth.pool.empty", "OpenAI OAuth pool is empty/unavailable", map[string]any{"side": sideLabel(side)})
+		return m, nil
+	}
+
+	if right {
+		m.chatInFlightRight = true
+		m.chatCorrelationIDRight = cid
+		m.chatActiveProfileRight = p.Profile
+		m.chatStreamTextRight = ""
+	} else {
+		m.chatInFlight = true
+		m.chatCorrelationID = cid
+		m.chatActiveProfile = p.Profile
+		m.chatStreamText = ""
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	if right {
+		m.chatCancelRight = cancel
+	} else {
+		m.chatCancel = cancel
+	}
+
+	endpoint := strings.TrimSpace(m.oauthPool.CodexEndpoint)
+	model := codexModelForSelection(strings.TrimSpace(m.selectedModel))
+	instructions := strings.TrimSpace(os.Getenv("WORKBENCH_SYSTEM_PROMPT"))
+	msgs := m.chatMessages
+	if right {
+		msgs = m.chatMessagesRight
+	}
+	msgs = append([]chatMessage{}, msgs...)
+	eventsPath := m.codexEventsPath
+	thoughtStream := m.thoughtStream
+	audit := m.audit
+
+	m.emitEvent("llm.request", "system", map[string]any{"provider": "openai-oauth-codex", "model": model, "profile": p.Profile, "runtime": "codex-chat", "side": sideLabel(side)}, cid, "")
+	audit.Append("codex.request", "system", map[string]any{"provider": "openai-oauth-codex", "model": model, "profile": p.Profile, "runtime": "codex-chat", "side": sideLabel(side)}, cid)
+	return m, func() tea.Msg {
+		if thoughtStream {
+			planned, early := runThoughtStreamPlan(ctx, endpoint, model, p.accessToken, p.accountID, instructions, msgs, eventsPath, cid, side)
+			if early != nil {
+				early.Profile = p.Profile
+				return *early
+			}
+			msgs = planned
+		}
+
+		res, statusErr, err := codexChatStreamDetailed(ctx, endpoint, model, p.accessToken, p.accountID, instructions, msgs, func(delta string) {
+			if strings.TrimSpace(delta) == "" {
+				return
+			}
+			_ = appendCodexEvent(eventsPath, codexTurnEvent{
+				Version:       1,
+				Type:          "turn.event",
+				CorrelationID: cid,
+				At:            time.Now().UTC().Format(time.RFC3339Nano),
+				Kind:          "delta",
+				Message:       delta,
+			})
+		})
+		if err != nil {
+			cancelled := errorsIsContextCanceled(err)
+			audit.Append("codex.response", "system", map[string]any{"error": err.Error(), "cancelled": cancelled, "clientAttempts": res.Attempts}, cid)
+			return chatReplyMsg{CorrelationID: cid, Provider: "openai-oauth-codex", Profile: p.Profile, Error: err.Error(), Cancelled: cancelled, Attempt: 0, Side: side}
+		}
+		if statusErr != nil {
+			audit.Append("codex.response", "system", map[string]any{"error": statusErr.Error(), "status": statusErr.Status, "clientAttempts": res.Attempts}, cid)
+			return chatReplyMsg{
+				CorrelationID: cid,
+				Provider:      "openai-oauth-codex",
+				Profile:       p.Profile,
+				Error:         statusErr.Error(),
+				Status:        statusErr.Status,
+				RetryAfterMs:  statusErr.RetryAfterMs,
+				Cancelled:     false,
+				Attempt:       0,
+				Side:          side,
 			}
-			return chatReplyMsg{CorrelationID: cid, Provider: "openai-oauth-codex", Profile: p.Profile, Text: text, Attempt: 0}
 		}
+		if res.Attempts > 1 || res.HedgedWon {
+			audit.Append("codex.retry", "system", map[string]any{"clientAttempts": res.Attempts, "hedgedWon": res.HedgedWon}, cid)
+		}
+		audit.Append("codex.response", "system", map[string]any{"text": res.Text, "clientAttempts": res.Attempts}, cid)
+		return chatReplyMsg{CorrelationID: cid, Provider: "openai-oauth-codex", Profile: p.Profile, Text: res.Text, Attempt: 0, Side: side}
 	}
-
-	m.systemAlert(alertWarn, "chat.unavailable", fmt.Sprintf("Runtime '%s' with provider '%s' is not wired in the managed cockpit yet", runtime, provider), map[string]any{"provider": provider, "runtime": runtime, "compatibility": compatLabel})
-	return m, nil
 }
 
 func (m appModel) updateModelSelect(k tea.KeyMsg) (tea.Model, tea.Cmd) {
@@ -2000,6 +3149,14 @@ func (m appModel) updateQuickActions(k tea.KeyMsg) (tea.Model, tea.Cmd) {
 			m.systemAlert(alertInfo, "thought_stream.toggled", "Thought stream "+status, map[string]any{"enabled": m.thoughtStream})
 			m = m.closeOverlay()
 			return m, nil
+		case "Browse Snapshots":
+			m.snapshotBrowserItems = listSnapshots(m.cfg.stateDir)
+			m.snapshotBrowserIndex = 0
+			m.snapshotBrowserMarkedHash = ""
+			m.snapshotBrowserDiffLines = nil
+			m = m.closeOverlay()
+			m = m.openOverlay(overlaySnapshotBrowser)
+			return m, nil
 		default:
 			m.systemAlert(alertInfo, "quick_action", item, nil)
 			m = m.closeOverlay()
@@ -2031,6 +3188,14 @@ func (m appModel) updateQuitConfirm(k tea.KeyMsg) (tea.Model, tea.Cmd) {
 }
 
 func (m appModel) updateAuthSelect(k tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.authPrompt.Active {
+		return m.updateAuthPrompt(k)
+	}
+	if k.Type == tea.KeyRunes && string(k.Runes) == "n" {
+		m = m.openAuthPrompt()
+		return m, nil
+	}
+
 	profiles := orderOAuthProfilesForDisplay(m.oauthPool.Profiles)
 	if len(profiles) == 0 {
 		m.systemAlert(alertWarn, "auth.pool.empty", "OAuth pool is empty", nil)
@@ -2091,7 +3256,7 @@ func (m appModel) updateSystemInfo(k tea.KeyMsg) (tea.Model, tea.Cmd) {
 }
 
 func (m appModel) updateProviderSelect(k tea.KeyMsg) (tea.Model, tea.Cmd) {
-	opts := providerOptions()
+	opts := m.providerOptions()
 	if len(opts) == 0 {
 		m = m.closeAllOverlays()
 		return m, nil
@@ -2113,53 +3278,29 @@ func (m appModel) updateProviderSelect(k tea.KeyMsg) (tea.Model, tea.Cmd) {
 		}
 		m.providerSelected = m.providerSelectIndex
 		m.selectedProvider = opts[m.providerSelectIndex]
-		m.selectedRuntime = defaultRuntimeForProvider(m.selectedProvider)
-		compat := getCompatibilityLabel(m.selectedProvider, m.selectedRuntime)
+		m.selectedRuntime = m.defaultRuntimeForProvider(m.selectedProvider)
+		compat := m.getCompatibilityLabel(m.selectedProvider, m.selectedRuntime)
 		m.systemAlert(alertInfo, "provider.set", fmt.Sprintf("Provider set to %s (%s)", m.selectedProvider, compat), map[string]any{"provider": m.selectedProvider, "runtime": m.selectedRuntime, "compatibility": compat})
 		m = m.closeAllOverlays()
 	}
 	return m, nil
 }
 
-type runtimeOption struct {
-	ID    string
-	Label string
-}
+// runtimeOption is a runtime as offered for a specific provider: ID plus a
+// display label annotated with "[proxy]" when it needs one.
+type runtimeOption = providers.RuntimeOption
 
 // runtimeOptionsForProvider returns all unified runtime options with compatibility info
-func runtimeOptionsForProvider(providerLabel string) []runtimeOption {
-	unified := runtimeOptionsUnified()
-	opts := make([]runtimeOption, 0, len(unified))
-	for _, u := range unified {
-		compat := getCompatibility(providerLabel, u.ID)
-		label := u.Label
-		if compat == compatProxy {
-			label = label + " [proxy]"
-		}
-		opts = append(opts, runtimeOption{ID: u.ID, Label: label})
-	}
-	return opts
-}
-
-func defaultRuntimeForProvider(providerLabel string) string {
-	p := strings.ToLower(strings.TrimSpace(providerLabel))
-	switch {
-	case strings.Contains(p, "anthropic"):
-		return "claude-code"
-	case strings.Contains(p, "openai"):
-		return "codex-cli"
-	default:
-		return "direct-api"
-	}
+func (m appModel) runtimeOptionsForProvider(providerLabel string) []runtimeOption {
+	return m.providerRegistry.RuntimesForProvider(providerLabel)
 }
 
-// Deprecated: use defaultRuntimeForProvider
-func defaultRuntimeForProviderLabel(providerLabel string) string {
-	return defaultRuntimeForProvider(providerLabel)
+func (m appModel) defaultRuntimeForProvider(providerLabel string) string {
+	return m.providerRegistry.DefaultRuntime(providerLabel)
 }
 
 func (m appModel) updateRuntimeSelect(k tea.KeyMsg) (tea.Model, tea.Cmd) {
-	unified := runtimeOptionsUnified()
+	unified := m.runtimeOptionsUnified()
 	if len(unified) == 0 {
 		m = m.closeAllOverlays()
 		return m, nil
@@ -2176,13 +3317,63 @@ func (m appModel) updateRuntimeSelect(k tea.KeyMsg) (tea.Model, tea.Cmd) {
 	case tea.KeyEnter:
 		selected := unified[m.runtimeSelectIndex]
 		m.selectedRuntime = selected.ID
-		compat := getCompatibilityLabel(m.selectedProvider, m.selectedRuntime)
+		compat := m.getCompatibilityLabel(m.selectedProvider, m.selectedRuntime)
 		m.systemAlert(alertInfo, "runtime.set", fmt.Sprintf("Runtime set to %s (%s)", selected.Label, compat), map[string]any{"runtime": m.selectedRuntime, "compatibility": compat})
 		m = m.closeAllOverlays()
 	}
 	return m, nil
 }
 
+// updateSessionBrowser drives the overlaySessionBrowser fuzzy-search input:
+// typing filters sessionBrowserSessions via filteredSessionSummaries,
+// Up/Down moves the highlighted row (whose last few turns the preview pane
+// shows), and Enter resumes it via resumeSession.
+func (m appModel) updateSessionBrowser(k tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if k.Type == tea.KeyBackspace {
+		if len(m.sessionBrowserQuery) == 0 {
+			m = m.closeOverlay()
+			return m, nil
+		}
+		m.sessionBrowserQuery = m.sessionBrowserQuery[:len(m.sessionBrowserQuery)-1]
+		m.sessionBrowserIndex = 0
+		return m, nil
+	}
+	if k.Type == tea.KeyRunes && len(k.Runes) > 0 {
+		m.sessionBrowserQuery += string(k.Runes)
+		m.sessionBrowserIndex = 0
+		return m, nil
+	}
+	if k.Type == tea.KeySpace {
+		m.sessionBrowserQuery += " "
+		m.sessionBrowserIndex = 0
+		return m, nil
+	}
+
+	items := filteredSessionSummaries(m.sessionBrowserSessions, m.sessionBrowserQuery)
+	if len(items) == 0 {
+		if k.Type == tea.KeyEnter {
+			m = m.closeOverlay()
+		}
+		return m, nil
+	}
+
+	switch k.Type {
+	case tea.KeyUp:
+		if m.sessionBrowserIndex > 0 {
+			m.sessionBrowserIndex--
+		}
+	case tea.KeyDown:
+		if m.sessionBrowserIndex < len(items)-1 {
+			m.sessionBrowserIndex++
+		}
+	case tea.KeyEnter:
+		selected := items[m.sessionBrowserIndex]
+		m = m.resumeSession(selected.ID)
+		m = m.closeAllOverlays()
+	}
+	return m, nil
+}
+
 func (m appModel) View() string {
 	w, h := m.effectiveSize()
 	// If the terminal is extremely small, render a stable hint instead of a broken layout.
@@ -2246,7 +3437,7 @@ func (m appModel) viewProviderConfig() string {
 		"",
 	}
 
-	opts := providerOptions()
+	opts := m.providerOptions()
 	if m.mode == modeA {
 		lines = append(lines, m.th.Accent.Render("Mode A: Delegator + Executor"))
 		lines = append(lines, renderProviderList(m.th, "Delegator", opts, m.providerSelectedA, m.providerFocus == 0))
@@ -2302,6 +3493,14 @@ func (m appModel) viewCockpit() string {
 		return renderOverlay(m.th, base, m.viewProviderSelect())
 	case overlayRuntimeSelect:
 		return renderOverlay(m.th, base, m.viewRuntimeSelect())
+	case overlaySessionBrowser:
+		return renderOverlay(m.th, base, m.viewSessionBrowser())
+	case overlayMCPServers:
+		return renderOverlay(m.th, base, m.viewMCPServers())
+	case overlayToolApprove:
+		return renderOverlay(m.th, base, m.viewToolApprove())
+	case overlaySnapshotBrowser:
+		return renderOverlay(m.th, base, m.viewSnapshotBrowser())
 	}
 	return base
 }
@@ -2340,6 +3539,17 @@ func (m appModel) viewStatusBar(width int) string {
 	if m.lastOAuthProfile != "" {
 		parts = append(parts, fmt.Sprintf("OAuth:%s", m.lastOAuthProfile))
 	}
+	if m.chatInFlight && !m.chatMetricsStartedAt.IsZero() {
+		elapsed := m.now.Sub(m.chatMetricsStartedAt)
+		rate := 0.0
+		if elapsed > 0 {
+			rate = float64(m.chatMetricsTokens) / elapsed.Seconds()
+		}
+		parts = append(parts, fmt.Sprintf("%.1ftok/s", rate), elapsed.Round(time.Second).String())
+	}
+	if m.chatCancelPending {
+		parts = append(parts, "⏸ cancelling…")
+	}
 
 	line := strings.Join(parts, " │ ")
 	return m.th.Muted.Width(width).Render(line)
@@ -2378,20 +3588,35 @@ func (m appModel) chatStreamDisplayText() string {
 }
 
 func (m appModel) chatHistoryLinesWrapped(innerWidth int) []string {
-	out := make([]string, 0, len(m.chatRoleLines)*4)
-	for _, e := range m.chatRoleLines {
-		text := styleChatContent(m.th, e.Text)
-		switch e.Role {
-		case "user":
-			out = append(out, wrapChatBlock(m.th.Accent.Render("You: "), "     ", text, innerWidth)...)
-		case "assistant":
-			out = append(out, wrapChatBlock(m.th.Success.Render("AI: "), "    ", text, innerWidth)...)
-		default:
-			out = append(out, wrapChatBlock(m.th.Muted.Render("[SYSTEM] "), "         ", text, innerWidth)...)
+	return m.chatHistoryLinesWrappedFor(m.chatRoleLines, m.chatStreamText, m.chatInFlight, innerWidth)
+}
+
+// chatHistoryLinesWrappedFor renders one pane's scrollback: lines is that
+// pane's role lines, streamText/inFlight its in-progress reply, if any.
+// chatHistoryLinesWrapped (the left/default pane) and viewChatSplit's right
+// pane both go through this so wrapping/styling can't drift between them.
+func (m appModel) chatHistoryLinesWrappedFor(lines []chatRoleLine, streamText string, inFlight bool, innerWidth int) []string {
+	out := make([]string, 0, len(lines)*4)
+	focusIdx := -1
+	if m.chatSearchActive && len(m.chatSearchMatches) > 0 && m.chatSearchMatchIndex < len(m.chatSearchMatches) {
+		focusIdx = m.chatSearchMatches[m.chatSearchMatchIndex]
+	}
+	for i, e := range lines {
+		if e.Role == "tool" {
+			out = append(out, m.renderChatToolCallBlock(e.ToolCallID, innerWidth)...)
+			continue
+		}
+		if i == focusIdx {
+			out = append(out, m.renderSearchHighlightedLine(e, innerWidth)...)
+			continue
 		}
+		out = append(out, m.cachedWrappedChatBlock(e.Role, e.Text, innerWidth)...)
+	}
+	if m.chatRenderCache != nil {
+		m.chatRenderCache.evictLRU(chatRenderCacheBound(len(lines)))
 	}
-	if m.chatInFlight {
-		if stream := m.chatStreamDisplayText(); strings.TrimSpace(stream) != "" {
+	if inFlight {
+		if stream := strings.TrimRight(streamText, "\n"); strings.TrimSpace(stream) != "" {
 			stream = styleChatContent(m.th, stream)
 			out = append(out, wrapChatBlock(m.th.Success.Render("AI: "), "    ", stream, innerWidth)...)
 		}
@@ -2400,6 +3625,49 @@ func (m appModel) chatHistoryLinesWrapped(innerWidth int) []string {
 	return out
 }
 
+// sliceHistoryWindow returns the last maxLines lines visible at offset
+// lines back from the bottom (offset 0 = follow), joined for rendering.
+func sliceHistoryWindow(lines []string, maxLines int, offset int) string {
+	start := 0
+	if len(lines) > maxLines {
+		start = len(lines) - maxLines - offset
+		if start < 0 {
+			start = 0
+		}
+	}
+	end := start + maxLines
+	if end > len(lines) {
+		end = len(lines)
+	}
+	visible := lines
+	if len(lines) > maxLines {
+		visible = lines[start:end]
+	}
+	return strings.Join(visible, "\n")
+}
+
+func clampScrollOffset(offset int, total int, maxLines int) int {
+	maxOff := total - maxLines
+	if maxOff < 0 {
+		maxOff = 0
+	}
+	return clamp(offset, 0, maxOff)
+}
+
+// currentChatHeight recomputes the chat panel's height the same way
+// viewCockpit does, for input handlers (PgUp/PgDn, the footer resize
+// keybind/drag) that need it outside of a render pass.
+func (m appModel) currentChatHeight() int {
+	w, h := m.effectiveSize()
+	header := renderHeader(m.th, m.cfg.applicationV, m.mcpConnected, m.sessionID)
+	statusBar := m.viewStatusBar(w)
+	chatHeight := h - lipgloss.Height(header) - lipgloss.Height(statusBar)
+	if chatHeight < 6 {
+		chatHeight = 6
+	}
+	return chatHeight
+}
+
 func (m appModel) chatHistoryMaxLines(chatHeight int) int {
 	// Panel border consumes 2 rows (top+bottom). Padding is 0 vertically.
 	innerHeight := chatHeight - 2
@@ -2407,20 +3675,22 @@ func (m appModel) chatHistoryMaxLines(chatHeight int) int {
 		innerHeight = 1
 	}
 
-	// Footer/input section:
-	// - 1 blank line
-	// - input line
-	// - info line (permission + view)
-	// - up to 3 alert lines
-	// - footer line
+	// Footer/input section, sized by footerExtraRows (chunk5-4's resizable
+	// split - see layout.go):
+	// - 1 draggable boundary row
+	// - blank/input/info/footer-hint block, footerBaseRows() rows (4 by
+	//   default, down to 1 - input only - once shrunk to the floor)
+	// - up to footerAlertCap() alert lines
+	// - footerPadRows() blank filler rows once grown past the default
+	alertCap := m.footerAlertCap()
 	alertCount := 0
 	if len(m.alerts) > 0 {
-		alertCount = 3
+		alertCount = alertCap
 		if len(m.alerts) < alertCount {
 			alertCount = len(m.alerts)
 		}
 	}
-	fixed := 1 + 1 + 1 + alertCount + 1
+	fixed := 1 + m.footerBaseRows() + alertCount + m.footerPadRows()
 	max := innerHeight - fixed
 	if max < 1 {
 		max = 1
@@ -2438,6 +3708,9 @@ func (m appModel) chatMaxScrollOffset(chatHeight int, innerWidth int) int {
 }
 
 func (m appModel) chatViewLabel(chatHeight int, innerWidth int) string {
+	if m.chatSearchActive {
+		return m.chatSearchViewLabel()
+	}
 	maxOff := m.chatMaxScrollOffset(chatHeight, innerWidth)
 	off := m.chatScrollOffset
 	if off < 0 {
@@ -2453,46 +3726,31 @@ func (m appModel) chatViewLabel(chatHeight int, innerWidth int) string {
 }
 
 func (m appModel) viewChatFull(width int, chatHeight int) string {
+	if strings.TrimSpace(m.splitRuntime) != "" {
+		return m.viewChatSplit(width, chatHeight)
+	}
+
 	innerW := chatInnerWidth(width)
 	historyLines := m.chatHistoryLinesWrapped(innerW)
 	historyMax := m.chatHistoryMaxLines(chatHeight)
-	maxOff := m.chatMaxScrollOffset(chatHeight, innerW)
-
-	off := m.chatScrollOffset
-	if off < 0 {
-		off = 0
-	}
-	if off > maxOff {
-		off = maxOff
-	}
-
-	start := 0
-	if len(historyLines) > historyMax {
-		start = len(historyLines) - historyMax - off
-		if start < 0 {
-			start = 0
-		}
-	}
-	end := start + historyMax
-	if end > len(historyLines) {
-		end = len(historyLines)
-	}
-	visible := historyLines
-	if len(historyLines) > historyMax {
-		visible = historyLines[start:end]
-	}
-	history := strings.Join(visible, "\n")
+	off := clampScrollOffset(m.chatScrollOffset, len(historyLines), historyMax)
+	history := sliceHistoryWindow(historyLines, historyMax, off)
 
 	clip := lipgloss.NewStyle().MaxWidth(innerW).Render
 
 	inputLine := clip("> " + m.th.Input.Render(m.input))
+	if m.chatSearchActive {
+		inputLine = clip(m.th.Accent.Render("(search) ") + m.th.Input.Render(m.chatSearchQuery))
+	}
 	infoLine := clip(m.th.Muted.Render("Permission: " + m.permissionModeLabel() + "  (Shift+Tab)  │  View: " + m.chatViewLabel(chatHeight, innerW) + "  (PgUp/PgDn)"))
 
-	// Show recent alerts inline
+	// Show recent alerts inline, up to footerAlertCap() - raised above the
+	// default 3 when footerExtraRows has grown the footer (chunk5-4).
+	alertCap := m.footerAlertCap()
 	alertLines := []string{}
 	recent := m.alerts
-	if len(recent) > 3 {
-		recent = recent[len(recent)-3:]
+	if len(recent) > alertCap {
+		recent = recent[len(recent)-alertCap:]
 	}
 	for _, a := range recent {
 		prefix := "[info]"
@@ -2512,9 +3770,88 @@ func (m appModel) viewChatFull(width int, chatHeight int) string {
 		alertSection = "\n" + strings.Join(alertLines, "\n")
 	}
 
-	footer := clip(m.th.Muted.Render("[Enter] Quick Menu    [/] Cmd Palette    [//] System Cmd    [End] Follow"))
+	footerHint := clip(m.th.Muted.Render("[Enter] Quick Menu    [/] Cmd Palette    [//] System Cmd    [End] Follow    [Ctrl-R] Search"))
+
+	// Boundary row: Alt+Up/Alt+Down or a mouse drag on this line
+	// shrinks/grows the footer (footerExtraRows, see layout.go). It
+	// highlights while the drag is hovered/active so the handle is
+	// discoverable.
+	boundaryStyle := m.th.Muted
+	if m.footerBoundaryHovered {
+		boundaryStyle = m.th.Accent
+	}
+	boundary := clip(boundaryStyle.Render(strings.Repeat("─", innerW)))
+
+	rows := m.footerBaseRows()
+	var footer strings.Builder
+	footer.WriteString(boundary)
+	if rows >= 3 {
+		footer.WriteString("\n")
+	}
+	footer.WriteString("\n" + inputLine)
+	if rows >= 2 {
+		footer.WriteString("\n" + infoLine)
+	}
+	footer.WriteString(alertSection)
+	for i := 0; i < m.footerPadRows(); i++ {
+		footer.WriteString("\n")
+	}
+	if rows >= 4 {
+		footer.WriteString("\n" + footerHint)
+	}
+
 	panelStyle := m.th.Panel.Width(width - 2).Height(chatHeight)
-	return panelStyle.Render(history + "\n\n" + inputLine + "\n" + infoLine + alertSection + "\n" + footer)
+	return panelStyle.Render(history + "\n" + footer.String())
+}
+
+// viewChatSplit is viewChatFull's "mode C" layout: two bordered panes side
+// by side, each with its own independent scrollback and scroll offset, fed
+// from the same input line below them. /diff and /pick promote one pane's
+// latest reply into the canonical chatMessages for the next turn.
+func (m appModel) viewChatSplit(width int, chatHeight int) string {
+	paneWidth := (width - 3) / 2
+	if paneWidth < 20 {
+		paneWidth = 20
+	}
+	innerW := chatInnerWidth(paneWidth)
+
+	reserved := 4 // blank line, input line, info line, footer line
+	paneHeight := chatHeight - reserved
+	if paneHeight < 4 {
+		paneHeight = 4
+	}
+	historyMax := paneHeight - 2 /* border */ - 1 /* label row */
+	if historyMax < 1 {
+		historyMax = 1
+	}
+
+	leftLines := m.chatHistoryLinesWrappedFor(m.chatRoleLines, m.chatStreamText, m.chatInFlight, innerW)
+	rightLines := m.chatHistoryLinesWrappedFor(m.chatRoleLinesRight, m.chatStreamTextRight, m.chatInFlightRight, innerW)
+	leftOff := clampScrollOffset(m.chatScrollOffset, len(leftLines), historyMax)
+	rightOff := clampScrollOffset(m.chatScrollOffsetRight, len(rightLines), historyMax)
+	leftBody := sliceHistoryWindow(leftLines, historyMax, leftOff)
+	rightBody := sliceHistoryWindow(rightLines, historyMax, rightOff)
+
+	leftLabel := m.selectedRuntimeLabel()
+	rightLabel := m.runtimeLabelForID(m.splitRuntime)
+	if m.splitFocus == "right" {
+		rightLabel += " ◂focus"
+	} else {
+		leftLabel += " ◂focus"
+	}
+
+	panelStyle := m.th.Panel.Width(paneWidth - 2).Height(paneHeight)
+	leftPanel := panelStyle.Render(m.th.Muted.Render(leftLabel) + "\n" + leftBody)
+	rightPanel := panelStyle.Render(m.th.Muted.Render(rightLabel) + "\n" + rightBody)
+	panes := lipgloss.JoinHorizontal(lipgloss.Top, leftPanel, " ", rightPanel)
+
+	clip := lipgloss.NewStyle().MaxWidth(width - 2).Render
+	inputLine := clip("> " + m.th.Input.Render(m.input))
+	infoLine := clip(m.th.Muted.Render(fmt.Sprintf("Split: %s vs %s  │  Permission: %s  (Shift+Tab)  │  Focus: %s (Tab)", m.selectedRuntimeLabel(), m.runtimeLabelForID(m.splitRuntime), m.permissionModeLabel(), splitFocusLabel(m.splitFocus))))
+	footer := clip(m.th.Muted.Render("[Enter] Quick Menu    [/] Cmd Palette    [/diff] Compare    [/pick left|right] Promote"))
+
+	outerStyle := lipgloss.NewStyle().Width(width - 2)
+	return outerStyle.Render(panes + "\n\n" + inputLine + "\n" + infoLine + "\n" + footer)
 }
 
 func spinner(now time.Time) string {
@@ -2558,7 +3895,7 @@ func extractCwdFromPrompt(text string) string {
 }
 
 func (m appModel) viewCommandPalette() string {
-	items := filteredCommandPaletteItems(m.commandPaletteNamespace, m.commandPaletteQuery)
+	items := filteredCommandPaletteItems(m.cmdRegistry, m.commandPaletteNamespace, m.commandPaletteQuery, m.recentCommands)
 	ns := m.commandPaletteNamespace
 	if ns != "//" {
 		ns = "/"
@@ -2569,16 +3906,95 @@ func (m appModel) viewCommandPalette() string {
 	}
 	for i, it := range items {
 		prefix := "  "
-		label := fmt.Sprintf("%s%s", ns, it.cmd)
+		label := fmt.Sprintf("%-10s", ns+it.cmd)
 		desc := it.desc
-		row := fmt.Sprintf("%-10s %s", label, desc)
-		if i == m.commandPaletteIndex {
+		selected := i == m.commandPaletteIndex
+		if !selected && len(it.matches) > 0 {
+			label = highlightPaletteMatches(m.th, label, len(ns), it.matches)
+		}
+		if !selected && len(it.descMatches) > 0 {
+			desc = highlightPaletteMatches(m.th, desc, 0, it.descMatches)
+		}
+		row := fmt.Sprintf("%s %s", label, desc)
+		if selected {
 			prefix = m.th.Accent.Render("> ")
 			row = m.th.Accent.Render(row)
 		}
 		lines = append(lines, prefix+row)
 	}
-	return m.th.OverlayBox.Render(strings.Join(lines, "\n"))
+	preview := []string{m.th.Accent.Render("PREVIEW"), ""}
+	if m.commandPaletteIndex < len(items) {
+		preview = m.commandPalettePreviewLines(items[m.commandPaletteIndex].cmd)
+	}
+	return m.th.OverlayBox.Render(renderPickerWithPreview(lines, preview))
+}
+
+// commandPalettePreviewLines renders viewCommandPalette's preview column for
+// the highlighted command: its Long help text, plus - for //verify and
+// //docker specifically - a summary of their last cached probe result, so
+// the palette doubles as a discovery surface instead of just a launcher.
+func (m appModel) commandPalettePreviewLines(cmd string) []string {
+	lines := []string{m.th.Accent.Render("PREVIEW"), ""}
+	c, ok := m.cmdRegistry.Lookup(cmd)
+	if !ok {
+		return lines
+	}
+	if c.Long != "" {
+		lines = append(lines, c.Long)
+	}
+	if (cmd == "verify" || cmd == "docker") && m.systemLastResult != nil && m.systemLastResult.Action == cmd {
+		status := "ok"
+		if !m.systemLastResult.Ok {
+			status = "failed"
+		}
+		lines = append(lines, "", fmt.Sprintf("Last run (%s): %s", status, summarizeForAlert(m.systemLastResult.Summary)))
+	}
+	return lines
+}
+
+// highlightPaletteMatches re-renders label (already padded to its column
+// width) with the runes at matchPositions - offset by nsLen, since
+// matchPositions are relative to the bare command name, not the "/"/"//"
+// prefixed label - bolded in the accent color, so a palette row visibly
+// shows which runes the fuzzy query actually matched.
+func highlightPaletteMatches(th theme, label string, nsLen int, matchPositions []int) string {
+	set := make(map[int]bool, len(matchPositions))
+	for _, p := range matchPositions {
+		set[p+nsLen] = true
+	}
+	runes := []rune(label)
+	var b strings.Builder
+	for i, r := range runes {
+		if set[i] {
+			b.WriteString(th.Accent.Bold(true).Render(string(r)))
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// renderPickerWithPreview lays list (already rendered, title + rows) out
+// next to preview (a right-hand detail column for the highlighted item),
+// the same 60/44-width split viewSessionBrowser uses for its list+preview
+// pair - a picker overlay that shows a list on the left and "why would I
+// pick this" detail on the right, rather than just a launcher.
+func renderPickerWithPreview(listLines []string, previewLines []string) string {
+	listPanel := lipgloss.NewStyle().Width(60).Render(strings.Join(listLines, "\n"))
+	previewPanel := lipgloss.NewStyle().Width(44).Render(strings.Join(previewLines, "\n"))
+	return lipgloss.JoinHorizontal(lipgloss.Top, listPanel, " ", previewPanel)
+}
+
+// modelPreviewLines renders viewModelSelect's preview column for model: the
+// repo doesn't track richer per-model metadata than the id string itself,
+// so this is intentionally thin - just enough to confirm what //model would
+// switch to.
+func (m appModel) modelPreviewLines(model string) []string {
+	return []string{
+		m.th.Accent.Render("PREVIEW"),
+		"",
+		"Invocation: //model " + model,
+	}
 }
 
 func (m appModel) viewModelSelect() string {
@@ -2596,11 +4012,41 @@ func (m appModel) viewModelSelect() string {
 		}
 		lines = append(lines, prefix+text)
 	}
-	return m.th.OverlayBox.Render(strings.Join(lines, "\n"))
+	preview := []string{m.th.Accent.Render("PREVIEW"), ""}
+	if m.modelSelectIndex < len(items) {
+		preview = m.modelPreviewLines(items[m.modelSelectIndex])
+	}
+	return m.th.OverlayBox.Render(renderPickerWithPreview(lines, preview))
+}
+
+// providerPreviewLines renders viewProviderSelect's preview column for
+// providerLabel: the auth kind and env vars it needs, plus - for an
+// OAuth-backed provider - the active pooled profile and its usage windows
+// (drawn by renderOAuthProfileBlockWithUsage).
+func (m appModel) providerPreviewLines(providerLabel string) []string {
+	lines := []string{m.th.Accent.Render("PREVIEW"), ""}
+	info, ok := m.providerRegistry.ProviderInfo(providerLabel)
+	if !ok {
+		return append(lines, m.th.Muted.Render("(no provider detail)"))
+	}
+	lines = append(lines, "Auth: "+string(info.Auth))
+	if len(info.EnvVars) > 0 {
+		lines = append(lines, "Env vars:")
+		for _, v := range info.EnvVars {
+			lines = append(lines, "  "+v)
+		}
+	}
+	if info.Auth == providers.AuthOAuth {
+		if p, ok := m.activeOAuthProfile(); ok {
+			lines = append(lines, "")
+			lines = append(lines, renderOAuthProfileBlockWithUsage(m.th, p, m.usageByProfile[p.Profile], m.now)...)
+		}
+	}
+	return lines
 }
 
 func (m appModel) viewProviderSelect() string {
-	opts := providerOptions()
+	opts := m.providerOptions()
 	lines := []string{
 		m.th.Accent.Render("//provider  LLM PROVIDERS"),
 		m.th.Muted.Render("Esc: back    Enter: select"),
@@ -2609,7 +4055,7 @@ func (m appModel) viewProviderSelect() string {
 	}
 	for i, p := range opts {
 		prefix := "  "
-		compat := getCompatibility(p, m.selectedRuntime)
+		compat := m.getCompatibility(p, m.selectedRuntime)
 		compatLabel := "✓"
 		if compat == compatProxy {
 			compatLabel = "⚠ proxy"
@@ -2624,11 +4070,36 @@ func (m appModel) viewProviderSelect() string {
 		}
 		lines = append(lines, prefix+row)
 	}
-	return m.th.OverlayBox.Render(strings.Join(lines, "\n"))
+	preview := []string{m.th.Accent.Render("PREVIEW"), ""}
+	if m.providerSelectIndex < len(opts) {
+		preview = m.providerPreviewLines(opts[m.providerSelectIndex])
+	}
+	return m.th.OverlayBox.Render(renderPickerWithPreview(lines, preview))
+}
+
+// runtimePreviewLines renders viewRuntimeSelect's preview column for
+// runtime r: its compatibility with the currently selected provider, an
+// example invocation, its description, and which env vars the selected
+// provider needs before that runtime can be driven.
+func (m appModel) runtimePreviewLines(r unifiedRuntime) []string {
+	lines := []string{m.th.Accent.Render("PREVIEW"), ""}
+	compat := m.getCompatibility(m.selectedProvider, r.ID)
+	lines = append(lines, "Compatibility: "+compat.Label())
+	lines = append(lines, "Invocation:    //runtime "+r.ID)
+	if r.Description != "" {
+		lines = append(lines, "", r.Description)
+	}
+	if info, ok := m.providerRegistry.ProviderInfo(m.selectedProvider); ok && len(info.EnvVars) > 0 {
+		lines = append(lines, "", "Env vars:")
+		for _, v := range info.EnvVars {
+			lines = append(lines, "  "+v)
+		}
+	}
+	return lines
 }
 
 func (m appModel) viewRuntimeSelect() string {
-	unified := runtimeOptionsUnified()
+	unified := m.runtimeOptionsUnified()
 	lines := []string{
 		m.th.Accent.Render("//runtime  RUNTIMES"),
 		m.th.Muted.Render("Esc: back    Enter: select"),
@@ -2637,7 +4108,7 @@ func (m appModel) viewRuntimeSelect() string {
 	}
 	for i, r := range unified {
 		prefix := "  "
-		compat := getCompatibility(m.selectedProvider, r.ID)
+		compat := m.getCompatibility(m.selectedProvider, r.ID)
 		compatLabel := "✓"
 		if compat == compatProxy {
 			compatLabel = "⚠ proxy"
@@ -2655,6 +4126,146 @@ func (m appModel) viewRuntimeSelect() string {
 	lines = append(lines, "", m.th.Muted.Render("Compatibility matrix:"))
 	lines = append(lines, m.th.Muted.Render("✓ Native = works directly"))
 	lines = append(lines, m.th.Muted.Render("⚠ Proxy = requires proxy setup"))
+	preview := []string{m.th.Accent.Render("PREVIEW"), ""}
+	if m.runtimeSelectIndex < len(unified) {
+		preview = m.runtimePreviewLines(unified[m.runtimeSelectIndex])
+	}
+	return m.th.OverlayBox.Render(renderPickerWithPreview(lines, preview))
+}
+
+// viewSessionBrowser renders the overlaySessionBrowser as two panels side
+// by side (the same lipgloss.JoinHorizontal treatment viewChatSplit uses
+// for its two chat panes): a fuzzy-searchable list of prior sessions on the
+// left, and a preview of the highlighted one's last few turns on the right.
+func (m appModel) viewSessionBrowser() string {
+	items := filteredSessionSummaries(m.sessionBrowserSessions, m.sessionBrowserQuery)
+
+	listLines := []string{
+		m.th.Accent.Render("SESSIONS"),
+		m.th.Muted.Render("> " + m.sessionBrowserQuery),
+		m.th.Muted.Render("Esc: back    Enter: resume"),
+		"",
+	}
+	if len(items) == 0 {
+		listLines = append(listLines, m.th.Muted.Render("(no matching sessions)"))
+	}
+	for i, s := range items {
+		prefix := "  "
+		row := fmt.Sprintf("%-40s %2d turns  %s", s.Title, s.Turns, s.UpdatedAt.Format("2006-01-02 15:04"))
+		if i == m.sessionBrowserIndex {
+			prefix = m.th.Accent.Render("> ")
+			row = m.th.Accent.Render(row)
+		}
+		listLines = append(listLines, prefix+row)
+	}
+	listPanel := m.th.Panel.Width(60).Render(strings.Join(listLines, "\n"))
+
+	previewLines := []string{m.th.Accent.Render("PREVIEW")}
+	if len(items) > 0 && m.sessionBrowserIndex < len(items) {
+		selected := items[m.sessionBrowserIndex]
+		for _, l := range sessionPreviewLines(m.cfg.stateDir, selected.ID, 8) {
+			previewLines = append(previewLines, wrapChatBlockForRole(m.th, l.Role, l.Text, 40)...)
+		}
+	} else {
+		previewLines = append(previewLines, m.th.Muted.Render("(nothing to preview)"))
+	}
+	previewPanel := m.th.Panel.Width(44).Render(strings.Join(previewLines, "\n"))
+
+	return lipgloss.JoinHorizontal(lipgloss.Top, listPanel, " ", previewPanel)
+}
+
+// updateSnapshotBrowser drives the overlaySnapshotBrowser: Up/Down move the
+// selection, 'd' marks the highlighted snapshot for diffing (or, if one is
+// already marked, renders the diff against it and clears diffLines on the
+// next 'd'), Enter forks a session from the highlighted snapshot.
+func (m appModel) updateSnapshotBrowser(k tea.KeyMsg) (tea.Model, tea.Cmd) {
+	items := m.snapshotBrowserItems
+	if len(items) == 0 {
+		if k.Type == tea.KeyEnter {
+			m = m.closeOverlay()
+		}
+		return m, nil
+	}
+
+	switch {
+	case k.Type == tea.KeyUp:
+		if m.snapshotBrowserIndex > 0 {
+			m.snapshotBrowserIndex--
+		}
+	case k.Type == tea.KeyDown:
+		if m.snapshotBrowserIndex < len(items)-1 {
+			m.snapshotBrowserIndex++
+		}
+	case k.Type == tea.KeyRunes && string(k.Runes) == "d":
+		if len(m.snapshotBrowserDiffLines) > 0 {
+			m.snapshotBrowserDiffLines = nil
+			m.snapshotBrowserMarkedHash = ""
+			return m, nil
+		}
+		selected := items[m.snapshotBrowserIndex].Hash
+		if m.snapshotBrowserMarkedHash == "" {
+			m.snapshotBrowserMarkedHash = selected
+			return m, nil
+		}
+		a, errA := loadSnapshotBundle(m.cfg.stateDir, m.snapshotBrowserMarkedHash)
+		b, errB := loadSnapshotBundle(m.cfg.stateDir, selected)
+		if errA != nil || errB != nil {
+			m.systemAlert(alertWarn, "snapshot.diff.failed", "Failed to load snapshots to diff", nil)
+			m.snapshotBrowserMarkedHash = ""
+			return m, nil
+		}
+		m.snapshotBrowserDiffLines = diffSnapshotMessages(a, b)
+	case k.Type == tea.KeyEnter:
+		selected := items[m.snapshotBrowserIndex]
+		forked, err := m.forkSession(selected.Hash)
+		if err != nil {
+			m.systemAlert(alertError, "session.fork.failed", "Failed to fork session from snapshot", map[string]any{"error": err.Error()})
+			return m, nil
+		}
+		m = forked
+		m = m.closeAllOverlays()
+	}
+	return m, nil
+}
+
+// viewSnapshotBrowser renders the overlaySnapshotBrowser: a list of
+// snapshots with their timestamp, parent hash, and message count, or - once
+// 'd' has picked two snapshots - a diff of their message lists instead.
+func (m appModel) viewSnapshotBrowser() string {
+	if len(m.snapshotBrowserDiffLines) > 0 {
+		lines := []string{
+			m.th.Accent.Render("SNAPSHOT DIFF"),
+			m.th.Muted.Render("d: back to list    Esc: close"),
+			"",
+		}
+		lines = append(lines, m.snapshotBrowserDiffLines...)
+		return m.th.OverlayBox.Render(strings.Join(lines, "\n"))
+	}
+
+	lines := []string{
+		m.th.Accent.Render("SNAPSHOTS"),
+		m.th.Muted.Render("Esc: back    Enter: fork    d: mark/diff"),
+		"",
+	}
+	if len(m.snapshotBrowserItems) == 0 {
+		lines = append(lines, m.th.Muted.Render("(no snapshots yet - //session snapshot to create one)"))
+	}
+	for i, s := range m.snapshotBrowserItems {
+		prefix := "  "
+		parent := "(none)"
+		if s.ParentHash != "" {
+			parent = shortHash(s.ParentHash)
+		}
+		row := fmt.Sprintf("%s  %2d turns  parent %-10s  %s", shortHash(s.Hash), s.Turns, parent, s.CreatedAt.Format("2006-01-02 15:04"))
+		if s.Hash == m.snapshotBrowserMarkedHash {
+			row += "  [marked]"
+		}
+		if i == m.snapshotBrowserIndex {
+			prefix = m.th.Accent.Render("> ")
+			row = m.th.Accent.Render(row)
+		}
+		lines = append(lines, prefix+row)
+	}
 	return m.th.OverlayBox.Render(strings.Join(lines, "\n"))
 }
 
@@ -2684,14 +4295,22 @@ func (m appModel) viewQuitConfirm() string {
 }
 
 func (m appModel) viewAuthSelect() string {
+	if m.authPrompt.Active {
+		return m.viewAuthPrompt()
+	}
 	profiles := orderOAuthProfilesForDisplay(m.oauthPool.Profiles)
 	lines := []string{
 		m.th.Accent.Render("//auth  OAUTH ACCOUNTS"),
-		m.th.Muted.Render("Esc: back    Enter: select"),
+		m.th.Muted.Render("Esc: back    Enter: select    n: new"),
 	}
 	for i, p := range profiles {
 		prefix := "  "
 		row := fmt.Sprintf("%s (%s)", p.Email, strings.ToLower(p.Status))
+		if until, ok := m.profileCooldowns[p.Profile]; ok {
+			if remaining := until.Sub(m.now); remaining > 0 {
+				row += fmt.Sprintf("  cooldown %s", remaining.Round(time.Second))
+			}
+		}
 		if i == m.authSelectIndex {
 			prefix = m.th.Accent.Render("> ")
 			row = m.th.Accent.Render(row)
@@ -2783,156 +4402,137 @@ func (m appModel) viewSystemInfo() string {
 }
 
 type paletteItem struct {
-	cmd    string
-	desc   string
-	label  string
-	action string
+	cmd     string
+	desc    string
+	matches []int // rune positions within cmd that matched the query, for highlighting
+	// descMatches holds the same kind of positions as matches, but into
+	// desc instead of cmd - set only when the query matched via the
+	// description fallback (cmd itself didn't match), so "oauth" still
+	// surfaces and highlights "Manage OAuth Accounts" even though "oauth"
+	// doesn't appear in the command name.
+	descMatches []int
 }
 
-func commandPaletteItems() []paletteItem {
-	return []paletteItem{
-		{cmd: "clear", desc: "Clear Context Window", label: "Clear Context Window", action: "clear"},
+// commandPaletteItemsFor is a thin view over cmdRegistry for the "/" and "//"
+// palette listings. Hidden commands (permission_mode, cancel) are reachable
+// by typing them or via /help but don't clutter the picker.
+func commandPaletteItemsFor(reg *commands.Registry[appModel], namespace string) []paletteItem {
+	cmds := reg.ForNamespace(namespace)
+	items := make([]paletteItem, 0, len(cmds))
+	for _, c := range cmds {
+		if c.Hidden {
+			continue
+		}
+		items = append(items, paletteItem{cmd: c.Name, desc: c.Short})
 	}
+	return items
 }
 
-func systemCommandPaletteItems() []paletteItem {
-	return []paletteItem{
-		{cmd: "provider", desc: "Switch LLM Provider (OpenAI/Anthropic/Google/Ollama)", label: "Switch LLM Provider", action: "provider"},
-		{cmd: "runtime", desc: "Switch Runtime (Codex Chat/CLI, Claude Code, Direct API)", label: "Switch Runtime", action: "runtime"},
-		{cmd: "model", desc: "Switch AI Model", label: "Switch AI Model", action: "model"},
-		{cmd: "auth", desc: "Manage OAuth Accounts", label: "Manage OAuth Accounts", action: "auth"},
-		{cmd: "mode", desc: "Switch Session Mode (A <-> B)", label: "Switch Session Mode", action: "mode"},
-		{cmd: "session", desc: "Start a new session (clears context + cancels stuck turns)", label: "New Session", action: "session"},
-		{cmd: "stats", desc: "View Detailed Statistics", label: "View Detailed Statistics", action: "stats"},
-		{cmd: "docker", desc: "Docker status/probe", label: "Docker status/probe", action: "docker"},
-		{cmd: "verify", desc: "Run verification gates", label: "Run verification gates", action: "verify"},
-		{cmd: "exit", desc: "Close Session", label: "Close Session", action: "exit"},
+// commandRecency looks up how recently cmd was run via recentCommands
+// (entries there are full "/cmd"/"//cmd" text or a few bare event names;
+// either way the leading slashes are stripped before comparing). Higher is
+// more recent; a command recentCommands never mentions ranks -1, below
+// every command it does mention.
+func commandRecency(recentCommands []string, cmd string) int {
+	rank := -1
+	for i, c := range recentCommands {
+		if strings.TrimLeft(c, "/") == cmd {
+			rank = i
+		}
 	}
+	return rank
 }
 
-func filteredCommandPaletteItems(namespace string, query string) []paletteItem {
-	items := commandPaletteItems()
-	if namespace == "//" {
-		items = systemCommandPaletteItems()
-	}
-	q := strings.TrimSpace(strings.ToLower(query))
-	if q == "" {
-		return items
+// filteredCommandPaletteItems ranks namespace's visible commands against
+// query with fuzzyMatch and returns them score descending, ties broken by
+// recency in recentCommands and then alphabetically. A command-name match
+// always outranks a description-only match (fuzzyMatch also runs against
+// desc so e.g. typing what a command does still surfaces it). An empty
+// query skips matching entirely and just orders by recency then name.
+func filteredCommandPaletteItems(reg *commands.Registry[appModel], namespace string, query string, recentCommands []string) []paletteItem {
+	ns := namespace
+	if ns != "//" {
+		ns = "/"
 	}
+	items := commandPaletteItemsFor(reg, ns)
+	q := strings.TrimSpace(query)
+
 	type scored struct {
 		it    paletteItem
 		score int
-		idx   int
 	}
-	matches := make([]scored, 0, len(items))
-	for i, it := range items {
-		cmd := strings.ToLower(it.cmd)
-		desc := strings.ToLower(it.desc)
-		score := -1
-		if strings.HasPrefix(cmd, q) {
-			score = 0
-		} else if strings.Contains(cmd, q) {
-			score = 1
-		} else if strings.Contains(desc, q) {
-			score = 2
+	candidates := make([]scored, 0, len(items))
+	if q == "" {
+		for _, it := range items {
+			candidates = append(candidates, scored{it: it})
 		}
-		if score >= 0 {
-			matches = append(matches, scored{it: it, score: score, idx: i})
+	} else {
+		for _, it := range items {
+			if cmdScore, positions, ok := fuzzyMatch(q, it.cmd); ok {
+				it.matches = positions
+				candidates = append(candidates, scored{it: it, score: cmdScore})
+				continue
+			}
+			if descScore, positions, ok := fuzzyMatch(q, it.desc); ok {
+				it.descMatches = positions
+				candidates = append(candidates, scored{it: it, score: descScore - 1000})
+			}
 		}
 	}
-	sort.SliceStable(matches, func(i, j int) bool {
-		if matches[i].score != matches[j].score {
-			return matches[i].score < matches[j].score
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.score != b.score {
+			return a.score > b.score
 		}
-		return matches[i].idx < matches[j].idx
+		ra, rb := commandRecency(recentCommands, a.it.cmd), commandRecency(recentCommands, b.it.cmd)
+		if ra != rb {
+			return ra > rb
+		}
+		return a.it.cmd < b.it.cmd
 	})
-	out := make([]paletteItem, 0, len(matches))
-	for _, m := range matches {
-		out = append(out, m.it)
+
+	out := make([]paletteItem, 0, len(candidates))
+	for _, c := range candidates {
+		out = append(out, c.it)
 	}
 	return out
 }
 
 func quickActionItems() []string {
-	return []string{"New Session (clear context)", "Switch Provider", "Switch Runtime", "Change Mode", "Toggle Thought Stream", "Snapshot Evidence"}
-}
-
-// Provider selection - LLM vendor
-func providerOptions() []string {
-	return []string{
-		"OpenAI",
-		"Anthropic",
-		"Google (Gemini)",
-		"Ollama (local)",
-	}
-}
-
-// Runtime selection - unified runtime + mode
-type unifiedRuntime struct {
-	ID          string
-	Label       string
-	Description string
+	return []string{"New Session (clear context)", "Browse Snapshots", "Switch Provider", "Switch Runtime", "Change Mode", "Toggle Thought Stream", "Snapshot Evidence"}
 }
 
-func runtimeOptionsUnified() []unifiedRuntime {
-	return []unifiedRuntime{
-		{ID: "codex-chat", Label: "Codex – Chat Mode", Description: "OpenAI API, chat-only interface"},
-		{ID: "codex-cli", Label: "Codex – CLI Mode", Description: "OpenAI with Codex CLI, full code editing with file access"},
-		{ID: "opencode-run", Label: "OpenCode – Run Mode", Description: "OpenCode headless runner (streams tool/step events)"},
-		{ID: "claude-code", Label: "Claude Code", Description: "Anthropic native TTY, full capabilities (code editing, tools)"},
-		{ID: "direct-api", Label: "Direct API", Description: "Any provider, chat-only interface"},
-	}
+// Provider selection - LLM vendor. Backed by m.providerRegistry; see the
+// providers package for the registration data and any stateDir overrides.
+func (m appModel) providerOptions() []string {
+	return m.providerRegistry.Providers()
 }
 
-// Compatibility types
-type compatibilityType int
+// unifiedRuntime and compatibilityType are aliases onto the providers
+// package's types, kept under their old names here since every call site in
+// this file and command_bus.go/commands_registry.go already spells them
+// this way.
+type unifiedRuntime = providers.Runtime
+type compatibilityType = providers.Compatibility
 
 const (
-	compatNative compatibilityType = iota // Works directly
-	compatProxy                            // Needs proxy setup
+	compatNative = providers.CompatNative
+	compatProxy  = providers.CompatProxy
 )
 
-func (c compatibilityType) String() string {
-	switch c {
-	case compatNative:
-		return "native"
-	case compatProxy:
-		return "proxy"
-	default:
-		return "unknown"
-	}
+func (m appModel) runtimeOptionsUnified() []unifiedRuntime {
+	return m.providerRegistry.Runtimes()
 }
 
-// Check if combination needs proxy (configurable)
-func getCompatibility(provider, runtime string) compatibilityType {
-	p := strings.ToLower(strings.TrimSpace(provider))
-	r := strings.ToLower(strings.TrimSpace(runtime))
-
-	switch {
-	case strings.Contains(r, "opencode"):
-		return compatNative
-	case strings.Contains(r, "claude"):
-		if strings.Contains(p, "anthropic") {
-			return compatNative
-		}
-		return compatProxy
-	case strings.Contains(r, "codex"):
-		if strings.Contains(p, "openai") {
-			return compatNative
-		}
-		return compatProxy
-	case strings.Contains(r, "direct"):
-		return compatNative // All providers work directly
-	}
-	return compatProxy
+// getCompatibility reports whether provider can drive runtime natively or
+// needs a proxy, per m.providerRegistry.
+func (m appModel) getCompatibility(provider, runtime string) compatibilityType {
+	return m.providerRegistry.Compatibility(provider, runtime)
 }
 
-func getCompatibilityLabel(provider, runtime string) string {
-	compat := getCompatibility(provider, runtime)
-	if compat == compatNative {
-		return "✓ Native"
-	}
-	return "⚠ Proxy required"
+func (m appModel) getCompatibilityLabel(provider, runtime string) string {
+	return m.getCompatibility(provider, runtime).Label()
 }
 
 func (m appModel) selectedProviderLabel() string {
@@ -2940,7 +4540,7 @@ func (m appModel) selectedProviderLabel() string {
 		return m.selectedProvider
 	}
 	// Fallback to index-based selection for Mode A
-	opts := providerOptions()
+	opts := m.providerOptions()
 	if len(opts) == 0 {
 		return "unknown"
 	}
@@ -2958,16 +4558,26 @@ func (m appModel) selectedRuntimeLabel() string {
 	if strings.TrimSpace(m.selectedRuntime) == "" {
 		return "direct-api"
 	}
-	for _, r := range runtimeOptionsUnified() {
-		if r.ID == m.selectedRuntime {
-			return r.Label
-		}
+	return m.runtimeLabelForID(m.selectedRuntime)
+}
+
+// runtimeLabelForID looks up a runtime id's display label the same way
+// selectedRuntimeLabel does, for runtime ids that aren't selectedRuntime
+// (e.g. the split-screen right pane's splitRuntime).
+func (m appModel) runtimeLabelForID(id string) string {
+	return m.providerRegistry.RuntimeLabel(id)
+}
+
+// splitFocusLabel names which pane PgUp/PgDn/Home/End scroll.
+func splitFocusLabel(focus string) string {
+	if focus == "right" {
+		return "right"
 	}
-	return m.selectedRuntime
+	return "left"
 }
 
 func (m appModel) currentCompatibility() compatibilityType {
-	return getCompatibility(m.selectedProvider, m.selectedRuntime)
+	return m.getCompatibility(m.selectedProvider, m.selectedRuntime)
 }
 
 func modelOptions() []string {
@@ -3102,7 +4712,7 @@ func renderOAuthProfileBlockWithUsage(th theme, p oauthPoolProfile, usage *usage
 	case "LIMITED":
 		bullet = "‼"
 	}
-	label := fmt.Sprintf("%s %s (%s)", bullet, p.Email, strings.ToLower(p.Status))
+	label := fmt.Sprintf("%s %s (%s, score=%.3f)", bullet, p.Email, strings.ToLower(p.Status), p.Score)
 	lines := []string{label}
 
 	if usage != nil && len(usage.Windows) > 0 {
@@ -3,13 +3,28 @@ package main
 import (
 	"encoding/base64"
 	"encoding/json"
+	"fmt"
+	"math"
 	"os"
 	"path/filepath"
+	"regexp"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 )
 
+// weeklyCapDemotionThreshold is the secondary (weekly) usage percentage past
+// which a profile is pushed to the back of the ranking even if its 5h
+// window still has room, so the pool doesn't exhaust an account's weekly
+// allotment just because it happens to look fresh on the short window.
+const weeklyCapDemotionThreshold = 80.0
+
+// defaultOAuthHalfLifeSec is the decay half-life, in seconds, for the
+// recent-use penalty applied in rankOAuthCandidates. Override with
+// WORKBENCH_OAUTH_HALFLIFE_SEC.
+const defaultOAuthHalfLifeSec = 900
+
 type oauthPoolProfile struct {
 	Profile            string
 	Email              string
@@ -18,11 +33,20 @@ type oauthPoolProfile struct {
 	RateLimitedUntilMs int64
 	Disabled           bool
 	Status             string // ACTIVE|STANDBY|LIMITED
+	Score              float64
 
 	accountID    string
 	accessToken  string
 }
 
+// oauthRecentUsage tracks a decayed exponential-moving-average of turns
+// routed through a profile, stored in openai_codex_oauth_pool.json under
+// usage.recent[profile].
+type oauthRecentUsage struct {
+	Value float64 `json:"value"`
+	AtMs  int64   `json:"atMs"`
+}
+
 type oauthPoolSnapshot struct {
 	ActiveProfile string
 	ActiveEmail   string
@@ -101,7 +125,20 @@ func readOAuthPoolSnapshot(stateDir string, at time.Time) (oauthPoolSnapshot, bo
 		}
 	}
 
-	out.Ranked = rankOAuthCandidates(out.Profiles)
+	recent := map[string]oauthRecentUsage{}
+	if usage, ok := parsed["usage"].(map[string]any); ok {
+		if rm, ok := usage["recent"].(map[string]any); ok {
+			for k, v := range rm {
+				obj, ok := v.(map[string]any)
+				if !ok {
+					continue
+				}
+				recent[k] = oauthRecentUsage{Value: parseFloat0(obj["value"]), AtMs: parseInt64(obj["atMs"])}
+			}
+		}
+	}
+
+	out.Ranked = rankOAuthCandidates(out.Profiles, at, stateDir, recent)
 	if out.ActiveProfile == "" {
 		// If no ACTIVE recorded, choose deterministically for display.
 		for _, c := range out.Ranked {
@@ -137,7 +174,13 @@ func setOAuthPoolLastUsedProfile(stateDir string, profile string) error {
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(path, append(b, '\n'), 0o600)
+	if err := os.WriteFile(path, append(b, '\n'), 0o600); err != nil {
+		return err
+	}
+	// Best-effort: record the turn against this profile's recent-use EMA so
+	// future ranking decays its score relative to fresher profiles.
+	_ = bumpOAuthRecentUsage(stateDir, profile, time.Now())
+	return nil
 }
 
 func setOAuthProfileRateLimitedUntil(stateDir string, profile string, untilMs int64) error {
@@ -167,21 +210,42 @@ func setOAuthProfileRateLimitedUntil(stateDir string, profile string, untilMs in
 	return os.WriteFile(path, append(b, '\n'), 0o600)
 }
 
-func rankOAuthCandidates(profiles []oauthPoolProfile) []oauthPoolProfile {
+// rankOAuthCandidates scores non-LIMITED profiles by remaining headroom in
+// their current reset window, discounted by a decayed recent-use penalty so
+// the scheduler spreads turns across the pool instead of hammering whichever
+// profile happens to be most depleted. Profiles whose weekly window is past
+// weeklyCapDemotionThreshold are demoted below every fresher profile
+// regardless of score, since burning a weekly allotment is much harder to
+// recover from than a 5h window resetting.
+func rankOAuthCandidates(profiles []oauthPoolProfile, now time.Time, stateDir string, recent map[string]oauthRecentUsage) []oauthPoolProfile {
+	halfLife := oauthHalfLife()
+	nowMs := now.UTC().UnixMilli()
+
 	candidates := make([]oauthPoolProfile, 0, len(profiles))
+	weeklyCapped := make(map[string]bool, len(profiles))
 	for _, p := range profiles {
 		if p.Status == "LIMITED" {
 			continue
 		}
+		secsUntilReset := float64(p.ResetAtMs-nowMs) / 1000
+		if secsUntilReset < 1 {
+			secsUntilReset = 1
+		}
+		penalty := decayedUsage(recent[p.Profile], now, halfLife)
+		p.Score = p.Remaining/secsUntilReset - penalty
+		weeklyCapped[p.Profile] = weeklyUsagePercent(stateDir, p.Profile, now) >= weeklyCapDemotionThreshold
 		candidates = append(candidates, p)
 	}
+
 	sort.SliceStable(candidates, func(i, j int) bool {
 		ai := candidates[i]
 		aj := candidates[j]
-		ri := ai.Remaining
-		rj := aj.Remaining
-		if ri != rj {
-			return ri < rj
+		ci, cj := weeklyCapped[ai.Profile], weeklyCapped[aj.Profile]
+		if ci != cj {
+			return cj // the non-capped profile sorts first
+		}
+		if ai.Score != aj.Score {
+			return ai.Score > aj.Score
 		}
 		if ai.ResetAtMs != aj.ResetAtMs {
 			return ai.ResetAtMs < aj.ResetAtMs
@@ -191,6 +255,81 @@ func rankOAuthCandidates(profiles []oauthPoolProfile) []oauthPoolProfile {
 	return candidates
 }
 
+// oauthHalfLife returns the decay half-life for the recent-use penalty,
+// overridable via WORKBENCH_OAUTH_HALFLIFE_SEC for testing or tuning.
+func oauthHalfLife() time.Duration {
+	secs := defaultOAuthHalfLifeSec
+	if v := strings.TrimSpace(os.Getenv("WORKBENCH_OAUTH_HALFLIFE_SEC")); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			secs = n
+		}
+	}
+	return time.Duration(secs) * time.Second
+}
+
+func decayedUsage(v oauthRecentUsage, now time.Time, halfLife time.Duration) float64 {
+	if v.AtMs <= 0 || v.Value <= 0 {
+		return 0
+	}
+	elapsed := now.Sub(time.UnixMilli(v.AtMs))
+	if elapsed <= 0 {
+		return v.Value
+	}
+	halvings := elapsed.Seconds() / halfLife.Seconds()
+	return v.Value * math.Pow(0.5, halvings)
+}
+
+// weeklyUsagePercent returns the cached secondary (weekly) usage percentage
+// for profile, or 0 if no usage has been fetched yet.
+func weeklyUsagePercent(stateDir string, profile string, now time.Time) float64 {
+	u, ok := loadCachedUsage(stateDir, profile, now)
+	if !ok || u == nil {
+		return 0
+	}
+	if w, ok := findUsageWindow(u, "weekly"); ok {
+		return w.Percent
+	}
+	return 0
+}
+
+// bumpOAuthRecentUsage decays profile's recorded EMA to now and adds one
+// turn, persisting it under usage.recent so rankOAuthCandidates can read it
+// back on the next selection.
+func bumpOAuthRecentUsage(stateDir string, profile string, now time.Time) error {
+	path := filepath.Join(stateDir, "auth", "openai_codex_oauth_pool.json")
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var parsed map[string]any
+	if err := json.Unmarshal(raw, &parsed); err != nil {
+		return err
+	}
+	usage, ok := parsed["usage"].(map[string]any)
+	if !ok {
+		usage = map[string]any{}
+		parsed["usage"] = usage
+	}
+	recent, ok := usage["recent"].(map[string]any)
+	if !ok {
+		recent = map[string]any{}
+		usage["recent"] = recent
+	}
+
+	var prev oauthRecentUsage
+	if obj, ok := recent[profile].(map[string]any); ok {
+		prev = oauthRecentUsage{Value: parseFloat0(obj["value"]), AtMs: parseInt64(obj["atMs"])}
+	}
+	next := decayedUsage(prev, now, oauthHalfLife()) + 1.0
+	recent[profile] = map[string]any{"value": next, "atMs": now.UTC().UnixMilli()}
+
+	b, err := json.MarshalIndent(parsed, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(b, '\n'), 0o600)
+}
+
 func parseInt64(v any) int64 {
 	switch t := v.(type) {
 	case int:
@@ -217,6 +356,95 @@ func parseFloat(v any) float64 {
 	}
 }
 
+// parseFloat0 is like parseFloat but defaults to 0 rather than 1e18; used
+// for fields (like usage.recent[profile].value) where "missing" means "no
+// recorded usage yet" rather than "unbounded remaining".
+func parseFloat0(v any) float64 {
+	switch t := v.(type) {
+	case int:
+		return float64(t)
+	case int64:
+		return float64(t)
+	case float64:
+		return t
+	default:
+		return 0
+	}
+}
+
+// oauthPasteTokenMinLen is the minimum accepted length for a pasted token in
+// the viewAuthSelect "n" -> paste flow (see auth_prompt.go): short enough to
+// not reject a short-lived test token, long enough to catch an obvious
+// truncated paste.
+const oauthPasteTokenMinLen = 20
+
+// oauthPasteTokenCharset matches the characters real OpenAI OAuth access
+// tokens use (base64url plus the "." separating a JWT's segments); anything
+// else in a pasted token is almost certainly a copy-paste mistake.
+var oauthPasteTokenCharset = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+// validateOAuthPaste checks username/token before registerOAuthPastedCredential
+// writes them to the pool file, so the paste prompt can show an inline error
+// and let the user retry instead of silently writing garbage.
+func validateOAuthPaste(username, token string) error {
+	username = strings.TrimSpace(username)
+	token = strings.TrimSpace(token)
+	if username == "" {
+		return fmt.Errorf("username is required")
+	}
+	if len(token) < oauthPasteTokenMinLen {
+		return fmt.Errorf("token must be at least %d characters", oauthPasteTokenMinLen)
+	}
+	if !oauthPasteTokenCharset.MatchString(token) {
+		return fmt.Errorf("token may only contain letters, digits, '.', '_', and '-'")
+	}
+	return nil
+}
+
+// registerOAuthPastedCredential adds username as a new profile keyed by its
+// own name, with token as its accessToken, to the same
+// openai_codex_oauth_pool.json file readOAuthPoolSnapshot reads back. New
+// profiles start with full headroom and a far-future reset so the very next
+// selection and rankOAuthCandidates treat them as immediately usable.
+func registerOAuthPastedCredential(stateDir string, username string, token string) (string, error) {
+	username = strings.TrimSpace(username)
+	token = strings.TrimSpace(token)
+	path := filepath.Join(stateDir, "auth", "openai_codex_oauth_pool.json")
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", err
+	}
+
+	parsed := map[string]any{}
+	if raw, err := os.ReadFile(path); err == nil {
+		if err := json.Unmarshal(raw, &parsed); err != nil {
+			return "", err
+		}
+	}
+
+	profs, ok := parsed["profiles"].(map[string]any)
+	if !ok {
+		profs = map[string]any{}
+		parsed["profiles"] = profs
+	}
+	profs[username] = map[string]any{
+		"email":       username,
+		"accessToken": token,
+		"disabled":    false,
+		"remaining":   100.0,
+		"resetAtMs":   0,
+		"updatedAt":   time.Now().UTC().Format(time.RFC3339Nano),
+	}
+
+	b, err := json.MarshalIndent(parsed, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(path, append(b, '\n'), 0o600); err != nil {
+		return "", err
+	}
+	return username, nil
+}
+
 func extractEmailFromJwt(token string) string {
 	parts := strings.Split(strings.TrimSpace(token), ".")
 	if len(parts) != 3 {
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRankOAuthCandidatesResetBoundary(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	stateDir := t.TempDir()
+
+	// "depleted" has less Remaining than "fresh" but its reset window is
+	// seconds away, so its score (Remaining/secsUntilReset) comes out far
+	// higher than a profile with more headroom but hours left on the clock.
+	profiles := []oauthPoolProfile{
+		{Profile: "depleted", Email: "depleted@example.com", Remaining: 5, ResetAtMs: now.Add(10 * time.Second).UnixMilli()},
+		{Profile: "fresh", Email: "fresh@example.com", Remaining: 90, ResetAtMs: now.Add(4 * time.Hour).UnixMilli()},
+	}
+
+	ranked := rankOAuthCandidates(profiles, now, stateDir, nil)
+	if len(ranked) != 2 {
+		t.Fatalf("len(ranked) = %d, want 2", len(ranked))
+	}
+	if ranked[0].Profile != "depleted" {
+		t.Fatalf("ranked[0].Profile = %q, want %q (higher remaining_pct/secsUntilReset score)", ranked[0].Profile, "depleted")
+	}
+}
+
+func TestRankOAuthCandidatesResetBoundaryTieBreak(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	stateDir := t.TempDir()
+	resetAt := now.Add(time.Hour).UnixMilli()
+
+	// Equal scores and equal ResetAtMs: tie-break falls through to Email.
+	profiles := []oauthPoolProfile{
+		{Profile: "b", Email: "bravo@example.com", Remaining: 50, ResetAtMs: resetAt},
+		{Profile: "a", Email: "alpha@example.com", Remaining: 50, ResetAtMs: resetAt},
+	}
+
+	ranked := rankOAuthCandidates(profiles, now, stateDir, nil)
+	if ranked[0].Email != "alpha@example.com" {
+		t.Fatalf("ranked[0].Email = %q, want %q", ranked[0].Email, "alpha@example.com")
+	}
+}
+
+func TestRankOAuthCandidatesWeeklyCapDemotion(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	stateDir := t.TempDir()
+
+	// "capped" scores far higher on the 5h window alone, but its weekly
+	// usage is past weeklyCapDemotionThreshold, so it must still rank below
+	// "roomy" which hasn't hit its weekly cap.
+	saveCachedUsage(stateDir, "capped", &usageData{
+		FetchedAt: now.UnixMilli(),
+		Windows:   []usageWindow{{Type: "weekly", Percent: 95}},
+	})
+	saveCachedUsage(stateDir, "roomy", &usageData{
+		FetchedAt: now.UnixMilli(),
+		Windows:   []usageWindow{{Type: "weekly", Percent: 10}},
+	})
+
+	profiles := []oauthPoolProfile{
+		{Profile: "capped", Email: "capped@example.com", Remaining: 95, ResetAtMs: now.Add(time.Second).UnixMilli()},
+		{Profile: "roomy", Email: "roomy@example.com", Remaining: 5, ResetAtMs: now.Add(4 * time.Hour).UnixMilli()},
+	}
+
+	ranked := rankOAuthCandidates(profiles, now, stateDir, nil)
+	if ranked[0].Profile != "roomy" {
+		t.Fatalf("ranked[0].Profile = %q, want %q (capped profile must demote below it)", ranked[0].Profile, "roomy")
+	}
+	// "capped" still wins on raw score, which is exactly why this is a
+	// meaningful test of the demotion rule rather than the score alone.
+	if ranked[1].Score <= ranked[0].Score {
+		t.Fatalf("capped.Score = %v, want it higher than roomy.Score = %v (demotion, not score, decided the order)", ranked[1].Score, ranked[0].Score)
+	}
+}
+
+func TestDecayedUsageHalfLife(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	halfLife := 900 * time.Second
+
+	v := oauthRecentUsage{Value: 4, AtMs: now.Add(-halfLife).UnixMilli()}
+	got := decayedUsage(v, now, halfLife)
+	if got < 1.9 || got > 2.1 {
+		t.Fatalf("decayedUsage after one half-life = %v, want ~2", got)
+	}
+}
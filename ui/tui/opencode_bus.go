@@ -1,8 +1,8 @@
 package main
 
 import (
-	"bufio"
 	"encoding/json"
+	"errors"
 	"os"
 	"path/filepath"
 	"strings"
@@ -80,88 +80,46 @@ func appendOpencodeRequest(path string, req opencodeTurnRequest) error {
 	return err
 }
 
-func readOpencodeResponses(path string, offset int64) ([]opencodeTurnResponse, int64) {
+// readOpencodeResponses returns the responses available at offset, the
+// advanced offset, and a non-nil *multiError of ErrCorruptLine entries if
+// any lines failed to parse as JSON (the well-formed responses are still
+// returned; the error is informational so the TUI can badge a partial read).
+// A nil path or an I/O failure opening the file returns a plain, unchanged
+// offset with no error (same as before; there's nothing to badge).
+func readOpencodeResponses(path string, offset int64) ([]opencodeTurnResponse, int64, error) {
 	if strings.TrimSpace(path) == "" {
-		return nil, offset
+		return nil, offset, nil
 	}
-	f, err := os.Open(path)
-	if err != nil {
-		return nil, offset
-	}
-	defer f.Close()
-
-	st, err := f.Stat()
-	if err == nil && offset > st.Size() {
-		offset = st.Size()
-	}
-	if offset > 0 {
-		if _, err := f.Seek(offset, 0); err != nil {
-			return nil, offset
+	out, cur, err := readJSONLFrom(path, offset, func(line []byte) (opencodeTurnResponse, bool) {
+		var r opencodeTurnResponse
+		if json.Unmarshal(line, &r) == nil && r.Version == 1 && strings.TrimSpace(r.Type) != "" {
+			return r, true
 		}
+		return opencodeTurnResponse{}, false
+	})
+	var be *busError
+	if errors.As(err, &be) && be.Code == ErrIO {
+		return nil, offset, nil
 	}
-
-	var out []opencodeTurnResponse
-	reader := bufio.NewReader(f)
-	cur := offset
-	for {
-		line, err := reader.ReadString('\n')
-		if line != "" {
-			cur += int64(len(line))
-			txt := strings.TrimSpace(line)
-			if txt != "" {
-				var r opencodeTurnResponse
-				if json.Unmarshal([]byte(txt), &r) == nil && r.Version == 1 && strings.TrimSpace(r.Type) != "" {
-					out = append(out, r)
-				}
-			}
-		}
-		if err != nil {
-			break
-		}
-	}
-	return out, cur
+	return out, cur, err
 }
 
-func readOpencodeEvents(path string, offset int64) ([]opencodeTurnEvent, int64) {
+func readOpencodeEvents(path string, offset int64) ([]opencodeTurnEvent, int64, error) {
 	if strings.TrimSpace(path) == "" {
-		return nil, offset
-	}
-	f, err := os.Open(path)
-	if err != nil {
-		return nil, offset
+		return nil, offset, nil
 	}
-	defer f.Close()
-
-	st, err := f.Stat()
-	if err == nil && offset > st.Size() {
-		offset = st.Size()
-	}
-	if offset > 0 {
-		if _, err := f.Seek(offset, 0); err != nil {
-			return nil, offset
-		}
-	}
-
-	var out []opencodeTurnEvent
-	reader := bufio.NewReader(f)
-	cur := offset
-	for {
-		line, err := reader.ReadString('\n')
-		if line != "" {
-			cur += int64(len(line))
-			txt := strings.TrimSpace(line)
-			if txt != "" {
-				var ev opencodeTurnEvent
-				if json.Unmarshal([]byte(txt), &ev) == nil && ev.Version == 1 && strings.TrimSpace(ev.Type) == "turn.event" {
-					out = append(out, ev)
-				}
-			}
-		}
-		if err != nil {
-			break
+	out, cur, err := readJSONLFrom(path, offset, func(line []byte) (opencodeTurnEvent, bool) {
+		var ev opencodeTurnEvent
+		if json.Unmarshal(line, &ev) == nil && ev.Version == 1 && strings.TrimSpace(ev.Type) == "turn.event" {
+			return ev, true
 		}
+		return opencodeTurnEvent{}, false
+	})
+	var be *busError
+	if errors.As(err, &be) && be.Code == ErrIO {
+		return nil, offset, nil
 	}
-	return out, cur
+	return out, cur, err
 }
 
 func opencodeExecutorReadyPath(stateDir string, sessionID string) string {
@@ -179,3 +137,22 @@ func isOpencodeExecutorReady(stateDir string, sessionID string, now time.Time) b
 	}
 	return true
 }
+
+// opencodeExecutorBusAddr reads the gRPC listen address the executor
+// published in its ready sentinel (only present when WORKBENCH_BUS=grpc).
+// Returns "" for the file-bus transport, where the sentinel carries no
+// address and readiness is determined purely by its mtime.
+func opencodeExecutorBusAddr(stateDir string, sessionID string) string {
+	p := opencodeExecutorReadyPath(stateDir, sessionID)
+	raw, err := os.ReadFile(p)
+	if err != nil {
+		return ""
+	}
+	var parsed struct {
+		BusAddr string `json:"busAddr"`
+	}
+	if json.Unmarshal(raw, &parsed) != nil {
+		return ""
+	}
+	return strings.TrimSpace(parsed.BusAddr)
+}
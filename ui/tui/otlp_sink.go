@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	otlpBatchMax      = 50
+	otlpFlushInterval = 2 * time.Second
+)
+
+// otlpEventSink is eventLogger's built-in EventSink: it batches eventRecords
+// in memory and POSTs them as an OTLP/HTTP logs request (the JSON encoding
+// an OpenTelemetry Collector's otlphttp receiver accepts) on a timer or once
+// a batch fills up, rather than paying a network round trip on every
+// Append. CorrelationID/CausationID are hashed down to OTLP's fixed-width
+// trace/span IDs, so every event in a turn (request, deltas, response)
+// lands in the same trace in Jaeger/Tempo, and Type/Source become log
+// attributes Loki-style label filters can key on.
+type otlpEventSink struct {
+	endpoint string
+	client   *http.Client
+
+	mu      sync.Mutex
+	pending []eventRecord
+
+	flushCh   chan struct{}
+	closeCh   chan struct{}
+	closeOnce sync.Once
+}
+
+func newOTLPEventSink(endpoint string) *otlpEventSink {
+	s := &otlpEventSink{
+		endpoint: strings.TrimSpace(endpoint),
+		client:   &http.Client{Timeout: 10 * time.Second},
+		flushCh:  make(chan struct{}, 1),
+		closeCh:  make(chan struct{}),
+	}
+	go s.loop()
+	return s
+}
+
+func (s *otlpEventSink) Emit(rec eventRecord) error {
+	s.mu.Lock()
+	s.pending = append(s.pending, rec)
+	full := len(s.pending) >= otlpBatchMax
+	s.mu.Unlock()
+	if full {
+		select {
+		case s.flushCh <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// Close flushes any pending batch and stops the background loop. Safe to
+// call more than once.
+func (s *otlpEventSink) Close() {
+	s.closeOnce.Do(func() { close(s.closeCh) })
+}
+
+func (s *otlpEventSink) loop() {
+	ticker := time.NewTicker(otlpFlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.flush()
+		case <-s.flushCh:
+			s.flush()
+		case <-s.closeCh:
+			s.flush()
+			return
+		}
+	}
+}
+
+func (s *otlpEventSink) flush() {
+	s.mu.Lock()
+	if len(s.pending) == 0 {
+		s.mu.Unlock()
+		return
+	}
+	batch := s.pending
+	s.pending = nil
+	s.mu.Unlock()
+
+	b, err := json.Marshal(otlpLogsPayload(batch))
+	if err != nil {
+		return
+	}
+	req, err := http.NewRequest(http.MethodPost, s.endpoint, bytes.NewReader(b))
+	if err != nil {
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return
+	}
+	_ = resp.Body.Close()
+}
+
+// otlpLogsPayload builds an ExportLogsServiceRequest (OTLP/JSON) body out of
+// a batch of eventRecords.
+func otlpLogsPayload(records []eventRecord) map[string]any {
+	logRecords := make([]map[string]any, 0, len(records))
+	for _, rec := range records {
+		payloadJSON, _ := json.Marshal(rec.Payload)
+		lr := map[string]any{
+			"timeUnixNano":         otlpUnixNano(rec.Timestamp),
+			"observedTimeUnixNano": otlpUnixNano(rec.Timestamp),
+			"severityText":         "INFO",
+			"body":                 map[string]any{"stringValue": string(payloadJSON)},
+			"attributes": []map[string]any{
+				{"key": "event.type", "value": map[string]any{"stringValue": rec.Type}},
+				{"key": "event.source", "value": map[string]any{"stringValue": rec.Source}},
+				{"key": "event.seq", "value": map[string]any{"intValue": strconv.FormatUint(rec.Seq, 10)}},
+			},
+		}
+		if tid := otlpTraceID(rec.CorrelationID); tid != "" {
+			lr["traceId"] = tid
+		}
+		if sid := otlpSpanID(rec.CausationID); sid != "" {
+			lr["spanId"] = sid
+		}
+		logRecords = append(logRecords, lr)
+	}
+	return map[string]any{
+		"resourceLogs": []map[string]any{
+			{
+				"resource": map[string]any{
+					"attributes": []map[string]any{
+						{"key": "service.name", "value": map[string]any{"stringValue": "workbench-tui"}},
+					},
+				},
+				"scopeLogs": []map[string]any{
+					{"logRecords": logRecords},
+				},
+			},
+		},
+	}
+}
+
+// otlpTraceID derives a 16-byte (32 hex char) OTLP trace ID from a
+// correlation ID by hashing it, so the same correlationID always maps to
+// the same trace regardless of its own length or format.
+func otlpTraceID(correlationID string) string {
+	if strings.TrimSpace(correlationID) == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(correlationID))
+	return hex.EncodeToString(sum[:16])
+}
+
+// otlpSpanID derives an 8-byte (16 hex char) OTLP span ID from a causation
+// ID the same way otlpTraceID derives a trace ID.
+func otlpSpanID(causationID string) string {
+	if strings.TrimSpace(causationID) == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(causationID))
+	return hex.EncodeToString(sum[:8])
+}
+
+// otlpUnixNano parses an eventRecord's RFC3339Nano timestamp into OTLP's
+// fixed64-as-decimal-string nanosecond encoding; an unparseable timestamp
+// (shouldn't happen - Append always writes time.Now().UTC().Format(...))
+// degrades to "0" rather than dropping the record.
+func otlpUnixNano(ts string) string {
+	t, err := time.Parse(time.RFC3339Nano, ts)
+	if err != nil {
+		return "0"
+	}
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
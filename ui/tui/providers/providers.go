@@ -0,0 +1,323 @@
+// Package providers holds the registry of LLM providers and chat runtimes:
+// which runtimes each provider can drive natively vs. through a proxy, each
+// provider's default runtime, and what auth a provider needs. Before this
+// package existed, that data was three parallel switch statements in
+// model.go (providerOptions, getCompatibility, defaultRuntimeForProvider);
+// centralizing it here lets a deployment add a provider - Anthropic direct,
+// Groq, Ollama, a self-hosted endpoint - by editing one registration (or
+// dropping a JSON file in stateDir) instead of touching every switch. It
+// also holds Handlers, the generic runtime-ID -> Handler table that
+// replaces the if/else chain dispatchChatRuntime used to pick a runtime's
+// submit logic with a lookup, the same way commands.Registry replaced the
+// slash-command switch.
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// AuthKind names what a Provider needs before a runtime can talk to it.
+type AuthKind string
+
+const (
+	AuthNone   AuthKind = "none"   // no credentials (e.g. a local Ollama daemon)
+	AuthOAuth  AuthKind = "oauth"  // drawn from the session's OAuth pool
+	AuthEnvVar AuthKind = "env"    // one or more environment variables
+)
+
+// Runtime is one selectable runtime + mode, independent of provider.
+type Runtime struct {
+	ID          string
+	Label       string
+	Description string
+}
+
+// Compatibility says whether a Provider/Runtime pairing works directly or
+// needs a proxy in front of it.
+type Compatibility int
+
+const (
+	CompatNative Compatibility = iota // works directly
+	CompatProxy                       // needs proxy setup
+)
+
+func (c Compatibility) String() string {
+	switch c {
+	case CompatNative:
+		return "native"
+	case CompatProxy:
+		return "proxy"
+	default:
+		return "unknown"
+	}
+}
+
+func (c Compatibility) Label() string {
+	if c == CompatNative {
+		return "✓ Native"
+	}
+	return "⚠ Proxy required"
+}
+
+// Provider is one selectable LLM vendor: its display label, which runtimes
+// it drives natively (anything else falls back to CompatProxy), its default
+// runtime, and what auth it needs.
+type Provider struct {
+	Label           string
+	NativeRuntimes  []string // runtime IDs this provider drives without a proxy
+	AnyRuntime      bool     // true if this provider is native with every runtime (e.g. Direct API's "any provider" runtimes)
+	DefaultRuntime  string
+	Auth            AuthKind
+	EnvVars         []string // names of the env vars Auth == AuthEnvVar needs
+}
+
+func (p Provider) isNative(runtimeID string) bool {
+	r := strings.ToLower(runtimeID)
+	for _, n := range p.NativeRuntimes {
+		if strings.Contains(r, strings.ToLower(n)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Registry is the live set of providers and runtimes. The zero value is not
+// usable; call NewRegistry.
+type Registry struct {
+	mu        sync.RWMutex
+	providers []Provider
+	runtimes  []Runtime
+	// runtimeNative, when set for a runtime ID, overrides every provider's
+	// per-runtime check (e.g. mcp-agent and opencode-run are native with
+	// any provider - they're tool-use/headless runtimes, not chat proxies).
+	runtimeNative map[string]bool
+}
+
+// NewRegistry builds the registry this repo ships with. Additional
+// providers can be layered on top via LoadOverrides.
+func NewRegistry() *Registry {
+	r := &Registry{
+		runtimes: []Runtime{
+			{ID: "codex-chat", Label: "Codex – Chat Mode", Description: "OpenAI API, chat-only interface"},
+			{ID: "codex-cli", Label: "Codex – CLI Mode", Description: "OpenAI with Codex CLI, full code editing with file access"},
+			{ID: "opencode-run", Label: "OpenCode – Run Mode", Description: "OpenCode headless runner (streams tool/step events)"},
+			{ID: "claude-code", Label: "Claude Code", Description: "Anthropic native TTY, full capabilities (code editing, tools)"},
+			{ID: "direct-api", Label: "Direct API", Description: "Any provider, chat-only interface"},
+			{ID: "mcp-agent", Label: "MCP Agent", Description: "Invoke a tool on a connected Model Context Protocol server"},
+		},
+		providers: []Provider{
+			{Label: "OpenAI", NativeRuntimes: []string{"codex"}, DefaultRuntime: "codex-cli", Auth: AuthOAuth},
+			{Label: "Anthropic", NativeRuntimes: []string{"claude"}, DefaultRuntime: "claude-code", Auth: AuthEnvVar, EnvVars: []string{"ANTHROPIC_API_KEY"}},
+			{Label: "Google (Gemini)", NativeRuntimes: nil, DefaultRuntime: "direct-api", Auth: AuthEnvVar, EnvVars: []string{"GEMINI_API_KEY"}},
+			{Label: "Ollama (local)", NativeRuntimes: nil, DefaultRuntime: "direct-api", Auth: AuthNone},
+		},
+		runtimeNative: map[string]bool{
+			"opencode-run": true, // headless runner, independent of the chat provider
+			"mcp-agent":    true, // tool-use runtime, independent of the chat provider
+			"direct-api":   true, // "any provider" runtime
+		},
+	}
+	return r
+}
+
+// Providers returns the registered providers' display labels, in
+// registration order.
+func (r *Registry) Providers() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]string, 0, len(r.providers))
+	for _, p := range r.providers {
+		out = append(out, p.Label)
+	}
+	return out
+}
+
+// Runtimes returns every registered runtime, in registration order.
+func (r *Registry) Runtimes() []Runtime {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]Runtime, len(r.runtimes))
+	copy(out, r.runtimes)
+	return out
+}
+
+func (r *Registry) provider(label string) (Provider, bool) {
+	want := strings.ToLower(strings.TrimSpace(label))
+	for _, p := range r.providers {
+		if strings.ToLower(p.Label) == want {
+			return p, true
+		}
+	}
+	return Provider{}, false
+}
+
+// Compatibility reports whether provider can drive runtimeID natively or
+// needs a proxy. An unregistered provider or runtime is treated as
+// CompatProxy rather than erroring, the same permissive fallback
+// getCompatibility used.
+func (r *Registry) Compatibility(providerLabel, runtimeID string) Compatibility {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.runtimeNative[strings.TrimSpace(runtimeID)] {
+		return CompatNative
+	}
+	p, ok := r.provider(providerLabel)
+	if !ok {
+		return CompatProxy
+	}
+	if p.AnyRuntime || p.isNative(runtimeID) {
+		return CompatNative
+	}
+	return CompatProxy
+}
+
+// DefaultRuntime returns the runtime a newly selected provider should start
+// on. An unregistered provider defaults to "direct-api".
+func (r *Registry) DefaultRuntime(providerLabel string) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if p, ok := r.provider(providerLabel); ok && strings.TrimSpace(p.DefaultRuntime) != "" {
+		return p.DefaultRuntime
+	}
+	return "direct-api"
+}
+
+// RuntimeOption is a runtime as offered for a specific provider: its label
+// is annotated with "[proxy]" when Compatibility says it needs one.
+type RuntimeOption struct {
+	ID    string
+	Label string
+}
+
+// RuntimesForProvider returns every registered runtime, labeled for
+// providerLabel's compatibility with each.
+func (r *Registry) RuntimesForProvider(providerLabel string) []RuntimeOption {
+	runtimes := r.Runtimes()
+	out := make([]RuntimeOption, 0, len(runtimes))
+	for _, rt := range runtimes {
+		label := rt.Label
+		if r.Compatibility(providerLabel, rt.ID) == CompatProxy {
+			label = label + " [proxy]"
+		}
+		out = append(out, RuntimeOption{ID: rt.ID, Label: label})
+	}
+	return out
+}
+
+// ProviderInfo returns the full registered Provider record for
+// providerLabel - its Auth kind and required EnvVars, not just the
+// yes/no Compatibility answers the provider/runtime pickers render by
+// default. Preview panes use this to show what a provider needs before a
+// user switches to it.
+func (r *Registry) ProviderInfo(providerLabel string) (Provider, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.provider(providerLabel)
+}
+
+// RuntimeLabel looks up a runtime ID's display label, or returns the ID
+// itself if it isn't registered; an empty ID returns "(none)".
+func (r *Registry) RuntimeLabel(id string) string {
+	id = strings.TrimSpace(id)
+	if id == "" {
+		return "(none)"
+	}
+	for _, rt := range r.Runtimes() {
+		if rt.ID == id {
+			return rt.Label
+		}
+	}
+	return id
+}
+
+// overridesFile is the shape LoadOverrides reads from stateDir/providers.json:
+// additional providers to register on top of the built-ins, in the same
+// shape mcp.json uses for MCP servers.
+type overridesFile struct {
+	Providers []Provider `json:"providers"`
+}
+
+// LoadOverrides reads stateDir/providers.json, if present, and registers
+// each provider it names (replacing any built-in provider with the same
+// label). A missing file isn't an error - it just means no overrides are
+// configured - but a malformed one is, so a typo surfaces instead of
+// silently running with only the built-ins.
+func (r *Registry) LoadOverrides(stateDir string) error {
+	path := filepath.Join(stateDir, "providers.json")
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	var overrides overridesFile
+	if err := json.Unmarshal(raw, &overrides); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, p := range overrides.Providers {
+		replaced := false
+		for i, existing := range r.providers {
+			if strings.EqualFold(existing.Label, p.Label) {
+				r.providers[i] = p
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			r.providers = append(r.providers, p)
+		}
+	}
+	return nil
+}
+
+// Turn is the provider-agnostic shape of one chat submission: the fields
+// sendChat threads through every runtime's dispatch today, pulled out so a
+// Handler doesn't need the tui package's richer chat-message types.
+type Turn struct {
+	CorrelationID  string
+	Text           string
+	Side           string // "" for the left pane, "right" for the split-screen pane
+	ExcludeProfile string
+}
+
+// Handler submits one Turn against runtime M's application model and
+// reports back the same way commands.Cmd[M].Run does: receive the model by
+// value, return the (possibly mutated) model plus an optional tea.Cmd. Chat
+// dispatch mutates in-flight/correlation state and schedules async work the
+// same way slash commands do, so it follows that convention rather than
+// inventing a second one.
+type Handler[M any] func(ctx context.Context, m M, turn Turn) (M, tea.Cmd)
+
+// Handlers is a lookup table of Handler by runtime ID. It is generic over M
+// the same way commands.Registry[M] is, so this package stays independent
+// of the tui package; tui instantiates Handlers[appModel] once in
+// newAppModel and registers its per-runtime dispatch funcs against it.
+type Handlers[M any] struct {
+	byID map[string]Handler[M]
+}
+
+// NewHandlers returns an empty Handlers ready for Register calls.
+func NewHandlers[M any]() *Handlers[M] {
+	return &Handlers[M]{byID: make(map[string]Handler[M])}
+}
+
+// Register binds runtimeID to fn, overwriting any previous binding.
+func (h *Handlers[M]) Register(runtimeID string, fn Handler[M]) {
+	h.byID[strings.TrimSpace(runtimeID)] = fn
+}
+
+// Lookup resolves runtimeID to its Handler, if one is registered.
+func (h *Handlers[M]) Lookup(runtimeID string) (Handler[M], bool) {
+	fn, ok := h.byID[strings.TrimSpace(runtimeID)]
+	return fn, ok
+}
@@ -0,0 +1,235 @@
+// Package ratelimit gives each OAuth profile its own token bucket plus a
+// three-state circuit breaker (closed/half-open/open), replacing the
+// "retry once after a fixed 10s" logic that used to live inline in the
+// chatReplyMsg 429 handler and treated every profile the same regardless of
+// how it was actually behaving. A profile's refill rate is learned from the
+// Retry-After header its own 429s report, and a tripped breaker backs off
+// exponentially with full jitter instead of retrying on a fixed timer, the
+// same shape as the circuit-breaker pattern Netflix's Hystrix popularized.
+// State is persisted to stateDir/oauth/breaker.json so a TUI restart doesn't
+// immediately re-hammer a profile that was still open when the process
+// exited.
+package ratelimit
+
+import (
+	"encoding/json"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// State is one of a profile's circuit breaker states.
+type State string
+
+const (
+	StateClosed   State = "closed"
+	StateHalfOpen State = "half_open"
+	StateOpen     State = "open"
+)
+
+const (
+	defaultCapacity      = 5.0
+	defaultRefillPerSec  = 5.0 / 300.0 // 5 tokens per 5 minutes, absent better data
+	minRefillPerSec      = 1.0 / 3600.0
+	openFailureThreshold = 2
+	baseBackoff          = 10 * time.Second
+	maxBackoff           = 10 * time.Minute
+)
+
+// profileState is one profile's persisted bucket + breaker state.
+type profileState struct {
+	Tokens       float64 `json:"tokens"`
+	Capacity     float64 `json:"capacity"`
+	RefillPerSec float64 `json:"refillPerSec"`
+	UpdatedAtMs  int64   `json:"updatedAtMs"`
+	State        State   `json:"state"`
+	OpenUntilMs  int64   `json:"openUntilMs"`
+	Failures     int     `json:"failures"`
+}
+
+// Limiter tracks every OAuth profile's bucket and breaker, persisting to a
+// single JSON file on every Observe so state survives a TUI restart.
+type Limiter struct {
+	mu       sync.Mutex
+	path     string
+	profiles map[string]*profileState
+}
+
+// Transition reports a breaker state change an Observe call caused, so the
+// caller can emit auth.breaker.open / auth.breaker.close events.
+type Transition struct {
+	Opened bool
+	Closed bool
+}
+
+func breakerPath(stateDir string) string {
+	return filepath.Join(stateDir, "oauth", "breaker.json")
+}
+
+// Open loads persisted breaker state for stateDir, if any, and returns a
+// Limiter ready for Allow/Observe/Pick calls. A missing or unreadable file
+// is not an error; the Limiter simply starts every profile from scratch.
+func Open(stateDir string) (*Limiter, error) {
+	path := breakerPath(stateDir)
+	l := &Limiter{path: path, profiles: map[string]*profileState{}}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return l, nil
+	}
+	var stored map[string]*profileState
+	if err := json.Unmarshal(raw, &stored); err != nil {
+		return l, nil
+	}
+	l.profiles = stored
+	return l, nil
+}
+
+func (l *Limiter) get(profile string) *profileState {
+	ps, ok := l.profiles[profile]
+	if !ok {
+		ps = &profileState{Tokens: defaultCapacity, Capacity: defaultCapacity, RefillPerSec: defaultRefillPerSec, State: StateClosed}
+		l.profiles[profile] = ps
+	}
+	return ps
+}
+
+// refill tops up ps.Tokens for elapsed time since UpdatedAtMs and, if an
+// open breaker's backoff window has passed, demotes it to half-open so the
+// next Allow call can admit exactly one trial request.
+func refill(ps *profileState, nowMs int64) {
+	if ps.UpdatedAtMs > 0 && nowMs > ps.UpdatedAtMs {
+		elapsed := float64(nowMs-ps.UpdatedAtMs) / 1000
+		ps.Tokens = math.Min(ps.Capacity, ps.Tokens+elapsed*ps.RefillPerSec)
+	}
+	ps.UpdatedAtMs = nowMs
+	if ps.State == StateOpen && nowMs >= ps.OpenUntilMs {
+		ps.State = StateHalfOpen
+	}
+}
+
+// Allow reports whether profile may take another turn right now: its
+// breaker must not be open, and it must have at least one token available.
+// A half-open breaker is allowed through (exactly one trial at a time, in
+// practice, since the caller only calls Allow once per dispatch).
+func (l *Limiter) Allow(profile string, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	ps := l.get(profile)
+	refill(ps, now.UTC().UnixMilli())
+	if ps.State == StateOpen {
+		return false
+	}
+	return ps.Tokens >= 1
+}
+
+// Pick returns whichever candidate (already ranked best-first by the
+// caller) Allow accepts with the most tokens available, so the scheduler
+// favors fresher profiles over the ranking's raw order when several are
+// usable. Candidates that Allow rejects are skipped entirely.
+func (l *Limiter) Pick(candidates []string, now time.Time) (string, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	nowMs := now.UTC().UnixMilli()
+
+	best := ""
+	bestTokens := -1.0
+	for _, c := range candidates {
+		ps := l.get(c)
+		refill(ps, nowMs)
+		if ps.State == StateOpen || ps.Tokens < 1 {
+			continue
+		}
+		if ps.Tokens > bestTokens {
+			best = c
+			bestTokens = ps.Tokens
+		}
+	}
+	if best == "" {
+		return "", false
+	}
+	return best, true
+}
+
+// Observe feeds one completed turn's outcome back into profile's bucket and
+// breaker: a token is consumed if one is available, a 429's retryAfterMs (if any)
+// recalibrates the refill rate, and consecutive failures trip the breaker
+// open with exponential backoff plus full jitter. A clean response closes a
+// half-open breaker and resets the failure streak.
+func (l *Limiter) Observe(profile string, status int, retryAfterMs int64, now time.Time) Transition {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	ps := l.get(profile)
+	nowMs := now.UTC().UnixMilli()
+	refill(ps, nowMs)
+
+	if ps.Tokens >= 1 {
+		ps.Tokens--
+	}
+
+	failed := status == 429 || status >= 500
+	var t Transition
+	if failed {
+		if retryAfterMs > 0 {
+			learned := 1.0 / (float64(retryAfterMs) / 1000)
+			if learned < minRefillPerSec {
+				learned = minRefillPerSec
+			}
+			ps.RefillPerSec = learned
+		}
+		ps.Failures++
+		if ps.Failures >= openFailureThreshold && ps.State != StateOpen {
+			ps.State = StateOpen
+			ps.OpenUntilMs = nowMs + jitteredBackoff(ps.Failures)
+			t.Opened = true
+		} else if ps.State == StateHalfOpen {
+			// The trial request failed too; reopen with a longer window.
+			ps.State = StateOpen
+			ps.OpenUntilMs = nowMs + jitteredBackoff(ps.Failures)
+			t.Opened = true
+		}
+	} else {
+		if ps.State != StateClosed {
+			t.Closed = true
+		}
+		ps.State = StateClosed
+		ps.Failures = 0
+	}
+
+	l.save()
+	return t
+}
+
+// jitteredBackoff is exponential backoff (baseBackoff * 2^(failures-1)),
+// capped at maxBackoff, with full jitter: the caller gets a random duration
+// in [0, backoff] so many profiles tripped by the same outage don't all
+// retry in lockstep.
+func jitteredBackoff(failures int) int64 {
+	backoff := float64(baseBackoff) * math.Pow(2, float64(failures-1))
+	if backoff > float64(maxBackoff) {
+		backoff = float64(maxBackoff)
+	}
+	return int64(rand.Float64() * backoff)
+}
+
+// save persists every known profile's state, best-effort: a write failure
+// just means the next restart re-learns from a cold state, not a crash.
+func (l *Limiter) save() {
+	if l.path == "" {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(l.path), 0o755); err != nil {
+		return
+	}
+	b, err := json.MarshalIndent(l.profiles, "", "  ")
+	if err != nil {
+		return
+	}
+	tmp := l.path + ".tmp"
+	if os.WriteFile(tmp, b, 0o644) != nil {
+		return
+	}
+	_ = os.Rename(tmp, l.path)
+}
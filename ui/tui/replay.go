@@ -0,0 +1,176 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// tapeRecord is one line of a "session tape" written by --record and fed
+// back by --replay: the busCommand verbatim, the elapsed time (ms since
+// recording started) it was accepted at, and the actionSource that ended
+// up applying it. Recording the elapsed time rather than a wall-clock
+// timestamp is what lets --replay reproduce the original cadence (scaled
+// by --speed) regardless of when the tape is replayed.
+type tapeRecord struct {
+	Version      int        `json:"version"`
+	AtMs         int64      `json:"atMs"`
+	Command      busCommand `json:"command"`
+	ActionSource string     `json:"actionSource"`
+}
+
+// appendTapeRecord appends one tapeRecord to path, creating it if needed.
+func appendTapeRecord(path string, startedAt time.Time, c busCommand, actionSource string) error {
+	rec := tapeRecord{
+		Version:      1,
+		AtMs:         time.Since(startedAt).Milliseconds(),
+		Command:      c,
+		ActionSource: actionSource,
+	}
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(b, '\n'))
+	return err
+}
+
+// loadTape reads a tape file written by --record. Malformed lines are
+// skipped rather than failing the whole load, matching readBusCommands'
+// tolerance for a truncated or hand-edited JSONL file.
+func loadTape(path string) ([]tapeRecord, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var out []tapeRecord
+	for _, line := range strings.Split(string(raw), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		var rec tapeRecord
+		if json.Unmarshal([]byte(line), &rec) != nil {
+			continue
+		}
+		out = append(out, rec)
+	}
+	return out, nil
+}
+
+// replayState drives a loaded tape through applyBusCommand. It's held
+// behind a pointer in appModel (like hookBus or auditLogger) since it's
+// mutated by the //replay pause|resume|step command independently of the
+// tick loop that actually consumes it.
+type replayState struct {
+	mu        sync.Mutex
+	records   []tapeRecord
+	cursor    int
+	speed     float64
+	until     string
+	paused    bool
+	stepOnce  bool
+	done      bool
+	startedAt time.Time
+}
+
+// newReplayState seeds a replay from records loaded with loadTape. speed
+// scales the recorded cadence (2 replays twice as fast, 0.5 half as fast);
+// speed <= 0 replays as fast as the tick loop can drive it, ignoring
+// recorded timing entirely. until, if set, stops the replay just before
+// the first command whose Type matches it (case-insensitively), e.g.
+// "stop".
+func newReplayState(records []tapeRecord, speed float64, until string) *replayState {
+	return &replayState{
+		records:   records,
+		speed:     speed,
+		until:     strings.ToLower(strings.TrimSpace(until)),
+		startedAt: time.Now(),
+	}
+}
+
+// Pause gates next from returning cadence-driven records until Resume or a
+// Step call.
+func (r *replayState) Pause() {
+	r.mu.Lock()
+	r.paused = true
+	r.mu.Unlock()
+}
+
+func (r *replayState) Resume() {
+	r.mu.Lock()
+	r.paused = false
+	r.mu.Unlock()
+}
+
+// Step applies exactly one more record on the next tick, regardless of
+// pause state or whether its scheduled cadence has arrived yet.
+func (r *replayState) Step() {
+	r.mu.Lock()
+	r.stepOnce = true
+	r.mu.Unlock()
+}
+
+// next returns the next tape record due to be applied, or ok=false if
+// nothing should be applied yet: the tape is exhausted, replay is paused
+// and no step was requested, the next record hasn't reached its scheduled
+// (speed-scaled) time, or it matches --until and the replay is stopping
+// before it.
+func (r *replayState) next(now time.Time) (tapeRecord, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.done || r.cursor >= len(r.records) {
+		r.done = true
+		return tapeRecord{}, false
+	}
+
+	step := r.stepOnce
+	if r.paused && !step {
+		return tapeRecord{}, false
+	}
+
+	rec := r.records[r.cursor]
+	if r.until != "" && strings.EqualFold(strings.TrimSpace(rec.Command.Type), r.until) {
+		r.done = true
+		return tapeRecord{}, false
+	}
+
+	if !step && r.speed > 0 {
+		due := r.startedAt.Add(time.Duration(float64(rec.AtMs)/r.speed) * time.Millisecond)
+		if now.Before(due) {
+			return tapeRecord{}, false
+		}
+	}
+
+	r.cursor++
+	r.stepOnce = false
+	return rec, true
+}
+
+// consumeReplay is onTick's hook into an in-progress --replay: it applies
+// at most one due tape record per tick (so a burst of overdue records
+// drains over a few ticks instead of all at once), tagging the replayed
+// command's Source as "replay" so audit.jsonl and systemAlerts can tell it
+// apart from a live cli/tui/system command.
+func (m appModel) consumeReplay() (appModel, tea.Cmd) {
+	if m.replay == nil {
+		return m, nil
+	}
+	rec, ok := m.replay.next(m.now)
+	if !ok {
+		return m, nil
+	}
+	c := rec.Command
+	c.Source = "replay"
+	return m.applyBusCommand(c)
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+// scrollbackMaxEntries bounds the on-disk ring buffer: enough to grep back
+// through a long session even after trimChatRoleLines has dropped the
+// oldest entries from memory, without the file growing without bound.
+const scrollbackMaxEntries = 10000
+
+// scrollbackCompactEvery is how many appends elapse between ring-buffer
+// compactions, the same batched-rewrite trade-off history.WAL's
+// snapshotInterval makes rather than truncating the file on every append.
+const scrollbackCompactEvery = 500
+
+// scrollbackEntry is one line in scrollback.jsonl.
+type scrollbackEntry struct {
+	Role string `json:"role"`
+	Text string `json:"text"`
+}
+
+// scrollback is a per-session, file-backed ring buffer of every chat line
+// appended this process (and, on resume, already on disk from earlier
+// runs), capped at scrollbackMaxEntries. It lives alongside history.WAL
+// under stateDir/<sessionID>/ but is a distinct file: the WAL is
+// the source of truth for replay/rewind, while scrollback.jsonl is purely
+// the search index openChatSearch loads, so a line chatRoleLinesMax
+// trims off the in-memory transcript is still greppable with Ctrl-R.
+type scrollback struct {
+	path    string
+	appends int
+}
+
+func openScrollback(stateDir string, sessionID string) *scrollback {
+	dir := filepath.Join(stateDir, sessionID)
+	_ = os.MkdirAll(dir, 0o755)
+	return &scrollback{path: filepath.Join(dir, "scrollback.jsonl")}
+}
+
+// Append writes one entry and, every scrollbackCompactEvery appends,
+// compacts the file back down to scrollbackMaxEntries so a day-long
+// session's search index doesn't grow without bound.
+func (s *scrollback) Append(role string, text string) {
+	if s == nil {
+		return
+	}
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return
+	}
+	b, err := json.Marshal(scrollbackEntry{Role: role, Text: text})
+	if err == nil {
+		_, _ = f.Write(append(b, '\n'))
+	}
+	_ = f.Close()
+	s.appends++
+	if s.appends%scrollbackCompactEvery == 0 {
+		s.compact()
+	}
+}
+
+// compact rewrites scrollback.jsonl to at most scrollbackMaxEntries
+// entries, dropping the oldest first.
+func (s *scrollback) compact() {
+	entries, err := s.Load()
+	if err != nil || len(entries) <= scrollbackMaxEntries {
+		return
+	}
+	entries = entries[len(entries)-scrollbackMaxEntries:]
+	tmp := s.path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return
+	}
+	w := bufio.NewWriter(f)
+	for _, e := range entries {
+		b, err := json.Marshal(e)
+		if err != nil {
+			continue
+		}
+		_, _ = w.Write(append(b, '\n'))
+	}
+	_ = w.Flush()
+	_ = f.Close()
+	_ = os.Rename(tmp, s.path)
+}
+
+// Load reads every entry currently in scrollback.jsonl, oldest first.
+func (s *scrollback) Load() ([]scrollbackEntry, error) {
+	if s == nil {
+		return nil, nil
+	}
+	f, err := os.Open(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	var out []scrollbackEntry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		var e scrollbackEntry
+		if json.Unmarshal(scanner.Bytes(), &e) == nil {
+			out = append(out, e)
+		}
+	}
+	return out, scanner.Err()
+}
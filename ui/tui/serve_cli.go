@@ -0,0 +1,46 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// runServeCLI handles "workbench serve [--listen addr] [--host-key path]",
+// hosting the workbench TUI over SSH for every connecting client instead of
+// running one locally. It is dispatched directly from main() before
+// flag.Parse() touches the TUI's own flags, the same way "audit" and
+// "events" are - and is unrelated to the pre-existing --serve flag, which
+// runs one headless, non-interactive local session for CLI/devops control
+// rather than accepting remote connections.
+func runServeCLI(args []string) int {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	listen := fs.String("listen", ":2222", "address to accept SSH connections on")
+	hostKeyPath := fs.String("host-key", "", "path to the server's persisted SSH host key (default stateDir/ssh_host_ed25519)")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	stateDir := os.Getenv("WORKBENCH_STATE_DIR")
+	if strings.TrimSpace(stateDir) == "" {
+		stateDir = ".workbench"
+	}
+	if err := os.MkdirAll(stateDir, 0o755); err != nil {
+		fmt.Fprintln(os.Stderr, "serve:", err)
+		return 1
+	}
+
+	keyPath := strings.TrimSpace(*hostKeyPath)
+	if keyPath == "" {
+		keyPath = filepath.Join(stateDir, "ssh_host_ed25519")
+	}
+
+	fmt.Printf("workbench serve: listening on %s (stateDir=%s)\n", *listen, stateDir)
+	if err := runSSHServer(sshServeConfig{addr: *listen, hostKeyPath: keyPath, stateDir: stateDir}); err != nil {
+		fmt.Fprintln(os.Stderr, "serve:", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"workbench/ui/tui/bus"
+	"workbench/ui/tui/history"
+)
+
+// sessionHistoryCap bounds how many sessions' history.wal directories are
+// kept under stateDir. startNewSession calls pruneOldSessions after
+// allocating the new id so the state dir doesn't grow without bound.
+const sessionHistoryCap = 200
+
+// sessionSummary is one row in the session browser overlay: a prior
+// session's id, a title derived from its first user turn, and when its
+// history.wal was last written to (display, recency ordering, and pruning).
+type sessionSummary struct {
+	ID        string
+	Title     string
+	UpdatedAt time.Time
+	Turns     int
+}
+
+// listSessions scans stateDir for session directories (anything holding a
+// history.wal, other than the reserved "state" metadata dir) and returns a
+// summary for each, most-recently-updated first. excludeID, normally the
+// session currently in progress, is left out.
+func listSessions(stateDir string, excludeID string) []sessionSummary {
+	entries, err := os.ReadDir(stateDir)
+	if err != nil {
+		return nil
+	}
+	out := make([]sessionSummary, 0, len(entries))
+	for _, e := range entries {
+		if !e.IsDir() || e.Name() == "state" || e.Name() == excludeID {
+			continue
+		}
+		info, err := os.Stat(filepath.Join(stateDir, e.Name(), "history.wal"))
+		if err != nil {
+			continue
+		}
+		state, err := history.Peek(stateDir, e.Name())
+		if err != nil {
+			continue
+		}
+		out = append(out, sessionSummary{
+			ID:        e.Name(),
+			Title:     sessionTitle(state.RoleLines),
+			UpdatedAt: info.ModTime(),
+			Turns:     len(state.RoleLines),
+		})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].UpdatedAt.After(out[j].UpdatedAt) })
+	return out
+}
+
+// sessionTitle picks the first user turn as a one-line title, the same
+// collapse-and-cap treatment summarizeForAlert gives assistant replies.
+func sessionTitle(lines []history.RoleLine) string {
+	for _, l := range lines {
+		if l.Role != "user" {
+			continue
+		}
+		s := strings.Join(strings.Fields(l.Text), " ")
+		if s == "" {
+			continue
+		}
+		const maxLen = 60
+		if len(s) > maxLen {
+			s = s[:maxLen-1] + "…"
+		}
+		return s
+	}
+	return "(empty session)"
+}
+
+// pruneOldSessions removes the oldest session directories once more than
+// sessionHistoryCap exist, keeping currentID regardless of age.
+func pruneOldSessions(stateDir string, currentID string) {
+	sessions := listSessions(stateDir, currentID)
+	if len(sessions) <= sessionHistoryCap {
+		return
+	}
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].UpdatedAt.Before(sessions[j].UpdatedAt) })
+	for _, s := range sessions[:len(sessions)-sessionHistoryCap] {
+		_ = os.RemoveAll(filepath.Join(stateDir, s.ID))
+	}
+}
+
+// filteredSessionSummaries ranks sessions against query with fuzzyMatch
+// (matched against the title, falling back to the raw id) and returns them
+// score descending. An empty query returns sessions in their given
+// (most-recently-updated-first) order.
+func filteredSessionSummaries(sessions []sessionSummary, query string) []sessionSummary {
+	q := strings.TrimSpace(query)
+	if q == "" {
+		return sessions
+	}
+
+	type scored struct {
+		s     sessionSummary
+		score int
+	}
+	candidates := make([]scored, 0, len(sessions))
+	for _, s := range sessions {
+		if score, _, ok := fuzzyMatch(q, s.Title); ok {
+			candidates = append(candidates, scored{s: s, score: score})
+			continue
+		}
+		if score, _, ok := fuzzyMatch(q, s.ID); ok {
+			candidates = append(candidates, scored{s: s, score: score - 1000})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].score > candidates[j].score })
+
+	out := make([]sessionSummary, 0, len(candidates))
+	for _, c := range candidates {
+		out = append(out, c.s)
+	}
+	return out
+}
+
+// sessionPreviewLines returns the last maxLines role lines of sessionID's
+// history, for the session browser's preview pane.
+func sessionPreviewLines(stateDir string, sessionID string, maxLines int) []history.RoleLine {
+	state, err := history.Peek(stateDir, sessionID)
+	if err != nil {
+		return nil
+	}
+	lines := state.RoleLines
+	if len(lines) > maxLines {
+		lines = lines[len(lines)-maxLines:]
+	}
+	return lines
+}
+
+// resumeSession switches the cockpit to sessionID: reopens its history WAL
+// (mirroring newAppModel's own Open call), restores chatRoleLines/
+// chatMessages from it, resets chatScrollOffset, and repoints every bus
+// path at the resumed session the same way startNewSession does for a
+// fresh one. Emits session.resumed instead of session.new's event.
+func (m appModel) resumeSession(sessionID string) appModel {
+	if m.chatCancel != nil {
+		m.chatCancel()
+		m.chatCancel = nil
+	}
+	m.chatInFlight = false
+	m.chatCorrelationID = ""
+	m.chatActiveProfile = ""
+	m.chatStreamText = ""
+	m.chatScrollOffset = 0
+	m = m.closeSplitRuntime()
+
+	if err := setCurrentSessionID(m.cfg.stateDir, sessionID); err != nil {
+		m.systemAlert(alertWarn, "session.current.failed", "Failed to update current session pointer", map[string]any{"error": err.Error()})
+	}
+
+	m.sessionID = sessionID
+	m.cfg.sessionID = sessionID
+	m.mcpConnected = readMcpConnectedCount(m.cfg.stateDir)
+	m.cfg.mcpConnected = m.mcpConnected
+
+	m.cfg.commandsPath = filepath.Join(m.cfg.stateDir, sessionID, "commands.jsonl")
+	m.cfg.codexRequestsPath = filepath.Join(m.cfg.stateDir, sessionID, "codex.requests.jsonl")
+	m.cfg.codexResponsesPath = filepath.Join(m.cfg.stateDir, sessionID, "codex.responses.jsonl")
+	m.cfg.codexEventsPath = filepath.Join(m.cfg.stateDir, sessionID, "codex.events.jsonl")
+	m.cfg.systemRequestsPath = filepath.Join(m.cfg.stateDir, sessionID, "system.requests.jsonl")
+	m.cfg.systemResponsesPath = filepath.Join(m.cfg.stateDir, sessionID, "system.responses.jsonl")
+	m.cfg.opencodeRequestsPath = filepath.Join(m.cfg.stateDir, sessionID, "opencode.requests.jsonl")
+	m.cfg.opencodeResponsesPath = filepath.Join(m.cfg.stateDir, sessionID, "opencode.responses.jsonl")
+	m.cfg.opencodeEventsPath = filepath.Join(m.cfg.stateDir, sessionID, "opencode.events.jsonl")
+
+	m.commandBusPath = m.cfg.commandsPath
+	m.codexRequestsPath = m.cfg.codexRequestsPath
+	m.codexResponsesPath = m.cfg.codexResponsesPath
+	m.codexEventsPath = m.cfg.codexEventsPath
+	m.systemRequestsPath = m.cfg.systemRequestsPath
+	m.systemResponsesPath = m.cfg.systemResponsesPath
+	m.opencodeRequestsPath = m.cfg.opencodeRequestsPath
+	m.opencodeResponsesPath = m.cfg.opencodeResponsesPath
+	m.opencodeEventsPath = m.cfg.opencodeEventsPath
+
+	if m.commandBusConsumer != nil {
+		_ = m.commandBusConsumer.Close()
+	}
+	consumer, err := bus.OpenConsumer(m.commandBusPath, "tui")
+	if err != nil {
+		m.systemAlert(alertWarn, "command_bus.open.failed", "Failed to open command bus consumer", map[string]any{"error": err.Error()})
+	}
+	m.commandBusConsumer = consumer
+	m.codexResponsesOffset, m.codexEventsOffset = initCodexBus(m.codexResponsesPath, m.codexRequestsPath, m.codexEventsPath)
+	m.opencodeResponsesOffset, m.opencodeEventsOffset = initOpencodeBus(m.opencodeResponsesPath, m.opencodeRequestsPath, m.opencodeEventsPath)
+	m.systemResponsesOffset = initSystemBus(m.systemResponsesPath, m.systemRequestsPath)
+
+	wal, state, err := history.Open(m.cfg.stateDir, sessionID)
+	if err != nil {
+		m.systemAlert(alertWarn, "history.open.failed", "Failed to open history WAL", map[string]any{"error": err.Error()})
+	} else {
+		m.historyWAL = wal
+		m.chatMessages = chatMessagesFromHistory(state.Messages)
+		m.chatRoleLines = chatRoleLinesFromHistory(state.RoleLines)
+		m = m.trimChatRoleLines()
+	}
+
+	m.systemAlert(alertInfo, "session.resumed", "Resumed session "+sessionID, map[string]any{"sessionId": sessionID})
+	m.emitEvent("session.resumed", m.actionSource, map[string]any{"sessionId": sessionID}, "", "")
+	return m
+}
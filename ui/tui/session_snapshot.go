@@ -0,0 +1,341 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"workbench/ui/tui/history"
+)
+
+// snapshotBundle is the content-addressed unit //session snapshot writes:
+// the session's scrollback at the moment of the snapshot, plus the hash of
+// the snapshot it was forked from (if any), so a chain of snapshots can be
+// walked back to its origin the same way a WAL replays from its own entries.
+type snapshotBundle struct {
+	SessionID        string             `json:"sessionId"`
+	ParentHash       string             `json:"parentHash,omitempty"`
+	CreatedAt        string             `json:"createdAt"`
+	Messages         []history.Message  `json:"messages"`
+	RoleLines        []history.RoleLine `json:"roleLines"`
+	SelectedProvider string             `json:"selectedProvider,omitempty"`
+	SelectedRuntime  string             `json:"selectedRuntime,omitempty"`
+	SelectedModel    string             `json:"selectedModel,omitempty"`
+	PermissionMode   string             `json:"permissionMode,omitempty"`
+	ActiveProfile    string             `json:"activeProfile,omitempty"`
+	CodexEventsTail  []string           `json:"codexEventsTail,omitempty"`
+}
+
+// snapshotEventsTailLines is how many trailing lines of codexEventsPath a
+// snapshot carries - enough to reconstruct what the thought stream was
+// doing at snapshot time without the blob growing unbounded for a long
+// session.
+const snapshotEventsTailLines = 50
+
+// tailLines returns the last n non-empty lines of path, or nil if it
+// doesn't exist - codexEventsPath is only written once a runtime that emits
+// codex turn events (codex-cli, or mcp-agent's tool calls) has run, so a
+// session that never used one has no tail to keep.
+func tailLines(path string, n int) []string {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	all := strings.Split(strings.TrimRight(string(raw), "\n"), "\n")
+	lines := make([]string, 0, len(all))
+	for _, l := range all {
+		if strings.TrimSpace(l) != "" {
+			lines = append(lines, l)
+		}
+	}
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return lines
+}
+
+// snapshotsDir is where snapshot blobs and refs live, parallel to the
+// per-session directories under stateDir.
+func snapshotsDir(stateDir string) string {
+	return filepath.Join(stateDir, "snapshots")
+}
+
+func snapshotRefsPath(stateDir string) string {
+	return filepath.Join(snapshotsDir(stateDir), "refs.json")
+}
+
+func snapshotBlobPath(stateDir string, hash string) string {
+	return filepath.Join(snapshotsDir(stateDir), hash+".json")
+}
+
+// hashSnapshot content-addresses a bundle by its canonical JSON encoding,
+// the same way a git commit hashes its tree.
+func hashSnapshot(b snapshotBundle) (string, []byte, error) {
+	raw, err := json.Marshal(b)
+	if err != nil {
+		return "", nil, err
+	}
+	sum := sha256.Sum256(raw)
+	return hex.EncodeToString(sum[:]), raw, nil
+}
+
+// loadSnapshotRefs reads stateDir/snapshots/refs.json, name -> hash. A
+// missing file is an empty map, not an error - no snapshot has been named
+// yet.
+func loadSnapshotRefs(stateDir string) (map[string]string, error) {
+	raw, err := os.ReadFile(snapshotRefsPath(stateDir))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return map[string]string{}, nil
+		}
+		return nil, err
+	}
+	refs := map[string]string{}
+	if err := json.Unmarshal(raw, &refs); err != nil {
+		return nil, err
+	}
+	return refs, nil
+}
+
+func saveSnapshotRefs(stateDir string, refs map[string]string) error {
+	path := snapshotRefsPath(stateDir)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	raw, err := json.MarshalIndent(refs, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(raw, '\n'), 0o644)
+}
+
+// resolveSnapshotRef resolves ref to a blob hash: a name registered in
+// refs.json takes precedence, falling back to treating ref itself as a
+// (possibly abbreviated) hash.
+func resolveSnapshotRef(stateDir string, ref string) (string, error) {
+	ref = strings.TrimSpace(ref)
+	refs, err := loadSnapshotRefs(stateDir)
+	if err != nil {
+		return "", err
+	}
+	if hash, ok := refs[ref]; ok {
+		return hash, nil
+	}
+	entries, err := os.ReadDir(snapshotsDir(stateDir))
+	if err != nil {
+		return "", fmt.Errorf("no snapshot named %q", ref)
+	}
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		name := strings.TrimSuffix(e.Name(), ".json")
+		if name == "refs" {
+			continue
+		}
+		if name == ref || strings.HasPrefix(name, ref) {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("no snapshot named %q", ref)
+}
+
+// loadSnapshotBundle reads hash's blob from stateDir/snapshots.
+func loadSnapshotBundle(stateDir string, hash string) (snapshotBundle, error) {
+	raw, err := os.ReadFile(snapshotBlobPath(stateDir, hash))
+	if err != nil {
+		return snapshotBundle{}, err
+	}
+	var b snapshotBundle
+	if err := json.Unmarshal(raw, &b); err != nil {
+		return snapshotBundle{}, err
+	}
+	return b, nil
+}
+
+// snapshotSession writes a content-addressed snapshot of the current
+// session's scrollback, chaining ParentHash to m.originSnapshotHash when
+// this session itself began life as a fork. name, if non-empty, is
+// registered in refs.json so //session fork can address the snapshot by
+// name instead of its hash. Returns the snapshot's hash.
+func (m appModel) snapshotSession(name string) (string, error) {
+	bundle := snapshotBundle{
+		SessionID:        m.sessionID,
+		ParentHash:       m.originSnapshotHash,
+		CreatedAt:        time.Now().UTC().Format(time.RFC3339),
+		Messages:         historyMessages(m.chatMessages),
+		RoleLines:        historyRoleLines(m.chatRoleLines),
+		SelectedProvider: m.selectedProvider,
+		SelectedRuntime:  m.selectedRuntime,
+		SelectedModel:    m.selectedModel,
+		PermissionMode:   m.permissionMode,
+		ActiveProfile:    m.oauthPool.ActiveProfile,
+		CodexEventsTail:  tailLines(m.codexEventsPath, snapshotEventsTailLines),
+	}
+	hash, raw, err := hashSnapshot(bundle)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(snapshotsDir(m.cfg.stateDir), 0o755); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(snapshotBlobPath(m.cfg.stateDir, hash), raw, 0o644); err != nil {
+		return "", err
+	}
+	name = strings.TrimSpace(name)
+	if name != "" {
+		refs, err := loadSnapshotRefs(m.cfg.stateDir)
+		if err != nil {
+			return hash, err
+		}
+		refs[name] = hash
+		if err := saveSnapshotRefs(m.cfg.stateDir, refs); err != nil {
+			return hash, err
+		}
+	}
+	return hash, nil
+}
+
+// forkSession starts a new session the same way startNewSession does, then
+// replays the hash snapshot's messages into its WAL so the fork picks up
+// the conversation exactly where the snapshot left off. The new session's
+// originSnapshotHash is set to hash so a later snapshotSession call of the
+// fork chains ParentHash back to it.
+func (m appModel) forkSession(hash string) (appModel, error) {
+	bundle, err := loadSnapshotBundle(m.cfg.stateDir, hash)
+	if err != nil {
+		return m, err
+	}
+	m = m.startNewSession()
+	for _, msg := range bundle.RoleLines {
+		if _, err := m.historyWAL.Append(history.EntryKind(msg.Role), msg.Role, msg.Text, ""); err != nil {
+			return m, err
+		}
+	}
+	m.chatMessages = chatMessagesFromHistory(bundle.Messages)
+	m.chatRoleLines = chatRoleLinesFromHistory(bundle.RoleLines)
+	m.originSnapshotHash = hash
+	if bundle.SelectedProvider != "" {
+		m.selectedProvider = bundle.SelectedProvider
+	}
+	if bundle.SelectedRuntime != "" {
+		m.selectedRuntime = bundle.SelectedRuntime
+	}
+	if bundle.SelectedModel != "" {
+		m.selectedModel = bundle.SelectedModel
+	}
+	if bundle.PermissionMode != "" {
+		m.permissionMode = bundle.PermissionMode
+	}
+	if bundle.ActiveProfile != "" {
+		m.oauthPool.ActiveProfile = bundle.ActiveProfile
+	}
+	m.systemAlert(alertInfo, "session.forked", "Forked session from snapshot "+shortHash(hash), map[string]any{"hash": hash, "sessionId": m.sessionID})
+	m.emitEvent("session.forked", m.actionSource, map[string]any{"hash": hash, "sessionId": m.sessionID}, "", "")
+	return m, nil
+}
+
+// historyMessages/historyRoleLines are the inverse of
+// chatMessagesFromHistory/chatRoleLinesFromHistory, converting the tui
+// package's chat types back to history's for snapshotSession to persist.
+func historyMessages(msgs []chatMessage) []history.Message {
+	out := make([]history.Message, 0, len(msgs))
+	for _, msg := range msgs {
+		out = append(out, history.Message{Role: msg.Role, Content: msg.Content})
+	}
+	return out
+}
+
+func historyRoleLines(lines []chatRoleLine) []history.RoleLine {
+	out := make([]history.RoleLine, 0, len(lines))
+	for _, l := range lines {
+		out = append(out, history.RoleLine{Role: l.Role, Text: l.Text})
+	}
+	return out
+}
+
+// snapshotSummary is one row in the snapshot browser overlay.
+type snapshotSummary struct {
+	Hash       string
+	ParentHash string
+	CreatedAt  time.Time
+	Turns      int
+}
+
+// listSnapshots scans stateDir/snapshots for blobs and returns a summary
+// for each, newest first.
+func listSnapshots(stateDir string) []snapshotSummary {
+	entries, err := os.ReadDir(snapshotsDir(stateDir))
+	if err != nil {
+		return nil
+	}
+	out := make([]snapshotSummary, 0, len(entries))
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		hash := strings.TrimSuffix(e.Name(), ".json")
+		if hash == "refs" || !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+		b, err := loadSnapshotBundle(stateDir, hash)
+		if err != nil {
+			continue
+		}
+		createdAt, _ := time.Parse(time.RFC3339, b.CreatedAt)
+		out = append(out, snapshotSummary{
+			Hash:       hash,
+			ParentHash: b.ParentHash,
+			CreatedAt:  createdAt,
+			Turns:      len(b.RoleLines),
+		})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.After(out[j].CreatedAt) })
+	return out
+}
+
+// shortHash renders hash the way a git short-sha would, for display.
+func shortHash(hash string) string {
+	if len(hash) <= 10 {
+		return hash
+	}
+	return hash[:10]
+}
+
+// diffSnapshotMessages renders a line-oriented diff between two snapshots'
+// role lines: lines only in a prefixed "-", lines only in b prefixed "+",
+// shared lines prefixed with two spaces. It's a content diff, not a
+// positional one - good enough for "what changed between these two forks"
+// without pulling in a general LCS diff for one overlay.
+func diffSnapshotMessages(a, b snapshotBundle) []string {
+	aLines := map[string]bool{}
+	for _, l := range a.RoleLines {
+		aLines[l.Role+": "+l.Text] = true
+	}
+	bLines := map[string]bool{}
+	for _, l := range b.RoleLines {
+		bLines[l.Role+": "+l.Text] = true
+	}
+	var out []string
+	for _, l := range a.RoleLines {
+		key := l.Role + ": " + l.Text
+		if bLines[key] {
+			out = append(out, "  "+key)
+		} else {
+			out = append(out, "- "+key)
+		}
+	}
+	for _, l := range b.RoleLines {
+		key := l.Role + ": " + l.Text
+		if !aLines[key] {
+			out = append(out, "+ "+key)
+		}
+	}
+	return out
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	gossh "golang.org/x/crypto/ssh"
+)
+
+// sshACLEntry says a connecting SSH public key (keyed by its
+// FingerprintSHA256, see sshFingerprint) is admitted at all, and what
+// permissionMode its session starts in. serve.acl.json is workbench serve's
+// authorized_keys: a fingerprint absent from it is refused the connection
+// (see sshAuthorized), not merely denied bypass.
+type sshACLEntry struct {
+	PermissionMode string `json:"permissionMode"`
+}
+
+// sshACL maps a public key's fingerprint to its entry. See serve.acl.json
+// in stateDir (sshACLPath) — one ACL governs every session workbench serve
+// accepts, the same way codex.acl.json governs one session's codex bus.
+type sshACL map[string]sshACLEntry
+
+// sshACLPath returns the ACL file workbench serve reads, sitting at the top
+// of stateDir rather than under a sessionID since it's consulted before a
+// connection's session even exists.
+func sshACLPath(stateDir string) string {
+	return filepath.Join(stateDir, "serve.acl.json")
+}
+
+var (
+	sshACLCacheMu sync.Mutex
+	sshACLCache   = map[string]sshACLCacheEntry{}
+)
+
+type sshACLCacheEntry struct {
+	acl     sshACL
+	modTime time.Time
+}
+
+// loadSSHACL returns the ACL at sshACLPath(stateDir), or nil if it doesn't
+// exist — callers (sshAuthorized in particular) treat a nil ACL as "no key
+// is admitted", the fail-closed posture an authorized_keys-style allow-list
+// needs; it deliberately does not mirror loadCodexACL's fail-open
+// convention, since codexACL only ever gates response *types* on an
+// already-trusted local bus, not a remote network connection.
+func loadSSHACL(stateDir string) sshACL {
+	path := sshACLPath(stateDir)
+	st, err := os.Stat(path)
+	if err != nil {
+		return nil
+	}
+
+	sshACLCacheMu.Lock()
+	if ce, ok := sshACLCache[path]; ok && ce.modTime.Equal(st.ModTime()) {
+		sshACLCacheMu.Unlock()
+		return ce.acl
+	}
+	sshACLCacheMu.Unlock()
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var acl sshACL
+	if json.Unmarshal(raw, &acl) != nil {
+		return nil
+	}
+
+	sshACLCacheMu.Lock()
+	sshACLCache[path] = sshACLCacheEntry{acl: acl, modTime: st.ModTime()}
+	sshACLCacheMu.Unlock()
+	return acl
+}
+
+// sshFingerprint is the ACL key a connecting public key is looked up by.
+func sshFingerprint(pub gossh.PublicKey) string {
+	if pub == nil {
+		return ""
+	}
+	return gossh.FingerprintSHA256(pub)
+}
+
+// sshAuthorized is the admission check wish.WithPublicKeyAuth calls: a key
+// is admitted only if its fingerprint has an entry in acl, whatever
+// permissionMode that entry grants. A nil acl (no serve.acl.json) or an
+// anonymous/unrecognized key is refused, the same as a real SSH server with
+// an empty or missing authorized_keys file.
+func sshAuthorized(acl sshACL, pub gossh.PublicKey) bool {
+	if acl == nil || pub == nil {
+		return false
+	}
+	_, ok := acl[sshFingerprint(pub)]
+	return ok
+}
+
+// sshPermissionMode resolves pub's starting permissionMode from acl,
+// defaulting to "plan" for an anonymous connection, an ACL-less server, or
+// a fingerprint the ACL doesn't mention.
+func sshPermissionMode(acl sshACL, pub gossh.PublicKey) string {
+	if sshAllowsBypass(acl, pub) {
+		return "bypass"
+	}
+	return "plan"
+}
+
+// sshAllowsBypass reports whether acl grants pub's fingerprint bypass mode.
+// This is the same check sshPermissionMode uses for a session's starting
+// mode, and it's also what appModel.bypassAllowed is seeded from — so a
+// connection that didn't start in bypass can't reach it later through
+// Shift+Tab or //permission_mode either.
+func sshAllowsBypass(acl sshACL, pub gossh.PublicKey) bool {
+	if acl == nil {
+		return false
+	}
+	entry, ok := acl[sshFingerprint(pub)]
+	return ok && entry.PermissionMode == "bypass"
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/ssh"
+	"github.com/charmbracelet/wish"
+)
+
+// sshServeConfig holds workbench serve's resolved settings: a wish/SSH
+// server hosting the same appModel the local TUI runs, one fresh
+// stateDir/<sessionID> per connection (see newSSHSession) instead of the
+// single shared session a local invocation resumes by default.
+type sshServeConfig struct {
+	addr        string
+	hostKeyPath string
+	stateDir    string
+}
+
+// runSSHServer starts the wish server and blocks until it stops or fails to
+// start; see runServeCLI for the "workbench serve" CLI entry point. It is
+// a distinct subsystem from the pre-existing --serve flag (a headless,
+// non-interactive command-bus session) — this opens real interactive TUI
+// sessions to remote SSH clients instead.
+func runSSHServer(cfg sshServeConfig) error {
+	srv, err := wish.NewServer(
+		wish.WithAddress(cfg.addr),
+		wish.WithHostKeyPath(cfg.hostKeyPath),
+		wish.WithPublicKeyAuth(func(ctx ssh.Context, key ssh.PublicKey) bool {
+			// Admission itself is gated here, the same as any real SSH server's
+			// authorized_keys: a key whose fingerprint isn't in serve.acl.json is
+			// refused the connection outright, not just denied bypass.
+			// sshPermissionMode then maps an admitted key's fingerprint to its
+			// starting permissionMode (plan by default, bypass only for
+			// fingerprints serve.acl.json marks as such).
+			return sshAuthorized(loadSSHACL(cfg.stateDir), key)
+		}),
+		wish.WithMiddleware(sshWorkbenchMiddleware(cfg.stateDir)),
+	)
+	if err != nil {
+		return err
+	}
+	return srv.ListenAndServe()
+}
+
+// sshWorkbenchMiddleware runs one workbench appModel per SSH session,
+// isolated under its own freshly minted stateDir/<sessionID> (per-connection
+// isolation, so two SSH clients never collide on the same commands.jsonl or
+// events.jsonl), and flushes writeSessionSummary when the client
+// disconnects the same way app.go's local run does on exit.
+func sshWorkbenchMiddleware(stateDir string) wish.Middleware {
+	return func(next ssh.Handler) ssh.Handler {
+		return func(s ssh.Session) {
+			pty, winCh, isPTY := s.Pty()
+			if !isPTY {
+				wish.Fatalln(s, "workbench serve requires a PTY")
+				return
+			}
+
+			sessionID, err := createNewSessionID(stateDir)
+			if err != nil {
+				wish.Fatalln(s, "workbench serve: ", err.Error())
+				return
+			}
+
+			m := newAppModel(appConfig{
+				stateDir:              stateDir,
+				sessionID:             sessionID,
+				targetSystem:          "SSH session",
+				applicationV:          "v1.0.0",
+				commandsPath:          filepath.Join(stateDir, sessionID, "commands.jsonl"),
+				codexRequestsPath:     filepath.Join(stateDir, sessionID, "codex.requests.jsonl"),
+				codexResponsesPath:    filepath.Join(stateDir, sessionID, "codex.responses.jsonl"),
+				codexEventsPath:       filepath.Join(stateDir, sessionID, "codex.events.jsonl"),
+				systemRequestsPath:    filepath.Join(stateDir, sessionID, "system.requests.jsonl"),
+				systemResponsesPath:   filepath.Join(stateDir, sessionID, "system.responses.jsonl"),
+				opencodeRequestsPath:  filepath.Join(stateDir, sessionID, "opencode.requests.jsonl"),
+				opencodeResponsesPath: filepath.Join(stateDir, sessionID, "opencode.responses.jsonl"),
+				opencodeEventsPath:    filepath.Join(stateDir, sessionID, "opencode.events.jsonl"),
+			})
+			acl := loadSSHACL(stateDir)
+			m.permissionMode = sshPermissionMode(acl, s.PublicKey())
+			m.bypassAllowed = sshAllowsBypass(acl, s.PublicKey())
+			m.th = buildTheme(builtinThemeSpecs["default-dark"], sshColorDepth(s, pty))
+
+			prog := tea.NewProgram(m,
+				tea.WithInput(s),
+				tea.WithOutput(s),
+				tea.WithAltScreen(),
+			)
+
+			go func() {
+				for win := range winCh {
+					prog.Send(tea.WindowSizeMsg{Width: win.Width, Height: win.Height})
+				}
+			}()
+
+			finalModel, err := prog.Run()
+			if err == nil {
+				if am, ok := finalModel.(appModel); ok {
+					writeSessionSummary(am)
+				}
+			}
+
+			next(s)
+		}
+	}
+}
+
+// sshColorDepth negotiates color depth from the connecting client's own
+// terminal instead of this process's environment (detectColorDepth would
+// only describe the server's terminal, which a remote SSH client never
+// sees): pty.Term is the client's TERM, and COLORTERM travels over as an
+// environment variable the client forwards, same as any other SSH session.
+func sshColorDepth(s ssh.Session, pty ssh.Pty) colorDepth {
+	colorterm := ""
+	for _, kv := range s.Environ() {
+		if v, ok := strings.CutPrefix(kv, "COLORTERM="); ok {
+			colorterm = v
+			break
+		}
+	}
+	return colorDepthFromTerm(colorterm, pty.Term)
+}
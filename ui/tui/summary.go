@@ -7,39 +7,194 @@ import (
 	"time"
 )
 
+// summaryLWWFields are the scalar fields summary.deltas.jsonl tracks as
+// LWW-registers: the shared pieces of session state two workbench
+// processes attached to the same sessionID (a laptop and an SSH session)
+// should converge on, rather than each clobbering the other's
+// summary.json on exit. Navigation state (mode/screen/overlay) is
+// per-instance UI and stays out of the CRDT - it's still reported in
+// summary.json, just not merged.
+var summaryLWWFields = []string{
+	"selectedProvider", "selectedRuntime", "selectedModel",
+	"theme", "permissionMode", "thoughtStream", "activeOAuthEmail",
+}
+
+// summaryLWWValue reads m's current value for one of summaryLWWFields.
+func summaryLWWValue(m appModel, field string) any {
+	switch field {
+	case "selectedProvider":
+		return m.selectedProvider
+	case "selectedRuntime":
+		return m.selectedRuntime
+	case "selectedModel":
+		return m.selectedModel
+	case "theme":
+		return m.themeName
+	case "permissionMode":
+		return m.permissionMode
+	case "thoughtStream":
+		return m.thoughtStream
+	case "activeOAuthEmail":
+		return m.lastOAuthProfile
+	default:
+		return nil
+	}
+}
+
+// mergeSessionSummary reads stateDir/<sessionID>/summary.deltas.jsonl and
+// applies its merged summaryState onto a freshly constructed m, so a
+// process attaching to a session another process is already running picks
+// up that process's selectedProvider/selectedModel/permissionMode/etc and
+// the union of every alert/recent-command either has recorded, instead of
+// starting from newAppModel's hardcoded defaults.
+func mergeSessionSummary(m appModel) appModel {
+	if m.cfg.stateDir == "" || m.sessionID == "" {
+		return m
+	}
+	dir := filepath.Join(m.cfg.stateDir, m.sessionID)
+	state := loadSummaryState(filepath.Join(dir, "summary.deltas.jsonl"))
+
+	// Seed this process's Lamport clock past whatever any other process
+	// attached to the same session has already written, the same way
+	// auditChainTail/eventChainTail resume a hash chain from its last
+	// record instead of forking a fresh one at zero. Without this, every
+	// process's first (and only) writeSessionSummary call would stamp its
+	// deltas with Lamport 1, so two processes that both exit would always
+	// tie and summaryState.apply would fall back to comparing NodeIDs
+	// instead of recency.
+	m.summaryLamport = state.maxLamport()
+
+	if v, ok := state.lwwString("selectedProvider"); ok {
+		m.selectedProvider = v
+	}
+	if v, ok := state.lwwString("selectedRuntime"); ok {
+		m.selectedRuntime = v
+	}
+	if v, ok := state.lwwString("selectedModel"); ok {
+		m.selectedModel = v
+	}
+	if v, ok := state.lwwString("permissionMode"); ok {
+		m.permissionMode = v
+	}
+	if v, ok := state.lwwString("activeOAuthEmail"); ok {
+		m.lastOAuthProfile = v
+	}
+
+	for _, raw := range state.sortedAdds("recentAlerts", 50) {
+		var a systemAlert
+		if json.Unmarshal(raw, &a) == nil {
+			m.alerts = append(m.alerts, a)
+		}
+	}
+	for _, raw := range state.sortedAdds("recentCommands", 50) {
+		var c string
+		if json.Unmarshal(raw, &c) == nil {
+			m.recentCommands = append(m.recentCommands, c)
+		}
+	}
+	return m
+}
+
+// writeSessionSummary appends m's current LWW fields and its last 10
+// alerts and last 10 recent-commands to summary.deltas.jsonl (elements
+// already recorded are appended again and collapse on ElemID), then
+// writes a compacted summary.json snapshot from the full merged
+// summaryState (this process's new deltas plus whatever any other
+// process attached to the same session has already written), so the
+// snapshot always reflects the converged view rather than just this
+// process's last-known state.
 func writeSessionSummary(m appModel) {
 	if m.cfg.stateDir == "" || m.sessionID == "" {
 		return
 	}
 	dir := filepath.Join(m.cfg.stateDir, m.sessionID)
 	_ = os.MkdirAll(dir, 0o755)
+	deltasPath := filepath.Join(dir, "summary.deltas.jsonl")
+
+	now := time.Now().UTC().Format(time.RFC3339Nano)
+	lamport := m.summaryLamport + 1
 
+	for _, field := range summaryLWWFields {
+		value, err := json.Marshal(summaryLWWValue(m, field))
+		if err != nil {
+			continue
+		}
+		_ = appendSummaryDelta(deltasPath, summaryDelta{
+			Kind: summaryDeltaLWW, Field: field, NodeID: m.summaryNodeID,
+			Lamport: lamport, At: now, Value: value,
+		})
+	}
 	alerts := m.alerts
 	if len(alerts) > 10 {
 		alerts = alerts[len(alerts)-10:]
 	}
+	for _, a := range alerts {
+		value, err := json.Marshal(a)
+		if err != nil {
+			continue
+		}
+		_ = appendSummaryDelta(deltasPath, summaryDelta{
+			Kind: summaryDeltaAdd, Field: "recentAlerts", NodeID: m.summaryNodeID,
+			Lamport: lamport, At: a.At, ElemID: summaryElemID("recentAlerts", value), Value: value,
+		})
+	}
 	cmds := m.recentCommands
 	if len(cmds) > 10 {
 		cmds = cmds[len(cmds)-10:]
 	}
+	for _, c := range cmds {
+		value, err := json.Marshal(c)
+		if err != nil {
+			continue
+		}
+		_ = appendSummaryDelta(deltasPath, summaryDelta{
+			Kind: summaryDeltaAdd, Field: "recentCommands", NodeID: m.summaryNodeID,
+			Lamport: lamport, At: now, ElemID: summaryElemID("recentCommands", value), Value: value,
+		})
+	}
+
+	state := loadSummaryState(deltasPath)
+	get := func(field string, fallback string) string {
+		if v, ok := state.lwwString(field); ok {
+			return v
+		}
+		return fallback
+	}
+
+	var mergedAlerts []systemAlert
+	for _, raw := range state.sortedAdds("recentAlerts", 10) {
+		var a systemAlert
+		if json.Unmarshal(raw, &a) == nil {
+			mergedAlerts = append(mergedAlerts, a)
+		}
+	}
+	var mergedCommands []string
+	for _, raw := range state.sortedAdds("recentCommands", 10) {
+		var c string
+		if json.Unmarshal(raw, &c) == nil {
+			mergedCommands = append(mergedCommands, c)
+		}
+	}
 
 	out := map[string]any{
-		"version":           1,
-		"updatedAt":         time.Now().UTC().Format(time.RFC3339Nano),
+		"version":           2,
+		"updatedAt":         now,
 		"sessionId":         m.sessionID,
 		"mode":              m.mode.String(),
 		"screen":            m.currentScreen().String(),
 		"overlay":           m.currentOverlay().String(),
-		"selectedProvider":  m.selectedProvider,
-		"selectedRuntime":   m.selectedRuntime,
-		"selectedModel":     m.selectedModel,
-		"permissionMode":    m.permissionMode,
+		"selectedProvider":  get("selectedProvider", m.selectedProvider),
+		"selectedRuntime":   get("selectedRuntime", m.selectedRuntime),
+		"selectedModel":     get("selectedModel", m.selectedModel),
+		"theme":             get("theme", m.themeName),
+		"permissionMode":    get("permissionMode", m.permissionMode),
 		"thoughtStream":     m.thoughtStream,
 		"compatibility":     m.currentCompatibility().String(),
-		"activeOAuthEmail":  m.lastOAuthProfile,
-		"recentAlerts":      alerts,
-		"recentCommands":    cmds,
+		"activeOAuthEmail":  get("activeOAuthEmail", m.lastOAuthProfile),
+		"recentAlerts":      mergedAlerts,
+		"recentCommands":    mergedCommands,
 		"eventsPath":        filepath.Join(dir, "events.jsonl"),
+		"eventsAuditPubKey": eventAuditPublicKey(dir),
 	}
 
 	b, err := json.MarshalIndent(out, "", "  ")
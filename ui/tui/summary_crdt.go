@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"sort"
+	"strings"
+)
+
+// summaryDeltaKind distinguishes an LWW-register write from an OR-set add
+// in summary.deltas.jsonl.
+type summaryDeltaKind string
+
+const (
+	summaryDeltaLWW summaryDeltaKind = "lww"
+	summaryDeltaAdd summaryDeltaKind = "add"
+)
+
+// summaryDelta is one line of stateDir/<sessionID>/summary.deltas.jsonl:
+// writeSessionSummary appends one per tracked scalar field (Kind lww,
+// keyed by NodeID+Lamport so the highest pair wins a merge) plus one per
+// alert/recent-command it writes (Kind add, keyed by ElemID so the same
+// event added twice, by one process or two, collapses to one entry).
+type summaryDelta struct {
+	Kind    summaryDeltaKind `json:"kind"`
+	Field   string           `json:"field"`
+	NodeID  string           `json:"nodeId"`
+	Lamport uint64           `json:"lamport"`
+	At      string           `json:"at"`
+	ElemID  string           `json:"elemId,omitempty"`
+	Value   json.RawMessage  `json:"value"`
+}
+
+// summaryElemID derives an add-set element's ID from its field and
+// marshaled value, so the same logical alert or command appended by two
+// concurrent processes merges into a single entry instead of two.
+func summaryElemID(field string, value json.RawMessage) string {
+	sum := sha256.Sum256(append([]byte(field+"|"), value...))
+	return hex.EncodeToString(sum[:])[:16]
+}
+
+func appendSummaryDelta(path string, d summaryDelta) error {
+	b, err := json.Marshal(d)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(b, '\n'))
+	return err
+}
+
+type summaryLWWEntry struct {
+	NodeID  string
+	Lamport uint64
+	Value   json.RawMessage
+}
+
+type summaryAddEntry struct {
+	At    string
+	Value json.RawMessage
+}
+
+// summaryState is what summary.deltas.jsonl converges to: one winning
+// value per LWW field, and the full union of every field's add-set
+// elements, regardless of which process wrote them or in what order.
+type summaryState struct {
+	LWW  map[string]summaryLWWEntry
+	Adds map[string]map[string]summaryAddEntry
+}
+
+func newSummaryState() summaryState {
+	return summaryState{LWW: map[string]summaryLWWEntry{}, Adds: map[string]map[string]summaryAddEntry{}}
+}
+
+// apply folds d into s: an lww delta only replaces the current winner if
+// its (Lamport, NodeID) pair is greater, so merge order doesn't matter; an
+// add delta is idempotent on ElemID.
+func (s summaryState) apply(d summaryDelta) {
+	switch d.Kind {
+	case summaryDeltaAdd:
+		set, ok := s.Adds[d.Field]
+		if !ok {
+			set = map[string]summaryAddEntry{}
+			s.Adds[d.Field] = set
+		}
+		set[d.ElemID] = summaryAddEntry{At: d.At, Value: d.Value}
+	default:
+		cur, ok := s.LWW[d.Field]
+		if !ok || d.Lamport > cur.Lamport || (d.Lamport == cur.Lamport && d.NodeID > cur.NodeID) {
+			s.LWW[d.Field] = summaryLWWEntry{NodeID: d.NodeID, Lamport: d.Lamport, Value: d.Value}
+		}
+	}
+}
+
+// loadSummaryState reads every delta in path and merges them into a
+// summaryState; a missing file is a trivially empty state, the same way a
+// session that hasn't written a summary yet has no prior state to merge.
+func loadSummaryState(path string) summaryState {
+	s := newSummaryState()
+	f, err := os.Open(path)
+	if err != nil {
+		return s
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var d summaryDelta
+		if json.Unmarshal([]byte(line), &d) != nil {
+			continue
+		}
+		s.apply(d)
+	}
+	return s
+}
+
+// maxLamport returns the highest Lamport value among s's LWW winners, or 0
+// if s has no LWW entries yet. Every writeSessionSummary call stamps all of
+// summaryLWWFields with the same Lamport, so the winning entries alone
+// carry the high-water mark — callers don't need to rescan Adds too.
+func (s summaryState) maxLamport() uint64 {
+	var max uint64
+	for _, e := range s.LWW {
+		if e.Lamport > max {
+			max = e.Lamport
+		}
+	}
+	return max
+}
+
+// lwwString returns field's winning LWW value unmarshaled as a string, or
+// ok=false if no delta has set it yet.
+func (s summaryState) lwwString(field string) (string, bool) {
+	entry, ok := s.LWW[field]
+	if !ok {
+		return "", false
+	}
+	var v string
+	if json.Unmarshal(entry.Value, &v) != nil {
+		return "", false
+	}
+	return v, true
+}
+
+// sortedAdds returns field's add-set elements oldest first, capped to the
+// most recent limit (0 means unlimited).
+func (s summaryState) sortedAdds(field string, limit int) []json.RawMessage {
+	set := s.Adds[field]
+	if len(set) == 0 {
+		return nil
+	}
+	entries := make([]summaryAddEntry, 0, len(set))
+	for _, e := range set {
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].At < entries[j].At })
+	if limit > 0 && len(entries) > limit {
+		entries = entries[len(entries)-limit:]
+	}
+	out := make([]json.RawMessage, len(entries))
+	for i, e := range entries {
+		out[i] = e.Value
+	}
+	return out
+}
@@ -1,8 +1,8 @@
 package main
 
 import (
-	"bufio"
 	"encoding/json"
+	"errors"
 	"os"
 	"path/filepath"
 	"strings"
@@ -63,46 +63,25 @@ func appendSystemRequest(path string, req systemRequest) error {
 	return err
 }
 
-func readSystemResponses(path string, offset int64) ([]systemResponse, int64) {
+// readSystemResponses returns the responses available at offset, the
+// advanced offset, and a non-nil *multiError of ErrCorruptLine entries for
+// any malformed lines (well-formed responses are still returned).
+func readSystemResponses(path string, offset int64) ([]systemResponse, int64, error) {
 	if strings.TrimSpace(path) == "" {
-		return nil, offset
+		return nil, offset, nil
 	}
-	f, err := os.Open(path)
-	if err != nil {
-		return nil, offset
-	}
-	defer f.Close()
-
-	st, err := f.Stat()
-	if err == nil && offset > st.Size() {
-		offset = st.Size()
-	}
-	if offset > 0 {
-		if _, err := f.Seek(offset, 0); err != nil {
-			return nil, offset
-		}
-	}
-
-	var out []systemResponse
-	reader := bufio.NewReader(f)
-	cur := offset
-	for {
-		line, err := reader.ReadString('\n')
-		if line != "" {
-			cur += int64(len(line))
-			txt := strings.TrimSpace(line)
-			if txt != "" {
-				var r systemResponse
-				if json.Unmarshal([]byte(txt), &r) == nil && r.Version == 1 && strings.TrimSpace(r.Type) != "" {
-					out = append(out, r)
-				}
-			}
-		}
-		if err != nil {
-			break
+	out, cur, err := readJSONLFrom(path, offset, func(line []byte) (systemResponse, bool) {
+		var r systemResponse
+		if json.Unmarshal(line, &r) == nil && r.Version == 1 && strings.TrimSpace(r.Type) != "" {
+			return r, true
 		}
+		return systemResponse{}, false
+	})
+	var be *busError
+	if errors.As(err, &be) && be.Code == ErrIO {
+		return nil, offset, nil
 	}
-	return out, cur
+	return out, cur, err
 }
 
 func systemExecutorReadyPath(stateDir string, sessionID string) string {
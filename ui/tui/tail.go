@@ -0,0 +1,280 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// errTimeout is returned by a tailer's internal read loop when a deadline
+// set via deadlineTailer.SetDeadline expires before new data arrives.
+var errTimeout = errors.New("tail: deadline exceeded")
+
+// deadlineTailer follows appends to a single JSONL file, decoding complete
+// lines as they land. It mirrors the deadline-timer pattern net.Conn
+// implementations use (e.g. gonet's setDeadline): a timer per reader is
+// reset on every SetDeadline call, and a cancel channel is closed exactly
+// once, when that timer fires; the caller's context is watched separately
+// and does not close it.
+type deadlineTailer struct {
+	path    string
+	watcher *fsnotify.Watcher
+	poll    time.Duration
+
+	mu       sync.Mutex
+	timer    *time.Timer
+	cancelCh chan struct{}
+}
+
+// newDeadlineTailer opens path (creating it if missing) and arranges to be
+// notified of appends via fsnotify, falling back to a poll interval for
+// filesystems where fsnotify doesn't fire (e.g. some network mounts).
+func newDeadlineTailer(path string, pollInterval time.Duration) (*deadlineTailer, error) {
+	if pollInterval <= 0 {
+		pollInterval = 250 * time.Millisecond
+	}
+	_ = os.MkdirAll(parentDir(path), 0o755)
+	if _, err := os.Stat(path); err != nil {
+		_ = os.WriteFile(path, []byte{}, 0o644)
+	}
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	if err := w.Add(path); err != nil {
+		_ = w.Close()
+		return nil, err
+	}
+	return &deadlineTailer{path: path, watcher: w, poll: pollInterval, cancelCh: make(chan struct{})}, nil
+}
+
+// SetDeadline arms (or re-arms) the timer that closes the tailer's cancel
+// channel once it fires. A zero deadline disables the timer (block until
+// ctx is canceled or Close is called).
+func (t *deadlineTailer) SetDeadline(deadline time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.timer != nil {
+		t.timer.Stop()
+	}
+	t.cancelCh = make(chan struct{})
+	if deadline.IsZero() {
+		return
+	}
+	d := time.Until(deadline)
+	if d <= 0 {
+		close(t.cancelCh)
+		return
+	}
+	ch := t.cancelCh
+	t.timer = time.AfterFunc(d, func() { closeOnce(ch) })
+}
+
+func (t *deadlineTailer) cancelChannel() <-chan struct{} {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.cancelCh
+}
+
+func (t *deadlineTailer) Close() error {
+	t.mu.Lock()
+	if t.timer != nil {
+		t.timer.Stop()
+	}
+	t.mu.Unlock()
+	return t.watcher.Close()
+}
+
+func closeOnce(ch chan struct{}) {
+	select {
+	case <-ch:
+	default:
+		close(ch)
+	}
+}
+
+func parentDir(path string) string {
+	idx := strings.LastIndexByte(path, '/')
+	if idx < 0 {
+		return "."
+	}
+	return path[:idx]
+}
+
+// waitForAppend blocks until the file has grown past lastSize, the fsnotify
+// watcher reports a write, the poll interval elapses (as a fallback), the
+// deadline cancel channel closes, or ctx is done. It returns errTimeout when
+// the deadline is the reason it woke up, and also when the watcher has been
+// closed.
+func (t *deadlineTailer) waitForAppend(ctx context.Context, lastSize int64) error {
+	poll := time.NewTimer(t.poll)
+	defer poll.Stop()
+	cancelCh := t.cancelChannel()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-cancelCh:
+			return errTimeout
+		case ev, ok := <-t.watcher.Events:
+			if !ok {
+				return errTimeout
+			}
+			if ev.Op&(fsnotify.Write|fsnotify.Create) != 0 {
+				return nil
+			}
+		case <-t.watcher.Errors:
+			return nil
+		case <-poll.C:
+			st, err := os.Stat(t.path)
+			if err == nil && st.Size() != lastSize {
+				return nil
+			}
+			poll.Reset(t.poll)
+		}
+	}
+}
+
+// tailJSONL reads complete newline-terminated JSON lines from offset,
+// blocking for more as they're appended, until ctx is canceled or deadline
+// (if non-zero) expires. decode is called once per raw line; returning
+// false from it skips the line (e.g. malformed JSON or version mismatch).
+// It returns only the item and error channels; the offset it has reached
+// is not reported back to the caller.
+func tailJSONL[T any](ctx context.Context, path string, offset int64, deadline time.Time, decode func([]byte) (T, bool)) (<-chan T, <-chan error) {
+	out := make(chan T, 16)
+	errs := make(chan error, 1)
+
+	go func() {
+		defer close(out)
+		defer close(errs)
+
+		tailer, err := newDeadlineTailer(path, 250*time.Millisecond)
+		if err != nil {
+			errs <- err
+			return
+		}
+		defer tailer.Close()
+		tailer.SetDeadline(deadline)
+
+		cur := offset
+		for {
+			items, next, readErr := readJSONLFrom(path, cur, decode)
+			cur = next
+			for _, it := range items {
+				select {
+				case out <- it:
+				case <-ctx.Done():
+					errs <- ctx.Err()
+					return
+				}
+			}
+			if readErr != nil {
+				errs <- readErr
+				return
+			}
+
+			st, statErr := os.Stat(path)
+			lastSize := cur
+			if statErr == nil {
+				lastSize = st.Size()
+			}
+			if err := tailer.waitForAppend(ctx, lastSize); err != nil {
+				errs <- err
+				return
+			}
+		}
+	}()
+
+	return out, errs
+}
+
+// readJSONLFrom reads every complete line available at or after offset,
+// decoding each with decode, and returns the offset just past the last
+// complete line consumed (a trailing partial line is left unread). Lines decode
+// rejects that aren't valid JSON at all are counted as ErrCorruptLine in the
+// returned multiError rather than silently dropped, so callers (e.g. the
+// TUI's consume* loops) can badge a partial read; lines that parse fine but
+// are filtered for another reason (wrong version, unrecognized type) are not
+// treated as corrupt.
+func readJSONLFrom[T any](path string, offset int64, decode func([]byte) (T, bool)) ([]T, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, offset, wrapBusError(ErrIO, "opening bus file", err)
+	}
+	defer f.Close()
+
+	st, err := f.Stat()
+	if err == nil && offset > st.Size() {
+		offset = st.Size()
+	}
+	if offset > 0 {
+		if _, err := f.Seek(offset, 0); err != nil {
+			return nil, offset, wrapBusError(ErrIO, "seeking bus file", err)
+		}
+	}
+
+	var out []T
+	var merr *multiError
+	reader := bufio.NewReader(f)
+	cur := offset
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			// A trailing line with no newline yet is left unread.
+			break
+		}
+		cur += int64(len(line))
+		txt := strings.TrimSpace(line)
+		if txt != "" {
+			if v, ok := decode([]byte(txt)); ok {
+				out = append(out, v)
+			} else if !json.Valid([]byte(txt)) {
+				merr = merr.add(newBusError(ErrCorruptLine, txt))
+			}
+		}
+	}
+	return out, cur, asMultiErrorOrNil(merr)
+}
+
+// TailOpencodeResponses streams opencode turn results as they're appended to
+// path, honoring ctx cancellation and an optional deadline. Pass a zero
+// time.Time for deadline to tail indefinitely.
+func TailOpencodeResponses(ctx context.Context, path string, offset int64, deadline time.Time) (<-chan opencodeTurnResponse, <-chan error) {
+	return tailJSONL(ctx, path, offset, deadline, func(line []byte) (opencodeTurnResponse, bool) {
+		var r opencodeTurnResponse
+		if json.Unmarshal(line, &r) == nil && r.Version == 1 && strings.TrimSpace(r.Type) != "" {
+			return r, true
+		}
+		return opencodeTurnResponse{}, false
+	})
+}
+
+// TailOpencodeEvents streams opencode turn events; see TailOpencodeResponses.
+func TailOpencodeEvents(ctx context.Context, path string, offset int64, deadline time.Time) (<-chan opencodeTurnEvent, <-chan error) {
+	return tailJSONL(ctx, path, offset, deadline, func(line []byte) (opencodeTurnEvent, bool) {
+		var ev opencodeTurnEvent
+		if json.Unmarshal(line, &ev) == nil && ev.Version == 1 && strings.TrimSpace(ev.Type) == "turn.event" {
+			return ev, true
+		}
+		return opencodeTurnEvent{}, false
+	})
+}
+
+// TailSystemResponses streams system bus results; see TailOpencodeResponses.
+func TailSystemResponses(ctx context.Context, path string, offset int64, deadline time.Time) (<-chan systemResponse, <-chan error) {
+	return tailJSONL(ctx, path, offset, deadline, func(line []byte) (systemResponse, bool) {
+		var r systemResponse
+		if json.Unmarshal(line, &r) == nil && r.Version == 1 && strings.TrimSpace(r.Type) != "" {
+			return r, true
+		}
+		return systemResponse{}, false
+	})
+}
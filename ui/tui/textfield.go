@@ -0,0 +1,30 @@
+package main
+
+import tea "github.com/charmbracelet/bubbletea"
+
+// textField is the minimal single-line editable buffer shared by this TUI's
+// inline "type to edit" prompts (chat search's query, the OAuth paste
+// prompt's username/token fields): Backspace, printable runes, and Space all
+// edit it the same way, so each call site just needs to route its field
+// through handleKey instead of repeating the switch.
+type textField string
+
+// handleKey applies k if it's a plain edit key, returning the updated field
+// and whether k was consumed. Callers still handle Enter/Esc/etc. themselves
+// since those vary by prompt.
+func (f textField) handleKey(k tea.KeyMsg) (textField, bool) {
+	switch k.Type {
+	case tea.KeyBackspace:
+		if len(f) == 0 {
+			return f, true
+		}
+		r := []rune(f)
+		return textField(string(r[:len(r)-1])), true
+	case tea.KeyRunes:
+		return f + textField(k.Runes), true
+	case tea.KeySpace:
+		return f + " ", true
+	default:
+		return f, false
+	}
+}
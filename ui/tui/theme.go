@@ -17,12 +17,52 @@ type theme struct {
 	OverlayBox lipgloss.Style
 }
 
+// themeSpec is the declarative form a theme is authored in, either one of
+// builtinThemeSpecs or a user's $XDG_CONFIG_HOME/workbench/themes/*.toml
+// file (see theme_load.go). buildTheme turns one into a theme, downgrading
+// every color to the terminal's actual depth.
+type themeSpec struct {
+	Name      string `toml:"name"`
+	Dark      bool   `toml:"dark"`
+	Accent    string `toml:"accent"`
+	Secondary string `toml:"secondary"`
+	Success   string `toml:"success"`
+	Alert     string `toml:"alert"`
+	Danger    string `toml:"danger"`
+}
+
+// builtinThemeSpecs ships two variants so a terminal with no user themes
+// directory still gets a background-appropriate default instead of only
+// the old hardcoded dark palette.
+var builtinThemeSpecs = map[string]themeSpec{
+	"default-dark": {
+		Name: "default-dark", Dark: true,
+		Accent: "#00FFFF", Secondary: "#7D7D7D",
+		Success: "#00FF00", Alert: "#FFBF00", Danger: "#FF0055",
+	},
+	"default-light": {
+		Name: "default-light", Dark: false,
+		Accent: "#007A7A", Secondary: "#5A5A5A",
+		Success: "#007A00", Alert: "#946200", Danger: "#B00040",
+	},
+}
+
+// defaultTheme returns the classic hardcoded dark palette, kept for call
+// sites (and tests) that want a theme without going through loadTheme's
+// registry/detection; newAppModel itself now calls loadTheme instead.
 func defaultTheme() theme {
-	accent := lipgloss.Color("#00FFFF")
-	secondary := lipgloss.Color("#7D7D7D")
-	success := lipgloss.Color("#00FF00")
-	alert := lipgloss.Color("#FFBF00")
-	danger := lipgloss.Color("#FF0055")
+	return buildTheme(builtinThemeSpecs["default-dark"], colorDepthTrueColor)
+}
+
+// buildTheme constructs a theme's lipgloss.Styles from spec, downgrading
+// every color through adaptColor first so a 16- or 256-color terminal gets
+// its nearest approximation instead of a truecolor escape it can't render.
+func buildTheme(spec themeSpec, depth colorDepth) theme {
+	accent := lipgloss.Color(adaptColor(spec.Accent, depth))
+	secondary := lipgloss.Color(adaptColor(spec.Secondary, depth))
+	success := lipgloss.Color(adaptColor(spec.Success, depth))
+	alert := lipgloss.Color(adaptColor(spec.Alert, depth))
+	danger := lipgloss.Color(adaptColor(spec.Danger, depth))
 
 	return theme{
 		Header: lipgloss.NewStyle().
@@ -59,4 +99,3 @@ func defaultTheme() theme {
 			Padding(0, 1),
 	}
 }
-
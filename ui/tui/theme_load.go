@@ -0,0 +1,249 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// colorDepth is how many distinct colors the connected terminal can render,
+// detected from COLORTERM/TERM the way detectDarkBackground reads COLORFGBG
+// for background polarity - neither requires an interactive probe, so both
+// work over the command bus and non-interactive --smoke/--replay runs too.
+type colorDepth int
+
+const (
+	colorDepth16 colorDepth = iota
+	colorDepth256
+	colorDepthTrueColor
+)
+
+// detectColorDepth reads COLORTERM and TERM the way most terminal-aware
+// CLIs do: an explicit COLORTERM=truecolor/24bit wins, "direct" in TERM
+// also means truecolor, "256color" in TERM means indexed 256-color, and
+// anything else downgrades to the lowest
+// common denominator (basic 16-color) rather than assuming truecolor
+// support a locked-down terminal (tmux -2, a serial console, screen)
+// doesn't have.
+func detectColorDepth() colorDepth {
+	return colorDepthFromTerm(os.Getenv("COLORTERM"), os.Getenv("TERM"))
+}
+
+// colorDepthFromTerm is detectColorDepth's pure core, split out so
+// ssh_serve.go can run the same downgrade logic on a connecting SSH
+// client's negotiated COLORTERM/TERM instead of this process's own
+// environment, which describes the server's terminal, not the remote
+// one's.
+func colorDepthFromTerm(colorterm, term string) colorDepth {
+	switch strings.ToLower(strings.TrimSpace(colorterm)) {
+	case "truecolor", "24bit":
+		return colorDepthTrueColor
+	}
+	if strings.Contains(term, "direct") {
+		return colorDepthTrueColor
+	}
+	if strings.Contains(term, "256color") {
+		return colorDepth256
+	}
+	return colorDepth16
+}
+
+// detectDarkBackground reads COLORFGBG ("fg;bg", set by many terminal
+// emulators and tmux), treating a background palette index below 8 as
+// dark. Unset or unparseable defaults to dark, matching the theme every
+// workbench session rendered before themes existed.
+func detectDarkBackground() bool {
+	fgbg := strings.TrimSpace(os.Getenv("COLORFGBG"))
+	if fgbg == "" {
+		return true
+	}
+	parts := strings.Split(fgbg, ";")
+	n, err := strconv.Atoi(strings.TrimSpace(parts[len(parts)-1]))
+	if err != nil {
+		return true
+	}
+	return n < 8
+}
+
+// rgb is a parsed themeSpec color, used only for nearest-match distance.
+type rgb struct{ r, g, b int }
+
+func hexRGB(hex string) (rgb, error) {
+	hex = strings.TrimPrefix(strings.TrimSpace(hex), "#")
+	if len(hex) != 6 {
+		return rgb{}, fmt.Errorf("theme: invalid color %q", hex)
+	}
+	v, err := strconv.ParseInt(hex, 16, 64)
+	if err != nil {
+		return rgb{}, fmt.Errorf("theme: invalid color %q: %w", hex, err)
+	}
+	return rgb{r: int(v >> 16 & 0xff), g: int(v >> 8 & 0xff), b: int(v & 0xff)}, nil
+}
+
+func (c rgb) distance(o rgb) int {
+	dr, dg, db := c.r-o.r, c.g-o.g, c.b-o.b
+	return dr*dr + dg*dg + db*db
+}
+
+// ansi16Palette holds the 16 basic ANSI colors' RGB values, indexed by
+// their escape-code number, for nearestInPalette to walk when the
+// terminal only supports 16 colors.
+var ansi16Palette = []rgb{
+	{0, 0, 0}, {170, 0, 0}, {0, 170, 0}, {170, 85, 0},
+	{0, 0, 170}, {170, 0, 170}, {0, 170, 170}, {170, 170, 170},
+	{85, 85, 85}, {255, 85, 85}, {85, 255, 85}, {255, 255, 85},
+	{85, 85, 255}, {255, 85, 255}, {85, 255, 255}, {255, 255, 255},
+}
+
+// ansi256Palette holds the full xterm 256-color table's RGB values,
+// indexed by escape-code number: the 16 basic colors, a 6x6x6 color cube,
+// then a 24-step grayscale ramp, for nearestInPalette to walk when the
+// terminal supports indexed but not truecolor output.
+var ansi256Palette = buildAnsi256Palette()
+
+func buildAnsi256Palette() []rgb {
+	table := make([]rgb, 0, 256)
+	table = append(table, ansi16Palette...)
+	steps := []int{0, 95, 135, 175, 215, 255}
+	for r := 0; r < 6; r++ {
+		for g := 0; g < 6; g++ {
+			for b := 0; b < 6; b++ {
+				table = append(table, rgb{steps[r], steps[g], steps[b]})
+			}
+		}
+	}
+	for i := 0; i < 24; i++ {
+		v := 8 + i*10
+		table = append(table, rgb{v, v, v})
+	}
+	return table
+}
+
+// nearestInPalette walks table for the entry closest to target by squared
+// Euclidean distance in RGB space, returning its index.
+func nearestInPalette(target rgb, table []rgb) int {
+	best, bestDist := 0, -1
+	for i, c := range table {
+		d := target.distance(c)
+		if bestDist < 0 || d < bestDist {
+			best, bestDist = i, d
+		}
+	}
+	return best
+}
+
+// adaptColor downgrades hex to depth: truecolor passes it straight
+// through, while 256/16 walk the matching nearestInPalette table and
+// return the ANSI index instead, since lipgloss.Color renders a bare
+// numeric string as an indexed color rather than truecolor.
+func adaptColor(hex string, depth colorDepth) string {
+	if depth == colorDepthTrueColor {
+		return hex
+	}
+	c, err := hexRGB(hex)
+	if err != nil {
+		return hex
+	}
+	if depth == colorDepth256 {
+		return strconv.Itoa(nearestInPalette(c, ansi256Palette))
+	}
+	return strconv.Itoa(nearestInPalette(c, ansi16Palette))
+}
+
+// themeConfigDir returns $XDG_CONFIG_HOME/workbench/themes (os.UserConfigDir
+// already honors XDG_CONFIG_HOME on Linux and falls back to the platform
+// default elsewhere), where a user's *.toml theme files live.
+func themeConfigDir() string {
+	base, err := os.UserConfigDir()
+	if err != nil || strings.TrimSpace(base) == "" {
+		base = "."
+	}
+	return filepath.Join(base, "workbench", "themes")
+}
+
+// loadUserThemeSpecs reads every *.toml file in dir into a themeSpec keyed
+// by its Name field (falling back to the filename stem if Name is blank),
+// skipping files that don't parse rather than failing startup over one bad
+// theme.
+func loadUserThemeSpecs(dir string) map[string]themeSpec {
+	specs := map[string]themeSpec{}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return specs
+	}
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".toml") {
+			continue
+		}
+		var spec themeSpec
+		if _, err := toml.DecodeFile(filepath.Join(dir, e.Name()), &spec); err != nil {
+			continue
+		}
+		name := strings.TrimSpace(spec.Name)
+		if name == "" {
+			name = strings.TrimSuffix(e.Name(), ".toml")
+		}
+		specs[name] = spec
+	}
+	return specs
+}
+
+// availableThemeSpecs merges builtinThemeSpecs with the user's
+// themeConfigDir() themes, the latter taking precedence so a user theme
+// can override "default-dark"/"default-light" by name.
+func availableThemeSpecs() map[string]themeSpec {
+	specs := make(map[string]themeSpec, len(builtinThemeSpecs))
+	for name, spec := range builtinThemeSpecs {
+		specs[name] = spec
+	}
+	for name, spec := range loadUserThemeSpecs(themeConfigDir()) {
+		specs[name] = spec
+	}
+	return specs
+}
+
+// themeNames lists availableThemeSpecs' keys, sorted, for the "/theme"
+// command's no-argument listing.
+func themeNames() []string {
+	specs := availableThemeSpecs()
+	names := make([]string, 0, len(specs))
+	for name := range specs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// loadTheme resolves name (or, given "", auto-selects "default-dark"/
+// "default-light" from detectDarkBackground) against availableThemeSpecs
+// and builds it for the terminal's detectColorDepth, returning the
+// resolved name alongside the theme so callers can record which one is
+// active.
+func loadTheme(name string) (theme, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		name = "default-dark"
+		if !detectDarkBackground() {
+			name = "default-light"
+		}
+	}
+	spec, ok := availableThemeSpecs()[name]
+	if !ok {
+		return theme{}, "", fmt.Errorf("theme: unknown theme %q", name)
+	}
+	return buildTheme(spec, detectColorDepth()), name, nil
+}
+
+// themeChangedMsg is emitted by the "/theme" command's Run (and could be
+// emitted by a future config-file watcher) to apply a newly loaded theme
+// from appModel.Update, the same async-result-into-state-update shape as
+// chatReplyMsg and usageFetchedMsg.
+type themeChangedMsg struct {
+	Name  string
+	Theme theme
+}
@@ -3,7 +3,7 @@ package main
 import (
 	"crypto/tls"
 	"encoding/json"
-	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"os"
@@ -67,7 +67,7 @@ func saveCachedUsage(stateDir string, profile string, u *usageData) {
 
 func fetchUsage(accessToken string, accountID string) (*usageData, error) {
 	if accessToken == "" {
-		return nil, errors.New("missing access token")
+		return nil, newBusError(ErrAuthExpired, "missing access token")
 	}
 
 	client := &http.Client{
@@ -90,29 +90,37 @@ func fetchUsage(accessToken string, accountID string) (*usageData, error) {
 
 	resp, err := client.Do(req)
 	if err != nil {
-		return nil, err
+		return nil, wrapBusError(ErrIO, "usage request failed", err)
 	}
 	defer resp.Body.Close()
+	if resp.StatusCode == 401 || resp.StatusCode == 403 {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil, newBusError(ErrAuthExpired, fmt.Sprintf("usage endpoint returned %d", resp.StatusCode))
+	}
 	if resp.StatusCode != 200 {
 		_, _ = io.Copy(io.Discard, resp.Body)
-		return nil, errors.New("non-200 from usage endpoint")
+		return nil, newBusError(ErrIO, fmt.Sprintf("non-200 from usage endpoint (%d)", resp.StatusCode))
 	}
 
 	raw, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, err
+		return nil, wrapBusError(ErrIO, "reading usage response body", err)
 	}
 
 	var parsed map[string]any
 	if err := json.Unmarshal(raw, &parsed); err != nil {
-		return nil, err
+		return nil, wrapBusError(ErrCorruptLine, "decoding usage response", err)
 	}
 
-	u := parseUsageResponse(parsed)
-	return &u, nil
+	u, rateLimitErr := parseUsageResponse(parsed)
+	return &u, rateLimitErr
 }
 
-func parseUsageResponse(data map[string]any) usageData {
+// parseUsageResponse returns the parsed usageData and, when the upstream
+// reports its rate limit as reached, a *busError with Code ErrRateLimited
+// carrying the reset timestamp so callers can schedule a retry without
+// string-matching an error message.
+func parseUsageResponse(data map[string]any) (usageData, error) {
 	u := usageData{
 		FetchedAt:    time.Now().UnixMilli(),
 		PlanType:     asString(data["plan_type"]),
@@ -151,7 +159,14 @@ func parseUsageResponse(data map[string]any) usageData {
 		}
 	}
 
-	return u
+	if u.LimitReached {
+		var resetAtMs int64
+		if w, ok := findUsageWindow(&u, "5h"); ok {
+			resetAtMs = w.ResetAtMs
+		}
+		return u, &busError{Code: ErrRateLimited, Message: "rate_limit.limit_reached", ResetAtMs: resetAtMs}
+	}
+	return u, nil
 }
 
 func asString(v any) string {